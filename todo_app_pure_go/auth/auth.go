@@ -0,0 +1,70 @@
+// Package auth describes what an authenticated caller may do. Credentials
+// such as API keys carry scopes; the scopes of the request travel in its
+// context so that routes, GraphQL mutations and WebSocket messages can check
+// them.
+package auth
+
+import (
+	"context"
+	"fmt"
+)
+
+// APIKeyPrefix starts every API key, which tells them apart from other
+// bearer tokens such as session tokens.
+const APIKeyPrefix = "tk_"
+
+// Scopes an API key can be given.
+const (
+	ScopeTodosRead      = "todos:read"
+	ScopeTodosWrite     = "todos:write"
+	ScopeWebhooksRead   = "webhooks:read"
+	ScopeWebhooksWrite  = "webhooks:write"
+	ScopeKeysRead       = "keys:read"
+	ScopeKeysWrite      = "keys:write"
+	ScopeWorkspaceRead  = "workspace:read"
+	ScopeWorkspaceWrite = "workspace:write"
+)
+
+// Scopes lists every scope.
+var Scopes = []string{
+	ScopeTodosRead, ScopeTodosWrite,
+	ScopeWebhooksRead, ScopeWebhooksWrite,
+	ScopeKeysRead, ScopeKeysWrite,
+	ScopeWorkspaceRead, ScopeWorkspaceWrite,
+}
+
+type contextKey struct{}
+
+// WithScopes returns a context limited to the given scopes.
+func WithScopes(ctx context.Context, scopes []string) context.Context {
+	return context.WithValue(ctx, contextKey{}, scopes)
+}
+
+// ScopesFrom returns the scopes set by WithScopes. ok is false for contexts
+// that are not limited, such as those of anonymous requests.
+func ScopesFrom(ctx context.Context) (scopes []string, ok bool) {
+	scopes, ok = ctx.Value(contextKey{}).([]string)
+	return scopes, ok
+}
+
+// Allows reports whether ctx may use scope.
+func Allows(ctx context.Context, scope string) bool {
+	scopes, ok := ScopesFrom(ctx)
+	if !ok {
+		return true
+	}
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Require returns a "forbidden" error when ctx may not use scope.
+func Require(ctx context.Context, scope string) error {
+	if !Allows(ctx, scope) {
+		return fmt.Errorf("forbidden: API key lacks the %s scope", scope)
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+// Package client is a typed Go client for the /api/todos endpoints.
+//
+//	c, err := client.New("http://localhost:8080", client.WithToken(token))
+//	todos, err := c.List(ctx, client.ListOptions{Completed: &open})
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"todo-app/dto"
+	"todo-app/models"
+)
+
+const defaultTimeout = 30 * time.Second
+
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	token      string
+	userAgent  string
+}
+
+type Option func(*Client)
+
+// WithHTTPClient replaces the default HTTP client, which has a 30 second
+// timeout.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithToken sends token as a bearer token with every request.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// New creates a client for the API served at baseURL, such as
+// "http://localhost:8080".
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q: expected http(s)://host[:port]", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+		userAgent:  "todo-app-client",
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// APIError is returned for every non-2xx response.
+type APIError struct {
+	StatusCode int
+	Message    string
+	// Err is the short error name from the response envelope, such as
+	// "Not Found".
+	Err string
+	// RetryAfter is set for 429 responses.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("todo api: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("todo api: %d %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound reports whether err is an API error with status 404.
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+type ListOptions struct {
+	Completed *bool
+	// Priority filters by priority unless empty.
+	Priority models.Priority
+	// Limit defaults to 10 on the server and is capped at 100.
+	Limit  int
+	Offset int
+}
+
+type TodoList struct {
+	Todos []*dto.TodoResponse
+	Meta  dto.MetaData
+}
+
+func (c *Client) List(ctx context.Context, opts ListOptions) (*TodoList, error) {
+	query := url.Values{}
+	if opts.Completed != nil {
+		query.Set("completed", strconv.FormatBool(*opts.Completed))
+	}
+	if opts.Priority != "" {
+		query.Set("priority", string(opts.Priority))
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Offset > 0 {
+		query.Set("offset", strconv.Itoa(opts.Offset))
+	}
+
+	var resp struct {
+		Data []*dto.TodoResponse `json:"data"`
+		Meta dto.MetaData        `json:"meta"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/todos", query, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &TodoList{Todos: resp.Data, Meta: resp.Meta}, nil
+}
+
+func (c *Client) Get(ctx context.Context, id uint) (*dto.TodoResponse, error) {
+	var todo dto.TodoResponse
+	if err := c.do(ctx, http.MethodGet, todoPath(id), nil, nil, envelope(&todo)); err != nil {
+		return nil, err
+	}
+	return &todo, nil
+}
+
+func (c *Client) Create(ctx context.Context, req dto.CreateTodoRequest) (*dto.TodoResponse, error) {
+	var todo dto.TodoResponse
+	if err := c.do(ctx, http.MethodPost, "/api/todos", nil, req, envelope(&todo)); err != nil {
+		return nil, err
+	}
+	return &todo, nil
+}
+
+// Update changes the fields that are set in req.
+func (c *Client) Update(ctx context.Context, id uint, req dto.UpdateTodoRequest) (*dto.TodoResponse, error) {
+	var todo dto.TodoResponse
+	if err := c.do(ctx, http.MethodPut, todoPath(id), nil, req, envelope(&todo)); err != nil {
+		return nil, err
+	}
+	return &todo, nil
+}
+
+// Toggle flips the completion status of a todo.
+func (c *Client) Toggle(ctx context.Context, id uint) (*dto.TodoResponse, error) {
+	var todo dto.TodoResponse
+	if err := c.do(ctx, http.MethodPatch, todoPath(id)+"/toggle", nil, nil, envelope(&todo)); err != nil {
+		return nil, err
+	}
+	return &todo, nil
+}
+
+func (c *Client) Delete(ctx context.Context, id uint) error {
+	return c.do(ctx, http.MethodDelete, todoPath(id), nil, nil, nil)
+}
+
+func todoPath(id uint) string {
+	return "/api/todos/" + strconv.FormatUint(uint64(id), 10)
+}
+
+// envelope decodes the data field of a dto.APIResponse into v.
+func envelope(v interface{}) interface{} {
+	return &struct {
+		Data interface{} `json:"data"`
+	}{Data: v}
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	u := *c.baseURL
+	u.Path += path
+	u.RawQuery = query.Encode()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return decodeError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding %s %s response: %w", method, path, err)
+	}
+	return nil
+}
+
+func decodeError(resp *http.Response) error {
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+	var body dto.APIResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err == nil {
+		apiErr.Message = body.Message
+		apiErr.Err = body.Error
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return apiErr
+}
@@ -0,0 +1,128 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"todo-app/controller"
+	"todo-app/dto"
+	"todo-app/models"
+	"todo-app/repository"
+	"todo-app/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestClient(t *testing.T) *Client {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.Todo{}, &models.TodoChange{}, &models.TodoStatusChange{}, &models.TodoDependency{}, &models.Attachment{}, &models.Comment{}, &models.CommentRevision{}, &models.TodoGrant{}, &models.ListGrant{}); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	tc := controller.NewTodoController(service.NewTodoService(repository.NewTodoRepository(db), repository.NewAccessRepository(db), nil, nil, nil))
+	todos := router.Group("/api/todos")
+	todos.GET("", tc.GetAllTodos)
+	todos.POST("", tc.CreateTodo)
+	todos.GET("/:id", tc.GetTodoByID)
+	todos.PUT("/:id", tc.UpdateTodo)
+	todos.DELETE("/:id", tc.DeleteTodo)
+	todos.PATCH("/:id/toggle", tc.ToggleTodoComplete)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	c, err := New(server.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestClientLifecycle(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	created, err := c.Create(ctx, dto.CreateTodoRequest{Title: "Write report", Priority: models.HIGH})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ID == 0 || created.Title != "Write report" || created.Priority != models.HIGH {
+		t.Fatalf("unexpected created todo: %+v", created)
+	}
+	if _, err := c.Create(ctx, dto.CreateTodoRequest{Title: "Buy milk"}); err != nil {
+		t.Fatal(err)
+	}
+
+	title := "Write final report"
+	updated, err := c.Update(ctx, created.ID, dto.UpdateTodoRequest{Title: &title})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Title != title || updated.Priority != models.HIGH {
+		t.Errorf("update changed more than the title: %+v", updated)
+	}
+
+	toggled, err := c.Toggle(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !toggled.Completed {
+		t.Error("expected todo to be completed after toggle")
+	}
+
+	done := true
+	list, err := c.List(ctx, ListOptions{Completed: &done})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Todos) != 1 || list.Todos[0].ID != created.ID || list.Meta.Total != 1 {
+		t.Errorf("unexpected completed list: %+v", list)
+	}
+	list, err = c.List(ctx, ListOptions{Limit: 1, Offset: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Todos) != 1 || list.Meta.Total != 2 || list.Meta.Offset != 1 {
+		t.Errorf("unexpected page: %+v", list.Meta)
+	}
+
+	if err := c.Delete(ctx, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(ctx, created.ID); !IsNotFound(err) {
+		t.Errorf("expected not found after delete, got %v", err)
+	}
+}
+
+func TestClientNotFoundError(t *testing.T) {
+	c := newTestClient(t)
+
+	_, err := c.Toggle(context.Background(), 42)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected APIError, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusNotFound || apiErr.Message == "" || !IsNotFound(err) {
+		t.Errorf("unexpected error: %+v", apiErr)
+	}
+}
+
+func TestNewRejectsInvalidURL(t *testing.T) {
+	for _, raw := range []string{"", "localhost:8080", "ftp://example.com"} {
+		if _, err := New(raw); err == nil {
+			t.Errorf("expected error for %q", raw)
+		}
+	}
+}
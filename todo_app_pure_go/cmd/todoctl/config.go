@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+const defaultURL = "http://localhost:8080"
+
+// cliConfig is read from the config file and overridden by the
+// TODOCTL_URL/TODOCTL_TOKEN environment variables and the --url/--token
+// flags, in that order.
+type cliConfig struct {
+	URL   string `yaml:"url"`
+	Token string `yaml:"token"`
+}
+
+// defaultConfigPath is $XDG_CONFIG_HOME/todoctl/config.yaml or the platform
+// equivalent.
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "todoctl", "config.yaml")
+}
+
+// loadConfig reads path; a missing file is only an error when the path was
+// given explicitly.
+func loadConfig(path string, explicit bool) (*cliConfig, error) {
+	cfg := &cliConfig{URL: defaultURL}
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) && !explicit {
+			return cfg, nil
+		}
+		return nil, err
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
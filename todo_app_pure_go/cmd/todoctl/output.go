@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"todo-app/dto"
+
+	"gopkg.in/yaml.v3"
+)
+
+var outputFormats = []string{"table", "json", "yaml"}
+
+func validOutput(format string) error {
+	for _, f := range outputFormats {
+		if f == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid output format %q, expected one of: %s", format, strings.Join(outputFormats, ", "))
+}
+
+// printTodos writes todos in the given format. JSON and YAML use the API's
+// field names so output can be piped into jq or yq.
+func printTodos(w io.Writer, format string, todos []*dto.TodoResponse) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(todos)
+	case "yaml":
+		return printYAML(w, todos)
+	default:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tTITLE\tPRIORITY\tDONE\tCREATED")
+		for _, todo := range todos {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n",
+				todo.ID, truncate(todo.Title, 50), todo.Priority, doneMark(todo.Completed), todo.CreatedAt.Local().Format("2006-01-02 15:04"))
+		}
+		return tw.Flush()
+	}
+}
+
+func printTodo(w io.Writer, format string, todo *dto.TodoResponse) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(todo)
+	case "yaml":
+		return printYAML(w, todo)
+	default:
+		description := "-"
+		if todo.Description != nil {
+			description = *todo.Description
+		}
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "ID:\t%d\n", todo.ID)
+		fmt.Fprintf(tw, "Title:\t%s\n", todo.Title)
+		fmt.Fprintf(tw, "Description:\t%s\n", description)
+		fmt.Fprintf(tw, "Priority:\t%s\n", todo.Priority)
+		fmt.Fprintf(tw, "Completed:\t%s\n", strconv.FormatBool(todo.Completed))
+		fmt.Fprintf(tw, "Created:\t%s\n", todo.CreatedAt.Local().Format(time.RFC3339))
+		fmt.Fprintf(tw, "Updated:\t%s\n", todo.UpdatedAt.Local().Format(time.RFC3339))
+		return tw.Flush()
+	}
+}
+
+// printYAML round-trips v through JSON so YAML keys match the API's JSON
+// field names.
+func printYAML(w io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return err
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(generic); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+func doneMark(completed bool) string {
+	if completed {
+		return "yes"
+	}
+	return "no"
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"todo-app/client"
+	"todo-app/dto"
+	"todo-app/models"
+
+	"github.com/spf13/cobra"
+)
+
+var priorities = []string{string(models.LOW), string(models.MEDIUM), string(models.HIGH)}
+
+func (a *app) newAddCmd() *cobra.Command {
+	var description, priority string
+
+	cmd := &cobra.Command{
+		Use:   "add TITLE...",
+		Short: "Create a todo",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := a.client(cmd)
+			if err != nil {
+				return err
+			}
+
+			req := dto.CreateTodoRequest{
+				Title:    strings.Join(args, " "),
+				Priority: models.Priority(strings.ToUpper(priority)),
+			}
+			if cmd.Flags().Changed("description") {
+				req.Description = &description
+			}
+
+			todo, err := c.Create(cmd.Context(), req)
+			if err != nil {
+				return err
+			}
+			return printTodo(cmd.OutOrStdout(), a.output, todo)
+		},
+	}
+	cmd.Flags().StringVarP(&description, "description", "d", "", "todo description")
+	cmd.Flags().StringVarP(&priority, "priority", "p", "", "LOW, MEDIUM or HIGH (default MEDIUM)")
+	cmd.RegisterFlagCompletionFunc("priority", fixedCompletion(priorities...))
+	return cmd
+}
+
+func (a *app) newListCmd() *cobra.Command {
+	var opts client.ListOptions
+	var completed bool
+	var priority string
+
+	cmd := &cobra.Command{
+		Use:     "ls",
+		Aliases: []string{"list"},
+		Short:   "List todos",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := a.client(cmd)
+			if err != nil {
+				return err
+			}
+
+			if cmd.Flags().Changed("completed") {
+				opts.Completed = &completed
+			}
+			opts.Priority = models.Priority(strings.ToUpper(priority))
+
+			list, err := c.List(cmd.Context(), opts)
+			if err != nil {
+				return err
+			}
+			if err := printTodos(cmd.OutOrStdout(), a.output, list.Todos); err != nil {
+				return err
+			}
+			if a.output == "table" && list.Meta.Total > int64(len(list.Todos)) {
+				fmt.Fprintf(cmd.ErrOrStderr(), "showing %d-%d of %d, use --offset for more\n",
+					list.Meta.Offset+1, list.Meta.Offset+len(list.Todos), list.Meta.Total)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&completed, "completed", false, "only completed (or, with =false, open) todos")
+	cmd.Flags().StringVarP(&priority, "priority", "p", "", "only todos with this priority")
+	cmd.Flags().IntVarP(&opts.Limit, "limit", "n", 0, "number of todos to show (server default 10, max 100)")
+	cmd.Flags().IntVar(&opts.Offset, "offset", 0, "number of todos to skip")
+	cmd.RegisterFlagCompletionFunc("priority", fixedCompletion(priorities...))
+	return cmd
+}
+
+func (a *app) newShowCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "show ID",
+		Short:             "Show a todo",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: a.completeIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0])
+			if err != nil {
+				return err
+			}
+			c, err := a.client(cmd)
+			if err != nil {
+				return err
+			}
+
+			todo, err := c.Get(cmd.Context(), id)
+			if err != nil {
+				return err
+			}
+			return printTodo(cmd.OutOrStdout(), a.output, todo)
+		},
+	}
+}
+
+func (a *app) newEditCmd() *cobra.Command {
+	var title, description, priority string
+	var completed bool
+
+	cmd := &cobra.Command{
+		Use:               "edit ID",
+		Short:             "Change fields of a todo",
+		Long:              "Change fields of a todo. Only the flags that are given are updated.",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: a.completeIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0])
+			if err != nil {
+				return err
+			}
+
+			var req dto.UpdateTodoRequest
+			flags := cmd.Flags()
+			if flags.Changed("title") {
+				req.Title = &title
+			}
+			if flags.Changed("description") {
+				req.Description = &description
+			}
+			if flags.Changed("priority") {
+				p := models.Priority(strings.ToUpper(priority))
+				req.Priority = &p
+			}
+			if flags.Changed("completed") {
+				req.Completed = &completed
+			}
+			if req == (dto.UpdateTodoRequest{}) {
+				return fmt.Errorf("nothing to change, pass at least one of --title, --description, --priority, --completed")
+			}
+
+			c, err := a.client(cmd)
+			if err != nil {
+				return err
+			}
+			todo, err := c.Update(cmd.Context(), id, req)
+			if err != nil {
+				return err
+			}
+			return printTodo(cmd.OutOrStdout(), a.output, todo)
+		},
+	}
+	cmd.Flags().StringVarP(&title, "title", "t", "", "new title")
+	cmd.Flags().StringVarP(&description, "description", "d", "", "new description")
+	cmd.Flags().StringVarP(&priority, "priority", "p", "", "new priority")
+	cmd.Flags().BoolVar(&completed, "completed", false, "mark completed (or, with =false, open)")
+	cmd.RegisterFlagCompletionFunc("priority", fixedCompletion(priorities...))
+	return cmd
+}
+
+func (a *app) newDoneCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "done ID...",
+		Short:             "Mark todos as completed",
+		Long:              "Mark todos as completed through the toggle route. Todos that are already completed are left as they are.",
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: a.completeIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ids, err := parseIDs(args)
+			if err != nil {
+				return err
+			}
+			c, err := a.client(cmd)
+			if err != nil {
+				return err
+			}
+
+			var todos []*dto.TodoResponse
+			for _, id := range ids {
+				todo, err := c.Get(cmd.Context(), id)
+				if err == nil && !todo.Completed {
+					todo, err = c.Toggle(cmd.Context(), id)
+				}
+				if err != nil {
+					return fmt.Errorf("todo %d: %w", id, err)
+				}
+				todos = append(todos, todo)
+			}
+			return printTodos(cmd.OutOrStdout(), a.output, todos)
+		},
+	}
+}
+
+func (a *app) newRemoveCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "rm ID...",
+		Aliases:           []string{"delete"},
+		Short:             "Delete todos",
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: a.completeIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ids, err := parseIDs(args)
+			if err != nil {
+				return err
+			}
+			c, err := a.client(cmd)
+			if err != nil {
+				return err
+			}
+
+			for _, id := range ids {
+				if err := c.Delete(cmd.Context(), id); err != nil {
+					return fmt.Errorf("todo %d: %w", id, err)
+				}
+				fmt.Fprintf(cmd.ErrOrStderr(), "deleted todo %d\n", id)
+			}
+			return nil
+		},
+	}
+}
+
+// completeIDs offers the IDs of open todos, with their titles as
+// descriptions, for shell completion.
+func (a *app) completeIDs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	c, err := a.client(cmd)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	open := false
+	list, err := c.List(cmd.Context(), client.ListOptions{Completed: &open, Limit: 100})
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	var ids []string
+	for _, todo := range list.Todos {
+		id := strconv.FormatUint(uint64(todo.ID), 10)
+		if strings.HasPrefix(id, toComplete) {
+			ids = append(ids, id+"\t"+todo.Title)
+		}
+	}
+	return ids, cobra.ShellCompDirectiveNoFileComp
+}
+
+func parseID(arg string) (uint, error) {
+	id, err := strconv.ParseUint(arg, 10, 32)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("invalid todo ID %q", arg)
+	}
+	return uint(id), nil
+}
+
+func parseIDs(args []string) ([]uint, error) {
+	ids := make([]uint, len(args))
+	for i, arg := range args {
+		id, err := parseID(arg)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
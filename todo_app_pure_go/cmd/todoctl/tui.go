@@ -0,0 +1,28 @@
+package main
+
+import (
+	"time"
+
+	"todo-app/tui"
+
+	"github.com/spf13/cobra"
+)
+
+func (a *app) newTUICmd() *cobra.Command {
+	var refresh time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "tui",
+		Short: "Open the interactive terminal UI",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := a.client(cmd)
+			if err != nil {
+				return err
+			}
+			return tui.Run(c, tui.Options{RefreshInterval: refresh})
+		},
+	}
+	cmd.Flags().DurationVar(&refresh, "refresh", 5*time.Second, "reload interval for live updates, 0 to disable")
+	return cmd
+}
@@ -0,0 +1,639 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"todo-app/workflow"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the complete application configuration.
+//
+// Values are resolved from, in increasing order of precedence: built-in
+// defaults, a YAML or TOML file (-config flag or CONFIG_FILE), environment
+// variables and command-line flags. Each field declares its file key, env var
+// and flag name through struct tags; fields tagged secret are redacted when
+// the configuration is printed.
+type Config struct {
+	Server      ServerConfig     `config:"server"`
+	Database    DatabaseConfig   `config:"database"`
+	Pool        PoolConfig       `config:"pool"`
+	Log         LogConfig        `config:"log"`
+	CORS        CORSConfig       `config:"cors"`
+	Webhooks    WebhookConfig    `config:"webhooks"`
+	Stream      StreamConfig     `config:"stream"`
+	GraphQL     GraphQLConfig    `config:"graphql"`
+	GRPC        GRPCConfig       `config:"grpc"`
+	Sync        SyncConfig       `config:"sync"`
+	Cache       CacheConfig      `config:"cache"`
+	Attachments AttachmentConfig `config:"attachments"`
+	Tenancy     TenancyConfig    `config:"tenancy"`
+	OIDC        OIDCConfig       `config:"oidc"`
+	Workflow    WorkflowConfig   `config:"workflow"`
+}
+
+type ServerConfig struct {
+	Port            string        `config:"port" env:"PORT" flag:"port" usage:"HTTP listen port"`
+	PublicURL       string        `config:"public_url" env:"PUBLIC_URL" flag:"public-url" usage:"externally visible base URL used in generated links (default: derived from the request)"`
+	ReadTimeout     time.Duration `config:"read_timeout" env:"SERVER_READ_TIMEOUT" flag:"read-timeout" usage:"maximum duration for reading a request"`
+	WriteTimeout    time.Duration `config:"write_timeout" env:"SERVER_WRITE_TIMEOUT" flag:"write-timeout" usage:"maximum duration for writing a response"`
+	IdleTimeout     time.Duration `config:"idle_timeout" env:"SERVER_IDLE_TIMEOUT" flag:"idle-timeout" usage:"keep-alive idle timeout"`
+	ShutdownTimeout time.Duration `config:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT" flag:"shutdown-timeout" usage:"grace period for in-flight requests on shutdown"`
+	// ShutdownDelay keeps serving after readiness fails on shutdown, until
+	// load balancers have noticed and stopped routing new requests here
+	ShutdownDelay time.Duration `config:"shutdown_delay" env:"SERVER_SHUTDOWN_DELAY" flag:"shutdown-delay" usage:"time between failing readiness and closing the listener on shutdown"`
+	// TrustedProxies are the proxies whose X-Forwarded-For and X-Real-IP
+	// headers are believed. Empty means clients are identified by the
+	// connection's address alone.
+	TrustedProxies []string `config:"trusted_proxies" env:"SERVER_TRUSTED_PROXIES" flag:"trusted-proxies" usage:"comma-separated IP addresses or CIDR ranges of reverse proxies allowed to set the client IP"`
+}
+
+type DatabaseConfig struct {
+	Host     string `config:"host" env:"DB_HOST" flag:"db-host" usage:"database host"`
+	Port     string `config:"port" env:"DB_PORT" flag:"db-port" usage:"database port"`
+	User     string `config:"user" env:"DB_USER" flag:"db-user" usage:"database user"`
+	Password string `config:"password" env:"DB_PASSWORD" flag:"db-password" usage:"database password" secret:"true"`
+	DBName   string `config:"name" env:"DB_NAME" flag:"db-name" usage:"database name"`
+	SSLMode  string `config:"sslmode" env:"DB_SSLMODE" flag:"db-sslmode" usage:"postgres sslmode"`
+}
+
+type PoolConfig struct {
+	MaxIdleConns    int           `config:"max_idle_conns" env:"DB_MAX_IDLE_CONNS" flag:"db-max-idle-conns" usage:"maximum idle connections"`
+	MaxOpenConns    int           `config:"max_open_conns" env:"DB_MAX_OPEN_CONNS" flag:"db-max-open-conns" usage:"maximum open connections"`
+	ConnMaxLifetime time.Duration `config:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME" flag:"db-conn-max-lifetime" usage:"maximum connection lifetime"`
+}
+
+type LogConfig struct {
+	Level     string `config:"level" env:"LOG_LEVEL" flag:"log-level" usage:"SQL log level: silent, error, warn or info"`
+	AccessLog bool   `config:"access_log" env:"LOG_ACCESS" flag:"access-log" usage:"log every HTTP request"`
+}
+
+type CORSConfig struct {
+	AllowedOrigins   []string      `config:"allowed_origins" env:"CORS_ALLOWED_ORIGINS" flag:"cors-allowed-origins" usage:"comma-separated allowed origins, * for any"`
+	AllowCredentials bool          `config:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS" flag:"cors-allow-credentials" usage:"allow credentialed requests (ignored for *)"`
+	MaxAge           time.Duration `config:"max_age" env:"CORS_MAX_AGE" flag:"cors-max-age" usage:"how long browsers may cache preflight responses"`
+}
+
+type WebhookConfig struct {
+	Workers             int           `config:"workers" env:"WEBHOOK_WORKERS" flag:"webhook-workers" usage:"concurrent webhook deliveries"`
+	MaxAttempts         int           `config:"max_attempts" env:"WEBHOOK_MAX_ATTEMPTS" flag:"webhook-max-attempts" usage:"attempts before a delivery is marked failed"`
+	BaseDelay           time.Duration `config:"base_delay" env:"WEBHOOK_BASE_DELAY" flag:"webhook-base-delay" usage:"delay before the first retry, doubled for each further retry"`
+	MaxDelay            time.Duration `config:"max_delay" env:"WEBHOOK_MAX_DELAY" flag:"webhook-max-delay" usage:"upper bound for the retry delay"`
+	Timeout             time.Duration `config:"timeout" env:"WEBHOOK_TIMEOUT" flag:"webhook-timeout" usage:"timeout for a single delivery request"`
+	PollInterval        time.Duration `config:"poll_interval" env:"WEBHOOK_POLL_INTERVAL" flag:"webhook-poll-interval" usage:"how often due retries are picked up"`
+	AllowPrivateTargets bool          `config:"allow_private_targets" env:"WEBHOOK_ALLOW_PRIVATE_TARGETS" flag:"webhook-allow-private-targets" usage:"allow webhook URLs on loopback, private and link-local addresses"`
+}
+
+type StreamConfig struct {
+	ReplayBuffer      int           `config:"replay_buffer" env:"STREAM_REPLAY_BUFFER" flag:"stream-replay-buffer" usage:"number of recent events kept for Last-Event-ID resumption"`
+	HeartbeatInterval time.Duration `config:"heartbeat_interval" env:"STREAM_HEARTBEAT_INTERVAL" flag:"stream-heartbeat-interval" usage:"how often idle event streams send a keep-alive comment"`
+}
+
+type GraphQLConfig struct {
+	MaxComplexity int  `config:"max_complexity" env:"GRAPHQL_MAX_COMPLEXITY" flag:"graphql-max-complexity" usage:"maximum estimated cost of a GraphQL operation"`
+	MaxDepth      int  `config:"max_depth" env:"GRAPHQL_MAX_DEPTH" flag:"graphql-max-depth" usage:"maximum selection depth of a GraphQL operation"`
+	MaxBatch      int  `config:"max_batch" env:"GRAPHQL_MAX_BATCH" flag:"graphql-max-batch" usage:"maximum number of operations in a batched GraphQL request"`
+	GraphiQL      bool `config:"graphiql" env:"GRAPHQL_GRAPHIQL" flag:"graphiql" usage:"serve the GraphiQL IDE to browsers opening /graphql; for development only"`
+}
+
+type GRPCConfig struct {
+	Enabled bool   `config:"enabled" env:"GRPC_ENABLED" flag:"grpc" usage:"serve the gRPC API on its own port"`
+	Port    string `config:"port" env:"GRPC_PORT" flag:"grpc-port" usage:"port of the gRPC server"`
+}
+
+type SyncConfig struct {
+	TombstoneTTL time.Duration `config:"tombstone_ttl" env:"SYNC_TOMBSTONE_TTL" flag:"sync-tombstone-ttl" usage:"how long deletions are kept for delta sync; older sync tokens need a full sync"`
+	MaxMutations int           `config:"max_mutations" env:"SYNC_MAX_MUTATIONS" flag:"sync-max-mutations" usage:"maximum number of client mutations in one sync request"`
+}
+
+type CacheConfig struct {
+	Enabled bool          `config:"enabled" env:"CACHE_ENABLED" flag:"cache" usage:"cache todo lookups, lists and counts in memory"`
+	Size    int           `config:"size" env:"CACHE_SIZE" flag:"cache-size" usage:"maximum number of cached todo lookups, lists and counts"`
+	TTL     time.Duration `config:"ttl" env:"CACHE_TTL" flag:"cache-ttl" usage:"how long a cached entry is served"`
+}
+
+type AttachmentConfig struct {
+	Dir          string   `config:"dir" env:"ATTACHMENTS_DIR" flag:"attachments-dir" usage:"directory attachment content is stored in"`
+	MaxSize      int      `config:"max_size" env:"ATTACHMENTS_MAX_SIZE" flag:"attachments-max-size" usage:"largest accepted attachment in bytes"`
+	AllowedTypes []string `config:"allowed_types" env:"ATTACHMENTS_ALLOWED_TYPES" flag:"attachments-allowed-types" usage:"comma-separated media types accepted as attachments, detected from the content"`
+}
+
+// TenancyConfig lists the workspaces and how requests find theirs: the
+// workspace claim in the caller's token, or DefaultWorkspace for anonymous
+// requests. The Header or a subdomain of BaseDomain may only name the same
+// workspace.
+type TenancyConfig struct {
+	Workspaces       []string `config:"workspaces" env:"TENANCY_WORKSPACES" flag:"workspaces" usage:"comma-separated slugs of the workspaces, created at startup; data from before workspaces existed goes to the first"`
+	DefaultWorkspace string   `config:"default_workspace" env:"TENANCY_DEFAULT_WORKSPACE" flag:"default-workspace" usage:"workspace of anonymous requests; empty to require authentication"`
+	Header           string   `config:"header" env:"TENANCY_HEADER" flag:"workspace-header" usage:"request header naming the workspace"`
+	BaseDomain       string   `config:"base_domain" env:"TENANCY_BASE_DOMAIN" flag:"workspace-base-domain" usage:"domain whose subdomains name workspaces, e.g. todo.example.com for acme.todo.example.com (default: subdomains are not used)"`
+}
+
+// OIDCConfig enables single sign-on through an OpenID Connect provider;
+// it is off while Issuer is empty. Signed-in users get session tokens
+// signed with SessionSecret.
+type OIDCConfig struct {
+	Issuer        string        `config:"issuer" env:"OIDC_ISSUER" flag:"oidc-issuer" usage:"issuer URL of the OpenID Connect provider (default: single sign-on is off)"`
+	ClientID      string        `config:"client_id" env:"OIDC_CLIENT_ID" flag:"oidc-client-id" usage:"client ID registered with the provider"`
+	ClientSecret  string        `config:"client_secret" env:"OIDC_CLIENT_SECRET" flag:"oidc-client-secret" usage:"client secret registered with the provider; empty for a public client" secret:"true"`
+	RedirectURL   string        `config:"redirect_url" env:"OIDC_REDIRECT_URL" flag:"oidc-redirect-url" usage:"callback URL registered with the provider (default: server.public_url + /auth/callback)"`
+	Scopes        []string      `config:"scopes" env:"OIDC_SCOPES" flag:"oidc-scopes" usage:"comma-separated scopes requested from the provider"`
+	SessionSecret string        `config:"session_secret" env:"OIDC_SESSION_SECRET" flag:"oidc-session-secret" usage:"key of at least 32 bytes that signs session tokens" secret:"true"`
+	SessionTTL    time.Duration `config:"session_ttl" env:"OIDC_SESSION_TTL" flag:"oidc-session-ttl" usage:"how long a session token is valid"`
+}
+
+// Enabled reports whether single sign-on is configured.
+func (c OIDCConfig) Enabled() bool {
+	return c.Issuer != ""
+}
+
+// OIDCRedirectURL returns the URL the provider sends users back to after
+// they sign in.
+func (c *Config) OIDCRedirectURL() string {
+	if c.OIDC.RedirectURL != "" {
+		return c.OIDC.RedirectURL
+	}
+	return strings.TrimSuffix(c.Server.PublicURL, "/") + "/auth/callback"
+}
+
+// WorkflowConfig defines the statuses todos move through. The first status
+// is the one new and reopened todos start in; completing a todo moves it to
+// the first done status.
+type WorkflowConfig struct {
+	Statuses    []string `config:"statuses" env:"WORKFLOW_STATUSES" flag:"workflow-statuses" usage:"comma-separated statuses in order"`
+	Done        []string `config:"done" env:"WORKFLOW_DONE" flag:"workflow-done" usage:"comma-separated statuses in which a todo counts as completed"`
+	Transitions []string `config:"transitions" env:"WORKFLOW_TRANSITIONS" flag:"workflow-transitions" usage:"comma-separated allowed moves written from->to, * for any status"`
+}
+
+// Workflow builds the configured workflow.
+func (c WorkflowConfig) Workflow() (*workflow.Workflow, error) {
+	return workflow.New(c.Statuses, c.Done, c.Transitions)
+}
+
+// Default returns the built-in configuration. It deliberately has no
+// database password.
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:            "8080",
+			ReadTimeout:     15 * time.Second,
+			WriteTimeout:    15 * time.Second,
+			IdleTimeout:     60 * time.Second,
+			ShutdownTimeout: 30 * time.Second,
+			ShutdownDelay:   5 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Host:    "localhost",
+			Port:    "5432",
+			User:    "postgres",
+			DBName:  "todoapp",
+			SSLMode: "disable",
+		},
+		Pool: PoolConfig{
+			MaxIdleConns:    10,
+			MaxOpenConns:    100,
+			ConnMaxLifetime: time.Hour,
+		},
+		Log: LogConfig{
+			Level:     "info",
+			AccessLog: true,
+		},
+		CORS: CORSConfig{
+			AllowedOrigins: []string{"*"},
+			MaxAge:         12 * time.Hour,
+		},
+		Webhooks: WebhookConfig{
+			Workers:      4,
+			MaxAttempts:  6,
+			BaseDelay:    30 * time.Second,
+			MaxDelay:     time.Hour,
+			Timeout:      10 * time.Second,
+			PollInterval: 5 * time.Second,
+		},
+		Stream: StreamConfig{
+			ReplayBuffer:      1000,
+			HeartbeatInterval: 15 * time.Second,
+		},
+		GraphQL: GraphQLConfig{
+			MaxComplexity: 1000,
+			MaxDepth:      10,
+			MaxBatch:      10,
+		},
+		GRPC: GRPCConfig{
+			Port: "9090",
+		},
+		Sync: SyncConfig{
+			TombstoneTTL: 30 * 24 * time.Hour,
+			MaxMutations: 500,
+		},
+		Cache: CacheConfig{
+			Size: 1000,
+			TTL:  30 * time.Second,
+		},
+		Attachments: AttachmentConfig{
+			Dir:          "data/attachments",
+			MaxSize:      10 << 20,
+			AllowedTypes: []string{"image/png", "image/jpeg", "image/gif", "image/webp", "application/pdf", "text/plain"},
+		},
+		Tenancy: TenancyConfig{
+			Workspaces:       []string{"default"},
+			DefaultWorkspace: "default",
+			Header:           "X-Workspace",
+		},
+		OIDC: OIDCConfig{
+			Scopes:     []string{"openid", "email", "profile"},
+			SessionTTL: 12 * time.Hour,
+		},
+		Workflow: WorkflowConfig{
+			Statuses:    workflow.DefaultStatuses,
+			Done:        workflow.DefaultDone,
+			Transitions: workflow.DefaultTransitions,
+		},
+	}
+}
+
+// Load resolves the configuration from defaults, file, environment and the
+// command-line arguments. Callers may register their own flags on fs before
+// calling Load; fs is parsed here.
+func Load(fs *flag.FlagSet, args []string) (*Config, error) {
+	cfg := Default()
+	fields := cfg.fields()
+
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML or TOML configuration file")
+	flagValues := make(map[string]*string, len(fields))
+	for _, f := range fields {
+		if f.flag != "" {
+			flagValues[f.flag] = fs.String(f.flag, "", f.usage+" (env "+f.env+")")
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	var errs []error
+
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile, fields); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, f := range fields {
+		if f.env == "" {
+			continue
+		}
+		if value, ok := os.LookupEnv(f.env); ok && value != "" {
+			if err := f.set(value); err != nil {
+				errs = append(errs, fmt.Errorf("env %s: %w", f.env, err))
+			}
+		}
+	}
+
+	fs.Visit(func(fl *flag.Flag) {
+		value, ok := flagValues[fl.Name]
+		if !ok {
+			return
+		}
+		for _, f := range fields {
+			if f.flag == fl.Name {
+				if err := f.set(*value); err != nil {
+					errs = append(errs, fmt.Errorf("flag -%s: %w", fl.Name, err))
+				}
+			}
+		}
+	})
+
+	errs = append(errs, cfg.Validate())
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string, fields []field) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	raw := make(map[string]interface{})
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return fmt.Errorf("unsupported config file type %q (use .yaml, .yml or .toml)", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	known := make(map[string]field, len(fields))
+	for _, f := range fields {
+		known[f.key] = f
+	}
+
+	values := flatten("", raw)
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, key := range keys {
+		value := values[key]
+		f, ok := known[key]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: unknown key %q", path, key))
+			continue
+		}
+		if err := f.set(value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s: %w", path, key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// flatten turns nested maps into dotted keys with string values; lists are
+// joined with commas so every source goes through the same parser.
+func flatten(prefix string, raw map[string]interface{}) map[string]string {
+	out := make(map[string]string)
+	for key, value := range raw {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			for k, val := range flatten(key, v) {
+				out[k] = val
+			}
+		case []interface{}:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			out[key] = strings.Join(items, ",")
+		default:
+			out[key] = fmt.Sprint(v)
+		}
+	}
+	return out
+}
+
+// Validate checks the whole configuration and reports every problem at once.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(validPort(c.Server.Port), "server.port: %q is not a valid port", c.Server.Port)
+	check(c.Server.PublicURL == "" || absoluteURL(c.Server.PublicURL),
+		"server.public_url: %q is not an absolute http(s) URL", c.Server.PublicURL)
+	check(c.Server.ReadTimeout > 0, "server.read_timeout must be positive")
+	check(c.Server.WriteTimeout > 0, "server.write_timeout must be positive")
+	check(c.Server.IdleTimeout > 0, "server.idle_timeout must be positive")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+	check(c.Server.ShutdownDelay >= 0, "server.shutdown_delay must not be negative")
+	for _, proxy := range c.Server.TrustedProxies {
+		check(validProxy(proxy), "server.trusted_proxies: %q is not an IP address or CIDR range", proxy)
+	}
+
+	check(c.Database.Host != "", "database.host is required")
+	check(validPort(c.Database.Port), "database.port: %q is not a valid port", c.Database.Port)
+	check(c.Database.User != "", "database.user is required")
+	check(c.Database.DBName != "", "database.name is required")
+	check(oneOf(c.Database.SSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full"),
+		"database.sslmode: %q is not a valid sslmode", c.Database.SSLMode)
+
+	check(c.Pool.MaxOpenConns > 0, "pool.max_open_conns must be positive")
+	check(c.Pool.MaxIdleConns >= 0, "pool.max_idle_conns must not be negative")
+	check(c.Pool.MaxIdleConns <= c.Pool.MaxOpenConns, "pool.max_idle_conns must not exceed pool.max_open_conns")
+	check(c.Pool.ConnMaxLifetime >= 0, "pool.conn_max_lifetime must not be negative")
+
+	check(oneOf(c.Log.Level, "silent", "error", "warn", "info"), "log.level: %q must be one of silent, error, warn, info", c.Log.Level)
+
+	check(len(c.CORS.AllowedOrigins) > 0, "cors.allowed_origins must not be empty")
+	for _, origin := range c.CORS.AllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && u.Path == "",
+			"cors.allowed_origins: %q is not an origin like https://example.com", origin)
+	}
+	check(c.CORS.MaxAge >= 0, "cors.max_age must not be negative")
+
+	check(c.Webhooks.Workers > 0, "webhooks.workers must be positive")
+	check(c.Webhooks.MaxAttempts > 0, "webhooks.max_attempts must be positive")
+	check(c.Webhooks.BaseDelay > 0, "webhooks.base_delay must be positive")
+	check(c.Webhooks.MaxDelay >= c.Webhooks.BaseDelay, "webhooks.max_delay must not be less than webhooks.base_delay")
+	check(c.Webhooks.Timeout > 0, "webhooks.timeout must be positive")
+	check(c.Webhooks.PollInterval > 0, "webhooks.poll_interval must be positive")
+	check(c.Stream.ReplayBuffer > 0, "stream.replay_buffer must be positive")
+	check(c.Stream.HeartbeatInterval > 0, "stream.heartbeat_interval must be positive")
+	check(c.GraphQL.MaxComplexity > 0, "graphql.max_complexity must be positive")
+	check(c.GraphQL.MaxDepth > 0, "graphql.max_depth must be positive")
+	check(c.GraphQL.MaxBatch > 0, "graphql.max_batch must be positive")
+	check(c.Sync.TombstoneTTL > 0, "sync.tombstone_ttl must be positive")
+	check(c.Sync.MaxMutations > 0, "sync.max_mutations must be positive")
+	if c.Cache.Enabled {
+		check(c.Cache.Size > 0, "cache.size must be positive")
+		check(c.Cache.TTL > 0, "cache.ttl must be positive")
+	}
+	check(c.Attachments.Dir != "", "attachments.dir is required")
+	check(c.Attachments.MaxSize > 0, "attachments.max_size must be positive")
+	check(len(c.Attachments.AllowedTypes) > 0, "attachments.allowed_types must not be empty")
+	for _, mediaType := range c.Attachments.AllowedTypes {
+		check(strings.Count(mediaType, "/") == 1 && !strings.ContainsAny(mediaType, "; "),
+			"attachments.allowed_types: %q is not a media type like image/png", mediaType)
+	}
+	if c.GRPC.Enabled {
+		check(validPort(c.GRPC.Port), "grpc.port: %q is not a valid port", c.GRPC.Port)
+		check(c.GRPC.Port != c.Server.Port, "grpc.port must differ from server.port")
+	}
+	check(len(c.Tenancy.Workspaces) > 0, "tenancy.workspaces must not be empty")
+	for _, slug := range c.Tenancy.Workspaces {
+		check(validSlug(slug), "tenancy.workspaces: %q is not a slug of lowercase letters, digits and inner hyphens", slug)
+	}
+	check(c.Tenancy.DefaultWorkspace == "" || oneOf(c.Tenancy.DefaultWorkspace, c.Tenancy.Workspaces...),
+		"tenancy.default_workspace: %q is not one of tenancy.workspaces", c.Tenancy.DefaultWorkspace)
+	check(c.Tenancy.Header != "", "tenancy.header is required")
+	check(c.Tenancy.BaseDomain == "" || !strings.ContainsAny(c.Tenancy.BaseDomain, ":/ "),
+		"tenancy.base_domain: %q is not a domain name", c.Tenancy.BaseDomain)
+	if c.OIDC.Enabled() {
+		check(absoluteURL(c.OIDC.Issuer), "oidc.issuer: %q is not an absolute http(s) URL", c.OIDC.Issuer)
+		check(c.OIDC.ClientID != "", "oidc.client_id is required")
+		check(c.OIDC.RedirectURL != "" || c.Server.PublicURL != "", "oidc.redirect_url or server.public_url is required")
+		check(c.OIDC.RedirectURL == "" || absoluteURL(c.OIDC.RedirectURL), "oidc.redirect_url: %q is not an absolute http(s) URL", c.OIDC.RedirectURL)
+		check(oneOf("openid", c.OIDC.Scopes...), "oidc.scopes must include openid")
+		check(len(c.OIDC.SessionSecret) >= 32, "oidc.session_secret must be at least 32 bytes")
+		check(c.OIDC.SessionTTL > 0, "oidc.session_ttl must be positive")
+	}
+	_, err := c.Workflow.Workflow()
+	check(err == nil, "workflow: %v", err)
+
+	return errors.Join(errs...)
+}
+
+// validSlug reports whether slug can name a workspace, including as a DNS
+// label.
+func validSlug(slug string) bool {
+	if slug == "" || len(slug) > 63 || slug[0] == '-' || slug[len(slug)-1] == '-' {
+		return false
+	}
+	for _, r := range slug {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// validProxy reports whether proxy is an IP address or a CIDR range.
+func validProxy(proxy string) bool {
+	if _, err := netip.ParsePrefix(proxy); err == nil {
+		return true
+	}
+	_, err := netip.ParseAddr(proxy)
+	return err == nil
+}
+
+// absoluteURL reports whether raw is an absolute http(s) URL.
+func absoluteURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n > 0 && n < 65536
+}
+
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
+
+// String renders the configuration as sorted key = value lines with secrets
+// redacted, suitable for logging.
+func (c *Config) String() string {
+	fields := c.fields()
+	sort.Slice(fields, func(i, j int) bool { return fields[i].key < fields[j].key })
+
+	var b strings.Builder
+	for _, f := range fields {
+		value := f.String()
+		if f.secret && value != "" {
+			value = "******"
+		}
+		fmt.Fprintf(&b, "%s = %s\n", f.key, value)
+	}
+	return b.String()
+}
+
+// field is one leaf setting of Config together with its source names.
+type field struct {
+	key    string
+	env    string
+	flag   string
+	usage  string
+	secret bool
+	value  reflect.Value
+}
+
+func (c *Config) fields() []field {
+	var fields []field
+	var walk func(prefix string, v reflect.Value)
+	walk = func(prefix string, v reflect.Value) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			key := sf.Tag.Get("config")
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			if sf.Type.Kind() == reflect.Struct && sf.Type != reflect.TypeOf(time.Duration(0)) {
+				walk(key, v.Field(i))
+				continue
+			}
+			fields = append(fields, field{
+				key:    key,
+				env:    sf.Tag.Get("env"),
+				flag:   sf.Tag.Get("flag"),
+				usage:  sf.Tag.Get("usage"),
+				secret: sf.Tag.Get("secret") == "true",
+				value:  v.Field(i),
+			})
+		}
+	}
+	walk("", reflect.ValueOf(c).Elem())
+	return fields
+}
+
+func (f field) set(raw string) error {
+	raw = strings.TrimSpace(raw)
+	switch f.value.Interface().(type) {
+	case string:
+		f.value.SetString(raw)
+	case int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", raw)
+		}
+		f.value.SetInt(int64(n))
+	case bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", raw)
+		}
+		f.value.SetBool(b)
+	case time.Duration:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration like 30s or 5m", raw)
+		}
+		f.value.SetInt(int64(d))
+	case []string:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		f.value.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", f.value.Type())
+	}
+	return nil
+}
+
+func (f field) String() string {
+	switch v := f.value.Interface().(type) {
+	case []string:
+		return strings.Join(v, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,208 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func load(t *testing.T, args ...string) (*Config, error) {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return Load(fs, args)
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := load(t)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Server.Port != "8080" || cfg.Pool.MaxOpenConns != 100 {
+		t.Errorf("unexpected defaults: %+v", cfg)
+	}
+	if cfg.Database.Password != "" {
+		t.Error("default configuration must not contain a password")
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, "app.yaml", `
+server:
+  port: 9000
+  read_timeout: 5s
+database:
+  host: file-host
+  user: file-user
+cors:
+  allowed_origins:
+    - https://a.example.com
+    - https://b.example.com
+`)
+	t.Setenv("DB_HOST", "env-host")
+	t.Setenv("PORT", "9100")
+
+	cfg, err := load(t, "-config", path, "-port", "9200")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.Server.Port != "9200" {
+		t.Errorf("flag should win over env and file, got port %s", cfg.Server.Port)
+	}
+	if cfg.Database.Host != "env-host" {
+		t.Errorf("env should win over file, got host %s", cfg.Database.Host)
+	}
+	if cfg.Database.User != "file-user" || cfg.Server.ReadTimeout != 5*time.Second {
+		t.Errorf("file values not applied: %+v", cfg)
+	}
+	if len(cfg.CORS.AllowedOrigins) != 2 || cfg.CORS.AllowedOrigins[1] != "https://b.example.com" {
+		t.Errorf("unexpected origins: %v", cfg.CORS.AllowedOrigins)
+	}
+}
+
+func TestLoadTOML(t *testing.T) {
+	path := writeFile(t, "app.toml", `
+[pool]
+max_open_conns = 20
+max_idle_conns = 5
+conn_max_lifetime = "30m"
+`)
+	t.Setenv("CONFIG_FILE", path)
+
+	cfg, err := load(t)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Pool.MaxOpenConns != 20 || cfg.Pool.MaxIdleConns != 5 || cfg.Pool.ConnMaxLifetime != 30*time.Minute {
+		t.Errorf("unexpected pool config: %+v", cfg.Pool)
+	}
+}
+
+func TestLoadAggregatesErrors(t *testing.T) {
+	path := writeFile(t, "app.yaml", `
+server:
+  prot: 1
+pool:
+  max_open_conns: many
+`)
+	t.Setenv("DB_SSLMODE", "sometimes")
+
+	_, err := load(t, "-config", path, "-port", "0", "-log-level", "loud")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{`unknown key "server.prot"`, "pool.max_open_conns", "server.port", "database.sslmode", "log.level"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error should mention %q:\n%v", want, err)
+		}
+	}
+
+	cfg := Default()
+	cfg.Server.Port = "0"
+	cfg.Database.SSLMode = "sometimes"
+	cfg.Log.Level = "loud"
+	cfg.Server.TrustedProxies = []string{"10.0.0.0/8", "proxy.internal"}
+	cfg.Server.ShutdownDelay = -time.Second
+	err = cfg.Validate()
+	if err == nil || strings.Count(err.Error(), "\n") != 4 || !strings.Contains(err.Error(), `"proxy.internal"`) || !strings.Contains(err.Error(), "server.shutdown_delay") {
+		t.Errorf("expected five validation errors, got:\n%v", err)
+	}
+}
+
+func TestLoadTenancy(t *testing.T) {
+	t.Setenv("TENANCY_WORKSPACES", "acme,globex")
+
+	cfg, err := load(t, "-default-workspace", "globex")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.Tenancy.Workspaces) != 2 || cfg.Tenancy.DefaultWorkspace != "globex" || cfg.Tenancy.Header != "X-Workspace" {
+		t.Errorf("unexpected tenancy config: %+v", cfg.Tenancy)
+	}
+
+	_, err = load(t, "-workspaces", "Acme,-x", "-default-workspace", "initech")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{`"Acme"`, `"-x"`, "tenancy.default_workspace"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error should mention %s:\n%v", want, err)
+		}
+	}
+}
+
+func TestLoadOIDC(t *testing.T) {
+	t.Setenv("OIDC_CLIENT_SECRET", "client-secret")
+	t.Setenv("OIDC_SESSION_SECRET", "0123456789abcdef0123456789abcdef")
+
+	cfg, err := load(t, "-oidc-issuer", "https://id.example.com", "-oidc-client-id", "todo-app", "-public-url", "https://todo.example.com/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.OIDC.Enabled() || cfg.OIDCRedirectURL() != "https://todo.example.com/auth/callback" || cfg.OIDC.SessionTTL != 12*time.Hour {
+		t.Errorf("unexpected oidc config: %+v", cfg.OIDC)
+	}
+	if out := cfg.String(); strings.Contains(out, "client-secret") || strings.Contains(out, "0123456789abcdef") {
+		t.Errorf("oidc secrets leaked:\n%s", out)
+	}
+
+	t.Setenv("OIDC_SESSION_SECRET", "short")
+	_, err = load(t, "-oidc-issuer", "id.example.com", "-oidc-scopes", "email")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{"oidc.issuer", "oidc.client_id", "oidc.redirect_url", "oidc.scopes", "oidc.session_secret"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error should mention %s:\n%v", want, err)
+		}
+	}
+}
+
+func TestLoadWorkflow(t *testing.T) {
+	t.Setenv("WORKFLOW_STATUSES", "todo,doing,done")
+	cfg, err := load(t, "-workflow-transitions", "todo->doing,doing->done,*->todo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wf, err := cfg.Workflow.Workflow()
+	if err != nil || wf.Initial() != "todo" || wf.Done() != "done" || !wf.Allows("done", "todo") || wf.Allows("todo", "done") {
+		t.Errorf("unexpected workflow from %+v: %v", cfg.Workflow, err)
+	}
+
+	// The default transitions name statuses that no longer exist
+	t.Setenv("WORKFLOW_STATUSES", "open,closed")
+	_, err = load(t)
+	if err == nil || !strings.Contains(err.Error(), "workflow:") {
+		t.Errorf("expected a workflow error, got %v", err)
+	}
+}
+
+func TestStringRedactsSecrets(t *testing.T) {
+	cfg := Default()
+	cfg.Database.Password = "hunter2"
+
+	out := cfg.String()
+	if strings.Contains(out, "hunter2") {
+		t.Errorf("password leaked:\n%s", out)
+	}
+	if !strings.Contains(out, "database.password = ******") {
+		t.Errorf("expected redacted password:\n%s", out)
+	}
+	if !strings.Contains(out, "server.port = 8080") {
+		t.Errorf("expected port in output:\n%s", out)
+	}
+}
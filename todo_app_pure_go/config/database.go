@@ -1,57 +1,49 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"log"
-	"os"
-	"time"
 
 	"todo-app/models"
+	"todo-app/tenant"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
-type DatabaseConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	DBName   string
-	SSLMode  string
+// migratedModels lists every model kept in sync by AutoMigrate.
+var migratedModels = []interface{}{
+	&models.Workspace{},
+	&models.Todo{},
+	&models.TodoChange{},
+	&models.TodoStatusChange{},
+	&models.TodoDependency{},
+	&models.Attachment{},
+	&models.Comment{},
+	&models.CommentRevision{},
+	&models.TodoGrant{},
+	&models.ListGrant{},
+	&models.CalendarFeed{},
+	&models.APIKey{},
+	&models.User{},
+	&models.Webhook{},
+	&models.WebhookDelivery{},
 }
 
-func LoadDatabaseConfig() *DatabaseConfig {
-	return &DatabaseConfig{
-		Host:     getEnv("DB_HOST", "localhost"),
-		Port:     getEnv("DB_PORT", "5432"),
-		User:     getEnv("DB_USER", "postgres"),
-		Password: getEnv("DB_PASSWORD", "password"),
-		DBName:   getEnv("DB_NAME", "todoapp"),
-		SSLMode:  getEnv("DB_SSLMODE", "disable"),
-	}
-}
-
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
-}
-
-func ConnectDatabase(config *DatabaseConfig) (*gorm.DB, error) {
+func ConnectDatabase(cfg *Config) (*gorm.DB, error) {
 	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s TimeZone=UTC",
-		config.Host,
-		config.User,
-		config.Password,
-		config.DBName,
-		config.Port,
-		config.SSLMode,
+		cfg.Database.Host,
+		cfg.Database.User,
+		cfg.Database.Password,
+		cfg.Database.DBName,
+		cfg.Database.Port,
+		cfg.Database.SSLMode,
 	)
 
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger: logger.Default.LogMode(logLevel(cfg.Log.Level)),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
@@ -64,16 +56,95 @@ func ConnectDatabase(config *DatabaseConfig) (*gorm.DB, error) {
 	}
 
 	// Set connection pool settings
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetMaxOpenConns(100)
-	sqlDB.SetConnMaxLifetime(time.Hour)
+	sqlDB.SetMaxIdleConns(cfg.Pool.MaxIdleConns)
+	sqlDB.SetMaxOpenConns(cfg.Pool.MaxOpenConns)
+	sqlDB.SetConnMaxLifetime(cfg.Pool.ConnMaxLifetime)
+
+	// Limit every statement on workspace-owned tables to one workspace
+	if err := db.Use(tenant.Plugin{}); err != nil {
+		return nil, fmt.Errorf("failed to register tenant scoping: %w", err)
+	}
 
 	// Auto migrate models
-	err = db.AutoMigrate(&models.Todo{})
+	err = db.AutoMigrate(migratedModels...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to auto migrate models: %w", err)
 	}
+	if err := dropObsoleteIndexes(db); err != nil {
+		return nil, fmt.Errorf("failed to drop obsolete indexes: %w", err)
+	}
 
 	log.Println("Database connected and migrated successfully")
 	return db, nil
 }
+
+// obsoleteIndexes are unique indexes that became unique per workspace.
+var obsoleteIndexes = []struct {
+	model interface{}
+	name  string
+}{
+	{&models.Todo{}, "idx_todos_client_id"},
+	{&models.ListGrant{}, "idx_list_grants_owner_principal"},
+}
+
+func dropObsoleteIndexes(db *gorm.DB) error {
+	migrator := db.Migrator()
+	for _, index := range obsoleteIndexes {
+		if !migrator.HasIndex(index.model, index.name) {
+			continue
+		}
+		if err := migrator.DropIndex(index.model, index.name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClaimOrphans moves rows created before workspaces existed, which have no
+// workspace, into the workspace with the given ID.
+func ClaimOrphans(db *gorm.DB, workspaceID uint) (int64, error) {
+	var claimed int64
+	for _, model := range migratedModels {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return claimed, err
+		}
+		field := stmt.Schema.LookUpField(tenant.Field)
+		if field == nil {
+			continue
+		}
+		result := db.Exec(fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s = 0",
+			stmt.Quote(stmt.Table), stmt.Quote(field.DBName), stmt.Quote(field.DBName)), workspaceID)
+		if result.Error != nil {
+			return claimed, result.Error
+		}
+		claimed += result.RowsAffected
+	}
+	return claimed, nil
+}
+
+func logLevel(level string) logger.LogLevel {
+	switch level {
+	case "silent":
+		return logger.Silent
+	case "error":
+		return logger.Error
+	case "warn":
+		return logger.Warn
+	default:
+		return logger.Info
+	}
+}
+
+// MigrationCheck reports whether the tables of all migrated models exist.
+func MigrationCheck(db *gorm.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		migrator := db.WithContext(ctx).Migrator()
+		for _, model := range migratedModels {
+			if !migrator.HasTable(model) {
+				return fmt.Errorf("table for %T is missing", model)
+			}
+		}
+		return nil
+	}
+}
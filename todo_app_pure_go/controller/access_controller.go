@@ -0,0 +1,189 @@
+package controller
+
+import (
+	"strings"
+	"todo-app/dto"
+	"todo-app/middleware"
+	"todo-app/service"
+	"todo-app/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AccessController struct {
+	accessService service.AccessService
+}
+
+func NewAccessController(accessService service.AccessService) *AccessController {
+	return &AccessController{
+		accessService: accessService,
+	}
+}
+
+// GetTodoAccess godoc
+// @Summary List who can access a todo
+// @Description The todo's owner, the caller's own role and the grants on the todo. Shares of the owner's whole list are not included.
+// @Tags access
+// @Produce json
+// @Param id path int true "Todo ID"
+// @Success 200 {object} dto.APIResponse{data=dto.TodoAccessResponse}
+// @Failure 404 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/todos/{id}/access [get]
+func (ac *AccessController) GetTodoAccess(c *gin.Context) {
+	todoID, ok := parseIDParam(c, "id", "Invalid todo ID")
+	if !ok {
+		return
+	}
+
+	access, err := ac.accessService.GetTodoAccess(c.Request.Context(), c.GetString(middleware.IdentityKey), todoID)
+	if err != nil {
+		writeAccessError(c, "Failed to get access: ", err)
+		return
+	}
+
+	utils.SuccessResponse(c, access, "Access retrieved successfully")
+}
+
+// GrantTodoAccess godoc
+// @Summary Share a todo
+// @Description Give a principal the viewer, commenter, editor or owner role on a todo, or change the role it has. Requires the owner role.
+// @Tags access
+// @Accept json
+// @Produce json
+// @Param id path int true "Todo ID"
+// @Param grant body dto.GrantAccessRequest true "Principal and role"
+// @Success 200 {object} dto.APIResponse{data=dto.GrantResponse}
+// @Failure 400 {object} dto.APIResponse
+// @Failure 403 {object} dto.APIResponse
+// @Failure 404 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/todos/{id}/access [post]
+func (ac *AccessController) GrantTodoAccess(c *gin.Context) {
+	todoID, ok := parseIDParam(c, "id", "Invalid todo ID")
+	if !ok {
+		return
+	}
+
+	var req dto.GrantAccessRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	grant, err := ac.accessService.GrantTodoAccess(c.Request.Context(), c.GetString(middleware.IdentityKey), todoID, &req)
+	if err != nil {
+		writeAccessError(c, "Failed to grant access: ", err)
+		return
+	}
+
+	utils.SuccessResponse(c, grant, "Access granted successfully")
+}
+
+// RevokeTodoAccess godoc
+// @Summary Stop sharing a todo
+// @Description Remove a principal's grant on a todo. Owners may revoke any grant; everyone may give up their own.
+// @Tags access
+// @Produce json
+// @Param id path int true "Todo ID"
+// @Param principal query string true "Principal whose grant is removed"
+// @Success 200 {object} dto.APIResponse
+// @Failure 400 {object} dto.APIResponse
+// @Failure 403 {object} dto.APIResponse
+// @Failure 404 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/todos/{id}/access [delete]
+func (ac *AccessController) RevokeTodoAccess(c *gin.Context) {
+	todoID, ok := parseIDParam(c, "id", "Invalid todo ID")
+	if !ok {
+		return
+	}
+
+	if err := ac.accessService.RevokeTodoAccess(c.Request.Context(), c.GetString(middleware.IdentityKey), todoID, c.Query("principal")); err != nil {
+		writeAccessError(c, "Failed to revoke access: ", err)
+		return
+	}
+
+	utils.SuccessResponse(c, nil, "Access revoked successfully")
+}
+
+// GetShares godoc
+// @Summary List list-wide shares
+// @Description The principals the caller shared all of its todos with, including todos created later
+// @Tags access
+// @Produce json
+// @Success 200 {object} dto.APIResponse{data=[]dto.GrantResponse}
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/shares [get]
+func (ac *AccessController) GetShares(c *gin.Context) {
+	shares, err := ac.accessService.GetShares(c.Request.Context(), c.GetString(middleware.IdentityKey))
+	if err != nil {
+		writeAccessError(c, "Failed to get shares: ", err)
+		return
+	}
+
+	utils.SuccessResponse(c, shares, "Shares retrieved successfully")
+}
+
+// ShareList godoc
+// @Summary Share all todos
+// @Description Give a principal a role on every todo the caller owns, now and later, or change the role it has
+// @Tags access
+// @Accept json
+// @Produce json
+// @Param grant body dto.GrantAccessRequest true "Principal and role"
+// @Success 200 {object} dto.APIResponse{data=dto.GrantResponse}
+// @Failure 400 {object} dto.APIResponse
+// @Failure 403 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/shares [post]
+func (ac *AccessController) ShareList(c *gin.Context) {
+	var req dto.GrantAccessRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	share, err := ac.accessService.ShareList(c.Request.Context(), c.GetString(middleware.IdentityKey), &req)
+	if err != nil {
+		writeAccessError(c, "Failed to share todos: ", err)
+		return
+	}
+
+	utils.SuccessResponse(c, share, "Todos shared successfully")
+}
+
+// UnshareList godoc
+// @Summary Stop sharing all todos
+// @Description Remove a list-wide share; grants on single todos are kept
+// @Tags access
+// @Produce json
+// @Param principal query string true "Principal whose share is removed"
+// @Success 200 {object} dto.APIResponse
+// @Failure 400 {object} dto.APIResponse
+// @Failure 404 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/shares [delete]
+func (ac *AccessController) UnshareList(c *gin.Context) {
+	if err := ac.accessService.UnshareList(c.Request.Context(), c.GetString(middleware.IdentityKey), c.Query("principal")); err != nil {
+		writeAccessError(c, "Failed to stop sharing todos: ", err)
+		return
+	}
+
+	utils.SuccessResponse(c, nil, "Share removed successfully")
+}
+
+func writeAccessError(c *gin.Context, prefix string, err error) {
+	switch {
+	case err.Error() == "todo not found":
+		utils.NotFoundResponse(c, "Todo not found")
+	case err.Error() == "grant not found":
+		utils.NotFoundResponse(c, "Grant not found")
+	case strings.HasPrefix(err.Error(), "forbidden"):
+		utils.ForbiddenResponse(c, err.Error())
+	case strings.HasPrefix(err.Error(), "validation failed"):
+		utils.BadRequestResponse(c, err.Error())
+	default:
+		utils.InternalServerErrorResponse(c, prefix+err.Error())
+	}
+}
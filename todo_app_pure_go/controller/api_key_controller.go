@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"strings"
+	"todo-app/dto"
+	"todo-app/middleware"
+	"todo-app/service"
+	"todo-app/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyController struct {
+	apiKeyService service.APIKeyService
+}
+
+func NewAPIKeyController(apiKeyService service.APIKeyService) *APIKeyController {
+	return &APIKeyController{
+		apiKeyService: apiKeyService,
+	}
+}
+
+// CreateKey godoc
+// @Summary Create an API key
+// @Description Create an API key that acts for the caller in the current workspace with the given scopes until it expires or is revoked. The key is only shown in this response; send it as "Authorization: Bearer <key>".
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Param key body dto.CreateAPIKeyRequest true "Key name, scopes and expiry"
+// @Success 201 {object} dto.APIResponse{data=dto.APIKeyResponse}
+// @Failure 400 {object} dto.APIResponse
+// @Failure 401 {object} dto.APIResponse
+// @Failure 403 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/keys [post]
+func (kc *APIKeyController) CreateKey(c *gin.Context) {
+	var req dto.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	key, err := kc.apiKeyService.CreateKey(c.Request.Context(), c.GetString(middleware.IdentityKey), &req)
+	if err != nil {
+		writeAPIKeyError(c, "Failed to create API key: ", err)
+		return
+	}
+
+	utils.CreatedResponse(c, key, "API key created successfully")
+}
+
+// GetKeys godoc
+// @Summary List API keys
+// @Description List the caller's API keys in the current workspace, including revoked and expired ones (keys are not shown)
+// @Tags api-keys
+// @Produce json
+// @Success 200 {object} dto.APIResponse{data=[]dto.APIKeyResponse}
+// @Failure 401 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/keys [get]
+func (kc *APIKeyController) GetKeys(c *gin.Context) {
+	keys, err := kc.apiKeyService.GetKeys(c.Request.Context(), c.GetString(middleware.IdentityKey))
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to get API keys: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, keys, "API keys retrieved successfully")
+}
+
+// RevokeKey godoc
+// @Summary Revoke an API key
+// @Description Revoke one of the caller's API keys; it stops working immediately and stays listed as revoked
+// @Tags api-keys
+// @Produce json
+// @Param id path int true "API key ID"
+// @Success 200 {object} dto.APIResponse{data=dto.APIKeyResponse}
+// @Failure 400 {object} dto.APIResponse
+// @Failure 401 {object} dto.APIResponse
+// @Failure 404 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/keys/{id} [delete]
+func (kc *APIKeyController) RevokeKey(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "Invalid API key ID")
+	if !ok {
+		return
+	}
+
+	key, err := kc.apiKeyService.RevokeKey(c.Request.Context(), c.GetString(middleware.IdentityKey), id)
+	if err != nil {
+		writeAPIKeyError(c, "Failed to revoke API key: ", err)
+		return
+	}
+
+	utils.SuccessResponse(c, key, "API key revoked successfully")
+}
+
+func writeAPIKeyError(c *gin.Context, prefix string, err error) {
+	switch {
+	case err.Error() == "api key not found":
+		utils.NotFoundResponse(c, "API key not found")
+	case strings.HasPrefix(err.Error(), "validation failed"):
+		utils.BadRequestResponse(c, err.Error())
+	case strings.HasPrefix(err.Error(), "forbidden"):
+		utils.ForbiddenResponse(c, err.Error())
+	default:
+		utils.InternalServerErrorResponse(c, prefix+err.Error())
+	}
+}
@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"todo-app/auth"
+	"todo-app/dto"
+	"todo-app/middleware"
+	"todo-app/models"
+	"todo-app/repository"
+	"todo-app/service"
+	"todo-app/tenant"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newAPIKeyTestRouter serves /api/keys the way the routes do, and returns
+// a key owned by "ci" that may manage keys and todos.
+func newAPIKeyTestRouter(t *testing.T) (*gin.Engine, string) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(tenant.Plugin{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.Workspace{}, &models.APIKey{}); err != nil {
+		t.Fatal(err)
+	}
+	workspaceRepo := repository.NewWorkspaceRepository(db)
+	if _, err := workspaceRepo.Ensure(tenant.AllWorkspaces(context.Background()), "acme"); err != nil {
+		t.Fatal(err)
+	}
+	keyService := service.NewAPIKeyService(repository.NewAPIKeyRepository(db), workspaceRepo)
+	ctx := tenant.WithWorkspace(context.Background(), 1)
+	key, err := keyService.CreateKey(ctx, "ci", &dto.CreateAPIKeyRequest{
+		Name:   "Admin",
+		Scopes: []string{auth.ScopeTodosRead, auth.ScopeTodosWrite, auth.ScopeKeysRead, auth.ScopeKeysWrite},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kc := NewAPIKeyController(keyService)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	keys := router.Group("/api/keys", middleware.APIKeyMiddleware(keyService.Authenticate), func(c *gin.Context) {
+		c.Request = c.Request.WithContext(tenant.WithWorkspace(c.Request.Context(), 1))
+	}, middleware.RequireIdentity())
+	keys.GET("", middleware.RequireScope(auth.ScopeKeysRead), kc.GetKeys)
+	keys.POST("", middleware.RequireScope(auth.ScopeKeysWrite), kc.CreateKey)
+	keys.DELETE("/:id", middleware.RequireScope(auth.ScopeKeysWrite), kc.RevokeKey)
+	return router, key.Key
+}
+
+func TestAPIKeysRequireAuthentication(t *testing.T) {
+	router, secret := newAPIKeyTestRouter(t)
+	body := `{"name":"Deploy bot","scopes":["todos:read","todos:write"]}`
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/api/keys", strings.NewReader(body)),
+		httptest.NewRequest(http.MethodGet, "/api/keys", nil),
+		httptest.NewRequest(http.MethodDelete, "/api/keys/1", nil),
+	} {
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("anonymous %s %s: %d %s", req.Method, req.URL.Path, w.Code, w.Body)
+		}
+	}
+
+	// The same requests with a key act for the key's owner
+	for _, tt := range []struct {
+		method, path string
+		wantCode     int
+	}{
+		{http.MethodPost, "/api/keys", http.StatusCreated},
+		{http.MethodGet, "/api/keys", http.StatusOK},
+	} {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+secret)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.wantCode {
+			t.Fatalf("%s %s with a key: %d %s", tt.method, tt.path, w.Code, w.Body)
+		}
+		if tt.method != http.MethodGet {
+			continue
+		}
+		var resp struct {
+			Data []dto.APIKeyResponse `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Data) != 2 {
+			t.Errorf("owner lists %d keys, want 2", len(resp.Data))
+		}
+	}
+}
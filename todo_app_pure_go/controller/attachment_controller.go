@@ -0,0 +1,209 @@
+package controller
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"todo-app/middleware"
+	"todo-app/service"
+	"todo-app/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead allows for the multipart headers and boundaries around
+// an upload of the maximum attachment size.
+const multipartOverhead = 64 << 10
+
+type AttachmentController struct {
+	attachmentService service.AttachmentService
+	maxSize           int64
+}
+
+func NewAttachmentController(attachmentService service.AttachmentService, maxSize int64) *AttachmentController {
+	return &AttachmentController{
+		attachmentService: attachmentService,
+		maxSize:           maxSize,
+	}
+}
+
+// UploadAttachment godoc
+// @Summary Upload an attachment
+// @Description Attach a file to a todo. The upload is streamed to storage; its type is detected from the content and must be one of the configured types. An optional X-Checksum-SHA256 header is verified against the content.
+// @Tags attachments
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "Todo ID"
+// @Param file formData file true "File to attach"
+// @Param X-Checksum-SHA256 header string false "Hex SHA-256 of the file"
+// @Success 201 {object} dto.APIResponse{data=dto.AttachmentResponse}
+// @Failure 400 {object} dto.APIResponse
+// @Failure 403 {object} dto.APIResponse
+// @Failure 404 {object} dto.APIResponse
+// @Failure 413 {object} dto.APIResponse
+// @Failure 415 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/todos/{id}/attachments [post]
+func (ac *AttachmentController) UploadAttachment(c *gin.Context) {
+	todoID, ok := parseIDParam(c, "id", "Invalid todo ID")
+	if !ok {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, ac.maxSize+multipartOverhead)
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		utils.BadRequestResponse(c, "Invalid upload: "+err.Error())
+		return
+	}
+
+	// Read parts until the file so the content is never buffered whole
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			utils.BadRequestResponse(c, "Invalid upload: missing file field")
+			return
+		}
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeAttachmentError(c, "", err)
+				return
+			}
+			utils.BadRequestResponse(c, "Invalid upload: "+err.Error())
+			return
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+
+		attachment, err := ac.attachmentService.Upload(c.Request.Context(), c.GetString(middleware.IdentityKey), todoID, part.FileName(), part, c.GetHeader("X-Checksum-SHA256"))
+		part.Close()
+		if err != nil {
+			writeAttachmentError(c, "Failed to upload attachment: ", err)
+			return
+		}
+		utils.CreatedResponse(c, attachment, "Attachment uploaded successfully")
+		return
+	}
+}
+
+// GetAttachments godoc
+// @Summary List attachments
+// @Description List the attachments of a todo, oldest first
+// @Tags attachments
+// @Produce json
+// @Param id path int true "Todo ID"
+// @Success 200 {object} dto.APIResponse{data=[]dto.AttachmentResponse}
+// @Failure 404 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/todos/{id}/attachments [get]
+func (ac *AttachmentController) GetAttachments(c *gin.Context) {
+	todoID, ok := parseIDParam(c, "id", "Invalid todo ID")
+	if !ok {
+		return
+	}
+
+	attachments, err := ac.attachmentService.GetAttachments(c.Request.Context(), c.GetString(middleware.IdentityKey), todoID)
+	if err != nil {
+		writeAttachmentError(c, "Failed to get attachments: ", err)
+		return
+	}
+
+	utils.SuccessResponse(c, attachments, "Attachments retrieved successfully")
+}
+
+// DownloadAttachment godoc
+// @Summary Download an attachment
+// @Description Stream the content of an attachment. Range requests and conditional requests with the SHA-256 ETag are supported.
+// @Tags attachments
+// @Produce octet-stream
+// @Param id path int true "Todo ID"
+// @Param attachmentId path int true "Attachment ID"
+// @Param Range header string false "Byte range such as bytes=0-1023"
+// @Success 200 {file} file
+// @Success 206 {file} file
+// @Failure 404 {object} dto.APIResponse
+// @Failure 416 {string} string
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/todos/{id}/attachments/{attachmentId} [get]
+func (ac *AttachmentController) DownloadAttachment(c *gin.Context) {
+	todoID, ok := parseIDParam(c, "id", "Invalid todo ID")
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(c, "attachmentId", "Invalid attachment ID")
+	if !ok {
+		return
+	}
+
+	attachment, content, err := ac.attachmentService.Open(c.Request.Context(), c.GetString(middleware.IdentityKey), todoID, id)
+	if err != nil {
+		writeAttachmentError(c, "Failed to download attachment: ", err)
+		return
+	}
+	defer content.Close()
+
+	// Always download rather than render, so an uploaded file can never run
+	// as a page of this origin
+	c.Header("Content-Type", attachment.ContentType)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("ETag", `"`+attachment.SHA256+`"`)
+	http.ServeContent(c.Writer, c.Request, "", attachment.CreatedAt, content)
+}
+
+// DeleteAttachment godoc
+// @Summary Delete an attachment
+// @Description Delete an attachment; its content is removed once no other attachment shares it
+// @Tags attachments
+// @Produce json
+// @Param id path int true "Todo ID"
+// @Param attachmentId path int true "Attachment ID"
+// @Success 200 {object} dto.APIResponse
+// @Failure 403 {object} dto.APIResponse
+// @Failure 404 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/todos/{id}/attachments/{attachmentId} [delete]
+func (ac *AttachmentController) DeleteAttachment(c *gin.Context) {
+	todoID, ok := parseIDParam(c, "id", "Invalid todo ID")
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(c, "attachmentId", "Invalid attachment ID")
+	if !ok {
+		return
+	}
+
+	if err := ac.attachmentService.DeleteAttachment(c.Request.Context(), c.GetString(middleware.IdentityKey), todoID, id); err != nil {
+		writeAttachmentError(c, "Failed to delete attachment: ", err)
+		return
+	}
+
+	utils.SuccessResponse(c, nil, "Attachment deleted successfully")
+}
+
+func writeAttachmentError(c *gin.Context, prefix string, err error) {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case err.Error() == "todo not found":
+		utils.NotFoundResponse(c, "Todo not found")
+	case err.Error() == "attachment not found":
+		utils.NotFoundResponse(c, "Attachment not found")
+	case strings.HasPrefix(err.Error(), "forbidden"):
+		utils.ForbiddenResponse(c, err.Error())
+	case errors.As(err, &tooLarge):
+		utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "Upload exceeds the maximum attachment size", "Request Entity Too Large")
+	case strings.HasPrefix(err.Error(), "attachment too large"):
+		utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, err.Error(), "Request Entity Too Large")
+	case strings.HasPrefix(err.Error(), "unsupported attachment type"):
+		utils.ErrorResponse(c, http.StatusUnsupportedMediaType, err.Error(), "Unsupported Media Type")
+	case strings.HasPrefix(err.Error(), "validation failed"):
+		utils.BadRequestResponse(c, err.Error())
+	default:
+		utils.InternalServerErrorResponse(c, prefix+err.Error())
+	}
+}
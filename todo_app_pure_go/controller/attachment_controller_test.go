@@ -0,0 +1,228 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"todo-app/dto"
+	"todo-app/events"
+	"todo-app/models"
+	"todo-app/repository"
+	"todo-app/service"
+	"todo-app/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// pngHeader is enough for content sniffing to report image/png.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n")
+
+type attachmentTestEnv struct {
+	router   *gin.Engine
+	todos    service.TodoService
+	service  service.AttachmentService
+	blobRoot string
+}
+
+func newAttachmentTestEnv(t *testing.T) *attachmentTestEnv {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.Todo{}, &models.TodoChange{}, &models.TodoStatusChange{}, &models.TodoDependency{}, &models.Attachment{}, &models.Comment{}, &models.CommentRevision{}, &models.TodoGrant{}, &models.ListGrant{}); err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	store, err := storage.NewLocalStore(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	todoRepo := repository.NewTodoRepository(db)
+	attachments := service.NewAttachmentService(repository.NewAttachmentRepository(db), todoRepo, repository.NewAccessRepository(db), store, service.AttachmentOptions{
+		MaxSize:      1024,
+		AllowedTypes: []string{"image/png", "application/pdf"},
+	})
+	ac := NewAttachmentController(attachments, 1024)
+	bus := events.NewBus()
+	bus.Subscribe(attachments.HandleEvent)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/todos/:id/attachments", ac.GetAttachments)
+	router.POST("/api/todos/:id/attachments", ac.UploadAttachment)
+	router.GET("/api/todos/:id/attachments/:attachmentId", ac.DownloadAttachment)
+	router.DELETE("/api/todos/:id/attachments/:attachmentId", ac.DeleteAttachment)
+	return &attachmentTestEnv{
+		router:   router,
+		todos:    service.NewTodoService(todoRepo, repository.NewAccessRepository(db), nil, bus, nil),
+		service:  attachments,
+		blobRoot: root,
+	}
+}
+
+func (e *attachmentTestEnv) upload(t *testing.T, path, filename string, content []byte, checksum string) (*httptest.ResponseRecorder, *dto.AttachmentResponse) {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("note", "ignored")
+	part, _ := form.CreateFormFile("file", filename)
+	part.Write(content)
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, path, &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if checksum != "" {
+		req.Header.Set("X-Checksum-SHA256", checksum)
+	}
+	w := httptest.NewRecorder()
+	e.router.ServeHTTP(w, req)
+
+	var resp struct {
+		Data *dto.AttachmentResponse `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp.Data
+}
+
+func (e *attachmentTestEnv) blobCount(t *testing.T) int {
+	t.Helper()
+	store, _ := storage.NewLocalStore(e.blobRoot)
+	count := 0
+	store.Walk(func(string) error {
+		count++
+		return nil
+	})
+	return count
+}
+
+func TestAttachmentLifecycle(t *testing.T) {
+	env := newAttachmentTestEnv(t)
+	todo, _ := env.todos.CreateTodo(context.Background(), "", &dto.CreateTodoRequest{Title: "Report bug"})
+	base := "/api/todos/1/attachments"
+
+	content := append(append([]byte{}, pngHeader...), []byte("0123456789")...)
+	digest := sha256.Sum256(content)
+	checksum := hex.EncodeToString(digest[:])
+
+	w, attachment := env.upload(t, base, `C:\shots\screen "1".png`, content, checksum)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("upload status = %d: %s", w.Code, w.Body.String())
+	}
+	if attachment.TodoID != todo.ID || attachment.ContentType != "image/png" || attachment.Size != int64(len(content)) ||
+		attachment.SHA256 != checksum || attachment.Filename != `screen "1".png` {
+		t.Errorf("unexpected attachment: %+v", attachment)
+	}
+
+	// The same content again shares the blob
+	if w, _ := env.upload(t, base, "copy.png", content, ""); w.Code != http.StatusCreated {
+		t.Fatalf("second upload status = %d", w.Code)
+	}
+	if n := env.blobCount(t); n != 1 {
+		t.Errorf("blobs = %d, want 1", n)
+	}
+
+	w = httptest.NewRecorder()
+	env.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, base, nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "copy.png") {
+		t.Errorf("list: %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	env.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, base+"/1", nil))
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), content) {
+		t.Fatalf("download: %d %q", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Disposition"); got != `attachment; filename="screen \"1\".png"` {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+	if got := w.Header().Get("ETag"); got != `"`+checksum+`"` {
+		t.Errorf("ETag = %q", got)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, base+"/1", nil)
+	req.Header.Set("Range", "bytes=8-11")
+	w = httptest.NewRecorder()
+	env.router.ServeHTTP(w, req)
+	if w.Code != http.StatusPartialContent || w.Body.String() != "0123" {
+		t.Errorf("range download: %d %q", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Range"); got != "bytes 8-11/18" {
+		t.Errorf("Content-Range = %q", got)
+	}
+
+	// Deleting one of two attachments keeps the shared blob
+	w = httptest.NewRecorder()
+	env.router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, base+"/1", nil))
+	if w.Code != http.StatusOK || env.blobCount(t) != 1 {
+		t.Fatalf("delete: %d, blobs = %d", w.Code, env.blobCount(t))
+	}
+
+	// Deleting the todo deletes its blob in the background, and only its
+	// own: blobs left behind by anything else wait for garbage collection
+	store, _ := storage.NewLocalStore(env.blobRoot)
+	if _, err := store.Put(strings.NewReader("orphan"), storage.PutOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.todos.DeleteTodo(context.Background(), "", todo.ID); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(3 * time.Second)
+	for env.blobCount(t) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("blobs after todo delete = %d, want 1", env.blobCount(t))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if removed, err := env.service.CollectGarbage(context.Background()); err != nil || removed != 1 {
+		t.Errorf("CollectGarbage = %d, %v", removed, err)
+	}
+	if n := env.blobCount(t); n != 0 {
+		t.Errorf("blobs after garbage collection = %d, want 0", n)
+	}
+}
+
+func TestAttachmentUploadRejections(t *testing.T) {
+	env := newAttachmentTestEnv(t)
+	env.todos.CreateTodo(context.Background(), "", &dto.CreateTodoRequest{Title: "Report bug"})
+	png := append(append([]byte{}, pngHeader...), 'x')
+
+	tests := []struct {
+		name     string
+		path     string
+		content  []byte
+		checksum string
+		want     int
+	}{
+		{"unknown todo", "/api/todos/9/attachments", png, "", http.StatusNotFound},
+		{"checksum mismatch", "/api/todos/1/attachments", png, strings.Repeat("0", 64), http.StatusBadRequest},
+		{"invalid checksum", "/api/todos/1/attachments", png, "abc", http.StatusBadRequest},
+		{"empty file", "/api/todos/1/attachments", nil, "", http.StatusBadRequest},
+		{"unsupported type", "/api/todos/1/attachments", []byte("<html><script></script>"), "", http.StatusUnsupportedMediaType},
+		{"too large", "/api/todos/1/attachments", append(png, make([]byte, 1024)...), "", http.StatusRequestEntityTooLarge},
+		{"far too large", "/api/todos/1/attachments", append(png, make([]byte, 128<<10)...), "", http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w, _ := env.upload(t, tt.path, "file.png", tt.content, tt.checksum); w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+	if n := env.blobCount(t); n != 0 {
+		t.Errorf("rejected uploads left %d blobs", n)
+	}
+}
@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"net/http"
+	"strings"
+	"todo-app/dto"
+	"todo-app/service"
+	"todo-app/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// loginCookie keeps the state of a login in progress between the redirect
+// to the identity provider and its callback.
+const loginCookie = "todo_login"
+
+type AuthController struct {
+	authService service.AuthService
+	// secureCookies marks the login cookie Secure, for HTTPS deployments
+	secureCookies bool
+}
+
+func NewAuthController(authService service.AuthService, secureCookies bool) *AuthController {
+	return &AuthController{
+		authService:   authService,
+		secureCookies: secureCookies,
+	}
+}
+
+// Login godoc
+// @Summary Sign in with single sign-on
+// @Description Redirect the browser to the OpenID Connect provider to sign in to the current workspace. The provider sends it back to /auth/callback.
+// @Tags auth
+// @Param X-Workspace header string false "Workspace slug (default: the configured default workspace)"
+// @Success 302
+// @Failure 404 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /auth/login [get]
+func (ac *AuthController) Login(c *gin.Context) {
+	login, err := ac.authService.StartLogin(c.Request.Context())
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to start login: "+err.Error())
+		return
+	}
+
+	// Lax, so the cookie comes along when the provider redirects back
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(loginCookie, login.State, 600, "/auth", "", ac.secureCookies, true)
+	c.Redirect(http.StatusFound, login.URL)
+}
+
+// Callback godoc
+// @Summary Complete single sign-on
+// @Description Complete a login started at /auth/login with the code the OpenID Connect provider redirected back with. The user is created on their first login to the workspace. Returns a session token to send as "Authorization: Bearer <token>".
+// @Tags auth
+// @Produce json
+// @Param code query string false "Authorization code"
+// @Param state query string true "State sent to the provider"
+// @Param error query string false "Error reported by the provider"
+// @Success 200 {object} dto.APIResponse{data=dto.SessionResponse}
+// @Failure 400 {object} dto.APIResponse
+// @Failure 401 {object} dto.APIResponse
+// @Failure 403 {object} dto.APIResponse
+// @Failure 502 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /auth/callback [get]
+func (ac *AuthController) Callback(c *gin.Context) {
+	var callback dto.LoginCallback
+	if err := c.ShouldBindQuery(&callback); err != nil {
+		utils.BadRequestResponse(c, "Invalid callback: "+err.Error())
+		return
+	}
+	state, _ := c.Cookie(loginCookie)
+	// The state is single use whatever the outcome
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(loginCookie, "", -1, "/auth", "", ac.secureCookies, true)
+
+	session, err := ac.authService.FinishLogin(c.Request.Context(), state, &callback)
+	if err != nil {
+		switch {
+		case err.Error() == "invalid login state":
+			utils.BadRequestResponse(c, "Login expired or was started in another browser, sign in again")
+		case strings.HasPrefix(err.Error(), "validation failed"):
+			utils.BadRequestResponse(c, err.Error())
+		case strings.HasPrefix(err.Error(), "login failed"):
+			utils.UnauthorizedResponse(c, err.Error())
+		case strings.HasPrefix(err.Error(), "forbidden"):
+			utils.ForbiddenResponse(c, err.Error())
+		case strings.HasPrefix(err.Error(), "provider error"):
+			utils.ErrorResponse(c, http.StatusBadGateway, err.Error(), "Bad Gateway")
+		default:
+			utils.InternalServerErrorResponse(c, "Failed to complete login: "+err.Error())
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, session, "Signed in successfully")
+}
@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"todo-app/dto"
+	"todo-app/models"
+	"todo-app/oidc"
+	"todo-app/oidc/oidctest"
+	"todo-app/repository"
+	"todo-app/service"
+	"todo-app/tenant"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newAuthTestRouter(t *testing.T) (*gin.Engine, *oidctest.Provider) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.Workspace{}, &models.User{}); err != nil {
+		t.Fatal(err)
+	}
+	workspaceRepo := repository.NewWorkspaceRepository(db)
+	if _, err := workspaceRepo.Ensure(context.Background(), "acme"); err != nil {
+		t.Fatal(err)
+	}
+
+	fake := oidctest.NewProvider("todo-app", "s3cret")
+	t.Cleanup(fake.Close)
+	provider, err := oidc.Discover(context.Background(), fake.Issuer(), oidc.Config{
+		ClientID:     fake.ClientID,
+		ClientSecret: fake.ClientSecret,
+		RedirectURL:  "https://todo.example.com/auth/callback",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ac := NewAuthController(service.NewAuthService(provider, repository.NewUserRepository(db), workspaceRepo, service.AuthOptions{
+		SessionSecret: []byte("0123456789abcdef0123456789abcdef"),
+		SessionTTL:    time.Hour,
+	}), true)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/auth/login", func(c *gin.Context) {
+		c.Request = c.Request.WithContext(tenant.WithWorkspace(c.Request.Context(), 1))
+	}, ac.Login)
+	router.GET("/auth/callback", ac.Callback)
+	return router, fake
+}
+
+func TestSingleSignOn(t *testing.T) {
+	router, fake := newAuthTestRouter(t)
+	fake.SignIn(&oidctest.User{Subject: "u-1", Email: "alice@example.com", EmailVerified: true, Name: "Alice"})
+
+	// The login redirects to the provider and remembers its state in a
+	// cookie
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/login", nil))
+	if w.Code != http.StatusFound || !strings.HasPrefix(w.Header().Get("Location"), fake.Issuer()+"/authorize?") {
+		t.Fatalf("login: %d to %q", w.Code, w.Header().Get("Location"))
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != loginCookie || !cookies[0].HttpOnly || !cookies[0].Secure || cookies[0].SameSite != http.SameSiteLaxMode {
+		t.Fatalf("unexpected login cookie %+v", cookies)
+	}
+
+	// The provider sends the browser back with a code
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	back, _ := url.Parse(resp.Header.Get("Location"))
+	callback := "/auth/callback?" + back.RawQuery
+
+	// Without the cookie the callback is refused
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, callback, nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("callback without cookie: %d %s", w.Code, w.Body)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, callback, nil)
+	req.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("callback: %d %s", w.Code, w.Body)
+	}
+	var body struct {
+		Data dto.SessionResponse `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Data.Token == "" || body.Data.User.Principal != "alice@example.com" || body.Data.User.Workspace != "acme" {
+		t.Errorf("unexpected session %+v", body.Data)
+	}
+	// The login state is cleared
+	if cleared := w.Result().Cookies(); len(cleared) != 1 || cleared[0].MaxAge >= 0 {
+		t.Errorf("expected the login cookie to be cleared, got %+v", cleared)
+	}
+
+	// Errors reported by the provider fail the login
+	req = httptest.NewRequest(http.MethodGet, "/auth/callback?state="+back.Query().Get("state")+"&error=access_denied", nil)
+	req.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("provider error: %d %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,35 @@
+package controller
+
+import (
+	"todo-app/repository"
+	"todo-app/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CacheStatsSource reports the counters of a cache, such as
+// *repository.CachedTodoRepository.
+type CacheStatsSource interface {
+	Stats() repository.CacheStats
+}
+
+type CacheController struct {
+	cache CacheStatsSource
+}
+
+func NewCacheController(cache CacheStatsSource) *CacheController {
+	return &CacheController{
+		cache: cache,
+	}
+}
+
+// GetStats godoc
+// @Summary Get todo cache statistics
+// @Description Hits, misses, hit ratio, evictions, invalidations and current size of the todo cache since startup. Only available when the cache is enabled.
+// @Tags cache
+// @Produce json
+// @Success 200 {object} dto.APIResponse{data=repository.CacheStats}
+// @Router /api/cache/stats [get]
+func (cc *CacheController) GetStats(c *gin.Context) {
+	utils.SuccessResponse(c, cc.cache.Stats(), "Cache statistics retrieved successfully")
+}
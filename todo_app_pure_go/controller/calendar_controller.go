@@ -0,0 +1,310 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"todo-app/dto"
+	"todo-app/ical"
+	"todo-app/middleware"
+	"todo-app/models"
+	"todo-app/service"
+	"todo-app/tenant"
+	"todo-app/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const icalProdID = "-//todo-app//Todo API//EN"
+
+type CalendarController struct {
+	todoService     service.TodoService
+	calendarService service.CalendarService
+	publicURL       string
+}
+
+func NewCalendarController(todoService service.TodoService, calendarService service.CalendarService, publicURL string) *CalendarController {
+	return &CalendarController{
+		todoService:     todoService,
+		calendarService: calendarService,
+		publicURL:       strings.TrimSuffix(publicURL, "/"),
+	}
+}
+
+// ExportTodosICS godoc
+// @Summary Export todos as iCalendar
+// @Description Render all todos matching the filters as RFC 5545 VTODO components
+// @Tags calendar
+// @Produce text/calendar
+// @Param completed query bool false "Filter by completion status"
+// @Param priority query string false "Filter by priority (LOW, MEDIUM, HIGH)"
+// @Success 200 {string} string "iCalendar file"
+// @Failure 400 {object} dto.APIResponse
+// @Router /api/todos/export.ics [get]
+func (cc *CalendarController) ExportTodosICS(c *gin.Context) {
+	completed, priority, ok := parseTodoFilters(c)
+	if !ok {
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="todos.ics"`)
+	cc.writeCalendar(c.Request.Context(), c, c.GetString(middleware.IdentityKey), "Todos", completed, priority)
+}
+
+// CreateFeed godoc
+// @Summary Create a calendar subscription
+// @Description Create a secret ICS subscription URL for the caller. The token is only shown in this response.
+// @Tags calendar
+// @Accept json
+// @Produce json
+// @Param feed body dto.CreateCalendarFeedRequest true "Feed name and filters"
+// @Success 201 {object} dto.APIResponse
+// @Failure 400 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/calendar/feeds [post]
+func (cc *CalendarController) CreateFeed(c *gin.Context) {
+	var req dto.CreateCalendarFeedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	feed, err := cc.calendarService.CreateFeed(c.Request.Context(), c.GetString(middleware.IdentityKey), &req)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "validation failed") {
+			utils.BadRequestResponse(c, err.Error())
+			return
+		}
+		utils.InternalServerErrorResponse(c, "Failed to create calendar feed: "+err.Error())
+		return
+	}
+	feed.URL = cc.baseURL(c) + "/calendar/" + feed.Token + ".ics"
+
+	utils.CreatedResponse(c, feed, "Calendar feed created successfully")
+}
+
+// GetFeeds godoc
+// @Summary List calendar subscriptions
+// @Description List the caller's calendar subscriptions (tokens are not shown)
+// @Tags calendar
+// @Produce json
+// @Success 200 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/calendar/feeds [get]
+func (cc *CalendarController) GetFeeds(c *gin.Context) {
+	feeds, err := cc.calendarService.GetFeeds(c.Request.Context(), c.GetString(middleware.IdentityKey))
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to get calendar feeds: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, feeds, "Calendar feeds retrieved successfully")
+}
+
+// DeleteFeed godoc
+// @Summary Revoke a calendar subscription
+// @Description Delete a calendar subscription; its URL stops working immediately
+// @Tags calendar
+// @Produce json
+// @Param id path int true "Feed ID"
+// @Success 200 {object} dto.APIResponse
+// @Failure 404 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/calendar/feeds/{id} [delete]
+func (cc *CalendarController) DeleteFeed(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "Invalid feed ID")
+		return
+	}
+
+	err = cc.calendarService.DeleteFeed(c.Request.Context(), c.GetString(middleware.IdentityKey), uint(id))
+	if err != nil {
+		if err.Error() == "calendar feed not found" {
+			utils.NotFoundResponse(c, "Calendar feed not found")
+			return
+		}
+		utils.InternalServerErrorResponse(c, "Failed to delete calendar feed: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, nil, "Calendar feed deleted successfully")
+}
+
+// Feed godoc
+// @Summary Calendar subscription feed
+// @Description Serve the todos of a calendar subscription as iCalendar. The secret token in the URL is the only credential.
+// @Tags calendar
+// @Produce text/calendar
+// @Param token path string true "Subscription token, optionally suffixed with .ics"
+// @Success 200 {string} string "iCalendar feed"
+// @Failure 404 {object} dto.APIResponse
+// @Router /calendar/{token} [get]
+func (cc *CalendarController) Feed(c *gin.Context) {
+	feed, err := cc.calendarService.ResolveFeed(c.Request.Context(), strings.TrimSuffix(c.Param("token"), ".ics"))
+	if err != nil {
+		if err.Error() == "calendar feed not found" {
+			utils.NotFoundResponse(c, "Calendar feed not found")
+			return
+		}
+		utils.InternalServerErrorResponse(c, "Failed to load calendar feed: "+err.Error())
+		return
+	}
+
+	// The feed route is not tied to a workspace; the feed is
+	c.Header("Cache-Control", "private, max-age=300")
+	cc.writeCalendar(tenant.WithWorkspace(c.Request.Context(), feed.Workspace), c, feed.Owner, feed.Name, feed.Completed, feed.Priority)
+}
+
+// ImportTodosICS godoc
+// @Summary Import todos from iCalendar
+// @Description Create todos from the VTODO components of an uploaded .ics file
+// @Tags calendar
+// @Accept multipart/form-data
+// @Accept text/calendar
+// @Produce json
+// @Param file formData file false "iCalendar file (multipart upload)"
+// @Param mode query string false "all_or_nothing (default) or best_effort"
+// @Param dry_run query bool false "Only validate and report which entries would fail"
+// @Success 200 {object} dto.APIResponse
+// @Failure 400 {object} dto.APIResponse
+// @Failure 403 {object} dto.APIResponse
+// @Failure 422 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/todos/import.ics [post]
+func (cc *CalendarController) ImportTodosICS(c *gin.Context) {
+	opts, ok := parseImportOptions(c)
+	if !ok {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	body, closeBody, err := uploadBody(c)
+	if err != nil {
+		utils.BadRequestResponse(c, "Invalid upload: "+err.Error())
+		return
+	}
+	defer closeBody()
+
+	cal, err := ical.Decode(body)
+	if err != nil {
+		utils.BadRequestResponse(c, "Invalid iCalendar file: "+err.Error())
+		return
+	}
+
+	rows := make([]dto.ImportRow, len(cal.Todos))
+	for i, todo := range cal.Todos {
+		rows[i] = dto.ImportRow{
+			Line: todo.Line,
+			Todo: dto.CreateTodoRequest{
+				Title:    todo.Summary,
+				Priority: priorityFromICal(todo.Priority),
+			},
+			Completed: todo.Status == ical.StatusCompleted || !todo.Completed.IsZero(),
+		}
+		if todo.Description != "" {
+			description := todo.Description
+			rows[i].Todo.Description = &description
+		}
+	}
+
+	result, err := cc.todoService.ImportTodos(c.Request.Context(), c.GetString(middleware.IdentityKey), rows, opts)
+	writeImportResult(c, result, err)
+}
+
+// writeCalendar renders the todos visible to principal in the workspace of
+// ctx.
+func (cc *CalendarController) writeCalendar(ctx context.Context, c *gin.Context, principal, name string, completed *bool, priority *models.Priority) {
+	c.Header("Content-Type", "text/calendar; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	enc := ical.NewEncoder(c.Writer)
+	if err := enc.Begin(icalProdID, name); err != nil {
+		return
+	}
+
+	count := 0
+	err := cc.todoService.ExportTodos(ctx, principal, completed, priority, func(todo *dto.TodoResponse) error {
+		if err := enc.Todo(todoToICal(todo)); err != nil {
+			return err
+		}
+		count++
+		if count%500 == 0 {
+			return enc.Flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = enc.End()
+	}
+	if err != nil {
+		// Headers are already sent; the truncated body is all we can signal
+		log.Printf("ICS export aborted after %d todos: %v", count, err)
+	}
+}
+
+// baseURL is the configured public URL or, failing that, the URL the
+// request was made to.
+func (cc *CalendarController) baseURL(c *gin.Context) string {
+	if cc.publicURL != "" {
+		return cc.publicURL
+	}
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+func todoToICal(todo *dto.TodoResponse) ical.Todo {
+	t := ical.Todo{
+		UID:          fmt.Sprintf("todo-%d@todo-app", todo.ID),
+		Summary:      todo.Title,
+		Priority:     priorityToICal(todo.Priority),
+		Status:       ical.StatusNeedsAction,
+		Created:      todo.CreatedAt,
+		LastModified: todo.UpdatedAt,
+	}
+	if todo.Description != nil {
+		t.Description = *todo.Description
+	}
+	if todo.Completed {
+		t.Status = ical.StatusCompleted
+		if todo.CompletedAt != nil {
+			t.Completed = *todo.CompletedAt
+		}
+	}
+	return t
+}
+
+// priorityToICal maps priorities onto the RFC 5545 scale, where 1 is the
+// highest, 5 is medium and 9 the lowest.
+func priorityToICal(p models.Priority) int {
+	switch p {
+	case models.HIGH:
+		return 1
+	case models.LOW:
+		return 9
+	default:
+		return 5
+	}
+}
+
+// priorityFromICal maps the RFC 5545 ranges 1-4, 5 and 6-9 to HIGH, MEDIUM
+// and LOW; 0 means undefined and falls back to the default priority.
+func priorityFromICal(p int) models.Priority {
+	switch {
+	case p == 0:
+		return ""
+	case p < 5:
+		return models.HIGH
+	case p == 5:
+		return models.MEDIUM
+	default:
+		return models.LOW
+	}
+}
@@ -0,0 +1,194 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"todo-app/dto"
+	"todo-app/ical"
+	"todo-app/middleware"
+	"todo-app/models"
+	"todo-app/repository"
+	"todo-app/service"
+	"todo-app/tenant"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newCalendarTestRouter serves the feed routes the way the routes do. The
+// caller of /api routes is named by the X-Identity header and acts in acme,
+// workspace 1; globex is workspace 2.
+func newCalendarTestRouter(t *testing.T) (*gin.Engine, service.TodoService, service.AccessService) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(tenant.Plugin{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.Workspace{}, &models.Todo{}, &models.TodoChange{}, &models.TodoStatusChange{}, &models.TodoDependency{}, &models.Attachment{}, &models.Comment{}, &models.CommentRevision{}, &models.TodoGrant{}, &models.ListGrant{}, &models.CalendarFeed{}); err != nil {
+		t.Fatal(err)
+	}
+	workspaceRepo := repository.NewWorkspaceRepository(db)
+	for _, slug := range []string{"acme", "globex"} {
+		if _, err := workspaceRepo.Ensure(tenant.AllWorkspaces(context.Background()), slug); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	todoRepo := repository.NewTodoRepository(db)
+	accessRepo := repository.NewAccessRepository(db)
+	todos := service.NewTodoService(todoRepo, accessRepo, nil, nil, nil)
+	cc := NewCalendarController(todos, service.NewCalendarService(repository.NewCalendarFeedRepository(db)), "https://todo.example.com")
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	feeds := router.Group("/api/calendar/feeds", func(c *gin.Context) {
+		c.Set(middleware.IdentityKey, c.GetHeader("X-Identity"))
+		c.Request = c.Request.WithContext(tenant.WithWorkspace(c.Request.Context(), 1))
+	})
+	feeds.POST("", cc.CreateFeed)
+	feeds.DELETE("/:id", cc.DeleteFeed)
+	router.GET("/calendar/:token", cc.Feed)
+	return router, todos, service.NewAccessService(accessRepo, todoRepo)
+}
+
+func calendarRequest(router *gin.Engine, method, path, identity, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if identity != "" {
+		req.Header.Set("X-Identity", identity)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func createFeed(t *testing.T, router *gin.Engine, identity, body string) *dto.CalendarFeedResponse {
+	t.Helper()
+	w := calendarRequest(router, http.MethodPost, "/api/calendar/feeds", identity, body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create feed: %d %s", w.Code, w.Body)
+	}
+	var resp struct {
+		Data dto.CalendarFeedResponse `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data.Token == "" || resp.Data.URL != "https://todo.example.com/calendar/"+resp.Data.Token+".ics" {
+		t.Fatalf("unexpected feed %+v", resp.Data)
+	}
+	return &resp.Data
+}
+
+func TestCalendarFeedShowsOwnersVisibleTodos(t *testing.T) {
+	router, todos, access := newCalendarTestRouter(t)
+	acme := tenant.WithWorkspace(context.Background(), 1)
+	globex := tenant.WithWorkspace(context.Background(), 2)
+
+	create := func(ctx context.Context, owner, title string, completed bool) uint {
+		todo, err := todos.CreateTodo(ctx, owner, &dto.CreateTodoRequest{Title: title})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if completed {
+			if _, err := todos.ToggleTodoComplete(ctx, owner, todo.ID); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return todo.ID
+	}
+	create(acme, "alice", "Alice open", false)
+	create(acme, "alice", "Alice done", true)
+	shared := create(acme, "bob", "Bob shared", false)
+	create(acme, "bob", "Bob private", false)
+	create(globex, "alice", "Alice at globex", false)
+	if _, err := access.GrantTodoAccess(acme, "bob", shared, &dto.GrantAccessRequest{Principal: "alice", Role: models.RoleViewer}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		body    string
+		want    []string
+		notWant []string
+	}{
+		{"all", `{"name":"Work"}`, []string{"Alice open", "Alice done", "Bob shared"}, []string{"Bob private", "Alice at globex"}},
+		{"open only", `{"name":"Open","completed":false}`, []string{"Alice open", "Bob shared"}, []string{"Alice done", "Bob private", "Alice at globex"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed := createFeed(t, router, "alice", tt.body)
+
+			// The token is the only credential
+			w := calendarRequest(router, http.MethodGet, "/calendar/"+feed.Token+".ics", "", "")
+			if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/calendar") {
+				t.Fatalf("feed: %d %s", w.Code, w.Header().Get("Content-Type"))
+			}
+			body := w.Body.String()
+			for _, title := range tt.want {
+				if !strings.Contains(body, "SUMMARY:"+title) {
+					t.Errorf("feed is missing %q", title)
+				}
+			}
+			for _, title := range tt.notWant {
+				if strings.Contains(body, "SUMMARY:"+title) {
+					t.Errorf("feed shows %q", title)
+				}
+			}
+		})
+	}
+}
+
+func TestCalendarFeedRejectsUnknownTokens(t *testing.T) {
+	router, _, _ := newCalendarTestRouter(t)
+	feed := createFeed(t, router, "alice", `{"name":"Work"}`)
+	feedPath := "/calendar/" + feed.Token + ".ics"
+
+	for _, path := range []string{"/calendar/.ics", "/calendar/not-a-token.ics", feedPath + "x", strings.TrimSuffix(feedPath, ".ics") + "x"} {
+		if w := calendarRequest(router, http.MethodGet, path, "", ""); w.Code != http.StatusNotFound {
+			t.Errorf("GET %s: %d, want 404", path, w.Code)
+		}
+	}
+
+	// Only the owner can revoke the feed, after which its URL stops working
+	deletePath := "/api/calendar/feeds/" + strconv.FormatUint(uint64(feed.ID), 10)
+	if w := calendarRequest(router, http.MethodDelete, deletePath, "mallory", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("revoke by another user: %d", w.Code)
+	}
+	if w := calendarRequest(router, http.MethodGet, feedPath, "", ""); w.Code != http.StatusOK {
+		t.Fatalf("feed before revoking: %d", w.Code)
+	}
+	if w := calendarRequest(router, http.MethodDelete, deletePath, "alice", ""); w.Code != http.StatusOK {
+		t.Fatalf("revoke: %d %s", w.Code, w.Body)
+	}
+	if w := calendarRequest(router, http.MethodGet, feedPath, "", ""); w.Code != http.StatusNotFound {
+		t.Errorf("revoked feed: %d, want 404", w.Code)
+	}
+}
+
+func TestTodoToICalUsesCompletionTime(t *testing.T) {
+	completedAt := time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)
+	todo := &dto.TodoResponse{
+		ID:          7,
+		Title:       "Write report",
+		Completed:   true,
+		CompletedAt: &completedAt,
+		// Edited after it was completed
+		UpdatedAt: completedAt.Add(48 * time.Hour),
+	}
+	if got := todoToICal(todo); got.Status != ical.StatusCompleted || !got.Completed.Equal(completedAt) {
+		t.Errorf("status %s completed %v, want COMPLETED at %v", got.Status, got.Completed, completedAt)
+	}
+}
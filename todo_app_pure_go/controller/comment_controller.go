@@ -0,0 +1,220 @@
+package controller
+
+import (
+	"strings"
+	"todo-app/dto"
+	"todo-app/middleware"
+	"todo-app/service"
+	"todo-app/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CommentController struct {
+	commentService service.CommentService
+}
+
+func NewCommentController(commentService service.CommentService) *CommentController {
+	return &CommentController{
+		commentService: commentService,
+	}
+}
+
+// CreateComment godoc
+// @Summary Comment on a todo
+// @Description Add a markdown comment to a todo, or a reply when parent_id names another comment of the todo
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param id path int true "Todo ID"
+// @Param comment body dto.CreateCommentRequest true "Comment"
+// @Success 201 {object} dto.APIResponse{data=dto.CommentResponse}
+// @Failure 400 {object} dto.APIResponse
+// @Failure 403 {object} dto.APIResponse
+// @Failure 404 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/todos/{id}/comments [post]
+func (cc *CommentController) CreateComment(c *gin.Context) {
+	todoID, ok := parseIDParam(c, "id", "Invalid todo ID")
+	if !ok {
+		return
+	}
+
+	var req dto.CreateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	comment, err := cc.commentService.CreateComment(c.Request.Context(), c.GetString(middleware.IdentityKey), todoID, &req)
+	if err != nil {
+		writeCommentError(c, "Failed to create comment: ", err)
+		return
+	}
+
+	utils.CreatedResponse(c, comment, "Comment created successfully")
+}
+
+// GetComments godoc
+// @Summary List the comments of a todo
+// @Description Top-level comments oldest first, each with its replies nested under replies. Deleted comments that still have replies are kept without a body.
+// @Tags comments
+// @Produce json
+// @Param id path int true "Todo ID"
+// @Success 200 {object} dto.APIResponse{data=[]dto.CommentResponse}
+// @Failure 404 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/todos/{id}/comments [get]
+func (cc *CommentController) GetComments(c *gin.Context) {
+	todoID, ok := parseIDParam(c, "id", "Invalid todo ID")
+	if !ok {
+		return
+	}
+
+	comments, err := cc.commentService.GetComments(c.Request.Context(), c.GetString(middleware.IdentityKey), todoID)
+	if err != nil {
+		writeCommentError(c, "Failed to get comments: ", err)
+		return
+	}
+
+	utils.SuccessResponse(c, comments, "Comments retrieved successfully")
+}
+
+// GetComment godoc
+// @Summary Get a comment
+// @Description Get a single comment without its replies
+// @Tags comments
+// @Produce json
+// @Param id path int true "Todo ID"
+// @Param commentId path int true "Comment ID"
+// @Success 200 {object} dto.APIResponse{data=dto.CommentResponse}
+// @Failure 404 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/todos/{id}/comments/{commentId} [get]
+func (cc *CommentController) GetComment(c *gin.Context) {
+	todoID, id, ok := parseCommentParams(c)
+	if !ok {
+		return
+	}
+
+	comment, err := cc.commentService.GetComment(c.Request.Context(), c.GetString(middleware.IdentityKey), todoID, id)
+	if err != nil {
+		writeCommentError(c, "Failed to get comment: ", err)
+		return
+	}
+
+	utils.SuccessResponse(c, comment, "Comment retrieved successfully")
+}
+
+// UpdateComment godoc
+// @Summary Edit a comment
+// @Description Replace the body of a comment; the previous body is kept in its edit history. Only the author may edit a comment.
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param id path int true "Todo ID"
+// @Param commentId path int true "Comment ID"
+// @Param comment body dto.UpdateCommentRequest true "New body"
+// @Success 200 {object} dto.APIResponse{data=dto.CommentResponse}
+// @Failure 400 {object} dto.APIResponse
+// @Failure 403 {object} dto.APIResponse
+// @Failure 404 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/todos/{id}/comments/{commentId} [put]
+func (cc *CommentController) UpdateComment(c *gin.Context) {
+	todoID, id, ok := parseCommentParams(c)
+	if !ok {
+		return
+	}
+
+	var req dto.UpdateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	comment, err := cc.commentService.UpdateComment(c.Request.Context(), c.GetString(middleware.IdentityKey), todoID, id, &req)
+	if err != nil {
+		writeCommentError(c, "Failed to update comment: ", err)
+		return
+	}
+
+	utils.SuccessResponse(c, comment, "Comment updated successfully")
+}
+
+// DeleteComment godoc
+// @Summary Delete a comment
+// @Description Delete a comment. A comment with replies keeps its place in the thread without a body. Only the author may delete a comment.
+// @Tags comments
+// @Produce json
+// @Param id path int true "Todo ID"
+// @Param commentId path int true "Comment ID"
+// @Success 200 {object} dto.APIResponse
+// @Failure 403 {object} dto.APIResponse
+// @Failure 404 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/todos/{id}/comments/{commentId} [delete]
+func (cc *CommentController) DeleteComment(c *gin.Context) {
+	todoID, id, ok := parseCommentParams(c)
+	if !ok {
+		return
+	}
+
+	if err := cc.commentService.DeleteComment(c.Request.Context(), c.GetString(middleware.IdentityKey), todoID, id); err != nil {
+		writeCommentError(c, "Failed to delete comment: ", err)
+		return
+	}
+
+	utils.SuccessResponse(c, nil, "Comment deleted successfully")
+}
+
+// GetRevisions godoc
+// @Summary Get the edit history of a comment
+// @Description Earlier bodies of a comment, oldest first, each with the time it was replaced
+// @Tags comments
+// @Produce json
+// @Param id path int true "Todo ID"
+// @Param commentId path int true "Comment ID"
+// @Success 200 {object} dto.APIResponse{data=[]dto.CommentRevisionResponse}
+// @Failure 404 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/todos/{id}/comments/{commentId}/revisions [get]
+func (cc *CommentController) GetRevisions(c *gin.Context) {
+	todoID, id, ok := parseCommentParams(c)
+	if !ok {
+		return
+	}
+
+	revisions, err := cc.commentService.GetRevisions(c.Request.Context(), c.GetString(middleware.IdentityKey), todoID, id)
+	if err != nil {
+		writeCommentError(c, "Failed to get comment history: ", err)
+		return
+	}
+
+	utils.SuccessResponse(c, revisions, "Comment history retrieved successfully")
+}
+
+func parseCommentParams(c *gin.Context) (todoID, id uint, ok bool) {
+	if todoID, ok = parseIDParam(c, "id", "Invalid todo ID"); !ok {
+		return 0, 0, false
+	}
+	if id, ok = parseIDParam(c, "commentId", "Invalid comment ID"); !ok {
+		return 0, 0, false
+	}
+	return todoID, id, true
+}
+
+func writeCommentError(c *gin.Context, prefix string, err error) {
+	switch {
+	case err.Error() == "todo not found":
+		utils.NotFoundResponse(c, "Todo not found")
+	case err.Error() == "comment not found":
+		utils.NotFoundResponse(c, "Comment not found")
+	case strings.HasPrefix(err.Error(), "forbidden"):
+		utils.ForbiddenResponse(c, err.Error())
+	case strings.HasPrefix(err.Error(), "validation failed"):
+		utils.BadRequestResponse(c, err.Error())
+	default:
+		utils.InternalServerErrorResponse(c, prefix+err.Error())
+	}
+}
@@ -0,0 +1,187 @@
+package controller
+
+import (
+	"net/http"
+	"strings"
+	"todo-app/depgraph"
+	"todo-app/dto"
+	"todo-app/middleware"
+	"todo-app/service"
+	"todo-app/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DependencyController struct {
+	dependencyService service.DependencyService
+}
+
+func NewDependencyController(dependencyService service.DependencyService) *DependencyController {
+	return &DependencyController{
+		dependencyService: dependencyService,
+	}
+}
+
+// GetDependencies godoc
+// @Summary List the dependencies of a todo
+// @Description The todos blocking a todo and the todos it blocks, as far as the caller can see them; requires the viewer role. blocked is true while any todo blocking it is open.
+// @Tags dependencies
+// @Produce json
+// @Param id path int true "Todo ID"
+// @Success 200 {object} dto.APIResponse{data=dto.DependenciesResponse}
+// @Failure 400 {object} dto.APIResponse
+// @Failure 404 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/todos/{id}/dependencies [get]
+func (dc *DependencyController) GetDependencies(c *gin.Context) {
+	todoID, ok := parseIDParam(c, "id", "Invalid todo ID")
+	if !ok {
+		return
+	}
+
+	dependencies, err := dc.dependencyService.GetDependencies(c.Request.Context(), c.GetString(middleware.IdentityKey), todoID)
+	if err != nil {
+		writeDependencyError(c, "Failed to get dependencies: ", err)
+		return
+	}
+
+	utils.SuccessResponse(c, dependencies, "Dependencies retrieved successfully")
+}
+
+// AddDependency godoc
+// @Summary Block a todo by another
+// @Description Record that the todo cannot be completed until blocked_by is; requires the editor role on the todo and the viewer role on blocked_by. Dependencies that would form a cycle are refused.
+// @Tags dependencies
+// @Accept json
+// @Produce json
+// @Param id path int true "Todo ID"
+// @Param dependency body dto.AddDependencyRequest true "Blocking todo"
+// @Success 201 {object} dto.APIResponse{data=dto.DependenciesResponse}
+// @Failure 400 {object} dto.APIResponse
+// @Failure 403 {object} dto.APIResponse
+// @Failure 404 {object} dto.APIResponse
+// @Failure 409 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/todos/{id}/dependencies [post]
+func (dc *DependencyController) AddDependency(c *gin.Context) {
+	todoID, ok := parseIDParam(c, "id", "Invalid todo ID")
+	if !ok {
+		return
+	}
+
+	var req dto.AddDependencyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	dependencies, err := dc.dependencyService.AddDependency(c.Request.Context(), c.GetString(middleware.IdentityKey), todoID, &req)
+	if err != nil {
+		writeDependencyError(c, "Failed to add dependency: ", err)
+		return
+	}
+
+	utils.CreatedResponse(c, dependencies, "Dependency added successfully")
+}
+
+// RemoveDependency godoc
+// @Summary Stop blocking a todo by another
+// @Description Remove the dependency of the todo on blockerId; requires the editor role on the todo
+// @Tags dependencies
+// @Produce json
+// @Param id path int true "Todo ID"
+// @Param blockerId path int true "Blocking todo ID"
+// @Success 200 {object} dto.APIResponse{data=dto.DependenciesResponse}
+// @Failure 400 {object} dto.APIResponse
+// @Failure 403 {object} dto.APIResponse
+// @Failure 404 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/todos/{id}/dependencies/{blockerId} [delete]
+func (dc *DependencyController) RemoveDependency(c *gin.Context) {
+	todoID, ok := parseIDParam(c, "id", "Invalid todo ID")
+	if !ok {
+		return
+	}
+	blockerID, ok := parseIDParam(c, "blockerId", "Invalid blocker ID")
+	if !ok {
+		return
+	}
+
+	dependencies, err := dc.dependencyService.RemoveDependency(c.Request.Context(), c.GetString(middleware.IdentityKey), todoID, blockerID)
+	if err != nil {
+		writeDependencyError(c, "Failed to remove dependency: ", err)
+		return
+	}
+
+	utils.SuccessResponse(c, dependencies, "Dependency removed successfully")
+}
+
+// GetGraph godoc
+// @Summary Export the dependency graph
+// @Description The todos the caller can see that block or are blocked by others, with edges from each blocker to the todo it blocks. format=dot renders a Graphviz digraph and format=mermaid a Mermaid flowchart; completed todos are dimmed.
+// @Tags dependencies
+// @Produce json
+// @Produce text/vnd.graphviz
+// @Produce text/plain
+// @Param format query string false "json (default), dot or mermaid"
+// @Success 200 {object} dto.APIResponse{data=dto.DependencyGraph}
+// @Failure 400 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/todos/dependencies/graph [get]
+func (dc *DependencyController) GetGraph(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "dot" && format != "mermaid" {
+		utils.BadRequestResponse(c, "Invalid format parameter")
+		return
+	}
+
+	graph, err := dc.dependencyService.GetGraph(c.Request.Context(), c.GetString(middleware.IdentityKey))
+	if err != nil {
+		writeDependencyError(c, "Failed to get dependency graph: ", err)
+		return
+	}
+
+	switch format {
+	case "dot":
+		c.Header("Content-Type", "text/vnd.graphviz; charset=utf-8")
+		c.Status(http.StatusOK)
+		depgraph.WriteDOT(c.Writer, graphToDepgraph(graph))
+	case "mermaid":
+		c.Header("Content-Type", "text/plain; charset=utf-8")
+		c.Status(http.StatusOK)
+		depgraph.WriteMermaid(c.Writer, graphToDepgraph(graph))
+	default:
+		utils.SuccessResponse(c, graph, "Dependency graph retrieved successfully")
+	}
+}
+
+func graphToDepgraph(graph *dto.DependencyGraph) *depgraph.Graph {
+	g := &depgraph.Graph{
+		Nodes: make([]depgraph.Node, len(graph.Nodes)),
+		Edges: make([]depgraph.Edge, len(graph.Edges)),
+	}
+	for i, n := range graph.Nodes {
+		g.Nodes[i] = depgraph.Node{ID: n.ID, Label: n.Title, Done: n.Completed}
+	}
+	for i, e := range graph.Edges {
+		g.Edges[i] = depgraph.Edge{From: e.From, To: e.To}
+	}
+	return g
+}
+
+func writeDependencyError(c *gin.Context, prefix string, err error) {
+	switch {
+	case err.Error() == "todo not found":
+		utils.NotFoundResponse(c, "Todo not found")
+	case err.Error() == "dependency not found":
+		utils.NotFoundResponse(c, "Dependency not found")
+	case strings.HasPrefix(err.Error(), "forbidden"):
+		utils.ForbiddenResponse(c, err.Error())
+	case strings.HasPrefix(err.Error(), "validation failed"):
+		utils.BadRequestResponse(c, err.Error())
+	case strings.HasPrefix(err.Error(), "dependency cycle"):
+		utils.ErrorResponse(c, http.StatusConflict, err.Error(), "Conflict")
+	default:
+		utils.InternalServerErrorResponse(c, prefix+err.Error())
+	}
+}
@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"todo-app/dto"
+	"todo-app/middleware"
+	"todo-app/models"
+	"todo-app/stream"
+	"todo-app/tenant"
+	"todo-app/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type EventStreamController struct {
+	hub       *stream.Hub
+	heartbeat time.Duration
+}
+
+func NewEventStreamController(hub *stream.Hub, heartbeat time.Duration) *EventStreamController {
+	return &EventStreamController{
+		hub:       hub,
+		heartbeat: heartbeat,
+	}
+}
+
+// StreamTodoEvents godoc
+// @Summary Stream todo changes
+// @Description Server-Sent Events stream of todo.created, todo.updated, todo.completed and todo.deleted events about the todos the caller can see. Reconnect with the Last-Event-ID header (or last_event_id parameter) to replay missed events; a reset event means they are no longer available and the client should reload the list. The blocked filter of the list is not supported, since a todo becomes unblocked without an event of its own.
+// @Tags todos
+// @Produce text/event-stream
+// @Param completed query bool false "Only events for todos with this completion status"
+// @Param priority query string false "Only events for todos with this priority (LOW, MEDIUM, HIGH)"
+// @Param last_event_id query int false "Resume after this event ID"
+// @Success 200 {string} string "event stream"
+// @Failure 400 {object} dto.APIResponse
+// @Failure 503 {object} dto.APIResponse
+// @Router /api/todos/events [get]
+func (ec *EventStreamController) StreamTodoEvents(c *gin.Context) {
+	completed, priority, ok := parseTodoFilters(c)
+	if !ok {
+		return
+	}
+	// Completing a blocker changes whether the todos it blocks are blocked
+	// but publishes events only about the blocker
+	if _, ok := c.GetQuery("blocked"); ok {
+		utils.BadRequestResponse(c, "The blocked filter is not supported on the event stream")
+		return
+	}
+	identity := c.GetString(middleware.IdentityKey)
+	workspace, _ := tenant.FromContext(c.Request.Context())
+
+	lastIDStr := c.GetHeader("Last-Event-ID")
+	if lastIDStr == "" {
+		lastIDStr = c.Query("last_event_id")
+	}
+	var lastID uint64
+	resume := lastIDStr != ""
+	if resume {
+		var err error
+		if lastID, err = strconv.ParseUint(lastIDStr, 10, 64); err != nil {
+			utils.BadRequestResponse(c, "Invalid Last-Event-ID")
+			return
+		}
+	}
+
+	sub, replay, complete, err := ec.hub.Subscribe(lastID, resume)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusServiceUnavailable, "Server is shutting down", "Service Unavailable")
+		return
+	}
+	defer sub.Close()
+
+	// Streams outlive the server's write timeout
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	w := c.Writer
+	fmt.Fprint(w, "retry: 3000\n\n")
+
+	if !complete {
+		fmt.Fprintf(w, "id: %d\nevent: reset\ndata: {}\n\n", ec.hub.LastID())
+	}
+	for _, msg := range replay {
+		if msg.Event.VisibleTo(workspace, identity) && matchesFilters(msg.Event.Todo, completed, priority) {
+			writeSSE(w, msg)
+		}
+	}
+	w.Flush()
+
+	heartbeat := time.NewTicker(ec.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			w.Flush()
+		case msg, ok := <-sub.C:
+			if !ok {
+				// Closed by shutdown or because the client fell behind; the
+				// client reconnects and resumes from its last event ID
+				return
+			}
+			if msg.Event.VisibleTo(workspace, identity) && matchesFilters(msg.Event.Todo, completed, priority) {
+				writeSSE(w, msg)
+				w.Flush()
+			}
+		}
+	}
+}
+
+func writeSSE(w gin.ResponseWriter, msg stream.Message) {
+	data, err := json.Marshal(msg.Event)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", msg.ID, msg.Event.Type, data)
+}
+
+// matchesFilters applies the list endpoint's filters to an event's todo.
+func matchesFilters(todo *dto.TodoResponse, completed *bool, priority *models.Priority) bool {
+	if todo == nil {
+		return true
+	}
+	if completed != nil && todo.Completed != *completed {
+		return false
+	}
+	if priority != nil && todo.Priority != *priority {
+		return false
+	}
+	return true
+}
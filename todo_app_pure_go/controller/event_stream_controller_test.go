@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"todo-app/dto"
+	"todo-app/events"
+	"todo-app/models"
+	"todo-app/stream"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestStreamTodoEventsResumesWithFilters(t *testing.T) {
+	hub := stream.NewHub(10)
+	hub.Publish(events.New(events.TodoCreated, &dto.TodoResponse{ID: 1, Priority: models.HIGH}))
+	hub.Publish(events.New(events.TodoCreated, &dto.TodoResponse{ID: 2, Priority: models.LOW}))
+	hub.Publish(events.New(events.TodoCompleted, &dto.TodoResponse{ID: 3, Priority: models.HIGH, Completed: true}))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/todos/events", NewEventStreamController(hub, time.Hour).StreamTodoEvents)
+
+	// A cancelled request still gets the replay before the handler returns
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodGet, "/api/todos/events?priority=HIGH", nil).WithContext(ctx)
+	req.Header.Set("Last-Event-ID", "1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	body := w.Body.String()
+	if strings.Contains(body, "id: 1\n") || strings.Contains(body, "id: 2\n") {
+		t.Errorf("replayed events that were seen or filtered out:\n%s", body)
+	}
+	if !strings.Contains(body, "id: 3\nevent: todo.completed\ndata: ") {
+		t.Errorf("missing replayed event:\n%s", body)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/todos/events", nil).WithContext(ctx)
+	req.Header.Set("Last-Event-ID", "99")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if !strings.Contains(w.Body.String(), "event: reset\n") {
+		t.Errorf("expected reset for unknown ID:\n%s", w.Body.String())
+	}
+}
+
+func TestStreamTodoEventsRejectsBlockedFilter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/todos/events", NewEventStreamController(stream.NewHub(10), time.Hour).StreamTodoEvents)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/todos/events?blocked=true", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "blocked") {
+		t.Errorf("blocked filter: %d %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,167 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"todo-app/gql"
+	"todo-app/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// maxGraphQLBodySize bounds the size of a GraphQL request body.
+const maxGraphQLBodySize = 1 << 20
+
+type GraphQLController struct {
+	server   *gql.Server
+	maxBatch int
+	graphiQL bool
+	limiter  *middleware.RateLimiter
+	// limits maps mutation kinds to the rate limits of the REST routes
+	// making the same change
+	limits map[string]middleware.RouteLimit
+}
+
+// NewGraphQLController serves GraphQL requests; graphiQL enables the
+// in-browser IDE, which is meant for development only. Every mutation,
+// including each one of a batch, spends the limit of its kind.
+func NewGraphQLController(server *gql.Server, maxBatch int, graphiQL bool, limiter *middleware.RateLimiter, limits map[string]middleware.RouteLimit) *GraphQLController {
+	return &GraphQLController{
+		server:   server,
+		maxBatch: maxBatch,
+		graphiQL: graphiQL,
+		limiter:  limiter,
+		limits:   limits,
+	}
+}
+
+// Query godoc
+// @Summary GraphQL endpoint
+// @Description Execute a GraphQL operation, or a JSON array of operations as a batch. Queries may also be sent with GET; mutations require POST. Operations over the complexity or depth limit are rejected before execution.
+// @Tags graphql
+// @Accept json
+// @Produce json
+// @Param request body gql.Request true "GraphQL request or array of requests"
+// @Success 200 {object} graphql.Result
+// @Failure 400 {object} graphql.Result
+// @Router /graphql [post]
+func (gc *GraphQLController) Query(c *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxGraphQLBodySize))
+	if err != nil {
+		writeGraphQLError(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var batch []gql.Request
+		if err := json.Unmarshal(body, &batch); err != nil {
+			writeGraphQLError(c, "Invalid request body: "+err.Error())
+			return
+		}
+		if len(batch) == 0 || len(batch) > gc.maxBatch {
+			writeGraphQLError(c, fmt.Sprintf("A batch must contain between 1 and %d operations", gc.maxBatch))
+			return
+		}
+
+		results := make([]*graphql.Result, len(batch))
+		for i, req := range batch {
+			results[i] = gc.server.Execute(gc.operationContext(c), req, true)
+		}
+		c.JSON(http.StatusOK, results)
+		return
+	}
+
+	var req gql.Request
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeGraphQLError(c, "Invalid request body: "+err.Error())
+		return
+	}
+	if req.Query == "" {
+		writeGraphQLError(c, "Missing query")
+		return
+	}
+
+	c.JSON(http.StatusOK, gc.server.Execute(gc.operationContext(c), req, true))
+}
+
+// QueryGET godoc
+// @Summary GraphQL endpoint (GET)
+// @Description Execute a GraphQL query passed in the query string. When graphql.graphiql is enabled a browser request without a query opens GraphiQL.
+// @Tags graphql
+// @Produce json
+// @Param query query string true "GraphQL query"
+// @Param operationName query string false "Operation to execute"
+// @Param variables query string false "JSON-encoded variables"
+// @Success 200 {object} graphql.Result
+// @Failure 400 {object} graphql.Result
+// @Router /graphql [get]
+func (gc *GraphQLController) QueryGET(c *gin.Context) {
+	req := gql.Request{
+		Query:         c.Query("query"),
+		OperationName: c.Query("operationName"),
+	}
+	if req.Query == "" {
+		if gc.graphiQL && strings.Contains(c.GetHeader("Accept"), "text/html") {
+			c.Data(http.StatusOK, "text/html; charset=utf-8", graphiQLPage)
+			return
+		}
+		writeGraphQLError(c, "Missing query")
+		return
+	}
+	if variables := c.Query("variables"); variables != "" {
+		if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+			writeGraphQLError(c, "Invalid variables: "+err.Error())
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gc.server.Execute(gc.operationContext(c), req, false))
+}
+
+// operationContext runs operations on behalf of the caller's identity, and
+// within the caller's write limits.
+func (gc *GraphQLController) operationContext(c *gin.Context) context.Context {
+	ctx := gql.WithPrincipal(c.Request.Context(), c.GetString(middleware.IdentityKey))
+	if gc.limiter != nil {
+		ctx = gql.WithLimiter(ctx, mutationLimiter(c, gc.limiter, gc.limits))
+	}
+	return ctx
+}
+
+// writeGraphQLError reports a request that could not be read as a GraphQL
+// operation at all, in the GraphQL response format.
+func writeGraphQLError(c *gin.Context, message string) {
+	c.JSON(http.StatusBadRequest, graphql.Result{
+		Errors: []gqlerrors.FormattedError{gqlerrors.NewFormattedError(message)},
+	})
+}
+
+var graphiQLPage = []byte(`<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>Todo API - GraphiQL</title>
+  <link rel="stylesheet" href="https://unpkg.com/graphiql@3/graphiql.min.css">
+  <style>body { margin: 0; height: 100vh; } #graphiql { height: 100vh; }</style>
+</head>
+<body>
+  <div id="graphiql"></div>
+  <script crossorigin src="https://unpkg.com/react@18/umd/react.production.min.js"></script>
+  <script crossorigin src="https://unpkg.com/react-dom@18/umd/react-dom.production.min.js"></script>
+  <script crossorigin src="https://unpkg.com/graphiql@3/graphiql.min.js"></script>
+  <script>
+    const fetcher = GraphiQL.createFetcher({ url: window.location.pathname });
+    ReactDOM.createRoot(document.getElementById('graphiql')).render(React.createElement(GraphiQL, { fetcher }));
+  </script>
+</body>
+</html>
+`)
@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"net/http"
+
+	"todo-app/health"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthController struct {
+	registry *health.Registry
+}
+
+func NewHealthController(registry *health.Registry) *HealthController {
+	return &HealthController{
+		registry: registry,
+	}
+}
+
+// Live godoc
+// @Summary Liveness probe
+// @Description Report whether the process is alive and making progress
+// @Tags health
+// @Produce json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /healthz/live [get]
+func (hc *HealthController) Live(c *gin.Context) {
+	writeReport(c, hc.registry.Live(c.Request.Context()))
+}
+
+// Ready godoc
+// @Summary Readiness probe
+// @Description Report whether the instance can serve traffic, with per-check status and latency
+// @Tags health
+// @Produce json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /healthz/ready [get]
+func (hc *HealthController) Ready(c *gin.Context) {
+	writeReport(c, hc.registry.Ready(c.Request.Context()))
+}
+
+func writeReport(c *gin.Context, report health.Report) {
+	status := http.StatusOK
+	if report.Status != health.StatusUp {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"todo-app/config"
+	"todo-app/middleware"
+	"todo-app/realtime"
+	"todo-app/service"
+	"todo-app/stream"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+type RealtimeController struct {
+	todoService service.TodoService
+	hub         *stream.Hub
+	upgrader    websocket.Upgrader
+	limiter     *middleware.RateLimiter
+	// limits maps mutation kinds to the rate limits of the REST routes
+	// making the same change
+	limits map[string]middleware.RouteLimit
+}
+
+func NewRealtimeController(todoService service.TodoService, hub *stream.Hub, cors config.CORSConfig, limiter *middleware.RateLimiter, limits map[string]middleware.RouteLimit) *RealtimeController {
+	return &RealtimeController{
+		todoService: todoService,
+		hub:         hub,
+		limiter:     limiter,
+		limits:      limits,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  4096,
+			WriteBufferSize: 4096,
+			CheckOrigin:     originChecker(cors.AllowedOrigins),
+		},
+	}
+}
+
+// Connect godoc
+// @Summary Real-time collaboration channel
+// @Description Upgrade to a WebSocket. Clients subscribe to filtered views of the todo list, receive matching change events and send create, update and toggle mutations as JSON messages; see package realtime for the protocol.
+// @Tags todos
+// @Success 101 {string} string "Switching Protocols"
+// @Failure 400 {string} string "Not a WebSocket handshake"
+// @Failure 403 {string} string "Origin not allowed"
+// @Router /api/todos/ws [get]
+func (rc *RealtimeController) Connect(c *gin.Context) {
+	conn, err := rc.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already written the error response
+		return
+	}
+	var limit realtime.Limiter
+	if rc.limiter != nil {
+		limit = mutationLimiter(c, rc.limiter, rc.limits)
+	}
+	realtime.Serve(c.Request.Context(), conn, c.GetString(middleware.IdentityKey), rc.todoService, rc.hub, limit)
+}
+
+// mutationLimiter spends the client's REST budgets on the mutations it
+// makes through another channel, so that channel is no way around them.
+// limits maps mutation kinds (create, update, delete, toggle) to the rate
+// limits of the REST routes making the same change.
+func mutationLimiter(c *gin.Context, limiter *middleware.RateLimiter, limits map[string]middleware.RouteLimit) func(kind string) (bool, time.Duration) {
+	return func(kind string) (bool, time.Duration) {
+		route, ok := limits[kind]
+		if !ok {
+			return true, 0
+		}
+		result, err := limiter.Take(c, route.Name, route.Rate)
+		if err != nil {
+			// Fail open like the middleware
+			log.Printf("rate limiter: %v", err)
+			return true, 0
+		}
+		return result.Allowed, result.RetryAfter
+	}
+}
+
+// originChecker applies the CORS origin list to the WebSocket handshake,
+// which browsers do not subject to CORS. Requests without an Origin header
+// come from non-browser clients and are allowed.
+func originChecker(allowedOrigins []string) func(r *http.Request) bool {
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		if origin == "*" {
+			return func(r *http.Request) bool { return true }
+		}
+		allowed[origin] = true
+	}
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		return origin == "" || allowed[origin]
+	}
+}
@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"todo-app/middleware"
+	"todo-app/service"
+	"todo-app/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type StatsController struct {
+	statsService service.StatsService
+}
+
+func NewStatsController(statsService service.StatsService) *StatsController {
+	return &StatsController{
+		statsService: statsService,
+	}
+}
+
+// GetStats godoc
+// @Summary Get todo statistics
+// @Description Counts by priority and completion, completion rate, average time to complete, todos created vs completed per day or week, and the oldest open todos. Counts cover all todos; the timeline and average time to complete cover the date range.
+// @Tags stats
+// @Produce json
+// @Param from query string false "First day of the range, YYYY-MM-DD (default: 29 days before to)"
+// @Param to query string false "Last day of the range, YYYY-MM-DD (default: today, UTC)"
+// @Param interval query string false "Timeline bucket size: day or week (default: day)"
+// @Param oldest query int false "Number of oldest open todos (default: 5, max: 50)"
+// @Success 200 {object} dto.APIResponse{data=dto.StatsResponse}
+// @Failure 400 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/stats [get]
+func (sc *StatsController) GetStats(c *gin.Context) {
+	from, ok := parseDateQuery(c, "from")
+	if !ok {
+		return
+	}
+	to, ok := parseDateQuery(c, "to")
+	if !ok {
+		return
+	}
+	oldest, err := strconv.Atoi(c.DefaultQuery("oldest", "0"))
+	if err != nil {
+		utils.BadRequestResponse(c, "Invalid oldest parameter")
+		return
+	}
+
+	stats, err := sc.statsService.GetStats(c.Request.Context(), c.GetString(middleware.IdentityKey), from, to, c.Query("interval"), oldest)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "validation failed") {
+			utils.BadRequestResponse(c, err.Error())
+			return
+		}
+		utils.InternalServerErrorResponse(c, "Failed to get statistics: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, stats, "Statistics retrieved successfully")
+}
+
+// parseDateQuery parses an optional YYYY-MM-DD query parameter, writing a
+// 400 response when it is invalid. A missing parameter is the zero time.
+func parseDateQuery(c *gin.Context, name string) (time.Time, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return time.Time{}, true
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		utils.BadRequestResponse(c, "Invalid "+name+" parameter, expected YYYY-MM-DD")
+		return time.Time{}, false
+	}
+	return date, true
+}
@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"todo-app/dto"
+	"todo-app/middleware"
+	"todo-app/service"
+	"todo-app/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SyncController struct {
+	syncService service.SyncService
+}
+
+func NewSyncController(syncService service.SyncService) *SyncController {
+	return &SyncController{
+		syncService: syncService,
+	}
+}
+
+// Sync godoc
+// @Summary Push offline changes and pull changes since a sync token
+// @Description Applies client mutations in order, resolving conflicts per field by last writer wins, then returns every change (deletions as tombstones) since sync_token together with a new token. Omit sync_token for a full sync. A 410 response means the token is too old and the client must do a full sync.
+// @Tags sync
+// @Accept json
+// @Produce json
+// @Param sync body dto.SyncRequest true "Sync token and client mutations"
+// @Success 200 {object} dto.APIResponse{data=dto.SyncResponse}
+// @Failure 400 {object} dto.APIResponse
+// @Failure 410 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/sync [post]
+func (sc *SyncController) Sync(c *gin.Context) {
+	var req dto.SyncRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	sc.sync(c, &req)
+}
+
+// Pull godoc
+// @Summary Pull changes since a sync token
+// @Description Returns every change (deletions as tombstones) since sync_token together with a new token, without pushing mutations. Omit sync_token for a full sync.
+// @Tags sync
+// @Produce json
+// @Param sync_token query string false "Token from the previous sync"
+// @Param limit query int false "Maximum number of changes (default 500, max 1000)"
+// @Success 200 {object} dto.APIResponse{data=dto.SyncResponse}
+// @Failure 400 {object} dto.APIResponse
+// @Failure 410 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/sync [get]
+func (sc *SyncController) Pull(c *gin.Context) {
+	req := dto.SyncRequest{SyncToken: c.Query("sync_token")}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil {
+			utils.BadRequestResponse(c, "Invalid limit parameter")
+			return
+		}
+		req.Limit = limit
+	}
+
+	sc.sync(c, &req)
+}
+
+func (sc *SyncController) sync(c *gin.Context, req *dto.SyncRequest) {
+	response, err := sc.syncService.Sync(c.Request.Context(), c.GetString(middleware.IdentityKey), req)
+	if err != nil {
+		switch {
+		case err.Error() == "sync token expired":
+			utils.ErrorResponse(c, http.StatusGone, "Sync token expired, a full sync is required", "Gone")
+		case strings.HasPrefix(err.Error(), "validation failed"):
+			utils.BadRequestResponse(c, err.Error())
+		default:
+			utils.InternalServerErrorResponse(c, "Failed to sync: "+err.Error())
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, response, "Sync completed")
+}
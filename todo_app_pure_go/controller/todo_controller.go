@@ -1,8 +1,11 @@
 package controller
 
 import (
+	"net/http"
 	"strconv"
+	"strings"
 	"todo-app/dto"
+	"todo-app/middleware"
 	"todo-app/models"
 	"todo-app/service"
 	"todo-app/utils"
@@ -22,13 +25,14 @@ func NewTodoController(todoService service.TodoService) *TodoController {
 
 // CreateTodo godoc
 // @Summary Create a new todo
-// @Description Create a new todo item
+// @Description Create a new todo item owned by the caller
 // @Tags todos
 // @Accept json
 // @Produce json
 // @Param todo body dto.CreateTodoRequest true "Todo object"
 // @Success 201 {object} dto.APIResponse
 // @Failure 400 {object} dto.APIResponse
+// @Failure 403 {object} dto.APIResponse
 // @Failure 500 {object} dto.APIResponse
 // @Router /api/todos [post]
 func (tc *TodoController) CreateTodo(c *gin.Context) {
@@ -38,8 +42,12 @@ func (tc *TodoController) CreateTodo(c *gin.Context) {
 		return
 	}
 
-	todo, err := tc.todoService.CreateTodo(&req)
+	todo, err := tc.todoService.CreateTodo(c.Request.Context(), c.GetString(middleware.IdentityKey), &req)
 	if err != nil {
+		if strings.HasPrefix(err.Error(), "forbidden") {
+			utils.ForbiddenResponse(c, err.Error())
+			return
+		}
 		utils.InternalServerErrorResponse(c, "Failed to create todo: "+err.Error())
 		return
 	}
@@ -47,9 +55,58 @@ func (tc *TodoController) CreateTodo(c *gin.Context) {
 	utils.CreatedResponse(c, todo, "Todo created successfully")
 }
 
+// QuickAdd godoc
+// @Summary Create a todo from one line of text
+// @Description Parse text such as "Buy milk tomorrow 5pm !high #home" and create the todo. Priority markers (!high, !!, !3), #tags, a +project, dates and times ("tomorrow 5pm", "on fri", "march 5", "in 2 hours") and recurrences ("every monday") are recognized in the given time zone; the rest is the title. All of them are stored with the todo, and returned with the character spans they were read from. With preview=true nothing is created.
+// @Tags todos
+// @Accept json
+// @Produce json
+// @Param request body dto.QuickAddRequest true "Text and time zone"
+// @Param preview query bool false "Only parse the text"
+// @Success 200 {object} dto.APIResponse{data=dto.QuickAddResponse}
+// @Success 201 {object} dto.APIResponse{data=dto.QuickAddResponse}
+// @Failure 400 {object} dto.APIResponse
+// @Failure 403 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/todos/quick [post]
+func (tc *TodoController) QuickAdd(c *gin.Context) {
+	var req dto.QuickAddRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request body: "+err.Error())
+		return
+	}
+	preview := false
+	if value := c.Query("preview"); value != "" {
+		var err error
+		if preview, err = strconv.ParseBool(value); err != nil {
+			utils.BadRequestResponse(c, "Invalid preview parameter")
+			return
+		}
+	}
+
+	result, err := tc.todoService.QuickAdd(c.Request.Context(), c.GetString(middleware.IdentityKey), &req, preview)
+	if err != nil {
+		switch {
+		case strings.HasPrefix(err.Error(), "validation failed"):
+			utils.BadRequestResponse(c, err.Error())
+		case strings.HasPrefix(err.Error(), "forbidden"):
+			utils.ForbiddenResponse(c, err.Error())
+		default:
+			utils.InternalServerErrorResponse(c, "Failed to create todo: "+err.Error())
+		}
+		return
+	}
+
+	if preview {
+		utils.SuccessResponse(c, result, "Todo parsed successfully")
+		return
+	}
+	utils.CreatedResponse(c, result, "Todo created successfully")
+}
+
 // GetTodoByID godoc
 // @Summary Get a todo by ID
-// @Description Get a specific todo item by its ID
+// @Description Get a specific todo item by its ID. Todos the caller cannot see are reported as not found.
 // @Tags todos
 // @Accept json
 // @Produce json
@@ -66,7 +123,7 @@ func (tc *TodoController) GetTodoByID(c *gin.Context) {
 		return
 	}
 
-	todo, err := tc.todoService.GetTodoByID(uint(id))
+	todo, err := tc.todoService.GetTodoByID(c.Request.Context(), c.GetString(middleware.IdentityKey), uint(id))
 	if err != nil {
 		if err.Error() == "todo not found" {
 			utils.NotFoundResponse(c, "Todo not found")
@@ -81,12 +138,14 @@ func (tc *TodoController) GetTodoByID(c *gin.Context) {
 
 // GetAllTodos godoc
 // @Summary Get all todos
-// @Description Get all todo items with optional filtering and pagination
+// @Description Get the todo items the caller can see with optional filtering and pagination
 // @Tags todos
 // @Accept json
 // @Produce json
 // @Param completed query bool false "Filter by completion status"
 // @Param priority query string false "Filter by priority (LOW, MEDIUM, HIGH)"
+// @Param blocked query bool false "Filter by whether the todo waits for an open todo"
+// @Param sort query string false "Order by created_at (newest first, default) or position (manual order)"
 // @Param limit query int false "Number of items per page (default: 10, max: 100)"
 // @Param offset query int false "Number of items to skip (default: 0)"
 // @Success 200 {object} dto.PaginatedResponse
@@ -95,32 +154,26 @@ func (tc *TodoController) GetTodoByID(c *gin.Context) {
 // @Router /api/todos [get]
 func (tc *TodoController) GetAllTodos(c *gin.Context) {
 	// Parse query parameters
-	completedStr := c.Query("completed")
-	priorityStr := c.Query("priority")
-	limitStr := c.DefaultQuery("limit", "10")
-	offsetStr := c.DefaultQuery("offset", "0")
-
-	// Parse completed filter
-	var completed *bool
-	if completedStr != "" {
-		completedVal, err := strconv.ParseBool(completedStr)
+	completed, priority, ok := parseTodoFilters(c)
+	if !ok {
+		return
+	}
+	var blocked *bool
+	if blockedStr := c.Query("blocked"); blockedStr != "" {
+		blockedVal, err := strconv.ParseBool(blockedStr)
 		if err != nil {
-			utils.BadRequestResponse(c, "Invalid completed parameter")
+			utils.BadRequestResponse(c, "Invalid blocked parameter")
 			return
 		}
-		completed = &completedVal
+		blocked = &blockedVal
 	}
-
-	// Parse priority filter
-	var priority *models.Priority
-	if priorityStr != "" {
-		priorityVal := models.Priority(priorityStr)
-		if priorityVal != models.LOW && priorityVal != models.MEDIUM && priorityVal != models.HIGH {
-			utils.BadRequestResponse(c, "Invalid priority parameter")
-			return
-		}
-		priority = &priorityVal
+	sort := models.TodoSort(c.DefaultQuery("sort", string(models.SortNewest)))
+	if sort != models.SortNewest && sort != models.SortPosition {
+		utils.BadRequestResponse(c, "Invalid sort parameter")
+		return
 	}
+	limitStr := c.DefaultQuery("limit", "10")
+	offsetStr := c.DefaultQuery("offset", "0")
 
 	// Parse pagination parameters
 	limit, err := strconv.Atoi(limitStr)
@@ -135,7 +188,7 @@ func (tc *TodoController) GetAllTodos(c *gin.Context) {
 		return
 	}
 
-	todos, total, err := tc.todoService.GetAllTodos(completed, priority, limit, offset)
+	todos, total, err := tc.todoService.GetAllTodos(c.Request.Context(), c.GetString(middleware.IdentityKey), completed, blocked, priority, sort, limit, offset)
 	if err != nil {
 		utils.InternalServerErrorResponse(c, "Failed to get todos: "+err.Error())
 		return
@@ -146,7 +199,7 @@ func (tc *TodoController) GetAllTodos(c *gin.Context) {
 
 // UpdateTodo godoc
 // @Summary Update a todo
-// @Description Update an existing todo item
+// @Description Update an existing todo item; requires the editor role. A todo blocked by open todos cannot be completed.
 // @Tags todos
 // @Accept json
 // @Produce json
@@ -154,7 +207,9 @@ func (tc *TodoController) GetAllTodos(c *gin.Context) {
 // @Param todo body dto.UpdateTodoRequest true "Updated todo object"
 // @Success 200 {object} dto.APIResponse
 // @Failure 400 {object} dto.APIResponse
+// @Failure 403 {object} dto.APIResponse
 // @Failure 404 {object} dto.APIResponse
+// @Failure 409 {object} dto.APIResponse
 // @Failure 500 {object} dto.APIResponse
 // @Router /api/todos/{id} [put]
 func (tc *TodoController) UpdateTodo(c *gin.Context) {
@@ -171,12 +226,20 @@ func (tc *TodoController) UpdateTodo(c *gin.Context) {
 		return
 	}
 
-	todo, err := tc.todoService.UpdateTodo(uint(id), &req)
+	todo, err := tc.todoService.UpdateTodo(c.Request.Context(), c.GetString(middleware.IdentityKey), uint(id), &req)
 	if err != nil {
 		if err.Error() == "todo not found" {
 			utils.NotFoundResponse(c, "Todo not found")
 			return
 		}
+		if strings.HasPrefix(err.Error(), "forbidden") {
+			utils.ForbiddenResponse(c, err.Error())
+			return
+		}
+		if strings.HasPrefix(err.Error(), "blocked") {
+			utils.ErrorResponse(c, http.StatusConflict, err.Error(), "Conflict")
+			return
+		}
 		utils.InternalServerErrorResponse(c, "Failed to update todo: "+err.Error())
 		return
 	}
@@ -186,12 +249,13 @@ func (tc *TodoController) UpdateTodo(c *gin.Context) {
 
 // DeleteTodo godoc
 // @Summary Delete a todo
-// @Description Delete a todo item by its ID
+// @Description Delete a todo item by its ID; requires the owner role
 // @Tags todos
 // @Accept json
 // @Produce json
 // @Param id path int true "Todo ID"
 // @Success 200 {object} dto.APIResponse
+// @Failure 403 {object} dto.APIResponse
 // @Failure 404 {object} dto.APIResponse
 // @Failure 500 {object} dto.APIResponse
 // @Router /api/todos/{id} [delete]
@@ -203,12 +267,16 @@ func (tc *TodoController) DeleteTodo(c *gin.Context) {
 		return
 	}
 
-	err = tc.todoService.DeleteTodo(uint(id))
+	err = tc.todoService.DeleteTodo(c.Request.Context(), c.GetString(middleware.IdentityKey), uint(id))
 	if err != nil {
 		if err.Error() == "todo not found" {
 			utils.NotFoundResponse(c, "Todo not found")
 			return
 		}
+		if strings.HasPrefix(err.Error(), "forbidden") {
+			utils.ForbiddenResponse(c, err.Error())
+			return
+		}
 		utils.InternalServerErrorResponse(c, "Failed to delete todo: "+err.Error())
 		return
 	}
@@ -218,13 +286,15 @@ func (tc *TodoController) DeleteTodo(c *gin.Context) {
 
 // ToggleTodoComplete godoc
 // @Summary Toggle todo completion status
-// @Description Toggle the completion status of a todo item
+// @Description Toggle the completion status of a todo item; requires the editor role. A todo blocked by open todos cannot be completed.
 // @Tags todos
 // @Accept json
 // @Produce json
 // @Param id path int true "Todo ID"
 // @Success 200 {object} dto.APIResponse
+// @Failure 403 {object} dto.APIResponse
 // @Failure 404 {object} dto.APIResponse
+// @Failure 409 {object} dto.APIResponse
 // @Failure 500 {object} dto.APIResponse
 // @Router /api/todos/{id}/toggle [patch]
 func (tc *TodoController) ToggleTodoComplete(c *gin.Context) {
@@ -235,15 +305,187 @@ func (tc *TodoController) ToggleTodoComplete(c *gin.Context) {
 		return
 	}
 
-	todo, err := tc.todoService.ToggleTodoComplete(uint(id))
+	todo, err := tc.todoService.ToggleTodoComplete(c.Request.Context(), c.GetString(middleware.IdentityKey), uint(id))
 	if err != nil {
 		if err.Error() == "todo not found" {
 			utils.NotFoundResponse(c, "Todo not found")
 			return
 		}
+		if strings.HasPrefix(err.Error(), "forbidden") {
+			utils.ForbiddenResponse(c, err.Error())
+			return
+		}
+		if strings.HasPrefix(err.Error(), "blocked") {
+			utils.ErrorResponse(c, http.StatusConflict, err.Error(), "Conflict")
+			return
+		}
 		utils.InternalServerErrorResponse(c, "Failed to toggle todo: "+err.Error())
 		return
 	}
 
 	utils.SuccessResponse(c, todo, "Todo completion status toggled successfully")
 }
+
+// TransitionTodo godoc
+// @Summary Move a todo to another status
+// @Description Move a todo to another status of the workflow; requires the editor role. The workflow decides which statuses a todo may move to from its current one, see GET /api/workflow. Moving a todo to a done status completes it, which a todo blocked by open todos cannot be.
+// @Tags todos
+// @Accept json
+// @Produce json
+// @Param id path int true "Todo ID"
+// @Param transition body dto.TransitionTodoRequest true "Status to move to"
+// @Success 200 {object} dto.APIResponse{data=dto.TodoResponse}
+// @Failure 400 {object} dto.APIResponse
+// @Failure 403 {object} dto.APIResponse
+// @Failure 404 {object} dto.APIResponse
+// @Failure 409 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/todos/{id}/transition [post]
+func (tc *TodoController) TransitionTodo(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "Invalid todo ID")
+		return
+	}
+
+	var req dto.TransitionTodoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	todo, err := tc.todoService.TransitionTodo(c.Request.Context(), c.GetString(middleware.IdentityKey), uint(id), &req)
+	if err != nil {
+		switch {
+		case err.Error() == "todo not found":
+			utils.NotFoundResponse(c, "Todo not found")
+		case strings.HasPrefix(err.Error(), "validation failed"):
+			utils.BadRequestResponse(c, err.Error())
+		case strings.HasPrefix(err.Error(), "forbidden"):
+			utils.ForbiddenResponse(c, err.Error())
+		case strings.HasPrefix(err.Error(), "invalid transition"), strings.HasPrefix(err.Error(), "blocked"):
+			utils.ErrorResponse(c, http.StatusConflict, err.Error(), "Conflict")
+		default:
+			utils.InternalServerErrorResponse(c, "Failed to move todo: "+err.Error())
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, todo, "Todo status changed successfully")
+}
+
+// MoveTodo godoc
+// @Summary Move a todo in the manual order
+// @Description Drop a todo between two neighbours in the manual order listed with sort=position; requires the editor role on the todo. Give after, before or both; with one neighbour the todo goes right next to it.
+// @Tags todos
+// @Accept json
+// @Produce json
+// @Param id path int true "Todo ID"
+// @Param move body dto.MoveTodoRequest true "Neighbours to move between"
+// @Success 200 {object} dto.APIResponse{data=dto.TodoResponse}
+// @Failure 400 {object} dto.APIResponse
+// @Failure 403 {object} dto.APIResponse
+// @Failure 404 {object} dto.APIResponse
+// @Failure 409 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/todos/{id}/move [post]
+func (tc *TodoController) MoveTodo(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "Invalid todo ID")
+		return
+	}
+
+	var req dto.MoveTodoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	todo, err := tc.todoService.MoveTodo(c.Request.Context(), c.GetString(middleware.IdentityKey), uint(id), &req)
+	if err != nil {
+		switch {
+		case err.Error() == "todo not found":
+			utils.NotFoundResponse(c, "Todo not found")
+		case strings.HasPrefix(err.Error(), "validation failed"):
+			utils.BadRequestResponse(c, err.Error())
+		case strings.HasPrefix(err.Error(), "forbidden"):
+			utils.ForbiddenResponse(c, err.Error())
+		case strings.HasPrefix(err.Error(), "conflict"):
+			utils.ErrorResponse(c, http.StatusConflict, err.Error(), "Conflict")
+		default:
+			utils.InternalServerErrorResponse(c, "Failed to move todo: "+err.Error())
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, todo, "Todo moved successfully")
+}
+
+// GetStatusHistory godoc
+// @Summary Get the status history of a todo
+// @Description Get the status changes of a todo and how long it spent in each status, the current one until now; requires the viewer role
+// @Tags todos
+// @Produce json
+// @Param id path int true "Todo ID"
+// @Success 200 {object} dto.APIResponse{data=dto.StatusHistoryResponse}
+// @Failure 400 {object} dto.APIResponse
+// @Failure 404 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/todos/{id}/status-history [get]
+func (tc *TodoController) GetStatusHistory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "Invalid todo ID")
+		return
+	}
+
+	history, err := tc.todoService.GetStatusHistory(c.Request.Context(), c.GetString(middleware.IdentityKey), uint(id))
+	if err != nil {
+		if err.Error() == "todo not found" {
+			utils.NotFoundResponse(c, "Todo not found")
+			return
+		}
+		utils.InternalServerErrorResponse(c, "Failed to retrieve status history: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, history, "Status history retrieved successfully")
+}
+
+// GetWorkflow godoc
+// @Summary Get the workflow
+// @Description Get the statuses todos move through, which of them count as completed and the transitions allowed between them
+// @Tags todos
+// @Produce json
+// @Success 200 {object} dto.APIResponse{data=dto.WorkflowResponse}
+// @Router /api/workflow [get]
+func (tc *TodoController) GetWorkflow(c *gin.Context) {
+	utils.SuccessResponse(c, tc.todoService.GetWorkflow(c.Request.Context()), "Workflow retrieved successfully")
+}
+
+// parseTodoFilters reads the completed and priority filters shared by the
+// list and export endpoints, writing a 400 response when they are invalid.
+func parseTodoFilters(c *gin.Context) (*bool, *models.Priority, bool) {
+	var completed *bool
+	if completedStr := c.Query("completed"); completedStr != "" {
+		completedVal, err := strconv.ParseBool(completedStr)
+		if err != nil {
+			utils.BadRequestResponse(c, "Invalid completed parameter")
+			return nil, nil, false
+		}
+		completed = &completedVal
+	}
+
+	var priority *models.Priority
+	if priorityStr := c.Query("priority"); priorityStr != "" {
+		priorityVal := models.Priority(priorityStr)
+		if priorityVal != models.LOW && priorityVal != models.MEDIUM && priorityVal != models.HIGH {
+			utils.BadRequestResponse(c, "Invalid priority parameter")
+			return nil, nil, false
+		}
+		priority = &priorityVal
+	}
+
+	return completed, priority, true
+}
@@ -0,0 +1,305 @@
+package controller
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"todo-app/dto"
+	"todo-app/middleware"
+	"todo-app/models"
+	"todo-app/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportSize bounds the size of an uploaded CSV file.
+const maxImportSize = 10 << 20
+
+var csvExportColumns = []string{"id", "title", "description", "priority", "completed", "created_at", "updated_at"}
+
+// csvImportFields are the todo fields a CSV column can be mapped to.
+var csvImportFields = map[string]bool{
+	"title":       true,
+	"description": true,
+	"priority":    true,
+	"completed":   true,
+}
+
+// ExportTodosCSV godoc
+// @Summary Export todos as CSV
+// @Description Stream all todos matching the filters as CSV, without pagination
+// @Tags todos
+// @Produce text/csv
+// @Param completed query bool false "Filter by completion status"
+// @Param priority query string false "Filter by priority (LOW, MEDIUM, HIGH)"
+// @Success 200 {string} string "CSV file"
+// @Failure 400 {object} dto.APIResponse
+// @Router /api/todos/export.csv [get]
+func (tc *TodoController) ExportTodosCSV(c *gin.Context) {
+	completed, priority, ok := parseTodoFilters(c)
+	if !ok {
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="todos-%s.csv"`, time.Now().UTC().Format("20060102")))
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	if err := w.Write(csvExportColumns); err != nil {
+		return
+	}
+
+	rows := 0
+	err := tc.todoService.ExportTodos(c.Request.Context(), c.GetString(middleware.IdentityKey), completed, priority, func(todo *dto.TodoResponse) error {
+		description := ""
+		if todo.Description != nil {
+			description = *todo.Description
+		}
+		if err := w.Write([]string{
+			strconv.FormatUint(uint64(todo.ID), 10),
+			csvSafe(todo.Title),
+			csvSafe(description),
+			string(todo.Priority),
+			strconv.FormatBool(todo.Completed),
+			todo.CreatedAt.UTC().Format(time.RFC3339),
+			todo.UpdatedAt.UTC().Format(time.RFC3339),
+		}); err != nil {
+			return err
+		}
+		rows++
+		if rows%500 == 0 {
+			w.Flush()
+			c.Writer.Flush()
+		}
+		return w.Error()
+	})
+	w.Flush()
+
+	if err != nil {
+		// Headers are already sent; the truncated body is all we can signal
+		log.Printf("CSV export aborted after %d rows: %v", rows, err)
+	}
+}
+
+// ImportTodosCSV godoc
+// @Summary Import todos from CSV
+// @Description Create todos from a CSV file. Columns are matched to fields by header name (title, description, priority, completed) or by explicit map=Header:field parameters.
+// @Tags todos
+// @Accept multipart/form-data
+// @Accept text/csv
+// @Produce json
+// @Param file formData file false "CSV file (multipart upload)"
+// @Param mode query string false "all_or_nothing (default) or best_effort"
+// @Param dry_run query bool false "Only validate and report which rows would fail"
+// @Param map query []string false "Header mapping such as Task:title" collectionFormat(multi)
+// @Success 200 {object} dto.APIResponse
+// @Failure 400 {object} dto.APIResponse
+// @Failure 403 {object} dto.APIResponse
+// @Failure 422 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/todos/import [post]
+func (tc *TodoController) ImportTodosCSV(c *gin.Context) {
+	opts, ok := parseImportOptions(c)
+	if !ok {
+		return
+	}
+
+	mapping, err := parseHeaderMapping(c.QueryArray("map"))
+	if err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	body, closeBody, err := uploadBody(c)
+	if err != nil {
+		utils.BadRequestResponse(c, "Invalid upload: "+err.Error())
+		return
+	}
+	defer closeBody()
+
+	rows, err := readImportRows(body, mapping)
+	if err != nil {
+		utils.BadRequestResponse(c, "Invalid CSV: "+err.Error())
+		return
+	}
+
+	result, err := tc.todoService.ImportTodos(c.Request.Context(), c.GetString(middleware.IdentityKey), rows, opts)
+	writeImportResult(c, result, err)
+}
+
+// parseImportOptions reads the mode and dry_run parameters shared by the
+// import endpoints, writing a 400 response when they are invalid.
+func parseImportOptions(c *gin.Context) (dto.ImportOptions, bool) {
+	opts := dto.ImportOptions{Mode: dto.ImportMode(c.DefaultQuery("mode", string(dto.ImportAllOrNothing)))}
+	if dryRun := c.Query("dry_run"); dryRun != "" {
+		val, err := strconv.ParseBool(dryRun)
+		if err != nil {
+			utils.BadRequestResponse(c, "Invalid dry_run parameter")
+			return opts, false
+		}
+		opts.DryRun = val
+	}
+	return opts, true
+}
+
+func writeImportResult(c *gin.Context, result *dto.ImportResult, err error) {
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "validation failed") {
+			utils.BadRequestResponse(c, err.Error())
+			return
+		}
+		if strings.HasPrefix(err.Error(), "forbidden") {
+			utils.ForbiddenResponse(c, err.Error())
+			return
+		}
+		utils.InternalServerErrorResponse(c, "Failed to import todos: "+err.Error())
+		return
+	}
+
+	switch {
+	case result.DryRun:
+		utils.SuccessResponse(c, result, "Dry run completed, nothing was imported")
+	case result.Imported == 0 && len(result.Failed) > 0:
+		utils.UnprocessableEntityResponse(c, result, "Import rejected, no rows were imported")
+	default:
+		utils.SuccessResponse(c, result, fmt.Sprintf("Imported %d of %d rows", result.Imported, result.Total))
+	}
+}
+
+// parseHeaderMapping turns "Header:field" pairs into a lower-cased header to
+// field lookup.
+func parseHeaderMapping(pairs []string) (map[string]string, error) {
+	mapping := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		header, field, ok := strings.Cut(pair, ":")
+		field = strings.ToLower(strings.TrimSpace(field))
+		if !ok || strings.TrimSpace(header) == "" || !csvImportFields[field] {
+			return nil, fmt.Errorf("invalid map parameter %q, expected Header:field with field one of title, description, priority, completed", pair)
+		}
+		mapping[strings.ToLower(strings.TrimSpace(header))] = field
+	}
+	return mapping, nil
+}
+
+// uploadBody returns the uploaded file of a multipart request or the raw
+// body otherwise.
+func uploadBody(c *gin.Context) (io.Reader, func(), error) {
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		file, err := c.FormFile("file")
+		if err != nil {
+			return nil, nil, err
+		}
+		f, err := file.Open()
+		if err != nil {
+			return nil, nil, err
+		}
+		return f, func() { f.Close() }, nil
+	}
+	return c.Request.Body, func() {}, nil
+}
+
+func readImportRows(r io.Reader, mapping map[string]string) ([]dto.ImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("file is empty")
+		}
+		return nil, err
+	}
+
+	// Resolve each column to a field: explicit mapping first, then the
+	// header itself when it names a field; other columns are ignored.
+	// Spreadsheet exports often start with a UTF-8 byte order mark.
+	columns := make([]string, len(header))
+	hasTitle := false
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if field, ok := mapping[name]; ok {
+			columns[i] = field
+		} else if csvImportFields[name] {
+			columns[i] = name
+		}
+		hasTitle = hasTitle || columns[i] == "title"
+	}
+	if !hasTitle {
+		return nil, errors.New("no column maps to title")
+	}
+
+	var rows []dto.ImportRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		row := dto.ImportRow{Line: line}
+		for i, value := range record {
+			if i >= len(columns) {
+				break
+			}
+			value = strings.TrimSpace(value)
+			switch columns[i] {
+			case "title":
+				row.Todo.Title = csvUnsafe(value)
+			case "description":
+				if value != "" {
+					description := csvUnsafe(value)
+					row.Todo.Description = &description
+				}
+			case "priority":
+				row.Todo.Priority = models.Priority(strings.ToUpper(value))
+			case "completed":
+				if value == "" {
+					continue
+				}
+				completed, err := strconv.ParseBool(value)
+				if err != nil {
+					row.Errors = append(row.Errors, "completed must be true or false")
+				}
+				row.Completed = completed
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// csvSafe neutralises values a spreadsheet would otherwise evaluate as a
+// formula. Values already quoted that way get another quote so that
+// csvUnsafe restores them exactly.
+func csvSafe(value string) string {
+	if formulaLike(value) {
+		return "'" + value
+	}
+	return value
+}
+
+// csvUnsafe undoes csvSafe on imported values.
+func csvUnsafe(value string) string {
+	if strings.HasPrefix(value, "'") && formulaLike(value[1:]) {
+		return value[1:]
+	}
+	return value
+}
+
+func formulaLike(value string) bool {
+	value = strings.TrimLeft(value, "'")
+	return value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0]))
+}
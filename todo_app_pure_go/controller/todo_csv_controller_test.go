@@ -0,0 +1,188 @@
+package controller
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"todo-app/dto"
+	"todo-app/models"
+	"todo-app/repository"
+	"todo-app/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestRouter(t *testing.T) (*gin.Engine, repository.TodoRepository) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.Todo{}, &models.TodoChange{}, &models.TodoStatusChange{}, &models.TodoDependency{}, &models.Attachment{}, &models.Comment{}, &models.CommentRevision{}, &models.TodoGrant{}, &models.ListGrant{}); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := repository.NewTodoRepository(db)
+	tc := NewTodoController(service.NewTodoService(repo, repository.NewAccessRepository(db), nil, nil, nil))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/todos/export.csv", tc.ExportTodosCSV)
+	router.POST("/api/todos/import", tc.ImportTodosCSV)
+	return router, repo
+}
+
+func importCSV(t *testing.T, router *gin.Engine, query, body string) (*httptest.ResponseRecorder, dto.ImportResult) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/todos/import"+query, strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/csv")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var resp struct {
+		Data dto.ImportResult `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response %q: %v", w.Body.String(), err)
+	}
+	return w, resp.Data
+}
+
+const mixedCSV = `Task,Notes,priority,completed
+Write report,Quarterly numbers,high,false
+,missing title,LOW,false
+Ship release,,URGENT,true
+Book flights,,LOW,yes please
+Call Bob,,,true
+`
+
+func TestImportAllOrNothingRejectsOnAnyFailure(t *testing.T) {
+	router, repo := newTestRouter(t)
+
+	w, result := importCSV(t, router, "?map=Task:title&map=Notes:description", mixedCSV)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d: %s", w.Code, w.Body.String())
+	}
+	if result.Total != 5 || result.Valid != 2 || result.Imported != 0 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	var lines []int
+	for _, failed := range result.Failed {
+		lines = append(lines, failed.Line)
+	}
+	if len(lines) != 3 || lines[0] != 3 || lines[1] != 4 || lines[2] != 5 {
+		t.Errorf("expected failures on lines 3, 4, 5, got %v", lines)
+	}
+
+	if count, _ := repo.GetTotalCount(context.Background(), "", nil, nil, nil); count != 0 {
+		t.Errorf("expected nothing imported, found %d todos", count)
+	}
+}
+
+func TestImportBestEffortAndDryRun(t *testing.T) {
+	router, repo := newTestRouter(t)
+
+	w, result := importCSV(t, router, "?map=Task:title&mode=best_effort&dry_run=true", mixedCSV)
+	if w.Code != http.StatusOK || !result.DryRun || result.Valid != 2 || len(result.Failed) != 3 {
+		t.Fatalf("unexpected dry run: %d %+v", w.Code, result)
+	}
+	if count, _ := repo.GetTotalCount(context.Background(), "", nil, nil, nil); count != 0 {
+		t.Fatalf("dry run must not write, found %d todos", count)
+	}
+
+	w, result = importCSV(t, router, "?map=Task:title&map=Notes:description&mode=best_effort", mixedCSV)
+	if w.Code != http.StatusOK || result.Imported != 2 {
+		t.Fatalf("unexpected import: %d %+v", w.Code, result)
+	}
+
+	completed := true
+	todos, _ := repo.GetAll(context.Background(), "", &completed, nil, nil, "", 10, 0)
+	if len(todos) != 1 || todos[0].Title != "Call Bob" || todos[0].Priority != models.MEDIUM {
+		t.Errorf("unexpected completed todos: %+v", todos)
+	}
+}
+
+func TestExportStreamsFilteredTodos(t *testing.T) {
+	router, repo := newTestRouter(t)
+
+	for i := 0; i < 300; i++ {
+		priority := models.LOW
+		if i%3 == 0 {
+			priority = models.HIGH
+		}
+		if _, err := repo.Create(context.Background(), &models.Todo{Title: "todo", Priority: priority}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := repo.Create(context.Background(), &models.Todo{Title: "=HYPERLINK(\"x\")", Priority: models.HIGH}); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/todos/export.csv?priority=HIGH", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Header plus 100 HIGH todos plus the formula one, beyond the 100-item list cap
+	if len(records) != 102 {
+		t.Fatalf("expected 102 records, got %d", len(records))
+	}
+	if records[0][1] != "title" || records[101][1] != `'=HYPERLINK("x")` {
+		t.Errorf("unexpected records: %v / %v", records[0], records[101])
+	}
+}
+
+func TestExportedFormulasImportUnchanged(t *testing.T) {
+	router, repo := newTestRouter(t)
+	titles := []string{"=SUM(A1:A2)", "+1 for the plan", "-5 minutes", "@home", "'=already quoted", "'plain quote", "Write report"}
+	for _, title := range titles {
+		description := title
+		if _, err := repo.Create(context.Background(), &models.Todo{Title: title, Description: &description, Priority: models.LOW}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/todos/export.csv", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("export: %d", w.Code)
+	}
+
+	imported, importedRepo := newTestRouter(t)
+	if w, result := importCSV(t, imported, "", w.Body.String()); w.Code != http.StatusOK || result.Imported != len(titles) {
+		t.Fatalf("import: %d %+v", w.Code, result)
+	}
+	todos, err := importedRepo.GetAll(context.Background(), "", nil, nil, nil, "", 100, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]bool{}
+	for _, todo := range todos {
+		if todo.Description == nil || *todo.Description != todo.Title {
+			t.Errorf("title %q came back with description %v", todo.Title, todo.Description)
+		}
+		got[todo.Title] = true
+	}
+	for _, title := range titles {
+		if !got[title] {
+			t.Errorf("%q did not survive the round trip, got %v", title, got)
+		}
+	}
+}
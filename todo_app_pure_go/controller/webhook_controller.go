@@ -0,0 +1,241 @@
+package controller
+
+import (
+	"strconv"
+	"strings"
+	"todo-app/dto"
+	"todo-app/middleware"
+	"todo-app/service"
+	"todo-app/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookController struct {
+	webhookService service.WebhookService
+}
+
+func NewWebhookController(webhookService service.WebhookService) *WebhookController {
+	return &WebhookController{
+		webhookService: webhookService,
+	}
+}
+
+// CreateWebhook godoc
+// @Summary Create a webhook
+// @Description Subscribe a URL to todo lifecycle events. The signing secret is only returned in this response.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param webhook body dto.CreateWebhookRequest true "Webhook subscription"
+// @Success 201 {object} dto.APIResponse
+// @Failure 400 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/webhooks [post]
+func (wc *WebhookController) CreateWebhook(c *gin.Context) {
+	var req dto.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	webhook, err := wc.webhookService.CreateWebhook(c.Request.Context(), c.GetString(middleware.IdentityKey), &req)
+	if err != nil {
+		writeWebhookError(c, "Failed to create webhook: ", err)
+		return
+	}
+
+	utils.CreatedResponse(c, webhook, "Webhook created successfully")
+}
+
+// GetWebhooks godoc
+// @Summary List webhooks
+// @Description List the caller's webhook subscriptions
+// @Tags webhooks
+// @Produce json
+// @Success 200 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/webhooks [get]
+func (wc *WebhookController) GetWebhooks(c *gin.Context) {
+	webhooks, err := wc.webhookService.GetWebhooks(c.Request.Context(), c.GetString(middleware.IdentityKey))
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to get webhooks: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, webhooks, "Webhooks retrieved successfully")
+}
+
+// GetWebhookByID godoc
+// @Summary Get a webhook
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} dto.APIResponse
+// @Failure 404 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/webhooks/{id} [get]
+func (wc *WebhookController) GetWebhookByID(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "Invalid webhook ID")
+	if !ok {
+		return
+	}
+
+	webhook, err := wc.webhookService.GetWebhookByID(c.Request.Context(), c.GetString(middleware.IdentityKey), id)
+	if err != nil {
+		writeWebhookError(c, "Failed to get webhook: ", err)
+		return
+	}
+
+	utils.SuccessResponse(c, webhook, "Webhook retrieved successfully")
+}
+
+// UpdateWebhook godoc
+// @Summary Update a webhook
+// @Description Change the target URL, secret, event filter or active flag
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param webhook body dto.UpdateWebhookRequest true "Fields to update"
+// @Success 200 {object} dto.APIResponse
+// @Failure 400 {object} dto.APIResponse
+// @Failure 404 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/webhooks/{id} [put]
+func (wc *WebhookController) UpdateWebhook(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "Invalid webhook ID")
+	if !ok {
+		return
+	}
+
+	var req dto.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	webhook, err := wc.webhookService.UpdateWebhook(c.Request.Context(), c.GetString(middleware.IdentityKey), id, &req)
+	if err != nil {
+		writeWebhookError(c, "Failed to update webhook: ", err)
+		return
+	}
+
+	utils.SuccessResponse(c, webhook, "Webhook updated successfully")
+}
+
+// DeleteWebhook godoc
+// @Summary Delete a webhook
+// @Description Delete a webhook subscription and its delivery log
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} dto.APIResponse
+// @Failure 404 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/webhooks/{id} [delete]
+func (wc *WebhookController) DeleteWebhook(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "Invalid webhook ID")
+	if !ok {
+		return
+	}
+
+	if err := wc.webhookService.DeleteWebhook(c.Request.Context(), c.GetString(middleware.IdentityKey), id); err != nil {
+		writeWebhookError(c, "Failed to delete webhook: ", err)
+		return
+	}
+
+	utils.SuccessResponse(c, nil, "Webhook deleted successfully")
+}
+
+// GetDeliveries godoc
+// @Summary List webhook deliveries
+// @Description Delivery log of a webhook with status, attempts and response codes, newest first
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param limit query int false "Number of items per page (default: 20, max: 100)"
+// @Param offset query int false "Number of items to skip (default: 0)"
+// @Success 200 {object} dto.PaginatedResponse
+// @Failure 404 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/webhooks/{id}/deliveries [get]
+func (wc *WebhookController) GetDeliveries(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "Invalid webhook ID")
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil {
+		utils.BadRequestResponse(c, "Invalid limit parameter")
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil {
+		utils.BadRequestResponse(c, "Invalid offset parameter")
+		return
+	}
+
+	deliveries, total, err := wc.webhookService.GetDeliveries(c.Request.Context(), c.GetString(middleware.IdentityKey), id, limit, offset)
+	if err != nil {
+		writeWebhookError(c, "Failed to get deliveries: ", err)
+		return
+	}
+
+	utils.PaginatedSuccessResponse(c, deliveries, total, limit, offset, "Deliveries retrieved successfully")
+}
+
+// Redeliver godoc
+// @Summary Redeliver a webhook delivery
+// @Description Send the payload of an earlier delivery again; the attempt is recorded as a new delivery
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param deliveryId path int true "Delivery ID"
+// @Success 202 {object} dto.APIResponse
+// @Failure 404 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/webhooks/{id}/deliveries/{deliveryId}/redeliver [post]
+func (wc *WebhookController) Redeliver(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "Invalid webhook ID")
+	if !ok {
+		return
+	}
+	deliveryID, ok := parseIDParam(c, "deliveryId", "Invalid delivery ID")
+	if !ok {
+		return
+	}
+
+	delivery, err := wc.webhookService.Redeliver(c.Request.Context(), c.GetString(middleware.IdentityKey), id, deliveryID)
+	if err != nil {
+		writeWebhookError(c, "Failed to redeliver: ", err)
+		return
+	}
+
+	utils.AcceptedResponse(c, delivery, "Redelivery scheduled")
+}
+
+func writeWebhookError(c *gin.Context, prefix string, err error) {
+	switch {
+	case err.Error() == "webhook not found":
+		utils.NotFoundResponse(c, "Webhook not found")
+	case err.Error() == "delivery not found":
+		utils.NotFoundResponse(c, "Delivery not found")
+	case strings.HasPrefix(err.Error(), "validation failed"):
+		utils.BadRequestResponse(c, err.Error())
+	default:
+		utils.InternalServerErrorResponse(c, prefix+err.Error())
+	}
+}
+
+// parseIDParam parses a numeric path parameter, writing a 400 response when
+// it is invalid.
+func parseIDParam(c *gin.Context, name, message string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, message)
+		return 0, false
+	}
+	return uint(id), true
+}
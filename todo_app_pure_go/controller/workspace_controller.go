@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"strings"
+	"todo-app/dto"
+	"todo-app/service"
+	"todo-app/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WorkspaceController struct {
+	workspaceService service.WorkspaceService
+}
+
+func NewWorkspaceController(workspaceService service.WorkspaceService) *WorkspaceController {
+	return &WorkspaceController{
+		workspaceService: workspaceService,
+	}
+}
+
+// GetWorkspace godoc
+// @Summary Get the current workspace
+// @Description Get the workspace the request acts in, chosen by the token's workspace claim, the X-Workspace header or the subdomain, with its settings
+// @Tags workspace
+// @Produce json
+// @Param X-Workspace header string false "Workspace slug (default: the configured default workspace)"
+// @Success 200 {object} dto.APIResponse{data=dto.WorkspaceResponse}
+// @Failure 404 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/workspace [get]
+func (wc *WorkspaceController) GetWorkspace(c *gin.Context) {
+	workspace, err := wc.workspaceService.GetWorkspace(c.Request.Context())
+	if err != nil {
+		wc.handleError(c, err, "Failed to get workspace")
+		return
+	}
+
+	utils.SuccessResponse(c, workspace, "Workspace retrieved successfully")
+}
+
+// UpdateSettings godoc
+// @Summary Update the workspace settings
+// @Description Replace the settings of the current workspace: the priority of new todos created without one and the maximum number of todos (0 for no limit). Requires an authenticated caller.
+// @Tags workspace
+// @Accept json
+// @Produce json
+// @Param X-Workspace header string false "Workspace slug (default: the configured default workspace)"
+// @Param settings body dto.UpdateWorkspaceSettingsRequest true "Workspace settings"
+// @Success 200 {object} dto.APIResponse{data=dto.WorkspaceResponse}
+// @Failure 400 {object} dto.APIResponse
+// @Failure 401 {object} dto.APIResponse
+// @Failure 404 {object} dto.APIResponse
+// @Failure 500 {object} dto.APIResponse
+// @Router /api/workspace/settings [put]
+func (wc *WorkspaceController) UpdateSettings(c *gin.Context) {
+	var req dto.UpdateWorkspaceSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	workspace, err := wc.workspaceService.UpdateSettings(c.Request.Context(), &req)
+	if err != nil {
+		wc.handleError(c, err, "Failed to update workspace settings")
+		return
+	}
+
+	utils.SuccessResponse(c, workspace, "Workspace settings updated successfully")
+}
+
+func (wc *WorkspaceController) handleError(c *gin.Context, err error, message string) {
+	switch {
+	case err.Error() == "workspace not found":
+		utils.NotFoundResponse(c, "Workspace not found")
+	case strings.HasPrefix(err.Error(), "validation failed"):
+		utils.BadRequestResponse(c, err.Error())
+	default:
+		utils.InternalServerErrorResponse(c, message+": "+err.Error())
+	}
+}
@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"todo-app/auth"
+	"todo-app/dto"
+	"todo-app/middleware"
+	"todo-app/models"
+	"todo-app/repository"
+	"todo-app/service"
+	"todo-app/tenant"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestWorkspaceSettingsRequireAuthentication(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(tenant.Plugin{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.Workspace{}, &models.APIKey{}); err != nil {
+		t.Fatal(err)
+	}
+	workspaceRepo := repository.NewWorkspaceRepository(db)
+	if _, err := workspaceRepo.Ensure(tenant.AllWorkspaces(context.Background()), "acme"); err != nil {
+		t.Fatal(err)
+	}
+	keyService := service.NewAPIKeyService(repository.NewAPIKeyRepository(db), workspaceRepo)
+	key, err := keyService.CreateKey(tenant.WithWorkspace(context.Background(), 1), "ci", &dto.CreateAPIKeyRequest{
+		Name:   "Admin",
+		Scopes: []string{auth.ScopeWorkspaceRead, auth.ScopeWorkspaceWrite},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Serve the route the way the routes do, in the default workspace
+	wc := NewWorkspaceController(service.NewWorkspaceService(workspaceRepo))
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.PUT("/api/workspace/settings", middleware.APIKeyMiddleware(keyService.Authenticate), func(c *gin.Context) {
+		c.Request = c.Request.WithContext(tenant.WithWorkspace(c.Request.Context(), 1))
+	}, middleware.RequireIdentity(), middleware.RequireScope(auth.ScopeWorkspaceWrite), wc.UpdateSettings)
+
+	put := func(authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/workspace/settings", strings.NewReader(`{"max_todos":1}`))
+		req.Header.Set("Content-Type", "application/json")
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := put(""); w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") == "" {
+		t.Fatalf("anonymous update: %d %s", w.Code, w.Body)
+	}
+	workspace, err := workspaceRepo.GetByID(tenant.AllWorkspaces(context.Background()), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if workspace.Settings.MaxTodos != 0 {
+		t.Errorf("anonymous update changed max_todos to %d", workspace.Settings.MaxTodos)
+	}
+
+	if w := put("Bearer " + key.Key); w.Code != http.StatusOK {
+		t.Fatalf("update with a key: %d %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,85 @@
+// Package depgraph renders todo dependency graphs as Graphviz DOT and
+// Mermaid flowcharts. Edges point from a blocker to the todo it blocks, so
+// the graph reads in the order work can be done.
+package depgraph
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Node is a todo in the graph.
+type Node struct {
+	ID    uint
+	Label string
+	// Done nodes are drawn dimmed
+	Done bool
+}
+
+// Edge says From blocks To.
+type Edge struct {
+	From, To uint
+}
+
+type Graph struct {
+	Nodes []Node
+	Edges []Edge
+}
+
+// WriteDOT writes g as a Graphviz digraph.
+func WriteDOT(w io.Writer, g *Graph) error {
+	b := bufio.NewWriter(w)
+	b.WriteString("digraph todos {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box];\n")
+	for _, n := range g.Nodes {
+		style := ""
+		if n.Done {
+			style = `, style=filled, fillcolor="#dddddd", fontcolor="#666666"`
+		}
+		fmt.Fprintf(b, "  t%d [label=%s%s];\n", n.ID, dotString(n.Label), style)
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(b, "  t%d -> t%d;\n", e.From, e.To)
+	}
+	b.WriteString("}\n")
+	return b.Flush()
+}
+
+// WriteMermaid writes g as a Mermaid flowchart.
+func WriteMermaid(w io.Writer, g *Graph) error {
+	b := bufio.NewWriter(w)
+	b.WriteString("flowchart LR\n")
+	for _, n := range g.Nodes {
+		fmt.Fprintf(b, "  t%d[%s]\n", n.ID, mermaidString(n.Label))
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(b, "  t%d --> t%d\n", e.From, e.To)
+	}
+	var done []string
+	for _, n := range g.Nodes {
+		if n.Done {
+			done = append(done, fmt.Sprintf("t%d", n.ID))
+		}
+	}
+	if len(done) > 0 {
+		b.WriteString("  classDef done fill:#ddd,color:#666\n")
+		fmt.Fprintf(b, "  class %s done\n", strings.Join(done, ","))
+	}
+	return b.Flush()
+}
+
+// dotString quotes s as a DOT string.
+func dotString(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", "")
+	return `"` + r.Replace(s) + `"`
+}
+
+// mermaidString quotes s as a Mermaid node label, which cannot contain
+// quotes or line breaks.
+func mermaidString(s string) string {
+	r := strings.NewReplacer(`"`, "#quot;", "\n", " ", "\r", "")
+	return `"` + r.Replace(s) + `"`
+}
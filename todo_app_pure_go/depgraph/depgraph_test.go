@@ -0,0 +1,44 @@
+package depgraph
+
+import (
+	"strings"
+	"testing"
+)
+
+var graph = &Graph{
+	Nodes: []Node{{ID: 1, Label: `Design "v2"`, Done: true}, {ID: 2, Label: "Build\nit"}},
+	Edges: []Edge{{From: 1, To: 2}},
+}
+
+func TestWriteDOT(t *testing.T) {
+	var b strings.Builder
+	if err := WriteDOT(&b, graph); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"digraph todos {\n",
+		`t1 [label="Design \"v2\"", style=filled`,
+		`t2 [label="Build\nit"];`,
+		"t1 -> t2;",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("DOT output missing %q:\n%s", want, b.String())
+		}
+	}
+}
+
+func TestWriteMermaid(t *testing.T) {
+	var b strings.Builder
+	if err := WriteMermaid(&b, graph); err != nil {
+		t.Fatal(err)
+	}
+	want := "flowchart LR\n" +
+		"  t1[\"Design #quot;v2#quot;\"]\n" +
+		"  t2[\"Build it\"]\n" +
+		"  t1 --> t2\n" +
+		"  classDef done fill:#ddd,color:#666\n" +
+		"  class t1 done\n"
+	if b.String() != want {
+		t.Errorf("Mermaid output =\n%s\nwant\n%s", b.String(), want)
+	}
+}
@@ -0,0 +1,27 @@
+package dto
+
+import (
+	"time"
+	"todo-app/models"
+)
+
+type GrantAccessRequest struct {
+	Principal string      `json:"principal" validate:"required,max=255"`
+	Role      models.Role `json:"role" validate:"required,oneof=viewer commenter editor owner"`
+}
+
+type GrantResponse struct {
+	Principal string      `json:"principal"`
+	Role      models.Role `json:"role"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// TodoAccessResponse lists who may access a todo besides its owner.
+type TodoAccessResponse struct {
+	TodoID uint   `json:"todo_id"`
+	Owner  string `json:"owner"`
+	// Role is the caller's own role on the todo
+	Role   models.Role      `json:"role"`
+	Grants []*GrantResponse `json:"grants"`
+}
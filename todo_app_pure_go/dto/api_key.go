@@ -0,0 +1,26 @@
+package dto
+
+import "time"
+
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name" validate:"required,min=1,max=100"`
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,oneof=todos:read todos:write webhooks:read webhooks:write keys:read keys:write workspace:read workspace:write"`
+	// ExpiresAt is when the key stops working; it never expires when omitted
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type APIKeyResponse struct {
+	ID uint `json:"id"`
+	// The key acts for Owner in Workspace
+	Owner      string     `json:"-"`
+	Workspace  string     `json:"-"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	// Key is only returned when the key is created
+	Key string `json:"key,omitempty"`
+}
@@ -0,0 +1,13 @@
+package dto
+
+import "time"
+
+type AttachmentResponse struct {
+	ID          uint      `json:"id"`
+	TodoID      uint      `json:"todo_id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,37 @@
+package dto
+
+import "time"
+
+// LoginRedirect starts a login at the identity provider. State has to come
+// back with the callback, so it is kept in a cookie meanwhile.
+type LoginRedirect struct {
+	URL   string
+	State string
+}
+
+// LoginCallback holds the parameters the identity provider redirects back
+// with.
+type LoginCallback struct {
+	Code             string `form:"code"`
+	State            string `form:"state"`
+	Error            string `form:"error"`
+	ErrorDescription string `form:"error_description"`
+}
+
+type SessionResponse struct {
+	// Token is sent as "Authorization: Bearer <token>"
+	Token     string        `json:"token"`
+	ExpiresAt time.Time     `json:"expires_at"`
+	User      *UserResponse `json:"user"`
+}
+
+type UserResponse struct {
+	ID uint `json:"id"`
+	// Principal is the name the user acts under, e.g. when sharing todos
+	Principal   string    `json:"principal"`
+	Email       string    `json:"email"`
+	Name        string    `json:"name"`
+	Workspace   string    `json:"workspace"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastLoginAt time.Time `json:"last_login_at"`
+}
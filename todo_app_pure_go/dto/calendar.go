@@ -0,0 +1,27 @@
+package dto
+
+import (
+	"time"
+	"todo-app/models"
+)
+
+type CreateCalendarFeedRequest struct {
+	Name      string           `json:"name" validate:"required,min=1,max=100"`
+	Completed *bool            `json:"completed"`
+	Priority  *models.Priority `json:"priority" validate:"omitempty,oneof=LOW MEDIUM HIGH"`
+}
+
+type CalendarFeedResponse struct {
+	ID uint `json:"id"`
+	// The feed serves the todos visible to Owner in Workspace
+	Owner     string           `json:"-"`
+	Workspace uint             `json:"-"`
+	Name      string           `json:"name"`
+	Completed *bool            `json:"completed"`
+	Priority  *models.Priority `json:"priority"`
+	CreatedAt time.Time        `json:"created_at"`
+	// Token is only returned when the feed is created
+	Token string `json:"token,omitempty"`
+	// URL is the subscription URL; only returned when the feed is created
+	URL string `json:"url,omitempty"`
+}
@@ -0,0 +1,35 @@
+package dto
+
+import "time"
+
+type CreateCommentRequest struct {
+	// Body is markdown; clients render it.
+	Body string `json:"body" validate:"required,max=10000"`
+	// ParentID makes the comment a reply to another comment of the todo.
+	ParentID *uint `json:"parent_id"`
+}
+
+type UpdateCommentRequest struct {
+	Body string `json:"body" validate:"required,max=10000"`
+}
+
+type CommentResponse struct {
+	ID       uint   `json:"id"`
+	TodoID   uint   `json:"todo_id"`
+	ParentID *uint  `json:"parent_id"`
+	Author   string `json:"author"`
+	Body     string `json:"body"`
+	// Deleted comments keep their place in a thread without a body.
+	Deleted   bool       `json:"deleted"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	EditedAt  *time.Time `json:"edited_at"`
+	// Replies is only filled when listing a todo's comments as threads.
+	Replies []*CommentResponse `json:"replies,omitempty"`
+}
+
+// CommentRevisionResponse is a body a comment had until ReplacedAt.
+type CommentRevisionResponse struct {
+	Body       string    `json:"body"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
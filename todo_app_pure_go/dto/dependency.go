@@ -0,0 +1,35 @@
+package dto
+
+type AddDependencyRequest struct {
+	// BlockedBy is the todo that must be completed first
+	BlockedBy uint `json:"blocked_by" validate:"required"`
+}
+
+// DependenciesResponse lists the todos a todo waits for and the todos
+// waiting for it, as far as the caller can see them.
+type DependenciesResponse struct {
+	TodoID uint `json:"todo_id"`
+	// Blocked is true while any todo blocking this one is open
+	Blocked   bool            `json:"blocked"`
+	BlockedBy []*TodoResponse `json:"blocked_by"`
+	Blocks    []*TodoResponse `json:"blocks"`
+}
+
+// DependencyGraph holds the todos that have dependencies and the edges
+// between them; each edge points from a blocker to the todo it blocks.
+type DependencyGraph struct {
+	Nodes []DependencyNode `json:"nodes"`
+	Edges []DependencyEdge `json:"edges"`
+}
+
+type DependencyNode struct {
+	ID        uint   `json:"id"`
+	Title     string `json:"title"`
+	Status    string `json:"status"`
+	Completed bool   `json:"completed"`
+}
+
+type DependencyEdge struct {
+	From uint `json:"from"`
+	To   uint `json:"to"`
+}
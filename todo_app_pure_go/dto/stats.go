@@ -0,0 +1,40 @@
+package dto
+
+import "todo-app/models"
+
+type StatsResponse struct {
+	Total     int64 `json:"total"`
+	Completed int64 `json:"completed"`
+	Open      int64 `json:"open"`
+	// CompletionRate is Completed / Total, 0 when there are no todos
+	CompletionRate float64         `json:"completion_rate"`
+	ByPriority     []PriorityStats `json:"by_priority"`
+	// AverageTimeToCompleteSeconds covers todos completed in the timeline's
+	// range; null when none were
+	AverageTimeToCompleteSeconds *float64        `json:"average_time_to_complete_seconds"`
+	Timeline                     StatsTimeline   `json:"timeline"`
+	OldestOpen                   []*TodoResponse `json:"oldest_open"`
+}
+
+type PriorityStats struct {
+	Priority       models.Priority `json:"priority"`
+	Total          int64           `json:"total"`
+	Completed      int64           `json:"completed"`
+	Open           int64           `json:"open"`
+	CompletionRate float64         `json:"completion_rate"`
+}
+
+type StatsTimeline struct {
+	Interval string `json:"interval"`
+	// From and To are the inclusive date range (YYYY-MM-DD, UTC)
+	From    string        `json:"from"`
+	To      string        `json:"to"`
+	Buckets []StatsBucket `json:"buckets"`
+}
+
+// StatsBucket counts the todos created and completed in one day or week.
+type StatsBucket struct {
+	Start     string `json:"start"`
+	Created   int64  `json:"created"`
+	Completed int64  `json:"completed"`
+}
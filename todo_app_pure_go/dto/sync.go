@@ -0,0 +1,85 @@
+package dto
+
+import "time"
+
+type SyncOp string
+
+const (
+	SyncCreate SyncOp = "create"
+	SyncUpdate SyncOp = "update"
+	SyncDelete SyncOp = "delete"
+)
+
+// Reasons a mutation is rejected.
+const (
+	SyncRejectValidation = "validation_failed"
+	SyncRejectNotFound   = "not_found"
+	SyncRejectDeleted    = "deleted"
+	SyncRejectConflict   = "conflict"
+	SyncRejectForbidden  = "forbidden"
+	SyncRejectBlocked    = "blocked"
+)
+
+// SyncRequest pushes client mutations and pulls the changes since SyncToken.
+type SyncRequest struct {
+	// SyncToken is the token from the previous sync; empty for a full sync
+	SyncToken string `json:"sync_token"`
+	// Limit caps the number of changes returned (default 500, max 1000)
+	Limit int `json:"limit"`
+	// Mutations are applied in order; an invalid one is rejected on its own
+	Mutations []SyncMutation `json:"mutations"`
+}
+
+// SyncMutation is one change made on the client while offline.
+type SyncMutation struct {
+	Op SyncOp `json:"op" validate:"required,oneof=create update delete"`
+	// ID is the server ID of the todo for update and delete
+	ID uint `json:"id"`
+	// ClientID identifies a created todo until it has a server ID; replaying
+	// a create with the same ClientID does not create a duplicate
+	ClientID string            `json:"client_id" validate:"omitempty,max=64"`
+	Fields   UpdateTodoRequest `json:"fields"`
+	// UpdatedAt is the client's clock when the change was made
+	UpdatedAt time.Time `json:"updated_at" validate:"required"`
+}
+
+type SyncResponse struct {
+	// SyncToken is passed to the next sync to receive only newer changes
+	SyncToken string `json:"sync_token"`
+	// HasMore is set when Changes was cut at the limit; sync again with the
+	// new token to fetch the rest
+	HasMore  bool            `json:"has_more"`
+	Changes  []SyncChange    `json:"changes"`
+	Applied  []SyncApplied   `json:"applied"`
+	Rejected []SyncRejection `json:"rejected"`
+}
+
+// SyncChange is the current state of a changed todo, or a tombstone when it
+// was deleted.
+type SyncChange struct {
+	ID        uint          `json:"id"`
+	Deleted   bool          `json:"deleted"`
+	Todo      *TodoResponse `json:"todo,omitempty"`
+	DeletedAt *time.Time    `json:"deleted_at,omitempty"`
+}
+
+type SyncApplied struct {
+	// Index is the position of the mutation in the request
+	Index    int    `json:"index"`
+	Op       SyncOp `json:"op"`
+	ID       uint   `json:"id"`
+	ClientID string `json:"client_id,omitempty"`
+}
+
+type SyncRejection struct {
+	Index    int    `json:"index"`
+	Op       SyncOp `json:"op"`
+	ID       uint   `json:"id,omitempty"`
+	ClientID string `json:"client_id,omitempty"`
+	Reason   string `json:"reason"`
+	Message  string `json:"message"`
+	// Fields lists the fields that kept the server's value in a conflict
+	Fields []string `json:"fields,omitempty"`
+	// Current is the server's version of the todo, when it exists
+	Current *TodoResponse `json:"current,omitempty"`
+}
@@ -0,0 +1,40 @@
+package dto
+
+type ImportMode string
+
+const (
+	// ImportAllOrNothing commits nothing if any row fails.
+	ImportAllOrNothing ImportMode = "all_or_nothing"
+	// ImportBestEffort commits every valid row and reports the rest.
+	ImportBestEffort ImportMode = "best_effort"
+)
+
+type ImportOptions struct {
+	Mode   ImportMode
+	DryRun bool
+}
+
+// ImportRow is one parsed input row; Line is its position in the source
+// file so errors can point back to it.
+type ImportRow struct {
+	Line      int
+	Todo      CreateTodoRequest
+	Completed bool
+	// Errors holds problems found while parsing the row
+	Errors []string
+}
+
+type ImportRowError struct {
+	Line   int      `json:"line"`
+	Title  string   `json:"title,omitempty"`
+	Errors []string `json:"errors"`
+}
+
+type ImportResult struct {
+	Mode     ImportMode       `json:"mode"`
+	DryRun   bool             `json:"dry_run"`
+	Total    int              `json:"total"`
+	Valid    int              `json:"valid"`
+	Imported int              `json:"imported"`
+	Failed   []ImportRowError `json:"failed"`
+}
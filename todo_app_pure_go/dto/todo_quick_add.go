@@ -0,0 +1,34 @@
+package dto
+
+type QuickAddRequest struct {
+	Text string `json:"text" validate:"required,max=500"`
+	// TimeZone is the IANA name of the user's time zone, e.g.
+	// Europe/Istanbul; dates and times in Text are read in it (default UTC)
+	TimeZone string `json:"time_zone"`
+}
+
+// QuickAddSpan is a part of the text that was recognized. Start and End
+// count characters (not bytes) from the beginning of the text; End is
+// exclusive.
+type QuickAddSpan struct {
+	Kind  string `json:"kind"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Text  string `json:"text"`
+	// Value is what the span was read as, e.g. HIGH, home or 2024-03-05
+	Value string `json:"value"`
+}
+
+// QuickAddResult is a line of text parsed into a todo. Todo holds the fields
+// the todo is created with, including its due date, tags, project and
+// recurrence.
+type QuickAddResult struct {
+	Todo  CreateTodoRequest `json:"todo"`
+	Spans []QuickAddSpan    `json:"spans"`
+}
+
+type QuickAddResponse struct {
+	Parsed *QuickAddResult `json:"parsed"`
+	// Todo is the created todo; it is omitted in preview mode
+	Todo *TodoResponse `json:"todo,omitempty"`
+}
@@ -1,11 +1,21 @@
 package dto
 
-import "todo-app/models"
+import (
+	"time"
+	"todo-app/models"
+)
 
 type CreateTodoRequest struct {
 	Title       string          `json:"title" validate:"required,min=1,max=100"`
 	Description *string         `json:"description" validate:"omitempty,max=500"`
 	Priority    models.Priority `json:"priority" validate:"omitempty,oneof=LOW MEDIUM HIGH"`
+	DueAt       *time.Time      `json:"due_at"`
+	// AllDay is set when only the date of DueAt counts
+	AllDay  bool     `json:"all_day"`
+	Tags    []string `json:"tags" validate:"omitempty,max=20,dive,min=1,max=50"`
+	Project string   `json:"project" validate:"omitempty,max=100"`
+	// Recurrence is an iCalendar RRULE such as FREQ=WEEKLY;BYDAY=MO
+	Recurrence string `json:"recurrence" validate:"omitempty,max=255,startswith=FREQ="`
 }
 
 type UpdateTodoRequest struct {
@@ -14,3 +24,11 @@ type UpdateTodoRequest struct {
 	Completed   *bool            `json:"completed"`
 	Priority    *models.Priority `json:"priority" validate:"omitempty,oneof=LOW MEDIUM HIGH"`
 }
+
+// MoveTodoRequest names the todos a todo is dropped between in the manual
+// order. At least one is required; with only After the todo goes right
+// after it, with only Before right before it.
+type MoveTodoRequest struct {
+	After  *uint `json:"after"`
+	Before *uint `json:"before"`
+}
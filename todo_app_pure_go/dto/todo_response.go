@@ -13,6 +13,24 @@ type TodoResponse struct {
 	Priority    models.Priority `json:"priority"`
 	CreatedAt   time.Time       `json:"created_at"`
 	UpdatedAt   time.Time       `json:"updated_at"`
+	CompletedAt *time.Time      `json:"completed_at"`
+	DueAt       *time.Time      `json:"due_at"`
+	// AllDay is set when only the date of due_at counts
+	AllDay     bool     `json:"all_day"`
+	Tags       []string `json:"tags"`
+	Project    string   `json:"project"`
+	Recurrence string   `json:"recurrence"`
+	// Status is the todo's step in the workflow; completed is true while it
+	// is a done status
+	Status          string     `json:"status"`
+	StatusChangedAt *time.Time `json:"status_changed_at"`
+	// Position orders todos manually; lower positions come first
+	Position string `json:"position"`
+	// Owner is the principal that created the todo; empty for todos open to
+	// everyone
+	Owner string `json:"owner"`
+	// CommentCount counts the comments that are not deleted
+	CommentCount int64 `json:"comment_count"`
 }
 
 type APIResponse struct {
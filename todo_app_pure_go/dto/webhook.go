@@ -0,0 +1,49 @@
+package dto
+
+import (
+	"time"
+	"todo-app/models"
+)
+
+type CreateWebhookRequest struct {
+	URL string `json:"url" validate:"required,url,max=2048"`
+	// Secret signs deliveries; one is generated when omitted
+	Secret string   `json:"secret" validate:"omitempty,min=16,max=255"`
+	Events []string `json:"events" validate:"required,min=1,dive,oneof=todo.created todo.updated todo.completed todo.deleted"`
+	Active *bool    `json:"active"`
+}
+
+type UpdateWebhookRequest struct {
+	URL    *string  `json:"url" validate:"omitempty,url,max=2048"`
+	Secret *string  `json:"secret" validate:"omitempty,min=16,max=255"`
+	Events []string `json:"events" validate:"omitempty,min=1,dive,oneof=todo.created todo.updated todo.completed todo.deleted"`
+	Active *bool    `json:"active"`
+}
+
+type WebhookResponse struct {
+	ID     uint     `json:"id"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Active bool     `json:"active"`
+	// Secret is only returned when it is created or changed
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type WebhookDeliveryResponse struct {
+	ID            uint                  `json:"id"`
+	WebhookID     uint                  `json:"webhook_id"`
+	EventID       string                `json:"event_id"`
+	EventType     string                `json:"event_type"`
+	Status        models.DeliveryStatus `json:"status"`
+	Attempts      int                   `json:"attempts"`
+	ResponseCode  int                   `json:"response_code"`
+	ResponseBody  string                `json:"response_body"`
+	Error         string                `json:"error"`
+	DurationMs    int64                 `json:"duration_ms"`
+	RedeliveryOf  *uint                 `json:"redelivery_of"`
+	NextAttemptAt *time.Time            `json:"next_attempt_at"`
+	DeliveredAt   *time.Time            `json:"delivered_at"`
+	CreatedAt     time.Time             `json:"created_at"`
+}
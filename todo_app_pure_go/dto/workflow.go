@@ -0,0 +1,46 @@
+package dto
+
+import "time"
+
+type WorkflowResponse struct {
+	Statuses []WorkflowStatus `json:"statuses"`
+}
+
+type WorkflowStatus struct {
+	Name string `json:"name"`
+	// Initial is the status new and reopened todos start in
+	Initial bool `json:"initial"`
+	// Done statuses count as completed
+	Done bool `json:"done"`
+	// Next lists the statuses a todo may be moved to from this one
+	Next []string `json:"next"`
+}
+
+type TransitionTodoRequest struct {
+	Status string `json:"status" validate:"required,max=32"`
+}
+
+// StatusHistoryResponse tells how a todo moved through the workflow and
+// how long it spent in each status, including the current one until now.
+type StatusHistoryResponse struct {
+	TodoID          uint                   `json:"todo_id"`
+	Status          string                 `json:"status"`
+	StatusChangedAt *time.Time             `json:"status_changed_at"`
+	Changes         []StatusChangeResponse `json:"changes"`
+	TimeInStatus    []StatusTime           `json:"time_in_status"`
+}
+
+// StatusChangeResponse is a move into To; From is empty for the status the
+// todo was created in.
+type StatusChangeResponse struct {
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+type StatusTime struct {
+	Status  string  `json:"status"`
+	Seconds float64 `json:"seconds"`
+	// Entries counts how often the todo entered the status
+	Entries int `json:"entries"`
+}
@@ -0,0 +1,21 @@
+package dto
+
+import (
+	"time"
+	"todo-app/models"
+)
+
+type WorkspaceResponse struct {
+	ID        uint                     `json:"id"`
+	Slug      string                   `json:"slug"`
+	Name      string                   `json:"name"`
+	Settings  models.WorkspaceSettings `json:"settings"`
+	CreatedAt time.Time                `json:"created_at"`
+	UpdatedAt time.Time                `json:"updated_at"`
+}
+
+// UpdateWorkspaceSettingsRequest replaces the settings of the workspace.
+type UpdateWorkspaceSettingsRequest struct {
+	DefaultPriority models.Priority `json:"default_priority" validate:"omitempty,oneof=LOW MEDIUM HIGH"`
+	MaxTodos        int             `json:"max_todos" validate:"min=0"`
+}
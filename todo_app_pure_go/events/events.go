@@ -0,0 +1,119 @@
+// Package events carries todo lifecycle events from the service layer to
+// interested components such as webhooks and live streams.
+package events
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"todo-app/dto"
+)
+
+type Type string
+
+const (
+	TodoCreated   Type = "todo.created"
+	TodoUpdated   Type = "todo.updated"
+	TodoCompleted Type = "todo.completed"
+	TodoDeleted   Type = "todo.deleted"
+)
+
+// Types lists every event type, in lifecycle order.
+var Types = []Type{TodoCreated, TodoUpdated, TodoCompleted, TodoDeleted}
+
+func (t Type) Valid() bool {
+	for _, known := range Types {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+type Event struct {
+	ID         string            `json:"id"`
+	Type       Type              `json:"type"`
+	OccurredAt time.Time         `json:"occurred_at"`
+	Todo       *dto.TodoResponse `json:"data"`
+	// Workspace is the workspace the todo belongs to.
+	Workspace uint `json:"-"`
+	// Audience lists the principals allowed to see the todo; nil when
+	// everyone is.
+	Audience []string `json:"-"`
+	// Attachments lists the content digests of the attachments deleted
+	// with the todo, for todo.deleted events.
+	Attachments []string `json:"-"`
+}
+
+// VisibleTo reports whether principal, acting in workspace, may receive the
+// event. Events never leave their workspace.
+func (e Event) VisibleTo(workspace uint, principal string) bool {
+	if e.Workspace != workspace {
+		return false
+	}
+	if e.Audience == nil {
+		return true
+	}
+	for _, allowed := range e.Audience {
+		if allowed == principal {
+			return true
+		}
+	}
+	return false
+}
+
+// New returns an event with a fresh ID and timestamp.
+func New(eventType Type, todo *dto.TodoResponse) Event {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return Event{
+		ID:         "evt_" + hex.EncodeToString(b),
+		Type:       eventType,
+		OccurredAt: time.Now().UTC(),
+		Todo:       todo,
+	}
+}
+
+// Publisher is implemented by anything events can be sent to.
+type Publisher interface {
+	Publish(event Event)
+}
+
+// Bus fans events out to subscribers synchronously.
+// Subscribers must not block; slow work belongs on their own goroutines.
+type Bus struct {
+	mu          sync.RWMutex
+	nextID      int
+	subscribers map[int]func(Event)
+}
+
+func NewBus() *Bus {
+	return &Bus{subscribers: make(map[int]func(Event))}
+}
+
+// Subscribe registers fn and returns a function that removes it again.
+func (b *Bus) Subscribe(fn func(Event)) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextID
+	b.nextID++
+	b.subscribers[id] = fn
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers, id)
+	}
+}
+
+func (b *Bus) Publish(event Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, fn := range b.subscribers {
+		fn(event)
+	}
+}
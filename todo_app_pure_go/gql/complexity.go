@@ -0,0 +1,144 @@
+package gql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// paginatedFields are the fields whose selection is repeated once per
+// returned item, with the default page size used when no limit is given.
+var paginatedFields = map[string]int{
+	"todos": defaultLimit,
+}
+
+// Cost is the estimated size of an operation.
+type Cost struct {
+	// Complexity counts one per resolved field, with the selection of a
+	// paginated field counted once per requested item.
+	Complexity int
+	// Depth is the deepest level of nested selections.
+	Depth int
+}
+
+// Measure estimates the cost of the operation that would be executed for
+// operationName. The document must already be validated, so fragments are
+// known to exist and not to form cycles. Introspection fields are free.
+func Measure(doc *ast.Document, operationName string, variables map[string]interface{}) (Cost, error) {
+	fragments := make(map[string]*ast.FragmentDefinition)
+	var operations []*ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		switch def := def.(type) {
+		case *ast.FragmentDefinition:
+			fragments[def.Name.Value] = def
+		case *ast.OperationDefinition:
+			operations = append(operations, def)
+		}
+	}
+
+	op, err := selectOperation(operations, operationName)
+	if err != nil {
+		return Cost{}, err
+	}
+
+	// Variables left out of the request take their declared defaults
+	values := make(map[string]interface{}, len(variables))
+	for _, def := range op.VariableDefinitions {
+		if n, ok := def.DefaultValue.(*ast.IntValue); ok {
+			values[def.Variable.Name.Value] = n.Value
+		}
+	}
+	for name, value := range variables {
+		values[name] = value
+	}
+
+	m := &measurer{fragments: fragments, variables: values}
+	complexity, depth := m.selectionSet(op.SelectionSet)
+	return Cost{Complexity: complexity, Depth: depth}, nil
+}
+
+// selectOperation picks the operation to run the way the executor does.
+func selectOperation(operations []*ast.OperationDefinition, name string) (*ast.OperationDefinition, error) {
+	if name == "" {
+		if len(operations) != 1 {
+			return nil, fmt.Errorf("must provide operation name if query contains multiple operations")
+		}
+		return operations[0], nil
+	}
+	for _, op := range operations {
+		if op.Name != nil && op.Name.Value == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation named %q", name)
+}
+
+type measurer struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+}
+
+func (m *measurer) selectionSet(set *ast.SelectionSet) (complexity, depth int) {
+	if set == nil {
+		return 0, 0
+	}
+	for _, selection := range set.Selections {
+		var c, d int
+		switch sel := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(sel.Name.Value, "__") {
+				continue
+			}
+			c, d = m.selectionSet(sel.SelectionSet)
+			if defaultSize, ok := paginatedFields[sel.Name.Value]; ok {
+				c *= m.pageSize(sel, defaultSize)
+			}
+			c, d = c+1, d+1
+		case *ast.InlineFragment:
+			c, d = m.selectionSet(sel.SelectionSet)
+		case *ast.FragmentSpread:
+			if fragment, ok := m.fragments[sel.Name.Value]; ok {
+				c, d = m.selectionSet(fragment.SelectionSet)
+			}
+		}
+		complexity += c
+		if d > depth {
+			depth = d
+		}
+	}
+	return complexity, depth
+}
+
+// pageSize resolves the limit argument of a paginated field, which may be a
+// literal or a variable.
+func (m *measurer) pageSize(field *ast.Field, defaultSize int) int {
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != "limit" {
+			continue
+		}
+		switch value := arg.Value.(type) {
+		case *ast.IntValue:
+			if n, err := strconv.Atoi(value.Value); err == nil && n > 0 {
+				return n
+			}
+		case *ast.Variable:
+			switch n := m.variables[value.Name.Value].(type) {
+			case string:
+				if n, err := strconv.Atoi(n); err == nil && n > 0 {
+					return n
+				}
+			case int:
+				if n > 0 {
+					return n
+				}
+			case float64:
+				if n > 0 {
+					return int(n)
+				}
+			}
+		}
+	}
+	return defaultSize
+}
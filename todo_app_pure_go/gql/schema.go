@@ -0,0 +1,359 @@
+// Package gql exposes the todo service as a GraphQL schema. Resolvers only
+// translate between GraphQL and service.TodoService so the business rules
+// stay in the service layer.
+package gql
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"todo-app/auth"
+	"todo-app/dto"
+	"todo-app/models"
+	"todo-app/service"
+
+	"github.com/graphql-go/graphql"
+)
+
+const (
+	defaultLimit = 10
+	maxLimit     = 100
+)
+
+// Error codes reported in the "extensions" of GraphQL errors.
+const (
+	CodeBadUserInput  = "BAD_USER_INPUT"
+	CodeNotFound      = "NOT_FOUND"
+	CodeForbidden     = "FORBIDDEN"
+	CodeConflict      = "CONFLICT"
+	CodeInternalError = "INTERNAL_SERVER_ERROR"
+	CodeTooComplex    = "QUERY_TOO_COMPLEX"
+	CodeRateLimited   = "RATE_LIMITED"
+)
+
+// Error is a GraphQL error carrying a machine-readable code.
+type Error struct {
+	Message string
+	Code    string
+	// RetryAfter is the number of seconds to wait after a rate limit
+	RetryAfter int
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{"code": e.Code}
+	if e.RetryAfter > 0 {
+		extensions["retryAfter"] = e.RetryAfter
+	}
+	return extensions
+}
+
+// serviceError maps the service's errors the same way the REST controllers
+// do.
+func serviceError(err error) error {
+	switch {
+	case err.Error() == "todo not found":
+		return &Error{Message: "Todo not found", Code: CodeNotFound}
+	case strings.HasPrefix(err.Error(), "forbidden"):
+		return &Error{Message: err.Error(), Code: CodeForbidden}
+	case strings.HasPrefix(err.Error(), "validation failed"):
+		return &Error{Message: err.Error(), Code: CodeBadUserInput}
+	case strings.HasPrefix(err.Error(), "blocked"):
+		return &Error{Message: err.Error(), Code: CodeConflict}
+	default:
+		return &Error{Message: err.Error(), Code: CodeInternalError}
+	}
+}
+
+var priorityEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name: "Priority",
+	Values: graphql.EnumValueConfigMap{
+		"LOW":    &graphql.EnumValueConfig{Value: models.LOW},
+		"MEDIUM": &graphql.EnumValueConfig{Value: models.MEDIUM},
+		"HIGH":   &graphql.EnumValueConfig{Value: models.HIGH},
+	},
+})
+
+var todoSortEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name: "TodoSort",
+	Values: graphql.EnumValueConfigMap{
+		"NEWEST":   &graphql.EnumValueConfig{Value: models.SortNewest},
+		"POSITION": &graphql.EnumValueConfig{Value: models.SortPosition},
+	},
+})
+
+var todoType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Todo",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.ID),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return strconv.FormatUint(uint64(p.Source.(*dto.TodoResponse).ID), 10), nil
+			},
+		},
+		"title":           &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"description":     &graphql.Field{Type: graphql.String},
+		"completed":       &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		"priority":        &graphql.Field{Type: graphql.NewNonNull(priorityEnum)},
+		"createdAt":       &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		"updatedAt":       &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		"completedAt":     &graphql.Field{Type: graphql.DateTime},
+		"status":          &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"statusChangedAt": &graphql.Field{Type: graphql.DateTime},
+		"position":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"owner":           &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"commentCount":    &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+	},
+})
+
+// todoPage is the result of the todos query.
+type todoPage struct {
+	Items  []*dto.TodoResponse `json:"items"`
+	Total  int64               `json:"total"`
+	Limit  int                 `json:"limit"`
+	Offset int                 `json:"offset"`
+}
+
+var todoPageType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "TodoPage",
+	Fields: graphql.Fields{
+		"items":  &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(todoType)))},
+		"total":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"limit":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"offset": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+	},
+})
+
+var createTodoInput = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "CreateTodoInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"title":       &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"description": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"priority":    &graphql.InputObjectFieldConfig{Type: priorityEnum},
+	},
+})
+
+var updateTodoInput = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "UpdateTodoInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"title":       &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"description": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"completed":   &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+		"priority":    &graphql.InputObjectFieldConfig{Type: priorityEnum},
+	},
+})
+
+// NewSchema builds the GraphQL schema resolved by todoService.
+func NewSchema(todoService service.TodoService) (graphql.Schema, error) {
+	r := &resolver{todoService: todoService}
+	idArg := graphql.FieldConfigArgument{
+		"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+	}
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"todo": &graphql.Field{
+				Type:    todoType,
+				Args:    idArg,
+				Resolve: r.todo,
+			},
+			"todos": &graphql.Field{
+				Type: graphql.NewNonNull(todoPageType),
+				Args: graphql.FieldConfigArgument{
+					"completed": &graphql.ArgumentConfig{Type: graphql.Boolean},
+					"priority":  &graphql.ArgumentConfig{Type: priorityEnum},
+					"sort":      &graphql.ArgumentConfig{Type: todoSortEnum},
+					"limit":     &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultLimit},
+					"offset":    &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+				},
+				Resolve: r.todos,
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createTodo": &graphql.Field{
+				Type: graphql.NewNonNull(todoType),
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(createTodoInput)},
+				},
+				Resolve: writing("create", r.createTodo),
+			},
+			"updateTodo": &graphql.Field{
+				Type: graphql.NewNonNull(todoType),
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(updateTodoInput)},
+				},
+				Resolve: writing("update", r.updateTodo),
+			},
+			"deleteTodo": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.ID),
+				Description: "Deletes a todo and returns its ID",
+				Args:        idArg,
+				Resolve:     writing("delete", r.deleteTodo),
+			},
+			"toggleTodo": &graphql.Field{
+				Type:    graphql.NewNonNull(todoType),
+				Args:    idArg,
+				Resolve: writing("toggle", r.toggleTodo),
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query:    query,
+		Mutation: mutation,
+	})
+}
+
+type resolver struct {
+	todoService service.TodoService
+}
+
+func (r *resolver) todo(p graphql.ResolveParams) (interface{}, error) {
+	id, err := idArgument(p)
+	if err != nil {
+		return nil, err
+	}
+	todo, err := r.todoService.GetTodoByID(p.Context, principalFrom(p.Context), id)
+	if err != nil {
+		if err.Error() == "todo not found" {
+			// A missing todo is a null result, not an error
+			return nil, nil
+		}
+		return nil, serviceError(err)
+	}
+	return todo, nil
+}
+
+func (r *resolver) todos(p graphql.ResolveParams) (interface{}, error) {
+	limit, _ := p.Args["limit"].(int)
+	offset, _ := p.Args["offset"].(int)
+	if limit < 1 || limit > maxLimit {
+		return nil, &Error{Message: "limit must be between 1 and " + strconv.Itoa(maxLimit), Code: CodeBadUserInput}
+	}
+	if offset < 0 {
+		return nil, &Error{Message: "offset must not be negative", Code: CodeBadUserInput}
+	}
+
+	var completed *bool
+	if val, ok := p.Args["completed"].(bool); ok {
+		completed = &val
+	}
+	var priority *models.Priority
+	if val, ok := p.Args["priority"].(models.Priority); ok {
+		priority = &val
+	}
+	sort, _ := p.Args["sort"].(models.TodoSort)
+
+	todos, total, err := r.todoService.GetAllTodos(p.Context, principalFrom(p.Context), completed, nil, priority, sort, limit, offset)
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	return &todoPage{Items: todos, Total: total, Limit: limit, Offset: offset}, nil
+}
+
+func (r *resolver) createTodo(p graphql.ResolveParams) (interface{}, error) {
+	input, _ := p.Args["input"].(map[string]interface{})
+	req := &dto.CreateTodoRequest{}
+	req.Title, _ = input["title"].(string)
+	if val, ok := input["description"].(string); ok {
+		req.Description = &val
+	}
+	if val, ok := input["priority"].(models.Priority); ok {
+		req.Priority = val
+	}
+
+	todo, err := r.todoService.CreateTodo(p.Context, principalFrom(p.Context), req)
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	return todo, nil
+}
+
+func (r *resolver) updateTodo(p graphql.ResolveParams) (interface{}, error) {
+	id, err := idArgument(p)
+	if err != nil {
+		return nil, err
+	}
+	input, _ := p.Args["input"].(map[string]interface{})
+	req := &dto.UpdateTodoRequest{}
+	if val, ok := input["title"].(string); ok {
+		req.Title = &val
+	}
+	if val, ok := input["description"].(string); ok {
+		req.Description = &val
+	}
+	if val, ok := input["completed"].(bool); ok {
+		req.Completed = &val
+	}
+	if val, ok := input["priority"].(models.Priority); ok {
+		req.Priority = &val
+	}
+
+	todo, err := r.todoService.UpdateTodo(p.Context, principalFrom(p.Context), id, req)
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	return todo, nil
+}
+
+func (r *resolver) deleteTodo(p graphql.ResolveParams) (interface{}, error) {
+	id, err := idArgument(p)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.todoService.DeleteTodo(p.Context, principalFrom(p.Context), id); err != nil {
+		return nil, serviceError(err)
+	}
+	return p.Args["id"], nil
+}
+
+func (r *resolver) toggleTodo(p graphql.ResolveParams) (interface{}, error) {
+	id, err := idArgument(p)
+	if err != nil {
+		return nil, err
+	}
+	todo, err := r.todoService.ToggleTodoComplete(p.Context, principalFrom(p.Context), id)
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	return todo, nil
+}
+
+// writing refuses a mutation when the request's credentials may not change
+// todos, or when the request's limiter refuses another mutation of kind.
+func writing(kind string, resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		if err := auth.Require(p.Context, auth.ScopeTodosWrite); err != nil {
+			return nil, serviceError(err)
+		}
+		if limit := limiterFrom(p.Context); limit != nil {
+			if allowed, retryAfter := limit(kind); !allowed {
+				return nil, &Error{
+					Message:    "rate limit exceeded, retry later",
+					Code:       CodeRateLimited,
+					RetryAfter: int(math.Ceil(retryAfter.Seconds())),
+				}
+			}
+		}
+		return resolve(p)
+	}
+}
+
+func idArgument(p graphql.ResolveParams) (uint, error) {
+	str, _ := p.Args["id"].(string)
+	id, err := strconv.ParseUint(str, 10, 32)
+	if err != nil || id == 0 {
+		return 0, &Error{Message: "Invalid todo ID", Code: CodeBadUserInput}
+	}
+	return uint(id), nil
+}
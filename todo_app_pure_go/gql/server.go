@@ -0,0 +1,139 @@
+package gql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"todo-app/service"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// Options bound the operations a Server accepts.
+type Options struct {
+	MaxComplexity int
+	MaxDepth      int
+}
+
+// Request is a single GraphQL operation as sent over HTTP.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+type Server struct {
+	schema graphql.Schema
+	opts   Options
+}
+
+func NewServer(todoService service.TodoService, opts Options) (*Server, error) {
+	schema, err := NewSchema(todoService)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{schema: schema, opts: opts}, nil
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a context under which operations run on behalf of
+// principal, the authenticated identity.
+func WithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// principalFrom returns the principal set by WithPrincipal, or "" when
+// there is none.
+func principalFrom(ctx context.Context) string {
+	principal, _ := ctx.Value(principalKey{}).(string)
+	return principal
+}
+
+// Limiter decides whether a mutation of the given kind (create, update,
+// delete or toggle) may be made now, and otherwise how long the client has
+// to wait.
+type Limiter func(kind string) (allowed bool, retryAfter time.Duration)
+
+type limiterKey struct{}
+
+// WithLimiter returns a context under which every mutation field, including
+// each one of a batch, is first passed to limit.
+func WithLimiter(ctx context.Context, limit Limiter) context.Context {
+	return context.WithValue(ctx, limiterKey{}, limit)
+}
+
+func limiterFrom(ctx context.Context) Limiter {
+	limit, _ := ctx.Value(limiterKey{}).(Limiter)
+	return limit
+}
+
+// Execute parses, validates, measures and runs a request. Mutations are
+// rejected unless allowMutations is set, so they cannot be triggered by a
+// GET request.
+func (s *Server) Execute(ctx context.Context, req Request, allowMutations bool) *graphql.Result {
+	doc, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"}),
+	})
+	if err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+
+	validation := graphql.ValidateDocument(&s.schema, doc, nil)
+	if !validation.IsValid {
+		return &graphql.Result{Errors: validation.Errors}
+	}
+
+	cost, err := Measure(doc, req.OperationName, req.Variables)
+	if err != nil {
+		return errorResult(&Error{Message: err.Error(), Code: CodeBadUserInput})
+	}
+	if s.opts.MaxDepth > 0 && cost.Depth > s.opts.MaxDepth {
+		return errorResult(&Error{
+			Message: fmt.Sprintf("query depth %d exceeds the maximum of %d", cost.Depth, s.opts.MaxDepth),
+			Code:    CodeTooComplex,
+		})
+	}
+	if s.opts.MaxComplexity > 0 && cost.Complexity > s.opts.MaxComplexity {
+		return errorResult(&Error{
+			Message: fmt.Sprintf("query complexity %d exceeds the maximum of %d", cost.Complexity, s.opts.MaxComplexity),
+			Code:    CodeTooComplex,
+		})
+	}
+
+	if !allowMutations && isMutation(doc, req.OperationName) {
+		return errorResult(&Error{Message: "mutations must be sent with POST", Code: CodeBadUserInput})
+	}
+
+	return graphql.Execute(graphql.ExecuteParams{
+		Schema:        s.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       ctx,
+	})
+}
+
+func isMutation(doc *ast.Document, operationName string) bool {
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if operationName == "" || (op.Name != nil && op.Name.Value == operationName) {
+			return op.Operation == ast.OperationTypeMutation
+		}
+	}
+	return false
+}
+
+func errorResult(err *Error) *graphql.Result {
+	formatted := gqlerrors.FormatError(err)
+	formatted.Extensions = err.Extensions()
+	return &graphql.Result{Errors: []gqlerrors.FormattedError{formatted}}
+}
@@ -0,0 +1,223 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"todo-app/auth"
+	"todo-app/models"
+	"todo-app/repository"
+	"todo-app/service"
+
+	"github.com/glebarez/sqlite"
+	"github.com/graphql-go/graphql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestServer(t *testing.T, opts Options) *Server {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.Todo{}, &models.TodoChange{}, &models.TodoStatusChange{}, &models.TodoDependency{}, &models.Attachment{}, &models.Comment{}, &models.CommentRevision{}, &models.TodoGrant{}, &models.ListGrant{}); err != nil {
+		t.Fatal(err)
+	}
+	server, err := NewServer(service.NewTodoService(repository.NewTodoRepository(db), repository.NewAccessRepository(db), nil, nil, nil), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server
+}
+
+// run executes query and decodes its data into v, failing on any error.
+func run(t *testing.T, s *Server, query string, variables map[string]interface{}, v interface{}) {
+	t.Helper()
+	result := s.Execute(context.Background(), Request{Query: query, Variables: variables}, true)
+	if result.HasErrors() {
+		t.Fatalf("unexpected errors: %+v", result.Errors)
+	}
+	data, _ := json.Marshal(result.Data)
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func errorCode(result *graphql.Result) interface{} {
+	if len(result.Errors) == 0 {
+		return nil
+	}
+	return result.Errors[0].Extensions["code"]
+}
+
+func TestMutationsAndFilteredList(t *testing.T) {
+	s := newTestServer(t, Options{})
+
+	var created struct {
+		CreateTodo struct {
+			ID       string `json:"id"`
+			Priority string `json:"priority"`
+		} `json:"createTodo"`
+	}
+	for _, title := range []string{"Write report", "Ship release", "Call Bob"} {
+		run(t, s, `mutation($title: String!) { createTodo(input: {title: $title, priority: HIGH}) { id priority } }`,
+			map[string]interface{}{"title": title}, &created)
+	}
+	if created.CreateTodo.Priority != "HIGH" {
+		t.Errorf("expected HIGH, got %q", created.CreateTodo.Priority)
+	}
+
+	var toggled struct {
+		ToggleTodo struct {
+			Completed bool `json:"completed"`
+		} `json:"toggleTodo"`
+	}
+	run(t, s, `mutation { toggleTodo(id: 3) { completed } }`, nil, &toggled)
+	if !toggled.ToggleTodo.Completed {
+		t.Error("expected todo to be completed")
+	}
+
+	var updated struct {
+		UpdateTodo struct {
+			Title       string  `json:"title"`
+			Description *string `json:"description"`
+		} `json:"updateTodo"`
+	}
+	run(t, s, `mutation { updateTodo(id: 1, input: {title: "Write summary", description: "one page"}) { title description } }`, nil, &updated)
+	if updated.UpdateTodo.Title != "Write summary" || updated.UpdateTodo.Description == nil {
+		t.Errorf("unexpected update result: %+v", updated.UpdateTodo)
+	}
+
+	var deleted struct {
+		DeleteTodo string `json:"deleteTodo"`
+	}
+	run(t, s, `mutation { deleteTodo(id: "2") }`, nil, &deleted)
+	if deleted.DeleteTodo != "2" {
+		t.Errorf("expected deleted ID 2, got %q", deleted.DeleteTodo)
+	}
+
+	var list struct {
+		Todos struct {
+			Items []struct {
+				Title string `json:"title"`
+			} `json:"items"`
+			Total int `json:"total"`
+			Limit int `json:"limit"`
+		} `json:"todos"`
+	}
+	run(t, s, `{ todos(completed: false, priority: HIGH, limit: 5) { items { title } total limit } }`, nil, &list)
+	if list.Todos.Total != 1 || len(list.Todos.Items) != 1 || list.Todos.Items[0].Title != "Write summary" || list.Todos.Limit != 5 {
+		t.Errorf("unexpected list: %+v", list.Todos)
+	}
+
+	var missing struct {
+		Todo *struct{} `json:"todo"`
+	}
+	run(t, s, `{ todo(id: 2) { id } }`, nil, &missing)
+	if missing.Todo != nil {
+		t.Error("expected null for a deleted todo")
+	}
+}
+
+func TestServiceErrorsCarryCodes(t *testing.T) {
+	s := newTestServer(t, Options{})
+
+	cases := map[string]string{
+		`mutation { createTodo(input: {title: ""}) { id } }`: CodeBadUserInput,
+		`mutation { toggleTodo(id: 42) { id } }`:             CodeNotFound,
+		`{ todos(limit: 500) { total } }`:                    CodeBadUserInput,
+		`mutation { updateTodo(id: "x", input: {}) { id } }`: CodeBadUserInput,
+	}
+	for query, code := range cases {
+		result := s.Execute(context.Background(), Request{Query: query}, true)
+		if errorCode(result) != code {
+			t.Errorf("%s: expected %s, got %+v", query, code, result.Errors)
+		}
+	}
+}
+
+func TestMutationsNeedWriteScope(t *testing.T) {
+	s := newTestServer(t, Options{})
+	readOnly := auth.WithScopes(context.Background(), []string{auth.ScopeTodosRead})
+
+	result := s.Execute(readOnly, Request{Query: `mutation { createTodo(input: {title: "Write report"}) { id } }`}, true)
+	if errorCode(result) != CodeForbidden {
+		t.Errorf("expected %s, got %+v", CodeForbidden, result.Errors)
+	}
+	result = s.Execute(readOnly, Request{Query: `{ todos { total } }`}, true)
+	if result.HasErrors() {
+		t.Errorf("read-only query failed: %+v", result.Errors)
+	}
+}
+
+func TestEveryMutationSpendsTheLimit(t *testing.T) {
+	s := newTestServer(t, Options{})
+	var limited []string
+	ctx := WithLimiter(context.Background(), func(kind string) (bool, time.Duration) {
+		limited = append(limited, kind)
+		return len(limited) < 2, 1500 * time.Millisecond
+	})
+
+	// Aliases cannot fit several mutations into one charge
+	result := s.Execute(ctx, Request{Query: `mutation {
+		a: createTodo(input: {title: "Buy milk"}) { id }
+		b: createTodo(input: {title: "Buy bread"}) { id }
+	}`}, true)
+	if errorCode(result) != CodeRateLimited || result.Errors[0].Extensions["retryAfter"] != 2 {
+		t.Errorf("expected %s with retryAfter 2, got %+v", CodeRateLimited, result.Errors)
+	}
+	if strings.Join(limited, ",") != "create,create" {
+		t.Errorf("limited %v", limited)
+	}
+
+	// Queries are not limited
+	if result := s.Execute(ctx, Request{Query: `{ todos { total } }`}, true); result.HasErrors() {
+		t.Errorf("query failed: %+v", result.Errors)
+	}
+	if len(limited) != 2 {
+		t.Errorf("limited %v", limited)
+	}
+}
+
+func TestComplexityAndDepthLimits(t *testing.T) {
+	s := newTestServer(t, Options{MaxComplexity: 100, MaxDepth: 3})
+
+	// 1 + 20 * (items + 2 fields) = 61
+	ok := s.Execute(context.Background(), Request{Query: `{ todos(limit: 20) { items { id title } } }`}, true)
+	if ok.HasErrors() {
+		t.Fatalf("unexpected errors: %+v", ok.Errors)
+	}
+
+	// The same selection with a limit of 50 from a variable default costs 151
+	tooBig := s.Execute(context.Background(), Request{
+		Query: `query($n: Int = 50) { todos(limit: $n) { ...fields } } fragment fields on TodoPage { items { id title } }`,
+	}, true)
+	if errorCode(tooBig) != CodeTooComplex {
+		t.Errorf("expected complexity error, got %+v", tooBig.Errors)
+	}
+
+	shallow := newTestServer(t, Options{MaxDepth: 2})
+	deep := shallow.Execute(context.Background(), Request{Query: `{ todos { items { id } } }`}, true)
+	if errorCode(deep) != CodeTooComplex {
+		t.Errorf("expected depth error, got %+v", deep.Errors)
+	}
+
+	// Introspection does not count, so GraphiQL keeps working
+	introspection := s.Execute(context.Background(), Request{Query: `{ __schema { types { name fields { name type { name ofType { name } } } } } }`}, true)
+	if introspection.HasErrors() {
+		t.Errorf("unexpected introspection errors: %+v", introspection.Errors)
+	}
+}
+
+func TestMutationsRequirePost(t *testing.T) {
+	s := newTestServer(t, Options{})
+
+	result := s.Execute(context.Background(), Request{Query: `mutation { createTodo(input: {title: "x"}) { id } }`}, false)
+	if errorCode(result) != CodeBadUserInput {
+		t.Errorf("expected mutation to be rejected, got %+v", result)
+	}
+}
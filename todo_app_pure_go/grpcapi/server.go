@@ -0,0 +1,329 @@
+// Package grpcapi serves the todo service over gRPC for internal consumers.
+// Handlers only convert between protobuf messages and DTOs; validation and
+// lifecycle events stay in service.TodoService.
+package grpcapi
+
+import (
+	"context"
+	"log"
+	"runtime/debug"
+	"strings"
+
+	"todo-app/auth"
+	"todo-app/dto"
+	"todo-app/models"
+	todov1 "todo-app/proto/todo/v1"
+	"todo-app/service"
+	"todo-app/tenant"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// NewServer returns a gRPC server with the todo service and server
+// reflection registered. interceptors run after panic recovery, in order.
+func NewServer(todoService service.TodoService, interceptors ...grpc.UnaryServerInterceptor) *grpc.Server {
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(append([]grpc.UnaryServerInterceptor{recoveryInterceptor}, interceptors...)...))
+	todov1.RegisterTodoServiceServer(server, &todoServer{todoService: todoService})
+	reflection.Register(server)
+	return server
+}
+
+// recoveryInterceptor turns a panicking handler into an Internal error, like
+// the HTTP recovery middleware does.
+func recoveryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("panic in %s: %v\n%s", info.FullMethod, r, debug.Stack())
+			err = status.Error(codes.Internal, "Internal server error")
+		}
+	}()
+	return handler(ctx, req)
+}
+
+// AuthorizationMetadata is the metadata key of a call's credentials, sent
+// as "Bearer <token>" like the HTTP Authorization header.
+const AuthorizationMetadata = "authorization"
+
+// methodScopes are the scopes an API key needs for each method.
+var methodScopes = map[string]string{
+	todov1.TodoService_CreateTodo_FullMethodName:         auth.ScopeTodosWrite,
+	todov1.TodoService_GetTodo_FullMethodName:            auth.ScopeTodosRead,
+	todov1.TodoService_ListTodos_FullMethodName:          auth.ScopeTodosRead,
+	todov1.TodoService_UpdateTodo_FullMethodName:         auth.ScopeTodosWrite,
+	todov1.TodoService_DeleteTodo_FullMethodName:         auth.ScopeTodosWrite,
+	todov1.TodoService_ToggleTodoComplete_FullMethodName: auth.ScopeTodosWrite,
+}
+
+type workspaceClaimKey struct{}
+
+// AuthInterceptor authenticates calls that send an API key or a session
+// token from single sign-on in their AuthorizationMetadata. The call then
+// runs on behalf of the credential's owner, bound to its workspace and,
+// for API keys, limited to its scopes. Calls without credentials stay
+// anonymous. authenticateKey and authenticateSession return the key or user
+// a token belongs to; session tokens are all refused while
+// authenticateSession is nil.
+func AuthInterceptor(
+	authenticateKey func(ctx context.Context, key string) (*dto.APIKeyResponse, error),
+	authenticateSession func(ctx context.Context, token string) (*dto.UserResponse, error),
+) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		values := metadata.ValueFromIncomingContext(ctx, AuthorizationMetadata)
+		if len(values) == 0 {
+			return handler(ctx, req)
+		}
+		scheme, token, _ := strings.Cut(values[0], " ")
+		token = strings.TrimSpace(token)
+		if !strings.EqualFold(scheme, "Bearer") || token == "" {
+			return nil, status.Error(codes.Unauthenticated, "Authorization must be Bearer <token>")
+		}
+
+		if strings.HasPrefix(token, auth.APIKeyPrefix) {
+			key, err := authenticateKey(ctx, token)
+			if err != nil {
+				switch err.Error() {
+				case "api key not found":
+					return nil, status.Error(codes.Unauthenticated, "Invalid API key")
+				case "api key revoked":
+					return nil, status.Error(codes.Unauthenticated, "API key has been revoked")
+				case "api key expired":
+					return nil, status.Error(codes.Unauthenticated, "API key has expired")
+				}
+				return nil, status.Error(codes.Internal, "Failed to check API key: "+err.Error())
+			}
+			ctx = context.WithValue(WithPrincipal(ctx, key.Owner), workspaceClaimKey{}, key.Workspace)
+			ctx = auth.WithScopes(ctx, key.Scopes)
+			if scope, ok := methodScopes[info.FullMethod]; ok {
+				if err := auth.Require(ctx, scope); err != nil {
+					return nil, status.Error(codes.PermissionDenied, err.Error())
+				}
+			}
+			return handler(ctx, req)
+		}
+
+		if authenticateSession == nil {
+			return nil, status.Error(codes.Unauthenticated, "Invalid token")
+		}
+		user, err := authenticateSession(ctx, token)
+		if err != nil {
+			switch err.Error() {
+			case "invalid session":
+				return nil, status.Error(codes.Unauthenticated, "Invalid token")
+			case "session expired":
+				return nil, status.Error(codes.Unauthenticated, "Session has expired, sign in again")
+			}
+			return nil, status.Error(codes.Internal, "Failed to check session: "+err.Error())
+		}
+		ctx = context.WithValue(WithPrincipal(ctx, user.Principal), workspaceClaimKey{}, user.Workspace)
+		return handler(ctx, req)
+	}
+}
+
+// WorkspaceMetadata is the metadata key naming the workspace of a call.
+const WorkspaceMetadata = "x-workspace"
+
+// WorkspaceInterceptor runs each call in the workspace its credential is
+// bound to (see AuthInterceptor), or anonymous calls in fallback. Calls may
+// name their workspace in WorkspaceMetadata, but naming another one is
+// refused. lookup returns the ID of the workspace with a slug.
+func WorkspaceInterceptor(lookup func(ctx context.Context, slug string) (uint, error), fallback string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		claim, _ := ctx.Value(workspaceClaimKey{}).(string)
+		slug := claim
+		if slug == "" {
+			slug = fallback
+		}
+		if values := metadata.ValueFromIncomingContext(ctx, WorkspaceMetadata); len(values) > 0 {
+			if requested := strings.ToLower(values[0]); requested != slug {
+				if claim != "" {
+					return nil, status.Error(codes.PermissionDenied, "Credential is not valid for workspace "+requested)
+				}
+				return nil, status.Error(codes.Unauthenticated, "Authentication required for workspace "+requested)
+			}
+		}
+		if slug == "" {
+			return nil, status.Error(codes.Unauthenticated, "Authentication required: no default workspace")
+		}
+		id, err := lookup(ctx, slug)
+		if err != nil {
+			if err.Error() == "workspace not found" {
+				return nil, status.Error(codes.NotFound, "Workspace not found")
+			}
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		return handler(tenant.WithWorkspace(ctx, id), req)
+	}
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a context under which calls run on behalf of
+// principal. AuthInterceptor sets it; anonymous calls only reach todos that
+// have no owner.
+func WithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+func principalFrom(ctx context.Context) string {
+	principal, _ := ctx.Value(principalKey{}).(string)
+	return principal
+}
+
+type todoServer struct {
+	todov1.UnimplementedTodoServiceServer
+	todoService service.TodoService
+}
+
+func (s *todoServer) CreateTodo(ctx context.Context, req *todov1.CreateTodoRequest) (*todov1.Todo, error) {
+	todo, err := s.todoService.CreateTodo(ctx, principalFrom(ctx), &dto.CreateTodoRequest{
+		Title:       req.GetTitle(),
+		Description: req.Description,
+		Priority:    priorityFromProto(req.GetPriority()),
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return todoToProto(todo), nil
+}
+
+func (s *todoServer) GetTodo(ctx context.Context, req *todov1.GetTodoRequest) (*todov1.Todo, error) {
+	if req.GetId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid todo ID")
+	}
+	todo, err := s.todoService.GetTodoByID(ctx, principalFrom(ctx), uint(req.GetId()))
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return todoToProto(todo), nil
+}
+
+func (s *todoServer) ListTodos(ctx context.Context, req *todov1.ListTodosRequest) (*todov1.ListTodosResponse, error) {
+	var priority *models.Priority
+	if p := priorityFromProto(req.GetPriority()); p != "" {
+		if p != models.LOW && p != models.MEDIUM && p != models.HIGH {
+			return nil, status.Error(codes.InvalidArgument, "Invalid priority filter")
+		}
+		priority = &p
+	}
+
+	todos, total, err := s.todoService.GetAllTodos(ctx, principalFrom(ctx), req.Completed, nil, priority, "", int(req.GetLimit()), int(req.GetOffset()))
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	resp := &todov1.ListTodosResponse{
+		Todos:  make([]*todov1.Todo, len(todos)),
+		Total:  total,
+		Limit:  req.GetLimit(),
+		Offset: req.GetOffset(),
+	}
+	for i, todo := range todos {
+		resp.Todos[i] = todoToProto(todo)
+	}
+	return resp, nil
+}
+
+func (s *todoServer) UpdateTodo(ctx context.Context, req *todov1.UpdateTodoRequest) (*todov1.Todo, error) {
+	if req.GetId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid todo ID")
+	}
+	update := &dto.UpdateTodoRequest{
+		Title:       req.Title,
+		Description: req.Description,
+		Completed:   req.Completed,
+	}
+	if p := priorityFromProto(req.GetPriority()); p != "" {
+		update.Priority = &p
+	}
+
+	todo, err := s.todoService.UpdateTodo(ctx, principalFrom(ctx), uint(req.GetId()), update)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return todoToProto(todo), nil
+}
+
+func (s *todoServer) DeleteTodo(ctx context.Context, req *todov1.DeleteTodoRequest) (*todov1.DeleteTodoResponse, error) {
+	if req.GetId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid todo ID")
+	}
+	if err := s.todoService.DeleteTodo(ctx, principalFrom(ctx), uint(req.GetId())); err != nil {
+		return nil, statusError(err)
+	}
+	return &todov1.DeleteTodoResponse{}, nil
+}
+
+func (s *todoServer) ToggleTodoComplete(ctx context.Context, req *todov1.ToggleTodoCompleteRequest) (*todov1.Todo, error) {
+	if req.GetId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid todo ID")
+	}
+	todo, err := s.todoService.ToggleTodoComplete(ctx, principalFrom(ctx), uint(req.GetId()))
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return todoToProto(todo), nil
+}
+
+// statusError maps service errors to gRPC status codes the same way the
+// REST controllers map them to HTTP status codes.
+func statusError(err error) error {
+	switch {
+	case err.Error() == "todo not found":
+		return status.Error(codes.NotFound, "Todo not found")
+	case strings.HasPrefix(err.Error(), "forbidden"):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case strings.HasPrefix(err.Error(), "validation failed"):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case strings.HasPrefix(err.Error(), "blocked"):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+func todoToProto(todo *dto.TodoResponse) *todov1.Todo {
+	return &todov1.Todo{
+		Id:          uint32(todo.ID),
+		Title:       todo.Title,
+		Description: todo.Description,
+		Completed:   todo.Completed,
+		Priority:    priorityToProto(todo.Priority),
+		CreatedAt:   timestamppb.New(todo.CreatedAt),
+		UpdatedAt:   timestamppb.New(todo.UpdatedAt),
+	}
+}
+
+func priorityToProto(p models.Priority) todov1.Priority {
+	switch p {
+	case models.LOW:
+		return todov1.Priority_PRIORITY_LOW
+	case models.MEDIUM:
+		return todov1.Priority_PRIORITY_MEDIUM
+	case models.HIGH:
+		return todov1.Priority_PRIORITY_HIGH
+	default:
+		return todov1.Priority_PRIORITY_UNSPECIFIED
+	}
+}
+
+// priorityFromProto returns an empty priority for PRIORITY_UNSPECIFIED and
+// passes unknown values through so the service rejects them.
+func priorityFromProto(p todov1.Priority) models.Priority {
+	switch p {
+	case todov1.Priority_PRIORITY_UNSPECIFIED:
+		return ""
+	case todov1.Priority_PRIORITY_LOW:
+		return models.LOW
+	case todov1.Priority_PRIORITY_MEDIUM:
+		return models.MEDIUM
+	case todov1.Priority_PRIORITY_HIGH:
+		return models.HIGH
+	default:
+		return models.Priority(p.String())
+	}
+}
@@ -0,0 +1,241 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"todo-app/auth"
+	"todo-app/dto"
+	"todo-app/models"
+	todov1 "todo-app/proto/todo/v1"
+	"todo-app/repository"
+	"todo-app/service"
+	"todo-app/tenant"
+
+	"github.com/glebarez/sqlite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// workspaces stands in for the workspace table.
+func workspaces(_ context.Context, slug string) (uint, error) {
+	switch slug {
+	case "acme":
+		return 1, nil
+	case "globex":
+		return 2, nil
+	}
+	return 0, errors.New("workspace not found")
+}
+
+// authenticateKey and authenticateSession stand in for the API key and
+// session lookups.
+func authenticateKey(_ context.Context, key string) (*dto.APIKeyResponse, error) {
+	readWrite := []string{auth.ScopeTodosRead, auth.ScopeTodosWrite}
+	switch key {
+	case "tk_acme":
+		return &dto.APIKeyResponse{Owner: "ci", Workspace: "acme", Scopes: readWrite}, nil
+	case "tk_globex":
+		return &dto.APIKeyResponse{Owner: "ci", Workspace: "globex", Scopes: readWrite}, nil
+	case "tk_initech":
+		return &dto.APIKeyResponse{Owner: "ci", Workspace: "initech", Scopes: readWrite}, nil
+	case "tk_reader":
+		return &dto.APIKeyResponse{Owner: "ci", Workspace: "acme", Scopes: []string{auth.ScopeTodosRead}}, nil
+	case "tk_revoked":
+		return nil, errors.New("api key revoked")
+	}
+	return nil, errors.New("api key not found")
+}
+
+func authenticateSession(_ context.Context, token string) (*dto.UserResponse, error) {
+	if token == "alice-session" {
+		return &dto.UserResponse{Principal: "alice@example.com", Workspace: "acme"}, nil
+	}
+	return nil, errors.New("invalid session")
+}
+
+// as returns a context whose calls send token.
+func as(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), AuthorizationMetadata, "Bearer "+token)
+}
+
+func newTestClient(t *testing.T) todov1.TodoServiceClient {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(tenant.Plugin{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.Todo{}, &models.TodoChange{}, &models.TodoStatusChange{}, &models.TodoDependency{}, &models.Attachment{}, &models.Comment{}, &models.CommentRevision{}, &models.TodoGrant{}, &models.ListGrant{}); err != nil {
+		t.Fatal(err)
+	}
+
+	listener := bufconn.Listen(1 << 20)
+	server := NewServer(service.NewTodoService(repository.NewTodoRepository(db), repository.NewAccessRepository(db), nil, nil, nil), AuthInterceptor(authenticateKey, authenticateSession), WorkspaceInterceptor(workspaces, "acme"))
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return todov1.NewTodoServiceClient(conn)
+}
+
+func TestTodoLifecycle(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	created, err := client.CreateTodo(ctx, &todov1.CreateTodoRequest{Title: "Write report"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Priority != todov1.Priority_PRIORITY_MEDIUM || created.CreatedAt == nil {
+		t.Errorf("unexpected todo: %v", created)
+	}
+	if _, err := client.CreateTodo(ctx, &todov1.CreateTodoRequest{Title: "Ship", Priority: todov1.Priority_PRIORITY_HIGH}); err != nil {
+		t.Fatal(err)
+	}
+
+	toggled, err := client.ToggleTodoComplete(ctx, &todov1.ToggleTodoCompleteRequest{Id: created.Id})
+	if err != nil || !toggled.Completed {
+		t.Fatalf("toggle failed: %v %v", toggled, err)
+	}
+
+	updated, err := client.UpdateTodo(ctx, &todov1.UpdateTodoRequest{Id: created.Id, Description: proto.String("one page")})
+	if err != nil || updated.GetDescription() != "one page" || updated.Title != "Write report" {
+		t.Fatalf("update failed: %v %v", updated, err)
+	}
+
+	list, err := client.ListTodos(ctx, &todov1.ListTodosRequest{Completed: proto.Bool(false)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 1 || list.Todos[0].Title != "Ship" {
+		t.Errorf("unexpected filtered list: %v", list)
+	}
+
+	if _, err := client.DeleteTodo(ctx, &todov1.DeleteTodoRequest{Id: created.Id}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetTodo(ctx, &todov1.GetTodoRequest{Id: created.Id}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound after delete, got %v", err)
+	}
+}
+
+func TestErrorsMapToStatusCodes(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	if _, err := client.CreateTodo(ctx, &todov1.CreateTodoRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for a missing title, got %v", err)
+	}
+	if _, err := client.ToggleTodoComplete(ctx, &todov1.ToggleTodoCompleteRequest{Id: 42}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound, got %v", err)
+	}
+	if _, err := client.GetTodo(ctx, &todov1.GetTodoRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for ID 0, got %v", err)
+	}
+	if _, err := client.ListTodos(ctx, &todov1.ListTodosRequest{Priority: todov1.Priority(9)}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for an unknown priority, got %v", err)
+	}
+}
+
+func TestCredentialsSelectWorkspace(t *testing.T) {
+	client := newTestClient(t)
+	acme, globex := as("tk_acme"), as("tk_globex")
+
+	created, err := client.CreateTodo(acme, &todov1.CreateTodoRequest{Title: "Acme plans"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.GetTodo(globex, &todov1.GetTodoRequest{Id: created.Id}); status.Code(err) != codes.NotFound {
+		t.Errorf("get from another workspace: %v", err)
+	}
+	if _, err := client.DeleteTodo(globex, &todov1.DeleteTodoRequest{Id: created.Id}); status.Code(err) != codes.NotFound {
+		t.Errorf("delete from another workspace: %v", err)
+	}
+	list, err := client.ListTodos(globex, &todov1.ListTodosRequest{})
+	if err != nil || list.Total != 0 {
+		t.Errorf("list from another workspace: %v %v", list, err)
+	}
+
+	// Metadata may repeat the credential's workspace but not contradict it
+	if _, err := client.GetTodo(metadata.AppendToOutgoingContext(acme, WorkspaceMetadata, "Acme"), &todov1.GetTodoRequest{Id: created.Id}); err != nil {
+		t.Errorf("get naming the key's workspace: %v", err)
+	}
+	if _, err := client.GetTodo(metadata.AppendToOutgoingContext(globex, WorkspaceMetadata, "acme"), &todov1.GetTodoRequest{Id: created.Id}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("key used in another workspace: %v", err)
+	}
+
+	// Anonymous calls act in the default workspace only
+	anonymous := metadata.AppendToOutgoingContext(context.Background(), WorkspaceMetadata, "globex")
+	if _, err := client.ListTodos(anonymous, &todov1.ListTodosRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("anonymous call naming another workspace: %v", err)
+	}
+	if _, err := client.ListTodos(metadata.AppendToOutgoingContext(context.Background(), WorkspaceMetadata, "acme"), &todov1.ListTodosRequest{}); err != nil {
+		t.Errorf("anonymous call naming the default workspace: %v", err)
+	}
+
+	if _, err := client.ListTodos(as("tk_initech"), &todov1.ListTodosRequest{}); status.Code(err) != codes.NotFound {
+		t.Errorf("unknown workspace: %v", err)
+	}
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	client := newTestClient(t)
+
+	created, err := client.CreateTodo(as("tk_acme"), &todov1.CreateTodoRequest{Title: "CI todo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The todo belongs to the key's owner, whom anonymous callers and
+	// other users are not
+	if _, err := client.GetTodo(as("tk_reader"), &todov1.GetTodoRequest{Id: created.Id}); err != nil {
+		t.Errorf("owner's other key: %v", err)
+	}
+	if _, err := client.GetTodo(context.Background(), &todov1.GetTodoRequest{Id: created.Id}); status.Code(err) != codes.NotFound {
+		t.Errorf("anonymous get of an owned todo: %v", err)
+	}
+	if _, err := client.GetTodo(as("alice-session"), &todov1.GetTodoRequest{Id: created.Id}); status.Code(err) != codes.NotFound {
+		t.Errorf("another user's get: %v", err)
+	}
+	if _, err := client.CreateTodo(as("alice-session"), &todov1.CreateTodoRequest{Title: "Alice's todo"}); err != nil {
+		t.Errorf("session create: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want codes.Code
+	}{
+		{"key outside scope", as("tk_reader"), codes.PermissionDenied},
+		{"revoked key", as("tk_revoked"), codes.Unauthenticated},
+		{"unknown key", as("tk_guess"), codes.Unauthenticated},
+		{"invalid session", as("eyJhbGciOiJIUzI1NiJ9"), codes.Unauthenticated},
+		{"basic auth", metadata.AppendToOutgoingContext(context.Background(), AuthorizationMetadata, "Basic Y2k6c2VjcmV0"), codes.Unauthenticated},
+	}
+	for _, tt := range tests {
+		if _, err := client.CreateTodo(tt.ctx, &todov1.CreateTodoRequest{Title: "Refused"}); status.Code(err) != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Pinger is satisfied by *sql.DB.
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// PingCheck reports whether the database answers a ping.
+func PingCheck(db Pinger) Check {
+	return func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	}
+}
+
+// Heartbeat tracks the last time a background component made progress.
+// The component calls Beat on every iteration; the check fails when no beat
+// has been seen within maxAge.
+type Heartbeat struct {
+	maxAge time.Duration
+	last   atomic.Int64
+}
+
+func NewHeartbeat(maxAge time.Duration) *Heartbeat {
+	hb := &Heartbeat{maxAge: maxAge}
+	hb.Beat()
+	return hb
+}
+
+func (hb *Heartbeat) Beat() {
+	hb.last.Store(time.Now().UnixNano())
+}
+
+func (hb *Heartbeat) Check() Check {
+	return func(ctx context.Context) error {
+		age := time.Since(time.Unix(0, hb.last.Load()))
+		if age > hb.maxAge {
+			return fmt.Errorf("no heartbeat for %s", age.Round(time.Second))
+		}
+		return nil
+	}
+}
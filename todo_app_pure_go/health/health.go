@@ -0,0 +1,171 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Status is the state of a single check or of a whole report.
+type Status string
+
+const (
+	StatusUp   Status = "up"
+	StatusDown Status = "down"
+)
+
+// Kind selects which probe a check contributes to. Liveness checks should
+// only fail when restarting the process would help; readiness checks fail
+// whenever the instance should not receive traffic.
+type Kind int
+
+const (
+	Readiness Kind = iota
+	Liveness
+)
+
+// Check reports the health of one dependency. It must honour ctx cancellation.
+type Check func(ctx context.Context) error
+
+// DefaultTimeout bounds a single check when none is given at registration.
+const DefaultTimeout = 2 * time.Second
+
+// ErrShuttingDown is reported by the readiness probe once shutdown has begun.
+var ErrShuttingDown = errors.New("server is shutting down")
+
+type CheckResult struct {
+	Name      string  `json:"name"`
+	Status    Status  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status Status        `json:"status"`
+	Checks []CheckResult `json:"checks"`
+}
+
+type registration struct {
+	name    string
+	kind    Kind
+	check   Check
+	timeout time.Duration
+}
+
+// Registry collects the checks registered by the application's components
+// and evaluates them for the liveness and readiness probes.
+type Registry struct {
+	mu           sync.RWMutex
+	checks       []registration
+	shuttingDown atomic.Bool
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a named check. A zero timeout uses DefaultTimeout.
+func (r *Registry) Register(name string, kind Kind, timeout time.Duration, check Check) {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, registration{
+		name:    name,
+		kind:    kind,
+		check:   check,
+		timeout: timeout,
+	})
+}
+
+// SetShuttingDown makes readiness fail from now on so load balancers stop
+// routing new requests while in-flight ones drain.
+func (r *Registry) SetShuttingDown() {
+	r.shuttingDown.Store(true)
+}
+
+func (r *Registry) ShuttingDown() bool {
+	return r.shuttingDown.Load()
+}
+
+// Live runs the liveness checks.
+func (r *Registry) Live(ctx context.Context) Report {
+	return r.run(ctx, Liveness)
+}
+
+// Ready runs the readiness checks, which include the liveness checks.
+func (r *Registry) Ready(ctx context.Context) Report {
+	report := r.run(ctx, Readiness)
+	if r.ShuttingDown() {
+		report.Status = StatusDown
+		report.Checks = append([]CheckResult{{
+			Name:   "shutdown",
+			Status: StatusDown,
+			Error:  ErrShuttingDown.Error(),
+		}}, report.Checks...)
+	}
+	return report
+}
+
+func (r *Registry) run(ctx context.Context, kind Kind) Report {
+	r.mu.RLock()
+	var selected []registration
+	for _, reg := range r.checks {
+		if reg.kind == kind || kind == Readiness {
+			selected = append(selected, reg)
+		}
+	}
+	r.mu.RUnlock()
+
+	report := Report{Status: StatusUp, Checks: make([]CheckResult, len(selected))}
+
+	var wg sync.WaitGroup
+	for i, reg := range selected {
+		wg.Add(1)
+		go func(i int, reg registration) {
+			defer wg.Done()
+			report.Checks[i] = runCheck(ctx, reg)
+		}(i, reg)
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status == StatusDown {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+func runCheck(ctx context.Context, reg registration) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, reg.timeout)
+	defer cancel()
+
+	start := time.Now()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- reg.check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := CheckResult{
+		Name:      reg.name,
+		Status:    StatusUp,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,79 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReadyAggregatesChecks(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("ok", Readiness, 0, func(ctx context.Context) error { return nil })
+	registry.Register("broken", Readiness, 0, func(ctx context.Context) error { return errors.New("boom") })
+
+	report := registry.Ready(context.Background())
+	if report.Status != StatusDown {
+		t.Fatalf("expected down, got %s", report.Status)
+	}
+	if len(report.Checks) != 2 {
+		t.Fatalf("expected 2 checks, got %d", len(report.Checks))
+	}
+	if report.Checks[0].Status != StatusUp || report.Checks[1].Error != "boom" {
+		t.Errorf("unexpected results: %+v", report.Checks)
+	}
+}
+
+func TestCheckTimeout(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("slow", Readiness, 10*time.Millisecond, func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	start := time.Now()
+	report := registry.Ready(context.Background())
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("check was not bounded by its timeout: %s", elapsed)
+	}
+	if report.Status != StatusDown || report.Checks[0].Error != context.DeadlineExceeded.Error() {
+		t.Errorf("expected deadline exceeded, got %+v", report.Checks[0])
+	}
+}
+
+func TestLivenessIgnoresReadinessChecks(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("database", Readiness, 0, func(ctx context.Context) error { return errors.New("down") })
+
+	if report := registry.Live(context.Background()); report.Status != StatusUp {
+		t.Errorf("expected liveness up, got %+v", report)
+	}
+}
+
+func TestShutdownFailsReadiness(t *testing.T) {
+	registry := NewRegistry()
+	if report := registry.Ready(context.Background()); report.Status != StatusUp {
+		t.Fatalf("expected up before shutdown, got %s", report.Status)
+	}
+
+	registry.SetShuttingDown()
+	report := registry.Ready(context.Background())
+	if report.Status != StatusDown || report.Checks[0].Name != "shutdown" {
+		t.Errorf("expected shutdown to fail readiness, got %+v", report)
+	}
+	if report := registry.Live(context.Background()); report.Status != StatusUp {
+		t.Errorf("liveness should not fail during shutdown, got %s", report.Status)
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	hb := NewHeartbeat(time.Minute)
+	if err := hb.Check()(context.Background()); err != nil {
+		t.Fatalf("fresh heartbeat failed: %v", err)
+	}
+
+	hb.last.Store(time.Now().Add(-2 * time.Minute).UnixNano())
+	if err := hb.Check()(context.Background()); err == nil {
+		t.Error("expected stale heartbeat to fail")
+	}
+}
@@ -0,0 +1,368 @@
+// Package ical reads and writes the subset of RFC 5545 iCalendar needed to
+// exchange todos as VTODO components.
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// maxLineOctets is the folding limit from RFC 5545 section 3.1, excluding CRLF.
+	maxLineOctets = 75
+	dateTimeUTC   = "20060102T150405Z"
+)
+
+// Todo is a VTODO component.
+type Todo struct {
+	UID          string
+	Summary      string
+	Description  string
+	Priority     int // 0 undefined, 1 highest, 9 lowest
+	Status       string
+	Completed    time.Time
+	Created      time.Time
+	LastModified time.Time
+
+	// Line is the line of BEGIN:VTODO in the parsed source, for error reports.
+	Line int
+}
+
+// Calendar is a VCALENDAR containing todos.
+type Calendar struct {
+	ProdID string
+	Name   string
+	Todos  []Todo
+}
+
+// Status values for VTODO.
+const (
+	StatusNeedsAction = "NEEDS-ACTION"
+	StatusCompleted   = "COMPLETED"
+	StatusInProcess   = "IN-PROCESS"
+	StatusCancelled   = "CANCELLED"
+)
+
+// Encoder writes a calendar incrementally so large feeds can be streamed.
+type Encoder struct {
+	w   *bufio.Writer
+	now time.Time
+	err error
+}
+
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: bufio.NewWriter(w), now: time.Now()}
+}
+
+// Begin writes the calendar header.
+func (e *Encoder) Begin(prodID, name string) error {
+	e.line("BEGIN", "VCALENDAR")
+	e.line("VERSION", "2.0")
+	e.line("PRODID", prodID)
+	e.line("CALSCALE", "GREGORIAN")
+	if name != "" {
+		e.text("X-WR-CALNAME", name)
+	}
+	return e.err
+}
+
+// Todo writes one VTODO component.
+func (e *Encoder) Todo(t Todo) error {
+	e.line("BEGIN", "VTODO")
+	e.line("UID", t.UID)
+	e.line("DTSTAMP", e.now.UTC().Format(dateTimeUTC))
+	e.text("SUMMARY", t.Summary)
+	if t.Description != "" {
+		e.text("DESCRIPTION", t.Description)
+	}
+	if t.Priority > 0 {
+		e.line("PRIORITY", strconv.Itoa(t.Priority))
+	}
+	if t.Status != "" {
+		e.line("STATUS", t.Status)
+	}
+	e.time("COMPLETED", t.Completed)
+	e.time("CREATED", t.Created)
+	e.time("LAST-MODIFIED", t.LastModified)
+	e.line("END", "VTODO")
+	return e.err
+}
+
+// End writes the calendar trailer and flushes the output.
+func (e *Encoder) End() error {
+	e.line("END", "VCALENDAR")
+	if e.err == nil {
+		e.err = e.w.Flush()
+	}
+	return e.err
+}
+
+// Flush sends buffered output to the underlying writer.
+func (e *Encoder) Flush() error {
+	if e.err == nil {
+		e.err = e.w.Flush()
+	}
+	return e.err
+}
+
+func (e *Encoder) text(name, value string) {
+	e.line(name, Escape(value))
+}
+
+func (e *Encoder) time(name string, t time.Time) {
+	if !t.IsZero() {
+		e.line(name, t.UTC().Format(dateTimeUTC))
+	}
+}
+
+func (e *Encoder) line(name, value string) {
+	if e.err != nil {
+		return
+	}
+	_, e.err = e.w.WriteString(Fold(name + ":" + value))
+}
+
+// Escape escapes a TEXT value (RFC 5545 section 3.3.11).
+func Escape(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ReplaceAll(s, "\r\n", "\n") {
+		switch r {
+		case '\\', ';', ',':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case '\n':
+			b.WriteString(`\n`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Unescape reverses Escape.
+func Unescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 'n', 'N':
+				b.WriteByte('\n')
+			default:
+				b.WriteByte(s[i])
+			}
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// Fold splits a content line into chunks of at most 75 octets joined by
+// CRLF and a space, never breaking a multi-byte character. The result ends
+// with CRLF.
+func Fold(line string) string {
+	var b strings.Builder
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// The leading space of a continuation line counts towards its length
+		limit = maxLineOctets - 1
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+// contentLine is an unfolded line with its property name, parameters and value.
+type contentLine struct {
+	name   string
+	params map[string]string
+	value  string
+	line   int
+}
+
+// Decode parses all VTODO components of a calendar. Unknown properties and
+// components are ignored.
+func Decode(r io.Reader) (*Calendar, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	cal := &Calendar{}
+	var current *Todo
+	depth := 0 // nesting inside components other than VTODO, e.g. VALARM
+	seenCalendar := false
+
+	for _, cl := range lines {
+		switch {
+		case cl.name == "BEGIN" && strings.EqualFold(cl.value, "VCALENDAR"):
+			seenCalendar = true
+		case cl.name == "BEGIN" && strings.EqualFold(cl.value, "VTODO") && current == nil:
+			current = &Todo{Line: cl.line}
+		case cl.name == "BEGIN":
+			depth++
+		case cl.name == "END" && strings.EqualFold(cl.value, "VTODO") && depth == 0 && current != nil:
+			cal.Todos = append(cal.Todos, *current)
+			current = nil
+		case cl.name == "END" && depth > 0:
+			depth--
+		case current != nil && depth == 0:
+			if err := current.set(cl); err != nil {
+				return nil, fmt.Errorf("line %d: %w", cl.line, err)
+			}
+		case current == nil && depth == 0:
+			switch cl.name {
+			case "PRODID":
+				cal.ProdID = cl.value
+			case "X-WR-CALNAME":
+				cal.Name = Unescape(cl.value)
+			}
+		}
+	}
+
+	if !seenCalendar {
+		return nil, fmt.Errorf("not an iCalendar stream: missing BEGIN:VCALENDAR")
+	}
+	if current != nil {
+		return nil, fmt.Errorf("line %d: VTODO is not terminated", current.Line)
+	}
+	return cal, nil
+}
+
+func (t *Todo) set(cl contentLine) error {
+	var err error
+	switch cl.name {
+	case "UID":
+		t.UID = cl.value
+	case "SUMMARY":
+		t.Summary = Unescape(cl.value)
+	case "DESCRIPTION":
+		t.Description = Unescape(cl.value)
+	case "PRIORITY":
+		t.Priority, err = strconv.Atoi(strings.TrimSpace(cl.value))
+		if err != nil || t.Priority < 0 || t.Priority > 9 {
+			return fmt.Errorf("PRIORITY must be an integer between 0 and 9")
+		}
+	case "STATUS":
+		t.Status = strings.ToUpper(cl.value)
+	case "COMPLETED":
+		t.Completed, err = parseDateTime(cl)
+	case "CREATED":
+		t.Created, err = parseDateTime(cl)
+	case "LAST-MODIFIED":
+		t.LastModified, err = parseDateTime(cl)
+	}
+	return err
+}
+
+func parseDateTime(cl contentLine) (time.Time, error) {
+	value := strings.TrimSpace(cl.value)
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(dateTimeUTC, value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%s: invalid date-time %q", cl.name, value)
+		}
+		return t, nil
+	}
+
+	loc := time.UTC
+	if tzid, ok := cl.params["TZID"]; ok {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	for _, layout := range []string{"20060102T150405", "20060102"} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%s: invalid date-time %q", cl.name, value)
+}
+
+// unfold joins continuation lines and splits each content line into its parts.
+func unfold(r io.Reader) ([]contentLine, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var lines []contentLine
+	var current strings.Builder
+	start, n := 0, 0
+
+	flush := func() error {
+		if current.Len() == 0 {
+			return nil
+		}
+		cl, err := parseContentLine(current.String())
+		if err != nil {
+			return fmt.Errorf("line %d: %w", start, err)
+		}
+		cl.line = start
+		lines = append(lines, cl)
+		current.Reset()
+		return nil
+	}
+
+	for scanner.Scan() {
+		n++
+		text := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t") {
+			current.WriteString(text[1:])
+			continue
+		}
+		if err := flush(); err != nil {
+			return nil, err
+		}
+		start = n
+		current.WriteString(text)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+func parseContentLine(s string) (contentLine, error) {
+	// The value starts at the first colon outside a quoted parameter value
+	inQuotes := false
+	colon := -1
+	for i, r := range s {
+		if r == '"' {
+			inQuotes = !inQuotes
+		} else if r == ':' && !inQuotes {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return contentLine{}, fmt.Errorf("malformed content line %q", s)
+	}
+
+	parts := strings.Split(s[:colon], ";")
+	cl := contentLine{
+		name:   strings.ToUpper(parts[0]),
+		params: make(map[string]string, len(parts)-1),
+		value:  s[colon+1:],
+	}
+	for _, param := range parts[1:] {
+		if key, value, ok := strings.Cut(param, "="); ok {
+			cl.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+		}
+	}
+	return cl, nil
+}
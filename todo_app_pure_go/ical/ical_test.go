@@ -0,0 +1,132 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestEscapeRoundTrip(t *testing.T) {
+	in := "Buy milk, eggs; bread\\butter\nthen cook"
+	escaped := Escape(in)
+	if escaped != `Buy milk\, eggs\; bread\\butter\nthen cook` {
+		t.Errorf("unexpected escape: %s", escaped)
+	}
+	if got := Unescape(escaped); got != in {
+		t.Errorf("round trip failed: %q", got)
+	}
+}
+
+func TestFoldLimitsOctetsAndKeepsRunes(t *testing.T) {
+	line := "SUMMARY:" + strings.Repeat("çalışma ", 30)
+	folded := Fold(line)
+
+	if !strings.HasSuffix(folded, "\r\n") {
+		t.Fatal("folded line must end with CRLF")
+	}
+	parts := strings.Split(strings.TrimSuffix(folded, "\r\n"), "\r\n")
+	if len(parts) < 2 {
+		t.Fatalf("expected the line to be folded, got %q", folded)
+	}
+	for i, part := range parts {
+		if len(part) > 75 {
+			t.Errorf("part %d is %d octets", i, len(part))
+		}
+		if i > 0 && part[0] != ' ' {
+			t.Errorf("continuation %d must start with a space", i)
+		}
+		if !utf8.ValidString(part) {
+			t.Errorf("part %d splits a multi-byte character: %q", i, part)
+		}
+	}
+
+	var unfolded strings.Builder
+	for i, part := range parts {
+		if i > 0 {
+			part = part[1:]
+		}
+		unfolded.WriteString(part)
+	}
+	if unfolded.String() != line {
+		t.Error("unfolding did not restore the original line")
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	todo := Todo{
+		UID:          "todo-1@todo-app",
+		Summary:      "Plan offsite, book venue; send invites",
+		Description:  strings.Repeat("A long description that has to be folded. ", 5) + "\nSecond line",
+		Priority:     1,
+		Status:       StatusCompleted,
+		Completed:    created.Add(time.Hour),
+		Created:      created,
+		LastModified: created.Add(time.Hour),
+	}
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	if err := enc.Begin("-//test//EN", "My todos"); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Todo(todo); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.End(); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{"BEGIN:VCALENDAR\r\n", "PRIORITY:1\r\n", "STATUS:COMPLETED\r\n", "CREATED:20260102T030405Z\r\n", "COMPLETED:20260102T040405Z\r\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q", want)
+		}
+	}
+
+	cal, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cal.Name != "My todos" || len(cal.Todos) != 1 {
+		t.Fatalf("unexpected calendar: %+v", cal)
+	}
+	got := cal.Todos[0]
+	if got.Summary != todo.Summary || got.Description != todo.Description || got.Priority != 1 ||
+		got.Status != StatusCompleted || !got.Created.Equal(todo.Created) || !got.Completed.Equal(todo.Completed) {
+		t.Errorf("round trip mismatch:\n got  %+v\n want %+v", got, todo)
+	}
+}
+
+func TestDecodeSkipsNestedComponents(t *testing.T) {
+	src := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n" +
+		"BEGIN:VEVENT\r\nSUMMARY:Not a todo\r\nEND:VEVENT\r\n" +
+		"BEGIN:VTODO\r\nSUMMARY:Real\r\n todo\r\nDUE;TZID=\"Europe/Istanbul\":20260301T090000\r\n" +
+		"BEGIN:VALARM\r\nDESCRIPTION:Reminder\r\nEND:VALARM\r\nEND:VTODO\r\n" +
+		"END:VCALENDAR\r\n"
+
+	cal, err := Decode(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cal.Todos) != 1 || cal.Todos[0].Summary != "Realtodo" || cal.Todos[0].Description != "" {
+		t.Errorf("unexpected todos: %+v", cal.Todos)
+	}
+	if cal.Todos[0].Line != 6 {
+		t.Errorf("expected VTODO on line 6, got %d", cal.Todos[0].Line)
+	}
+}
+
+func TestDecodeRejectsInvalidInput(t *testing.T) {
+	for name, src := range map[string]string{
+		"not a calendar": "hello world\r\n",
+		"unterminated":   "BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nSUMMARY:x\r\n",
+		"bad priority":   "BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nPRIORITY:high\r\nEND:VTODO\r\nEND:VCALENDAR\r\n",
+	} {
+		if _, err := Decode(strings.NewReader(src)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package jwt
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type claims struct {
+	Subject string `json:"sub"`
+}
+
+func TestSignAndVerify(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := []byte("0123456789abcdef0123456789abcdef")
+
+	rs, err := SignRS256(claims{Subject: "alice"}, key, "k1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := Parse(rs)
+	if err != nil || token.Header.Kid != "k1" {
+		t.Fatalf("Parse = %+v, %v", token, err)
+	}
+	if err := token.VerifyRS256(&key.PublicKey); err != nil {
+		t.Errorf("VerifyRS256: %v", err)
+	}
+	// An RS256 token never passes as HS256, whatever the secret
+	if err := token.VerifyHS256(secret); !errors.Is(err, ErrSignature) {
+		t.Errorf("VerifyHS256 of an RS256 token: %v", err)
+	}
+	var c claims
+	if err := token.Claims(&c); err != nil || c.Subject != "alice" {
+		t.Errorf("Claims = %+v, %v", c, err)
+	}
+
+	hs, _ := SignHS256(claims{Subject: "alice"}, secret)
+	token, _ = Parse(hs)
+	if err := token.VerifyHS256(secret); err != nil {
+		t.Errorf("VerifyHS256: %v", err)
+	}
+	if err := token.VerifyHS256([]byte("another secret")); !errors.Is(err, ErrSignature) {
+		t.Errorf("VerifyHS256 with the wrong secret: %v", err)
+	}
+	if err := token.VerifyRS256(&key.PublicKey); !errors.Is(err, ErrSignature) {
+		t.Errorf("VerifyRS256 of an HS256 token: %v", err)
+	}
+
+	// Changing the payload breaks the signature
+	parts := strings.Split(hs, ".")
+	forged, _ := SignHS256(claims{Subject: "mallory"}, []byte("x"))
+	token, _ = Parse(parts[0] + "." + strings.Split(forged, ".")[1] + "." + parts[2])
+	if err := token.VerifyHS256(secret); !errors.Is(err, ErrSignature) {
+		t.Errorf("forged payload: %v", err)
+	}
+
+	for _, raw := range []string{"", "a.b", "a.b.c.d", "!!.e30.", "e30.!!."} {
+		if _, err := Parse(raw); !errors.Is(err, ErrMalformed) {
+			t.Errorf("Parse(%q): %v", raw, err)
+		}
+	}
+}
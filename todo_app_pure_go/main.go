@@ -2,20 +2,39 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+	// Quick-add reads times in the user's time zone, also on hosts without
+	// a zone database
+	_ "time/tzdata"
 
 	"todo-app/config"
+	"todo-app/controller"
 	_ "todo-app/docs"
+	"todo-app/dto"
+	"todo-app/events"
+	"todo-app/gql"
+	"todo-app/grpcapi"
+	"todo-app/health"
+	"todo-app/oidc"
 	"todo-app/repository"
 	"todo-app/routes"
 	"todo-app/service"
+	"todo-app/storage"
+	"todo-app/stream"
+	"todo-app/tenant"
+	"todo-app/webhook"
 
 	"github.com/joho/godotenv"
+	"google.golang.org/grpc"
 )
 
 func main() {
@@ -24,47 +43,254 @@ func main() {
 		log.Println("Warning: .env file not found, using default values")
 	}
 
-	// Load database configuration
-	dbConfig := config.LoadDatabaseConfig()
+	// Load configuration from defaults, file, environment and flags
+	flags := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	printConfig := flags.Bool("print-config", false, "print the resolved configuration with secrets redacted and exit")
+	cfg, err := config.Load(flags, os.Args[1:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+	if *printConfig {
+		fmt.Print(cfg)
+		return
+	}
+	log.Printf("Configuration:\n%s", cfg)
 
 	// Connect to database
-	db, err := config.ConnectDatabase(dbConfig)
+	db, err := config.ConnectDatabase(cfg)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
-	// Initialize repository
-	todoRepo := repository.NewTodoRepository(db)
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatalf("Failed to get database handle: %v", err)
+	}
 
-	// Initialize service
-	todoService := service.NewTodoService(todoRepo)
+	// Register health checks
+	healthRegistry := health.NewRegistry()
+	healthRegistry.Register("database", health.Readiness, 2*time.Second, health.PingCheck(sqlDB))
+	healthRegistry.Register("migrations", health.Readiness, 2*time.Second, config.MigrationCheck(db))
 
-	// Setup routes
-	router := routes.SetupRoutes(todoService)
+	// Initialize repositories
+	var todoRepo repository.TodoRepository = repository.NewTodoRepository(db)
+	calendarFeedRepo := repository.NewCalendarFeedRepository(db)
+	webhookRepo := repository.NewWebhookRepository(db)
+	syncRepo := repository.NewSyncRepository(db)
+	statsRepo := repository.NewStatsRepository(db)
+	attachmentRepo := repository.NewAttachmentRepository(db)
+	commentRepo := repository.NewCommentRepository(db)
+	dependencyRepo := repository.NewDependencyRepository(db)
+	accessRepo := repository.NewAccessRepository(db)
+	workspaceRepo := repository.NewWorkspaceRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	userRepo := repository.NewUserRepository(db)
+
+	// Create the configured workspaces; data from before workspaces existed
+	// belongs to the first
+	startup := tenant.AllWorkspaces(context.Background())
+	for i, slug := range cfg.Tenancy.Workspaces {
+		workspace, err := workspaceRepo.Ensure(startup, slug)
+		if err != nil {
+			log.Fatalf("Failed to create workspace %s: %v", slug, err)
+		}
+		if i > 0 {
+			continue
+		}
+		if claimed, err := config.ClaimOrphans(db, workspace.ID); err != nil {
+			log.Fatalf("Failed to move existing data into workspace %s: %v", slug, err)
+		} else if claimed > 0 {
+			log.Printf("Moved %d existing rows into workspace %s", claimed, slug)
+		}
+	}
+
+	// Serve repeated todo reads from memory; sync saves, comment counts,
+	// grants and dependencies go through the cache so they invalidate it too
+	var todoCache controller.CacheStatsSource
+	if cfg.Cache.Enabled {
+		cachedRepo := repository.NewCachedTodoRepository(todoRepo, repository.CacheOptions{
+			Size: cfg.Cache.Size,
+			TTL:  cfg.Cache.TTL,
+		})
+		todoRepo, todoCache = cachedRepo, cachedRepo
+		syncRepo = cachedRepo.WrapSync(syncRepo)
+		commentRepo = cachedRepo.WrapComments(commentRepo)
+		accessRepo = cachedRepo.WrapAccess(accessRepo)
+		dependencyRepo = cachedRepo.WrapDependencies(dependencyRepo)
+	}
+
+	// Todos created before the change log existed must be part of a full sync
+	if err := syncRepo.Backfill(startup); err != nil {
+		log.Fatalf("Failed to backfill the sync change log: %v", err)
+	}
+	// Todos completed before completed_at existed count as completed at
+	// their last update
+	if _, err := statsRepo.BackfillCompletedAt(startup); err != nil {
+		log.Fatalf("Failed to backfill completion times: %v", err)
+	}
+	// Todos move through the configured workflow; those from before it
+	// existed get the status matching their completed flag
+	wf, err := cfg.Workflow.Workflow()
+	if err != nil {
+		log.Fatalf("Invalid workflow: %v", err)
+	}
+	if _, err := todoRepo.BackfillStatus(startup, wf.Initial(), wf.Done()); err != nil {
+		log.Fatalf("Failed to backfill todo statuses: %v", err)
+	}
+	// Todos from before manual ordering get positions, newest first
+	if _, err := todoRepo.BackfillPositions(startup); err != nil {
+		log.Fatalf("Failed to backfill todo positions: %v", err)
+	}
+
+	// Deliver todo lifecycle events to webhooks in the background
+	eventBus := events.NewBus()
+	dispatcher := webhook.NewDispatcher(webhookRepo, webhook.Options{
+		Workers:             cfg.Webhooks.Workers,
+		MaxAttempts:         cfg.Webhooks.MaxAttempts,
+		BaseDelay:           cfg.Webhooks.BaseDelay,
+		MaxDelay:            cfg.Webhooks.MaxDelay,
+		Timeout:             cfg.Webhooks.Timeout,
+		PollInterval:        cfg.Webhooks.PollInterval,
+		AllowPrivateTargets: cfg.Webhooks.AllowPrivateTargets,
+	})
+	eventBus.Subscribe(dispatcher.Handle)
+	dispatcher.Start()
+	healthRegistry.Register("webhook_scheduler", health.Liveness, 0, dispatcher.Heartbeat().Check())
+
+	// Fan the same events out to Server-Sent Events and WebSocket clients
+	eventHub := stream.NewHub(cfg.Stream.ReplayBuffer)
+	eventBus.Subscribe(eventHub.Publish)
+
+	// Attachment content lives outside the database
+	blobStore, err := storage.NewLocalStore(cfg.Attachments.Dir)
+	if err != nil {
+		log.Fatalf("Failed to open attachment storage: %v", err)
+	}
+
+	// Initialize services
+	todoService := service.NewTodoService(todoRepo, accessRepo, workspaceRepo, eventBus, wf)
+	calendarService := service.NewCalendarService(calendarFeedRepo)
+	webhookService := service.NewWebhookService(webhookRepo, dispatcher, service.WebhookOptions{
+		AllowPrivateTargets: cfg.Webhooks.AllowPrivateTargets,
+	})
+	statsService := service.NewStatsService(statsRepo)
+	accessService := service.NewAccessService(accessRepo, todoRepo)
+	workspaceService := service.NewWorkspaceService(workspaceRepo)
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo, workspaceRepo)
+	commentService := service.NewCommentService(commentRepo, todoRepo, accessRepo)
+	dependencyService := service.NewDependencyService(dependencyRepo, todoRepo, accessRepo)
+	attachmentService := service.NewAttachmentService(attachmentRepo, todoRepo, accessRepo, blobStore, service.AttachmentOptions{
+		MaxSize:      int64(cfg.Attachments.MaxSize),
+		AllowedTypes: cfg.Attachments.AllowedTypes,
+	})
 
-	// Get port from environment
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	// Deleting a todo deletes its attachment rows; the blobs follow here,
+	// and blobs left behind by an earlier run are removed at startup
+	eventBus.Subscribe(attachmentService.HandleEvent)
+	if removed, err := attachmentService.CollectGarbage(startup); err != nil {
+		log.Printf("Failed to remove unused attachment blobs: %v", err)
+	} else if removed > 0 {
+		log.Printf("Removed %d unused attachment blobs", removed)
 	}
+	syncService := service.NewSyncService(todoRepo, syncRepo, accessRepo, workspaceRepo, eventBus, wf, cfg.Sync.TombstoneTTL, cfg.Sync.MaxMutations)
+
+	// Single sign-on through the configured OpenID Connect provider
+	var authService service.AuthService
+	if cfg.OIDC.Enabled() {
+		discoverCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		provider, err := oidc.Discover(discoverCtx, cfg.OIDC.Issuer, oidc.Config{
+			ClientID:     cfg.OIDC.ClientID,
+			ClientSecret: cfg.OIDC.ClientSecret,
+			RedirectURL:  cfg.OIDCRedirectURL(),
+			Scopes:       cfg.OIDC.Scopes,
+			HTTPClient:   &http.Client{Timeout: 10 * time.Second},
+		})
+		cancel()
+		if err != nil {
+			log.Fatalf("Failed to discover the OpenID Connect provider: %v", err)
+		}
+		authService = service.NewAuthService(provider, userRepo, workspaceRepo, service.AuthOptions{
+			SessionSecret: []byte(cfg.OIDC.SessionSecret),
+			SessionTTL:    cfg.OIDC.SessionTTL,
+		})
+	}
+
+	graphQLServer, err := gql.NewServer(todoService, gql.Options{
+		MaxComplexity: cfg.GraphQL.MaxComplexity,
+		MaxDepth:      cfg.GraphQL.MaxDepth,
+	})
+	if err != nil {
+		log.Fatalf("Failed to build GraphQL schema: %v", err)
+	}
+
+	// Setup routes
+	router := routes.SetupRoutes(cfg, routes.Dependencies{
+		TodoService:       todoService,
+		CalendarService:   calendarService,
+		WebhookService:    webhookService,
+		SyncService:       syncService,
+		StatsService:      statsService,
+		AttachmentService: attachmentService,
+		CommentService:    commentService,
+		DependencyService: dependencyService,
+		AccessService:     accessService,
+		WorkspaceService:  workspaceService,
+		APIKeyService:     apiKeyService,
+		AuthService:       authService,
+		HealthRegistry:    healthRegistry,
+		EventHub:          eventHub,
+		GraphQL:           graphQLServer,
+		TodoCache:         todoCache,
+	})
 
 	// Create server
 	server := &http.Server{
-		Addr:         ":" + port,
+		Addr:         ":" + cfg.Server.Port,
 		Handler:      router,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
 	}
+	// Shutdown waits for active requests, which open event streams never
+	// finish on their own, and does not track WebSocket connections at all;
+	// closing the hub ends both
+	server.RegisterOnShutdown(eventHub.Close)
 
 	// Start server in a goroutine
 	go func() {
-		log.Printf("Server starting on port %s", port)
+		log.Printf("Server starting on port %s", cfg.Server.Port)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Failed to start server: %v", err)
 		}
 	}()
 
+	// Serve gRPC on its own port when enabled
+	var grpcServer *grpc.Server
+	if cfg.GRPC.Enabled {
+		listener, err := net.Listen("tcp", ":"+cfg.GRPC.Port)
+		if err != nil {
+			log.Fatalf("Failed to listen for gRPC: %v", err)
+		}
+		// Calls authenticate like HTTP requests do, before their workspace
+		// is resolved
+		var authenticateSession func(context.Context, string) (*dto.UserResponse, error)
+		if authService != nil {
+			authenticateSession = authService.Authenticate
+		}
+		grpcServer = grpcapi.NewServer(todoService,
+			grpcapi.AuthInterceptor(apiKeyService.Authenticate, authenticateSession),
+			grpcapi.WorkspaceInterceptor(workspaceService.Resolve, cfg.Tenancy.DefaultWorkspace))
+		go func() {
+			log.Printf("gRPC server starting on port %s", cfg.GRPC.Port)
+			if err := grpcServer.Serve(listener); err != nil {
+				log.Fatalf("Failed to start gRPC server: %v", err)
+			}
+		}()
+	}
+
 	// Wait for interrupt signal to gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -72,8 +298,13 @@ func main() {
 
 	log.Println("Shutting down server...")
 
+	// Fail readiness first and keep serving until load balancers have seen
+	// it and stopped sending new requests
+	healthRegistry.SetShuttingDown()
+	time.Sleep(cfg.Server.ShutdownDelay)
+
 	// Create a deadline for server shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
 
 	// Attempt graceful shutdown
@@ -81,5 +312,31 @@ func main() {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
 
+	if grpcServer != nil {
+		stopGRPC(ctx, grpcServer)
+	}
+
+	// Let in-flight webhook deliveries finish; pending ones resume on restart
+	if err := dispatcher.Stop(ctx); err != nil {
+		log.Printf("Webhook dispatcher did not stop cleanly: %v", err)
+	}
+
 	log.Println("Server exited gracefully")
 }
+
+// stopGRPC waits for in-flight RPCs to finish, cancelling them once ctx
+// expires.
+func stopGRPC(ctx context.Context, server *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		log.Println("gRPC server forced to stop")
+		server.Stop()
+	}
+}
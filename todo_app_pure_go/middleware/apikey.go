@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+
+	"todo-app/auth"
+	"todo-app/dto"
+	"todo-app/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyMiddleware authenticates requests that send an API key as
+// "Authorization: Bearer <key>". The request then acts for the key's owner,
+// in the key's workspace and with the key's scopes only. Requests without
+// an Authorization header stay anonymous, and other bearer tokens are left
+// to SessionMiddleware. authenticate returns the key a secret belongs to.
+func APIKeyMiddleware(authenticate func(ctx context.Context, key string) (*dto.APIKeyResponse, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		secret, ok := bearerToken(c)
+		if !ok {
+			return
+		}
+		if !strings.HasPrefix(secret, auth.APIKeyPrefix) {
+			c.Next()
+			return
+		}
+
+		key, err := authenticate(c.Request.Context(), secret)
+		if err != nil {
+			switch err.Error() {
+			case "api key not found":
+				unauthorized(c, "Invalid API key")
+			case "api key revoked":
+				unauthorized(c, "API key has been revoked")
+			case "api key expired":
+				unauthorized(c, "API key has expired")
+			default:
+				utils.InternalServerErrorResponse(c, "Failed to check API key: "+err.Error())
+				c.Abort()
+			}
+			return
+		}
+
+		c.Set(IdentityKey, key.Owner)
+		c.Set(WorkspaceClaimKey, key.Workspace)
+		c.Request = c.Request.WithContext(auth.WithScopes(c.Request.Context(), key.Scopes))
+		c.Next()
+	}
+}
+
+// RequireScope refuses requests whose credentials lack scope. Anonymous
+// requests are not limited by scopes.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := auth.Require(c.Request.Context(), scope); err != nil {
+			utils.ForbiddenResponse(c, err.Error())
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireIdentity refuses anonymous requests, for routes that act on what
+// the caller owns.
+func RequireIdentity() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString(IdentityKey) == "" {
+			unauthorized(c, "Authentication required")
+			return
+		}
+		c.Next()
+	}
+}
+
+// bearerToken returns the token of an "Authorization: Bearer <token>"
+// header, or "" when the request has no Authorization header. ok is false
+// when the request has been refused for a malformed header.
+func bearerToken(c *gin.Context) (token string, ok bool) {
+	header := c.GetHeader("Authorization")
+	if header == "" {
+		return "", true
+	}
+	scheme, token, _ := strings.Cut(header, " ")
+	token = strings.TrimSpace(token)
+	if !strings.EqualFold(scheme, "Bearer") || token == "" {
+		unauthorized(c, "Authorization header must be Bearer <token>")
+		return "", false
+	}
+	return token, true
+}
+
+func unauthorized(c *gin.Context, message string) {
+	c.Header("WWW-Authenticate", `Bearer realm="todo-app"`)
+	utils.UnauthorizedResponse(c, message)
+	c.Abort()
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"todo-app/auth"
+	"todo-app/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+func authenticateKey(_ context.Context, key string) (*dto.APIKeyResponse, error) {
+	switch key {
+	case "tk_reader":
+		return &dto.APIKeyResponse{Owner: "ci", Workspace: "globex", Scopes: []string{auth.ScopeTodosRead}}, nil
+	case "tk_revoked":
+		return nil, errors.New("api key revoked")
+	case "tk_broken":
+		return nil, errors.New("connection refused")
+	}
+	return nil, errors.New("api key not found")
+}
+
+// newKeyRouter answers with the caller's identity and workspace claim.
+func newKeyRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(APIKeyMiddleware(authenticateKey))
+	whoami := func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(IdentityKey)+"@"+c.GetString(WorkspaceClaimKey))
+	}
+	router.GET("/todos", RequireScope(auth.ScopeTodosRead), whoami)
+	router.POST("/todos", RequireScope(auth.ScopeTodosWrite), whoami)
+	return router
+}
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	tests := []struct {
+		name          string
+		method        string
+		authorization string
+		wantCode      int
+		wantBody      string
+	}{
+		{"anonymous read", http.MethodGet, "", http.StatusOK, "@"},
+		{"anonymous write", http.MethodPost, "", http.StatusOK, "@"},
+		{"key within scope", http.MethodGet, "Bearer tk_reader", http.StatusOK, "ci@globex"},
+		{"lowercase scheme", http.MethodGet, "bearer tk_reader", http.StatusOK, "ci@globex"},
+		{"key outside scope", http.MethodPost, "Bearer tk_reader", http.StatusForbidden, ""},
+		{"basic auth", http.MethodGet, "Basic Y2k6c2VjcmV0", http.StatusUnauthorized, ""},
+		{"empty bearer", http.MethodGet, "Bearer ", http.StatusUnauthorized, ""},
+		{"unknown key", http.MethodGet, "Bearer tk_guess", http.StatusUnauthorized, ""},
+		{"revoked key", http.MethodGet, "Bearer tk_revoked", http.StatusUnauthorized, ""},
+		{"lookup failure", http.MethodGet, "Bearer tk_broken", http.StatusInternalServerError, ""},
+		// Other bearer tokens are for SessionMiddleware
+		{"not an API key", http.MethodGet, "Bearer eyJhbGciOiJIUzI1NiJ9", http.StatusOK, "@"},
+	}
+	router := newKeyRouter()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/todos", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantCode, w.Body)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %s, want %s", w.Body, tt.wantBody)
+			}
+			if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without WWW-Authenticate")
+			}
+		})
+	}
+}
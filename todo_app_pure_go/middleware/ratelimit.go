@@ -0,0 +1,216 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"todo-app/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IdentityKey is the gin context key under which authentication middleware
+// stores the caller's identity. The rate limiter prefers it over the client IP.
+const IdentityKey = "identity"
+
+// Rate describes a token bucket that holds up to Burst tokens and refills
+// Limit tokens every Period.
+type Rate struct {
+	Limit  int
+	Period time.Duration
+	Burst  int
+}
+
+// PerSecond returns a rate of n requests per second with a burst of n.
+func PerSecond(n int) Rate {
+	return Rate{Limit: n, Period: time.Second, Burst: n}
+}
+
+// PerMinute returns a rate of n requests per minute with a burst of n.
+func PerMinute(n int) Rate {
+	return Rate{Limit: n, Period: time.Minute, Burst: n}
+}
+
+func (r Rate) capacity() float64 {
+	if r.Burst > 0 {
+		return float64(r.Burst)
+	}
+	return float64(r.Limit)
+}
+
+// perSecond returns the refill rate in tokens per second.
+func (r Rate) perSecond() float64 {
+	return float64(r.Limit) / r.Period.Seconds()
+}
+
+// RateLimitResult is the outcome of taking a token from a bucket.
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	ResetAfter time.Duration // time until the bucket is full again
+	RetryAfter time.Duration // time until the next token is available when not allowed
+}
+
+// RateLimitStore keeps token buckets. Implementations must be safe for
+// concurrent use; the in-memory store can be swapped for a shared one
+// (e.g. Redis) when running several instances.
+type RateLimitStore interface {
+	Take(key string, rate Rate) (RateLimitResult, error)
+}
+
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+	fullAt   time.Time
+}
+
+// MemoryRateLimitStore is a process-local RateLimitStore.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryRateLimitStore) Take(key string, rate Rate) (RateLimitResult, error) {
+	if rate.Limit <= 0 || rate.Period <= 0 {
+		return RateLimitResult{}, fmt.Errorf("invalid rate %+v", rate)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	capacity := rate.capacity()
+	refill := rate.perSecond()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, lastSeen: now}
+		s.buckets[key] = b
+	} else {
+		elapsed := now.Sub(b.lastSeen).Seconds()
+		b.tokens = math.Min(capacity, b.tokens+elapsed*refill)
+		b.lastSeen = now
+	}
+
+	result := RateLimitResult{Limit: int(capacity)}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - b.tokens) / refill)
+	}
+	result.Remaining = int(math.Floor(b.tokens))
+	result.ResetAfter = secondsToDuration((capacity - b.tokens) / refill)
+	b.fullAt = now.Add(result.ResetAfter)
+
+	return result, nil
+}
+
+// sweep drops buckets that have been idle long enough to be full again,
+// so the map does not grow with every client ever seen.
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		if now.After(b.fullAt) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
+
+// KeyFunc extracts the client key a request is rate limited by.
+type KeyFunc func(c *gin.Context) string
+
+// ClientKey keys requests by authenticated identity when present and by
+// client IP otherwise.
+func ClientKey(c *gin.Context) string {
+	if identity := c.GetString(IdentityKey); identity != "" {
+		return "id:" + identity
+	}
+	return "ip:" + c.ClientIP()
+}
+
+type RateLimiter struct {
+	store   RateLimitStore
+	keyFunc KeyFunc
+}
+
+func NewRateLimiter(store RateLimitStore, keyFunc KeyFunc) *RateLimiter {
+	if keyFunc == nil {
+		keyFunc = ClientKey
+	}
+	return &RateLimiter{
+		store:   store,
+		keyFunc: keyFunc,
+	}
+}
+
+// RouteLimit is the rate of a named bucket.
+type RouteLimit struct {
+	Name string
+	Rate Rate
+}
+
+// Take takes a token from the client's bucket for the named route, for
+// requests that are not limited per HTTP request, such as WebSocket messages.
+// It shares buckets with Limit.
+func (rl *RateLimiter) Take(c *gin.Context, name string, rate Rate) (RateLimitResult, error) {
+	return rl.store.Take(name+"|"+rl.keyFunc(c), rate)
+}
+
+// Limit returns a middleware enforcing rate for the named route. Each name
+// gets its own bucket per client, so limits on different routes are independent.
+func (rl *RateLimiter) Limit(name string, rate Rate) gin.HandlerFunc {
+	policy := fmt.Sprintf("%d;w=%d", int(rate.capacity()), int(rate.Period.Seconds()))
+
+	return func(c *gin.Context) {
+		result, err := rl.Take(c, name, rate)
+		if err != nil {
+			// Fail open: a broken store should not take the API down.
+			log.Printf("rate limiter: %v", err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Policy", policy)
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+
+		if !result.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			utils.TooManyRequestsResponse(c, "Rate limit exceeded, retry later")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
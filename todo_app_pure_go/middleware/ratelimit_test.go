@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"todo-app/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (f *fakeClock) now() time.Time { return f.t }
+
+func newLimitedRouter(store RateLimitStore, rate Rate) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	limiter := NewRateLimiter(store, ClientKey)
+	router.POST("/todos", limiter.Limit("todos:create", rate), func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+	router.GET("/todos", limiter.Limit("todos:list", rate), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func doRequest(router *gin.Engine, method, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/todos", nil)
+	req.RemoteAddr = remoteAddr
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimiterRejectsAfterBurst(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1700000000, 0)}
+	store := NewMemoryRateLimitStore()
+	store.now = clock.now
+	router := newLimitedRouter(store, PerMinute(2))
+
+	for i := 0; i < 2; i++ {
+		if w := doRequest(router, http.MethodPost, "10.0.0.1:1234"); w.Code != http.StatusCreated {
+			t.Fatalf("request %d: expected 201, got %d", i, w.Code)
+		}
+	}
+
+	w := doRequest(router, http.MethodPost, "10.0.0.1:1234")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("expected Retry-After 30, got %q", got)
+	}
+	if got := w.Header().Get("RateLimit-Remaining"); got != "0" {
+		t.Errorf("expected RateLimit-Remaining 0, got %q", got)
+	}
+
+	var body dto.APIResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid response body: %v", err)
+	}
+	if body.Success || body.Error != "Too Many Requests" {
+		t.Errorf("unexpected envelope: %+v", body)
+	}
+
+	// Other clients and other routes have their own buckets
+	if w := doRequest(router, http.MethodPost, "10.0.0.2:1234"); w.Code != http.StatusCreated {
+		t.Errorf("other client: expected 201, got %d", w.Code)
+	}
+	if w := doRequest(router, http.MethodGet, "10.0.0.1:1234"); w.Code != http.StatusOK {
+		t.Errorf("other route: expected 200, got %d", w.Code)
+	}
+
+	// Half the period refills one token
+	clock.t = clock.t.Add(30 * time.Second)
+	if w := doRequest(router, http.MethodPost, "10.0.0.1:1234"); w.Code != http.StatusCreated {
+		t.Errorf("after refill: expected 201, got %d", w.Code)
+	}
+}
+
+func TestRateLimiterHeaders(t *testing.T) {
+	router := newLimitedRouter(NewMemoryRateLimitStore(), PerMinute(10))
+
+	w := doRequest(router, http.MethodPost, "10.0.0.1:1234")
+	if got := w.Header().Get("RateLimit-Limit"); got != "10" {
+		t.Errorf("expected RateLimit-Limit 10, got %q", got)
+	}
+	if got := w.Header().Get("RateLimit-Remaining"); got != "9" {
+		t.Errorf("expected RateLimit-Remaining 9, got %q", got)
+	}
+	if got := w.Header().Get("RateLimit-Reset"); got != "6" {
+		t.Errorf("expected RateLimit-Reset 6, got %q", got)
+	}
+	if got := w.Header().Get("RateLimit-Policy"); got != "10;w=60" {
+		t.Errorf("expected RateLimit-Policy 10;w=60, got %q", got)
+	}
+}
+
+func TestClientKeyPrefersIdentity(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Request.RemoteAddr = "10.0.0.1:1234"
+
+	if got := ClientKey(c); got != "ip:10.0.0.1" {
+		t.Errorf("expected ip key, got %q", got)
+	}
+
+	c.Set(IdentityKey, "alice")
+	if got := ClientKey(c); got != "id:alice" {
+		t.Errorf("expected identity key, got %q", got)
+	}
+}
+
+func TestClientKeyTrustsOnlyConfiguredProxies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	keyFor := func(trustedProxies []string, remoteAddr string) string {
+		router := gin.New()
+		if err := router.SetTrustedProxies(trustedProxies); err != nil {
+			t.Fatal(err)
+		}
+		router.GET("/", func(c *gin.Context) { c.String(http.StatusOK, ClientKey(c)) })
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	// A client cannot choose its own key by forging the header
+	if got := keyFor(nil, "198.51.100.1:1234"); got != "ip:198.51.100.1" {
+		t.Errorf("untrusted forwarded address used: %q", got)
+	}
+	if got := keyFor([]string{"10.0.0.0/8"}, "198.51.100.1:1234"); got != "ip:198.51.100.1" {
+		t.Errorf("forwarded address from outside the proxies used: %q", got)
+	}
+	if got := keyFor([]string{"10.0.0.0/8"}, "10.1.2.3:1234"); got != "ip:203.0.113.7" {
+		t.Errorf("trusted proxy's forwarded address ignored: %q", got)
+	}
+}
+
+func TestTakeSharesRouteBuckets(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	router := newLimitedRouter(store, PerMinute(2))
+	limiter := NewRateLimiter(store, ClientKey)
+
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Request.RemoteAddr = "10.0.0.1:1234"
+	if result, err := limiter.Take(c, "todos:create", PerMinute(2)); err != nil || !result.Allowed {
+		t.Fatalf("take = %+v, %v", result, err)
+	}
+
+	if w := doRequest(router, http.MethodPost, "10.0.0.1:1234"); w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", w.Code)
+	}
+	if w := doRequest(router, http.MethodPost, "10.0.0.1:1234"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("the route did not count the taken token: %d", w.Code)
+	}
+}
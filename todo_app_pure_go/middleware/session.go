@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+
+	"todo-app/auth"
+	"todo-app/dto"
+	"todo-app/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SessionMiddleware authenticates requests that send a session token from
+// single sign-on as "Authorization: Bearer <token>". The request then acts
+// for the signed-in user in the workspace they signed in to, without scope
+// limits. It runs after APIKeyMiddleware and refuses every bearer token
+// that is neither an API key nor a valid session. authenticate returns the
+// user a token belongs to.
+func SessionMiddleware(authenticate func(ctx context.Context, token string) (*dto.UserResponse, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := bearerToken(c)
+		if !ok {
+			return
+		}
+		if token == "" || strings.HasPrefix(token, auth.APIKeyPrefix) {
+			c.Next()
+			return
+		}
+
+		user, err := authenticate(c.Request.Context(), token)
+		if err != nil {
+			switch err.Error() {
+			case "invalid session":
+				unauthorized(c, "Invalid token")
+			case "session expired":
+				unauthorized(c, "Session has expired, sign in again")
+			default:
+				utils.InternalServerErrorResponse(c, "Failed to check session: "+err.Error())
+				c.Abort()
+			}
+			return
+		}
+
+		c.Set(IdentityKey, user.Principal)
+		c.Set(WorkspaceClaimKey, user.Workspace)
+		c.Next()
+	}
+}
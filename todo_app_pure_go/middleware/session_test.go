@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"todo-app/auth"
+	"todo-app/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+func authenticateSession(_ context.Context, token string) (*dto.UserResponse, error) {
+	switch token {
+	case "session-alice":
+		return &dto.UserResponse{Principal: "alice@example.com", Workspace: "acme"}, nil
+	case "session-old":
+		return nil, errors.New("session expired")
+	case "session-broken":
+		return nil, errors.New("connection refused")
+	}
+	return nil, errors.New("invalid session")
+}
+
+func TestSessionMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(APIKeyMiddleware(authenticateKey), SessionMiddleware(authenticateSession))
+	router.POST("/todos", RequireScope(auth.ScopeTodosWrite), func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(IdentityKey)+"@"+c.GetString(WorkspaceClaimKey))
+	})
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantCode      int
+		wantBody      string
+	}{
+		{"anonymous", "", http.StatusOK, "@"},
+		// Sessions are not limited by scopes
+		{"session", "Bearer session-alice", http.StatusOK, "alice@example.com@acme"},
+		{"API key", "Bearer tk_reader", http.StatusForbidden, ""},
+		{"unknown token", "Bearer guess", http.StatusUnauthorized, ""},
+		{"expired session", "Bearer session-old", http.StatusUnauthorized, ""},
+		{"lookup failure", "Bearer session-broken", http.StatusInternalServerError, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/todos", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantCode, w.Body)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %s, want %s", w.Body, tt.wantBody)
+			}
+		})
+	}
+}
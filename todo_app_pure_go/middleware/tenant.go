@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"todo-app/config"
+	"todo-app/tenant"
+	"todo-app/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WorkspaceClaimKey is the gin context key under which authentication
+// middleware stores the workspace slug claimed by the caller's token. A
+// claim binds the caller to that workspace.
+const WorkspaceClaimKey = "workspace"
+
+// TenantMiddleware resolves the workspace a request acts in and puts it in
+// the request context for the repositories (see package tenant). Requests
+// act in the workspace claimed by their token, and anonymous requests in
+// the default workspace. The configured header or a subdomain of the base
+// domain may name the workspace too, but one that is not the request's own
+// is refused, so neither a token nor an anonymous caller can reach into
+// another workspace. lookup returns the ID of the workspace with a slug.
+func TenantMiddleware(cfg config.TenancyConfig, lookup func(ctx context.Context, slug string) (uint, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requested := strings.ToLower(c.GetHeader(cfg.Header))
+		if requested == "" {
+			requested = subdomain(c.Request.Host, cfg.BaseDomain)
+		}
+
+		claim := c.GetString(WorkspaceClaimKey)
+		slug := claim
+		if slug == "" {
+			slug = cfg.DefaultWorkspace
+		}
+		if requested != "" && requested != slug {
+			if claim != "" {
+				utils.ForbiddenResponse(c, "Token is not valid for workspace "+requested)
+				c.Abort()
+			} else {
+				unauthorized(c, "Sign in to use workspace "+requested)
+			}
+			return
+		}
+		if slug == "" {
+			unauthorized(c, "Authentication required: there is no default workspace")
+			return
+		}
+
+		id, err := lookup(c.Request.Context(), slug)
+		if err != nil {
+			if err.Error() == "workspace not found" {
+				utils.NotFoundResponse(c, "Workspace not found")
+			} else {
+				utils.InternalServerErrorResponse(c, "Failed to resolve workspace: "+err.Error())
+			}
+			c.Abort()
+			return
+		}
+
+		c.Request = c.Request.WithContext(tenant.WithWorkspace(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// subdomain returns the label in front of baseDomain in host, or "" when
+// host is not a direct subdomain of it.
+func subdomain(host, baseDomain string) string {
+	if baseDomain == "" {
+		return ""
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	label, ok := strings.CutSuffix(strings.ToLower(host), "."+strings.ToLower(baseDomain))
+	if !ok || strings.Contains(label, ".") {
+		return ""
+	}
+	return label
+}
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"todo-app/config"
+	"todo-app/tenant"
+
+	"github.com/gin-gonic/gin"
+)
+
+func lookupWorkspace(_ context.Context, slug string) (uint, error) {
+	switch slug {
+	case "acme":
+		return 1, nil
+	case "globex":
+		return 2, nil
+	case "broken":
+		return 0, errors.New("connection refused")
+	}
+	return 0, errors.New("workspace not found")
+}
+
+// newTenantRouter answers with the workspace ID the request acts in. A
+// claim query parameter stands in for authentication middleware.
+func newTenantRouter(cfg config.TenancyConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if claim := c.Query("claim"); claim != "" {
+			c.Set(WorkspaceClaimKey, claim)
+		}
+	})
+	router.GET("/todos", TenantMiddleware(cfg, lookupWorkspace), func(c *gin.Context) {
+		id, _ := tenant.FromContext(c.Request.Context())
+		c.String(http.StatusOK, strconv.FormatUint(uint64(id), 10))
+	})
+	return router
+}
+
+func TestTenantMiddlewareResolvesWorkspace(t *testing.T) {
+	cfg := config.TenancyConfig{DefaultWorkspace: "acme", Header: "X-Workspace", BaseDomain: "todo.example.com"}
+
+	tests := []struct {
+		name     string
+		cfg      config.TenancyConfig
+		host     string
+		header   string
+		claim    string
+		wantCode int
+		wantBody string
+	}{
+		{"default", cfg, "api.example.com", "", "", http.StatusOK, "1"},
+		{"header naming the default", cfg, "api.example.com", "Acme", "", http.StatusOK, "1"},
+		{"subdomain of the default", cfg, "acme.todo.example.com:8080", "", "", http.StatusOK, "1"},
+		{"nested subdomain is ignored", cfg, "a.globex.todo.example.com", "", "", http.StatusOK, "1"},
+		// Anonymous requests cannot reach into other workspaces
+		{"anonymous header", cfg, "api.example.com", "Globex", "", http.StatusUnauthorized, ""},
+		{"anonymous subdomain", cfg, "globex.todo.example.com:8080", "", "", http.StatusUnauthorized, ""},
+		{"anonymous without default", config.TenancyConfig{Header: "X-Workspace"}, "api.example.com", "", "", http.StatusUnauthorized, ""},
+		{"claim", cfg, "api.example.com", "", "globex", http.StatusOK, "2"},
+		{"claim without default", config.TenancyConfig{Header: "X-Workspace"}, "api.example.com", "", "globex", http.StatusOK, "2"},
+		{"claim matching header", cfg, "api.example.com", "globex", "globex", http.StatusOK, "2"},
+		{"claim matching subdomain", cfg, "globex.todo.example.com", "", "globex", http.StatusOK, "2"},
+		{"header over subdomain", cfg, "acme.todo.example.com", "globex", "globex", http.StatusOK, "2"},
+		{"claim contradicting header", cfg, "api.example.com", "acme", "globex", http.StatusForbidden, ""},
+		{"claim contradicting subdomain", cfg, "acme.todo.example.com", "", "globex", http.StatusForbidden, ""},
+		{"unknown workspace", cfg, "api.example.com", "", "initech", http.StatusNotFound, ""},
+		{"lookup failure", cfg, "api.example.com", "", "broken", http.StatusInternalServerError, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := "/todos"
+			if tt.claim != "" {
+				path += "?claim=" + tt.claim
+			}
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.Host = tt.host
+			if tt.header != "" {
+				req.Header.Set("X-Workspace", tt.header)
+			}
+			w := httptest.NewRecorder()
+			newTenantRouter(tt.cfg).ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantCode, w.Body)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("workspace = %s, want %s", w.Body, tt.wantBody)
+			}
+			if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without WWW-Authenticate")
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package models
+
+import "time"
+
+// Role is the level of access a principal has to a todo. Each role includes
+// the rights of the roles below it.
+type Role string
+
+const (
+	RoleViewer    Role = "viewer"
+	RoleCommenter Role = "commenter"
+	RoleEditor    Role = "editor"
+	RoleOwner     Role = "owner"
+)
+
+// Roles lists every role from the least to the most privileged.
+var Roles = []Role{RoleViewer, RoleCommenter, RoleEditor, RoleOwner}
+
+func (r Role) rank() int {
+	for i, role := range Roles {
+		if r == role {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func (r Role) Valid() bool {
+	return r.rank() > 0
+}
+
+// Allows reports whether r includes the rights of required.
+func (r Role) Allows(required Role) bool {
+	return r.Valid() && r.rank() >= required.rank()
+}
+
+// Max returns the more privileged of r and other.
+func (r Role) Max(other Role) Role {
+	if other.rank() > r.rank() {
+		return other
+	}
+	return r
+}
+
+// TodoGrant gives a principal a role on a single todo.
+type TodoGrant struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	WorkspaceID uint      `json:"-" gorm:"not null;default:0;index"`
+	TodoID      uint      `json:"todo_id" gorm:"not null;uniqueIndex:idx_todo_grants_todo_principal"`
+	Principal   string    `json:"principal" gorm:"not null;size:255;uniqueIndex:idx_todo_grants_todo_principal;index"`
+	Role        Role      `json:"role" gorm:"type:varchar(16);not null"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (g *TodoGrant) TableName() string {
+	return "todo_grants"
+}
+
+// ListGrant gives a principal a role on every todo of an owner, including
+// todos created later.
+type ListGrant struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	WorkspaceID uint      `json:"-" gorm:"not null;default:0;uniqueIndex:idx_list_grants_workspace_owner_principal"`
+	Owner       string    `json:"owner" gorm:"not null;size:255;uniqueIndex:idx_list_grants_workspace_owner_principal"`
+	Principal   string    `json:"principal" gorm:"not null;size:255;uniqueIndex:idx_list_grants_workspace_owner_principal;index"`
+	Role        Role      `json:"role" gorm:"type:varchar(16);not null"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (g *ListGrant) TableName() string {
+	return "list_grants"
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// APIKey is a credential for automation that acts on behalf of its owner
+// with a limited set of scopes. Only a hash of the key is stored; the key
+// itself is shown once when it is created, and Prefix identifies it later.
+type APIKey struct {
+	ID          uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	WorkspaceID uint       `json:"-" gorm:"not null;default:0;index"`
+	Owner       string     `json:"owner" gorm:"size:255;index"`
+	Name        string     `json:"name" gorm:"not null;size:100"`
+	Prefix      string     `json:"prefix" gorm:"not null;size:16"`
+	KeyHash     string     `json:"-" gorm:"not null;size:64;uniqueIndex"`
+	Scopes      string     `json:"scopes" gorm:"not null;size:255"` // comma-separated scopes
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (k *APIKey) TableName() string {
+	return "api_keys"
+}
+
+// ScopeList returns the scopes of the key.
+func (k *APIKey) ScopeList() []string {
+	if k.Scopes == "" {
+		return nil
+	}
+	return strings.Split(k.Scopes, ",")
+}
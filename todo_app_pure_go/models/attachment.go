@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Attachment is a file attached to a todo. The content lives in blob storage
+// under its SHA-256 digest, so identical uploads share one blob.
+type Attachment struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	WorkspaceID uint      `json:"-" gorm:"not null;default:0;index"`
+	TodoID      uint      `json:"todo_id" gorm:"not null;index"`
+	Filename    string    `json:"filename" gorm:"not null;size:255"`
+	ContentType string    `json:"content_type" gorm:"not null;size:100"`
+	Size        int64     `json:"size" gorm:"not null"`
+	SHA256      string    `json:"sha256" gorm:"not null;size:64;index"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (a *Attachment) TableName() string {
+	return "attachments"
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// CalendarFeed is a secret ICS subscription URL. Only a hash of the token is
+// stored; the token itself is shown once when the feed is created.
+type CalendarFeed struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	WorkspaceID uint      `json:"-" gorm:"not null;default:0;index"`
+	Owner       string    `json:"owner" gorm:"size:255;index"`
+	Name        string    `json:"name" gorm:"not null;size:100"`
+	TokenHash   string    `json:"-" gorm:"not null;size:64;uniqueIndex"`
+	Completed   *bool     `json:"completed"`
+	Priority    *Priority `json:"priority" gorm:"type:varchar(10)"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (f *CalendarFeed) TableName() string {
+	return "calendar_feeds"
+}
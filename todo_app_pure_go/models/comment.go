@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Comment is a markdown note on a todo. Replies point to their parent
+// comment on the same todo.
+type Comment struct {
+	ID          uint   `json:"id" gorm:"primaryKey;autoIncrement"`
+	WorkspaceID uint   `json:"-" gorm:"not null;default:0;index"`
+	TodoID      uint   `json:"todo_id" gorm:"not null;index"`
+	ParentID    *uint  `json:"parent_id" gorm:"index"`
+	Author      string `json:"author" gorm:"size:255"`
+	Body        string `json:"body" gorm:"type:text;not null"`
+	// Deleted marks a comment removed while it still had replies; its body
+	// is cleared but it stays in place to keep the thread together.
+	Deleted   bool       `json:"deleted" gorm:"default:false"`
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+	EditedAt  *time.Time `json:"edited_at"`
+}
+
+func (c *Comment) TableName() string {
+	return "comments"
+}
+
+// CommentRevision is a body a comment had before an edit.
+type CommentRevision struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	WorkspaceID uint      `json:"-" gorm:"not null;default:0;index"`
+	CommentID   uint      `json:"comment_id" gorm:"not null;index"`
+	Body        string    `json:"body" gorm:"type:text;not null"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (r *CommentRevision) TableName() string {
+	return "comment_revisions"
+}
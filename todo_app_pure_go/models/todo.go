@@ -12,6 +12,16 @@ const (
 	HIGH   Priority = "HIGH"
 )
 
+// TodoSort is the order todos are listed in.
+type TodoSort string
+
+const (
+	// SortNewest lists the most recently created todos first
+	SortNewest TodoSort = "created_at"
+	// SortPosition lists todos in their manual order
+	SortPosition TodoSort = "position"
+)
+
 type Todo struct {
 	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
 	Title       string    `json:"title" gorm:"not null;size:100"`
@@ -20,8 +30,82 @@ type Todo struct {
 	Priority    Priority  `json:"priority" gorm:"type:varchar(10);default:'MEDIUM'"`
 	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+	// CompletedAt is set while the todo is completed
+	CompletedAt *time.Time `json:"completed_at" gorm:"index"`
+	DueAt       *time.Time `json:"due_at" gorm:"index"`
+	// AllDay is set when only the date of DueAt counts
+	AllDay bool `json:"all_day" gorm:"not null;default:false"`
+	// Tags label the todo and Project groups it, as in "#home +garden"
+	Tags    []string `json:"tags" gorm:"serializer:json;type:text"`
+	Project string   `json:"project" gorm:"size:100;not null;default:'';index"`
+	// Recurrence is an iCalendar RRULE such as FREQ=WEEKLY;BYDAY=MO
+	Recurrence string `json:"recurrence" gorm:"size:255;not null;default:''"`
+	// Status is the todo's step in the workflow; Completed follows from it.
+	// Todos from before workflows existed get one at startup.
+	Status string `json:"status" gorm:"size:32;not null;default:'';index"`
+	// StatusChangedAt is when the todo entered its status
+	StatusChangedAt *time.Time `json:"status_changed_at"`
+	// Position is the todo's rank in the workspace's manual order; see
+	// package rank. Ties are ordered newest first.
+	Position string `json:"position" gorm:"size:32;not null;default:'';index"`
+	// Owner is the principal that created the todo. Todos without an owner,
+	// created anonymously, are open to everyone in their workspace.
+	Owner string `json:"owner" gorm:"size:255;not null;default:'';index"`
+	// WorkspaceID is the workspace the todo belongs to. Like every
+	// workspace-owned row it is set and checked by tenant.Plugin.
+	WorkspaceID uint `json:"-" gorm:"not null;default:0;index;uniqueIndex:idx_todos_workspace_client"`
+	// ClientID is the ID an offline client gave the todo before it was
+	// synced; it makes replayed creates idempotent.
+	ClientID *string   `json:"-" gorm:"size:64;uniqueIndex:idx_todos_workspace_client"`
+	Clock    TodoClock `json:"-" gorm:"embedded;embeddedPrefix:clock_"`
+	// CommentCount counts the comments that are not deleted. It is read
+	// only here and maintained by the comment repository, so writing a
+	// todo can never overwrite a concurrent change to it.
+	CommentCount int64 `json:"comment_count" gorm:"->;not null;default:0"`
+}
+
+// TodoClock records when each field of a todo was last written. Sync uses it
+// to resolve conflicting offline edits field by field. Todos written before
+// clocks existed have nil times.
+type TodoClock struct {
+	Title       *time.Time
+	Description *time.Time
+	Completed   *time.Time
+	Priority    *time.Time
+}
+
+// Fill sets every unset field time to at.
+func (c *TodoClock) Fill(at time.Time) {
+	for _, field := range []**time.Time{&c.Title, &c.Description, &c.Completed, &c.Priority} {
+		if *field == nil {
+			t := at
+			*field = &t
+		}
+	}
 }
 
 func (t *Todo) TableName() string {
 	return "todos"
 }
+
+// SetStatus moves the todo to status, stamping StatusChangedAt with at when
+// the status changes. done tells whether the status counts as completed.
+func (t *Todo) SetStatus(status string, done bool, at time.Time) {
+	if status != t.Status {
+		t.Status = status
+		t.StatusChangedAt = &at
+	}
+	t.SetCompleted(done, at)
+}
+
+// SetCompleted changes the completion status, stamping CompletedAt with at
+// when the todo becomes completed and clearing it when it is reopened.
+func (t *Todo) SetCompleted(completed bool, at time.Time) {
+	if completed && !t.Completed {
+		t.CompletedAt = &at
+	}
+	if !completed {
+		t.CompletedAt = nil
+	}
+	t.Completed = completed
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// TodoChange is an entry in the change log that sync tokens point into. The
+// log keeps only the latest entry per todo, so a deleted todo is represented
+// by a single entry with Deleted set (a tombstone).
+type TodoChange struct {
+	Seq         uint64    `json:"seq" gorm:"primaryKey;autoIncrement"`
+	WorkspaceID uint      `json:"-" gorm:"not null;default:0;index"`
+	TodoID      uint      `json:"todo_id" gorm:"not null;index"`
+	Deleted     bool      `json:"deleted" gorm:"not null;default:false"`
+	ChangedAt   time.Time `json:"changed_at" gorm:"not null;index"`
+	// Owner and Viewers record who could see a deleted todo, since its
+	// grants are deleted with it; only tombstones set them.
+	Owner   string   `json:"-" gorm:"size:255;not null;default:''"`
+	Viewers []string `json:"-" gorm:"serializer:json;type:text"`
+}
+
+func (c *TodoChange) TableName() string {
+	return "todo_changes"
+}
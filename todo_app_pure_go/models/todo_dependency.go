@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// TodoDependency records that a todo is blocked by another: TodoID cannot
+// start until BlockerID is completed. Dependencies never form a cycle.
+type TodoDependency struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	WorkspaceID uint      `json:"-" gorm:"not null;default:0;index"`
+	TodoID      uint      `json:"todo_id" gorm:"not null;uniqueIndex:idx_todo_dependencies_pair"`
+	BlockerID   uint      `json:"blocker_id" gorm:"not null;index;uniqueIndex:idx_todo_dependencies_pair"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (d *TodoDependency) TableName() string {
+	return "todo_dependencies"
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// TodoStatusChange records a todo moving into a status. A todo's changes
+// in order give the time it spent in each status.
+type TodoStatusChange struct {
+	ID          uint `json:"id" gorm:"primaryKey;autoIncrement"`
+	WorkspaceID uint `json:"-" gorm:"not null;default:0;index"`
+	TodoID      uint `json:"todo_id" gorm:"not null;index"`
+	// From is empty for the status a todo was created in
+	From      string    `json:"from" gorm:"column:from_status;size:32;not null;default:''"`
+	To        string    `json:"to" gorm:"column:to_status;size:32;not null"`
+	ChangedAt time.Time `json:"changed_at" gorm:"not null"`
+}
+
+func (c *TodoStatusChange) TableName() string {
+	return "todo_status_changes"
+}
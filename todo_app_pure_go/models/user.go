@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// User is a person who signed in through the OpenID Connect provider. The
+// account is created on the first login to a workspace; Issuer and Subject
+// identify it at the provider, Principal is the name it acts under here.
+type User struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	WorkspaceID uint      `json:"-" gorm:"not null;default:0;uniqueIndex:idx_users_identity;uniqueIndex:idx_users_principal"`
+	Issuer      string    `json:"-" gorm:"not null;size:255;uniqueIndex:idx_users_identity"`
+	Subject     string    `json:"-" gorm:"not null;size:255;uniqueIndex:idx_users_identity"`
+	Principal   string    `json:"principal" gorm:"not null;size:255;uniqueIndex:idx_users_principal"`
+	Email       string    `json:"email" gorm:"size:255"`
+	Name        string    `json:"name" gorm:"size:255"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+	LastLoginAt time.Time `json:"last_login_at"`
+}
+
+func (u *User) TableName() string {
+	return "users"
+}
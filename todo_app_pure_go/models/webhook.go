@@ -0,0 +1,74 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Webhook is a subscription that receives todo lifecycle events by HTTP POST.
+type Webhook struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	WorkspaceID uint      `json:"-" gorm:"not null;default:0;index"`
+	Owner       string    `json:"owner" gorm:"size:255;index"`
+	URL         string    `json:"url" gorm:"not null;size:2048"`
+	Secret      string    `json:"-" gorm:"not null;size:255"`
+	Events      string    `json:"events" gorm:"not null;size:255"` // comma-separated event types
+	Active      bool      `json:"active" gorm:"default:true"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (w *Webhook) TableName() string {
+	return "webhooks"
+}
+
+// EventList returns the subscribed event types.
+func (w *Webhook) EventList() []string {
+	if w.Events == "" {
+		return nil
+	}
+	return strings.Split(w.Events, ",")
+}
+
+// Subscribes reports whether the webhook wants events of the given type.
+func (w *Webhook) Subscribes(eventType string) bool {
+	for _, e := range w.EventList() {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "PENDING"
+	DeliverySucceeded DeliveryStatus = "SUCCEEDED"
+	DeliveryFailed    DeliveryStatus = "FAILED"
+)
+
+// WebhookDelivery records one event sent to one webhook, including retries.
+type WebhookDelivery struct {
+	ID            uint           `json:"id" gorm:"primaryKey;autoIncrement"`
+	WorkspaceID   uint           `json:"-" gorm:"not null;default:0;index"`
+	WebhookID     uint           `json:"webhook_id" gorm:"not null;index"`
+	EventID       string         `json:"event_id" gorm:"not null;size:64;index"`
+	EventType     string         `json:"event_type" gorm:"not null;size:50"`
+	Payload       string         `json:"-" gorm:"type:text;not null"`
+	Status        DeliveryStatus `json:"status" gorm:"type:varchar(10);not null;index"`
+	Attempts      int            `json:"attempts" gorm:"not null;default:0"`
+	ResponseCode  int            `json:"response_code"`
+	ResponseBody  string         `json:"response_body" gorm:"size:1024"`
+	Error         string         `json:"error" gorm:"size:1024"`
+	DurationMs    int64          `json:"duration_ms"`
+	RedeliveryOf  *uint          `json:"redelivery_of"`
+	NextAttemptAt *time.Time     `json:"next_attempt_at" gorm:"index"`
+	DeliveredAt   *time.Time     `json:"delivered_at"`
+	CreatedAt     time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt     time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (d *WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Workspace is a tenant: a team whose todos, grants, comments, feeds and
+// webhooks are invisible to every other workspace.
+type Workspace struct {
+	ID        uint              `json:"id" gorm:"primaryKey;autoIncrement"`
+	Slug      string            `json:"slug" gorm:"not null;size:63;uniqueIndex"`
+	Name      string            `json:"name" gorm:"not null;size:100"`
+	Settings  WorkspaceSettings `json:"settings" gorm:"serializer:json;type:text"`
+	CreatedAt time.Time         `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time         `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (w *Workspace) TableName() string {
+	return "workspaces"
+}
+
+// WorkspaceSettings are the preferences of one workspace. Zero values mean
+// the application defaults.
+type WorkspaceSettings struct {
+	// DefaultPriority is given to new todos created without a priority
+	DefaultPriority Priority `json:"default_priority,omitempty"`
+	// MaxTodos limits how many todos the workspace may hold; 0 is unlimited
+	MaxTodos int `json:"max_todos,omitempty"`
+}
@@ -0,0 +1,88 @@
+// Package netguard keeps requests the server makes on behalf of users, such
+// as webhook deliveries, out of the server's own network. Only public
+// addresses may be reached: loopback, private, link-local (which includes
+// the 169.254.169.254 cloud metadata endpoint), shared and reserved ranges
+// are refused.
+//
+// Checking a URL's host when it is saved is not enough, as its name may
+// resolve to another address later (DNS rebinding). Dialers that connect to
+// such URLs should use Control, which checks the address actually dialled.
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+)
+
+// ErrNotPublic is returned for addresses that are not public.
+var ErrNotPublic = errors.New("netguard: address is not public")
+
+// blocked are the ranges that are not public, besides those netip.Addr
+// recognises itself.
+var blocked = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "this" network
+	netip.MustParsePrefix("100.64.0.0/10"),   // carrier-grade NAT, also used for cloud metadata
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // documentation
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // documentation
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved, and broadcast
+	netip.MustParsePrefix("100::/64"),        // discard-only
+	netip.MustParsePrefix("2001:db8::/32"),   // documentation
+	netip.MustParsePrefix("fec0::/10"),       // deprecated site-local
+}
+
+// Public reports whether addr may be reached.
+func Public(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsUnspecified() || addr.IsLoopback() || addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range blocked {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckHost resolves host, a name or an IP address, and returns an error
+// wrapping ErrNotPublic unless all of its addresses are public.
+func CheckHost(ctx context.Context, host string) error {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return check(addr)
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if err := check(addr); err != nil {
+			return fmt.Errorf("%s resolves to %w", host, err)
+		}
+	}
+	return nil
+}
+
+// Control refuses connections to addresses that are not public. It is
+// meant for net.Dialer.Control.
+func Control(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("netguard: cannot check address %q: %w", address, err)
+	}
+	return check(addrPort.Addr())
+}
+
+func check(addr netip.Addr) error {
+	if !Public(addr) {
+		return fmt.Errorf("%w: %s", ErrNotPublic, addr.Unmap())
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package netguard
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+)
+
+func TestPublic(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"8.8.8.8", true},
+		{"2606:4700:4700::1111", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.100.100.200", false},
+		{"0.0.0.0", false},
+		{"255.255.255.255", false},
+		{"224.0.0.1", false},
+		{"::1", false},
+		{"::", false},
+		{"fd00:ec2::254", false},
+		{"fe80::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+	}
+	for _, tt := range tests {
+		if got := Public(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("Public(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestCheckHost(t *testing.T) {
+	if err := CheckHost(context.Background(), "93.184.216.34"); err != nil {
+		t.Errorf("public address: %v", err)
+	}
+	for _, host := range []string{"127.0.0.1", "::1", "169.254.169.254", "localhost"} {
+		if err := CheckHost(context.Background(), host); !errors.Is(err, ErrNotPublic) {
+			t.Errorf("CheckHost(%s) = %v, want ErrNotPublic", host, err)
+		}
+	}
+}
+
+func TestControlRefusesPrivateDials(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	dialer := &net.Dialer{Control: Control}
+	if _, err := dialer.Dial("tcp", listener.Addr().String()); !errors.Is(err, ErrNotPublic) {
+		t.Errorf("dial to loopback: %v", err)
+	}
+}
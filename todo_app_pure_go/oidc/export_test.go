@@ -0,0 +1,9 @@
+package oidc
+
+import "time"
+
+// SetClock replaces the clock p checks token lifetimes and key refreshes
+// against.
+func SetClock(p *Provider, now func() time.Time) {
+	p.now = now
+}
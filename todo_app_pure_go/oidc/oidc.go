@@ -0,0 +1,360 @@
+// Package oidc is a client for OpenID Connect providers: it discovers a
+// provider's endpoints, builds authorization code requests protected with
+// PKCE, exchanges codes for ID tokens and verifies those against the
+// provider's published keys.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"todo-app/jwt"
+)
+
+// leeway tolerates clock differences between the provider and us.
+const leeway = time.Minute
+
+// Metadata is the part of a provider's discovery document the client uses.
+type Metadata struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	SigningAlgs           []string `json:"id_token_signing_alg_values_supported"`
+	CodeChallengeMethods  []string `json:"code_challenge_methods_supported"`
+}
+
+// Config identifies the application to the provider.
+type Config struct {
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is where the provider sends the browser back to with the
+	// authorization code; it must be registered with the provider
+	RedirectURL string
+	// Scopes are requested in addition to openid
+	Scopes []string
+	// HTTPClient talks to the provider; http.DefaultClient when nil
+	HTTPClient *http.Client
+}
+
+// IDToken holds the verified claims of an ID token.
+type IDToken struct {
+	Issuer            string   `json:"iss"`
+	Subject           string   `json:"sub"`
+	Audience          audience `json:"aud"`
+	AuthorizedParty   string   `json:"azp,omitempty"`
+	Expiry            int64    `json:"exp"`
+	IssuedAt          int64    `json:"iat"`
+	Nonce             string   `json:"nonce,omitempty"`
+	Email             string   `json:"email,omitempty"`
+	EmailVerified     bool     `json:"email_verified,omitempty"`
+	Name              string   `json:"name,omitempty"`
+	PreferredUsername string   `json:"preferred_username,omitempty"`
+}
+
+// audience is a string or a list of strings in JSON.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+func (a audience) contains(clientID string) bool {
+	for _, aud := range a {
+		if aud == clientID {
+			return true
+		}
+	}
+	return false
+}
+
+// Provider is a discovered OpenID Connect provider. It is safe for
+// concurrent use.
+type Provider struct {
+	Metadata
+	config Config
+	now    func() time.Time
+
+	mu      sync.Mutex
+	keys    map[string]*rsa.PublicKey
+	fetched time.Time
+}
+
+// Discover fetches the discovery document of issuer and returns the
+// provider it describes.
+func Discover(ctx context.Context, issuer string, config Config) (*Provider, error) {
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+	p := &Provider{config: config, now: time.Now}
+
+	wellKnown := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	if err := p.getJSON(ctx, wellKnown, &p.Metadata); err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	// The document must be about the issuer it was fetched from
+	if p.Issuer != issuer {
+		return nil, fmt.Errorf("oidc discovery: issuer %q does not match %q", p.Issuer, issuer)
+	}
+	if p.AuthorizationEndpoint == "" || p.TokenEndpoint == "" || p.JWKSURI == "" {
+		return nil, errors.New("oidc discovery: authorization, token and jwks endpoints are required")
+	}
+	if len(p.SigningAlgs) > 0 && !contains(p.SigningAlgs, jwt.RS256) {
+		return nil, errors.New("oidc discovery: provider does not sign ID tokens with RS256")
+	}
+	// Providers that list their PKCE methods must support S256; those that
+	// do not list any are expected to ignore the parameters
+	if len(p.CodeChallengeMethods) > 0 && !contains(p.CodeChallengeMethods, "S256") {
+		return nil, errors.New("oidc discovery: provider does not support the S256 PKCE method")
+	}
+	return p, nil
+}
+
+// AuthCodeURL returns the provider URL that starts a login. state is
+// returned with the code, nonce is echoed in the ID token and verifier is
+// the PKCE secret the code will only be exchanged against.
+func (p *Provider) AuthCodeURL(state, nonce, verifier string) string {
+	scopes := append([]string{"openid"}, p.config.Scopes...)
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.config.ClientID},
+		"redirect_uri":          {p.config.RedirectURL},
+		"scope":                 {strings.Join(uniqueScopes(scopes), " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {S256Challenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(p.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return p.AuthorizationEndpoint + separator + query.Encode()
+}
+
+// Exchange trades an authorization code for the raw ID token issued with
+// it. The token still has to be verified.
+func (p *Provider) Exchange(ctx context.Context, code, verifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"code_verifier": {verifier},
+	}
+	if p.config.ClientSecret == "" {
+		form.Set("client_id", p.config.ClientID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	resp, err := p.config.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil && resp.StatusCode == http.StatusOK {
+		return "", fmt.Errorf("token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if body.Error == "" {
+			body.Error = resp.Status
+		}
+		return "", fmt.Errorf("token request: %s %s", body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return "", errors.New("token response has no id_token")
+	}
+	return body.IDToken, nil
+}
+
+// Verify checks the signature, issuer, audience, lifetime and nonce of an
+// ID token and returns its claims.
+func (p *Provider) Verify(ctx context.Context, rawIDToken, nonce string) (*IDToken, error) {
+	token, err := jwt.Parse(rawIDToken)
+	if err != nil {
+		return nil, err
+	}
+	// Only accept the algorithm we asked for, never "none" or HS256 with
+	// the public key as secret
+	if token.Header.Alg != jwt.RS256 {
+		return nil, fmt.Errorf("id token signed with unsupported algorithm %q", token.Header.Alg)
+	}
+	key, err := p.key(ctx, token.Header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := token.VerifyRS256(key); err != nil {
+		return nil, err
+	}
+
+	var claims IDToken
+	if err := token.Claims(&claims); err != nil {
+		return nil, err
+	}
+	now := p.now()
+	switch {
+	case claims.Issuer != p.Issuer:
+		return nil, fmt.Errorf("id token issued by %q, not %q", claims.Issuer, p.Issuer)
+	case !claims.Audience.contains(p.config.ClientID):
+		return nil, errors.New("id token is not meant for this client")
+	case len(claims.Audience) > 1 && claims.AuthorizedParty != p.config.ClientID:
+		return nil, errors.New("id token is not authorized for this client")
+	case claims.Subject == "":
+		return nil, errors.New("id token has no subject")
+	case !now.Before(time.Unix(claims.Expiry, 0).Add(leeway)):
+		return nil, errors.New("id token expired")
+	case time.Unix(claims.IssuedAt, 0).After(now.Add(leeway)):
+		return nil, errors.New("id token issued in the future")
+	case claims.Nonce != nonce:
+		return nil, errors.New("id token nonce does not match")
+	}
+	return &claims, nil
+}
+
+// key returns the signing key named kid, fetching the key set again when
+// the provider has rotated its keys.
+func (p *Provider) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.lookup(kid); ok {
+		return key, nil
+	}
+	// Unknown keys cannot make us hammer the provider
+	if !p.fetched.IsZero() && p.now().Sub(p.fetched) < time.Minute {
+		return nil, fmt.Errorf("id token signed with unknown key %q", kid)
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Use string `json:"use"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := p.getJSON(ctx, p.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	p.keys, p.fetched = keys, p.now()
+
+	if key, ok := p.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("id token signed with unknown key %q", kid)
+}
+
+// lookup finds kid in the cached keys; a token without kid matches the
+// only key of a single-key set.
+func (p *Provider) lookup(kid string) (*rsa.PublicKey, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+func (p *Provider) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.config.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// RandomString returns 256 random bits, URL-safe encoded; it serves as
+// state, nonce and PKCE verifier.
+func RandomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// S256Challenge returns the PKCE code challenge of verifier.
+func S256Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func uniqueScopes(scopes []string) []string {
+	var unique []string
+	for _, scope := range scopes {
+		if !contains(unique, scope) {
+			unique = append(unique, scope)
+		}
+	}
+	return unique
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+package oidc_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"todo-app/jwt"
+	"todo-app/oidc"
+	"todo-app/oidc/oidctest"
+)
+
+func discover(t *testing.T, fake *oidctest.Provider) *oidc.Provider {
+	t.Helper()
+	provider, err := oidc.Discover(context.Background(), fake.Issuer(), oidc.Config{
+		ClientID:     fake.ClientID,
+		ClientSecret: fake.ClientSecret,
+		RedirectURL:  "https://todo.example.com/auth/callback",
+		Scopes:       []string{"openid", "email"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+// authorize follows the login URL and returns the query the provider
+// redirects back with.
+func authorize(t *testing.T, authURL string) url.Values {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if resp.StatusCode != http.StatusFound || err != nil || !strings.HasPrefix(location.String(), "https://todo.example.com/auth/callback?") {
+		t.Fatalf("authorize: %s to %q", resp.Status, resp.Header.Get("Location"))
+	}
+	return location.Query()
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	fake := oidctest.NewProvider("todo-app", "s3cret")
+	defer fake.Close()
+	provider := discover(t, fake)
+
+	authURL := provider.AuthCodeURL("state-1", "nonce-1", "verifier-1")
+	if query := mustQuery(t, authURL); query.Get("scope") != "openid email" || query.Get("code_challenge") != oidc.S256Challenge("verifier-1") {
+		t.Errorf("unexpected authorization request %s", authURL)
+	}
+
+	// Nobody is logged in at the provider yet
+	if back := authorize(t, authURL); back.Get("error") != "access_denied" || back.Get("state") != "state-1" {
+		t.Errorf("expected access_denied, got %v", back)
+	}
+
+	fake.SignIn(&oidctest.User{Subject: "u-1", Email: "alice@example.com", EmailVerified: true, Name: "Alice"})
+	back := authorize(t, authURL)
+	if back.Get("code") == "" || back.Get("state") != "state-1" {
+		t.Fatalf("expected a code, got %v", back)
+	}
+
+	// The code needs the PKCE verifier it was issued for
+	code := back.Get("code")
+	if _, err := provider.Exchange(context.Background(), code, "verifier-2"); err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Errorf("exchange with the wrong verifier: %v", err)
+	}
+	code = authorize(t, authURL).Get("code")
+	raw, err := provider.Exchange(context.Background(), code, "verifier-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Exchange(context.Background(), code, "verifier-1"); err == nil {
+		t.Error("a code must only work once")
+	}
+
+	idToken, err := provider.Verify(context.Background(), raw, "nonce-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idToken.Subject != "u-1" || idToken.Email != "alice@example.com" || !idToken.EmailVerified || idToken.Name != "Alice" {
+		t.Errorf("unexpected claims %+v", idToken)
+	}
+	if _, err := provider.Verify(context.Background(), raw, "nonce-2"); err == nil {
+		t.Error("expected a nonce mismatch")
+	}
+}
+
+func TestVerifyRejectsBadTokens(t *testing.T) {
+	fake := oidctest.NewProvider("todo-app", "s3cret")
+	defer fake.Close()
+	provider := discover(t, fake)
+	user := oidctest.User{Subject: "u-1"}
+
+	claims := func(change func(map[string]interface{})) map[string]interface{} {
+		c := fake.IDTokenClaims(user, "n")
+		change(c)
+		return c
+	}
+	other := oidctest.NewProvider("todo-app", "s3cret")
+	defer other.Close()
+	unsigned, _ := jwt.SignHS256(fake.IDTokenClaims(user, "n"), []byte("public key as secret"))
+
+	tests := []struct {
+		name  string
+		token string
+		want  string
+	}{
+		{"wrong issuer", fake.Sign(claims(func(c map[string]interface{}) { c["iss"] = "https://evil.example.com" })), "issued by"},
+		{"wrong audience", fake.Sign(claims(func(c map[string]interface{}) { c["aud"] = "another-app" })), "not meant for this client"},
+		{"shared audience without azp", fake.Sign(claims(func(c map[string]interface{}) { c["aud"] = []string{"todo-app", "another-app"} })), "not authorized"},
+		{"expired", fake.Sign(claims(func(c map[string]interface{}) { c["exp"] = time.Now().Add(-2 * time.Minute).Unix() })), "expired"},
+		{"issued in the future", fake.Sign(claims(func(c map[string]interface{}) { c["iat"] = time.Now().Add(time.Hour).Unix() })), "future"},
+		{"no subject", fake.Sign(claims(func(c map[string]interface{}) { delete(c, "sub") })), "no subject"},
+		{"wrong nonce", fake.Sign(claims(func(c map[string]interface{}) { c["nonce"] = "other" })), "nonce"},
+		{"signed by another key", other.Sign(fake.IDTokenClaims(user, "n")), "signature"},
+		{"HS256", unsigned, "unsupported algorithm"},
+		{"garbage", "not-a-token", "malformed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := provider.Verify(context.Background(), tt.token, "n")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	shared := fake.Sign(claims(func(c map[string]interface{}) {
+		c["aud"] = []string{"todo-app", "another-app"}
+		c["azp"] = "todo-app"
+	}))
+	if _, err := provider.Verify(context.Background(), shared, "n"); err != nil {
+		t.Errorf("shared audience with azp: %v", err)
+	}
+}
+
+func TestVerifyFollowsKeyRotation(t *testing.T) {
+	fake := oidctest.NewProvider("todo-app", "s3cret")
+	defer fake.Close()
+	provider := discover(t, fake)
+	now := time.Now()
+	oidc.SetClock(provider, func() time.Time { return now })
+	user := oidctest.User{Subject: "u-1"}
+
+	if _, err := provider.Verify(context.Background(), fake.Sign(fake.IDTokenClaims(user, "n")), "n"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The new key is fetched, but not more than once a minute
+	fake.RotateKey()
+	rotated := fake.Sign(fake.IDTokenClaims(user, "n"))
+	if _, err := provider.Verify(context.Background(), rotated, "n"); err == nil || !strings.Contains(err.Error(), "unknown key") {
+		t.Errorf("expected an unknown key right after a fetch, got %v", err)
+	}
+	now = now.Add(time.Minute)
+	if _, err := provider.Verify(context.Background(), rotated, "n"); err != nil {
+		t.Errorf("rotated key: %v", err)
+	}
+}
+
+func TestDiscoverChecksIssuer(t *testing.T) {
+	fake := oidctest.NewProvider("todo-app", "s3cret")
+	defer fake.Close()
+
+	if _, err := oidc.Discover(context.Background(), fake.Issuer()+"/", oidc.Config{ClientID: "todo-app"}); err == nil {
+		t.Error("expected an issuer mismatch")
+	}
+	if _, err := oidc.Discover(context.Background(), fake.Issuer()+"/missing", oidc.Config{ClientID: "todo-app"}); err == nil {
+		t.Error("expected a discovery error")
+	}
+}
+
+func mustQuery(t *testing.T, raw string) url.Values {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Query()
+}
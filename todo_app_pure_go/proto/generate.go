@@ -0,0 +1,6 @@
+// Package proto holds the protobuf definitions of the gRPC API. Regenerate
+// the Go code after editing a .proto file with go generate ./proto (requires
+// protoc, protoc-gen-go and protoc-gen-go-grpc on the PATH).
+package proto
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative todo/v1/todo.proto
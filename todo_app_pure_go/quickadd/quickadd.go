@@ -0,0 +1,607 @@
+// Package quickadd reads a todo typed as one line of text, such as
+// "Buy milk tomorrow 5pm !high #home". It recognizes priority markers,
+// #tags, a +project, dates and times relative to the current time, and
+// recurrence phrases starting with "every"; the remaining words make the
+// title.
+//
+// Recognized words are only taken from the text when their meaning is
+// clear: weekday abbreviations need a preposition ("on fri"), month names
+// need a day ("march 5"), and bare numbers are never times. Single-valued
+// parts (priority, project, date, time, recurrence) are taken from their
+// first occurrence; later ones stay in the title.
+package quickadd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"todo-app/dto"
+	"todo-app/models"
+)
+
+// Kinds of recognized spans.
+const (
+	KindPriority   = "priority"
+	KindTag        = "tag"
+	KindProject    = "project"
+	KindDate       = "date"
+	KindTime       = "time"
+	KindRecurrence = "recurrence"
+)
+
+// tonight is the time of day "tonight" stands for when no time is given.
+const tonight = 20
+
+var (
+	priorities = map[string]models.Priority{
+		"!!!": models.HIGH, "!high": models.HIGH, "!hi": models.HIGH, "!h": models.HIGH, "!1": models.HIGH,
+		"!!": models.MEDIUM, "!medium": models.MEDIUM, "!med": models.MEDIUM, "!m": models.MEDIUM, "!2": models.MEDIUM,
+		"!low": models.LOW, "!lo": models.LOW, "!l": models.LOW, "!3": models.LOW,
+	}
+
+	// label matches the name of a tag or project: letters, digits, - and _,
+	// with at least one letter so that "#123" stays an issue number
+	label = regexp.MustCompile(`^[\p{L}\p{N}_-]*\p{L}[\p{L}\p{N}_-]*$`)
+
+	isoDate   = regexp.MustCompile(`^(\d{4})-(\d{2})-(\d{2})$`)
+	clockTime = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm)?$`)
+	ordinal   = regexp.MustCompile(`^(\d{1,2})(?:st|nd|rd|th)?$`)
+
+	weekdays = map[string]time.Weekday{
+		"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+		"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+	}
+	weekdayAbbreviations = map[string]time.Weekday{
+		"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "tues": time.Tuesday, "wed": time.Wednesday,
+		"thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+	}
+	// byDay names weekdays in RRULEs
+	byDay = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+	months = map[string]time.Month{
+		"january": time.January, "february": time.February, "march": time.March, "april": time.April,
+		"may": time.May, "june": time.June, "july": time.July, "august": time.August,
+		"september": time.September, "october": time.October, "november": time.November, "december": time.December,
+		"jan": time.January, "feb": time.February, "mar": time.March, "apr": time.April, "jun": time.June,
+		"jul": time.July, "aug": time.August, "sep": time.September, "sept": time.September,
+		"oct": time.October, "nov": time.November, "dec": time.December,
+	}
+
+	numbers = map[string]int{
+		"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6,
+		"seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12,
+	}
+)
+
+// token is one whitespace-separated word of the text.
+type token struct {
+	text string
+	// word is text in lower case without trailing punctuation, for matching
+	word       string
+	start, end int // character offsets
+	used       bool
+}
+
+// date is a calendar day, free of any location.
+type date struct {
+	year  int
+	month time.Month
+	day   int
+}
+
+type parser struct {
+	tokens []token
+	now    time.Time
+	result *dto.QuickAddResult
+
+	date      *date
+	clock     *[2]int // hour and minute
+	tonight   bool
+	exact     *time.Time
+	frequency string
+	interval  int
+	days      []time.Weekday
+}
+
+// Parse reads text as a todo. Dates and times are read relative to now, in
+// now's location.
+func Parse(text string, now time.Time) *dto.QuickAddResult {
+	p := &parser{
+		tokens: tokenize(text),
+		now:    now,
+		result: &dto.QuickAddResult{Todo: dto.CreateTodoRequest{Tags: []string{}}, Spans: []dto.QuickAddSpan{}},
+	}
+
+	for i := 0; i < len(p.tokens); {
+		if n := p.match(i); n > 0 {
+			i += n
+		} else {
+			i++
+		}
+	}
+
+	var title []string
+	for _, t := range p.tokens {
+		if !t.used {
+			title = append(title, t.text)
+		}
+	}
+	p.result.Todo.Title = strings.Join(title, " ")
+	p.resolveDue()
+	return p.result
+}
+
+// match tries every kind of phrase at token i and returns how many tokens
+// the phrase took.
+func (p *parser) match(i int) int {
+	t := p.tokens[i]
+	if priority, ok := priorities[strings.TrimRight(strings.ToLower(t.text), ",.;:?")]; ok {
+		if p.result.Todo.Priority != "" {
+			return 0
+		}
+		p.result.Todo.Priority = priority
+		return p.use(i, 1, KindPriority, string(priority))
+	}
+	if name, ok := strings.CutPrefix(t.word, "#"); ok && label.MatchString(name) {
+		if !contains(p.result.Todo.Tags, name) {
+			p.result.Todo.Tags = append(p.result.Todo.Tags, name)
+		}
+		return p.use(i, 1, KindTag, name)
+	}
+	if name, ok := strings.CutPrefix(t.word, "+"); ok && label.MatchString(name) {
+		if p.result.Todo.Project != "" {
+			return 0
+		}
+		p.result.Todo.Project = name
+		return p.use(i, 1, KindProject, name)
+	}
+
+	if t.word == "every" && p.frequency == "" {
+		if n := p.recurrence(i + 1); n > 0 {
+			return p.use(i, n+1, KindRecurrence, p.rrule())
+		}
+		return 0
+	}
+
+	// Prepositions belong to the phrase they introduce
+	skip := 0
+	if t.word == "due" {
+		skip++
+	}
+	if w := p.word(i + skip); w == "on" || w == "by" || w == "at" || w == "@" {
+		skip++
+	}
+	if p.date == nil && p.exact == nil {
+		if n, value := p.dateAt(i+skip, skip > 0); n > 0 {
+			return p.use(i, skip+n, KindDate, value)
+		}
+	}
+	if p.clock == nil && p.exact == nil {
+		if n, value := p.timeAt(i+skip, skip > 0); n > 0 {
+			return p.use(i, skip+n, KindTime, value)
+		}
+	}
+	return 0
+}
+
+// dateAt reads a date at token i. Weekday abbreviations need a
+// preposition in front.
+func (p *parser) dateAt(i int, introduced bool) (int, string) {
+	today := dayOf(p.now)
+	w := p.word(i)
+
+	switch w {
+	case "today":
+		return p.setDate(today, 1)
+	case "tonight":
+		p.tonight = true
+		return p.setDate(today, 1)
+	case "tomorrow", "tmrw", "tmr":
+		return p.setDate(today.add(0, 0, 1), 1)
+	case "weekend":
+		return p.setDate(today.next(time.Saturday, true), 1)
+	case "this", "next":
+		next := p.word(i + 1)
+		if day, ok := weekdayNamed(next, true); ok {
+			return p.setDate(today.next(day, false), 2)
+		}
+		if next == "weekend" {
+			return p.setDate(today.next(time.Saturday, w == "this"), 2)
+		}
+		if w == "next" {
+			switch next {
+			case "week":
+				return p.setDate(today.next(time.Monday, false), 2)
+			case "month":
+				return p.setDate(date{today.year, today.month + 1, 1}.normalize(), 2)
+			case "year":
+				return p.setDate(date{today.year + 1, time.January, 1}, 2)
+			}
+		}
+		return 0, ""
+	case "in":
+		n, ok := number(p.word(i + 1))
+		if !ok {
+			return 0, ""
+		}
+		switch unit := strings.TrimSuffix(p.word(i+2), "s"); unit {
+		case "day":
+			return p.setDate(today.add(0, 0, n), 3)
+		case "week":
+			return p.setDate(today.add(0, 0, 7*n), 3)
+		case "month":
+			return p.setDate(today.add(0, n, 0), 3)
+		case "year":
+			return p.setDate(today.add(n, 0, 0), 3)
+		case "hour", "hr", "minute", "min":
+			duration := time.Duration(n) * time.Hour
+			if unit == "minute" || unit == "min" {
+				duration = time.Duration(n) * time.Minute
+			}
+			exact := p.now.Add(duration).Truncate(time.Minute)
+			p.exact = &exact
+			return 3, exact.Format(time.RFC3339)
+		}
+		return 0, ""
+	}
+
+	if day, ok := weekdayNamed(w, introduced); ok {
+		return p.setDate(today.next(day, false), 1)
+	}
+	if m := isoDate.FindStringSubmatch(w); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		month, _ := strconv.Atoi(m[2])
+		day, _ := strconv.Atoi(m[3])
+		if d, ok := validDate(year, time.Month(month), day); ok {
+			return p.setDate(d, 1)
+		}
+		return 0, ""
+	}
+
+	// "march 5", "march 5th 2025", "5 march", "5th of march 2025"
+	var month time.Month
+	var day, n int
+	if m, ok := months[w]; ok {
+		if d := ordinal.FindStringSubmatch(p.word(i + 1)); d != nil {
+			month, n = m, 2
+			day, _ = strconv.Atoi(d[1])
+		}
+	} else if d := ordinal.FindStringSubmatch(w); d != nil {
+		of := 0
+		if p.word(i+1) == "of" {
+			of = 1
+		}
+		if m, ok := months[p.word(i+1+of)]; ok {
+			month, n = m, 2+of
+			day, _ = strconv.Atoi(d[1])
+		}
+	}
+	if n == 0 {
+		return 0, ""
+	}
+	year := today.year
+	if y, err := strconv.Atoi(p.word(i + n)); err == nil && len(p.word(i+n)) == 4 {
+		year, n = y, n+1
+	} else if d, ok := validDate(year, month, day); ok && d.before(today) {
+		// Dates without a year are the next such date
+		year++
+	}
+	if d, ok := validDate(year, month, day); ok {
+		return p.setDate(d, n)
+	}
+	return 0, ""
+}
+
+// timeAt reads a time of day at token i. Bare numbers are only read as
+// hours after "at".
+func (p *parser) timeAt(i int, introduced bool) (int, string) {
+	w := p.word(i)
+	switch w {
+	case "noon", "midday":
+		return p.setClock(12, 0, 1)
+	case "midnight":
+		return p.setClock(0, 0, 1)
+	}
+
+	m := clockTime.FindStringSubmatch(w)
+	if m == nil {
+		return 0, ""
+	}
+	n := 1
+	suffix := m[3]
+	if suffix == "" {
+		if next := p.word(i + 1); next == "am" || next == "pm" {
+			suffix, n = next, 2
+		}
+	}
+	if suffix == "" && m[2] == "" && !introduced {
+		return 0, ""
+	}
+
+	hour, _ := strconv.Atoi(m[1])
+	minute := 0
+	if m[2] != "" {
+		minute, _ = strconv.Atoi(m[2])
+	}
+	if minute > 59 {
+		return 0, ""
+	}
+	switch {
+	case suffix == "":
+		if hour > 23 {
+			return 0, ""
+		}
+	case hour < 1 || hour > 12:
+		return 0, ""
+	case suffix[0] == 'p' && hour < 12:
+		hour += 12
+	case suffix[0] == 'a' && hour == 12:
+		hour = 0
+	}
+	return p.setClock(hour, minute, n)
+}
+
+// recurrence reads what follows "every" at token i.
+func (p *parser) recurrence(i int) int {
+	n, interval := 0, 1
+	if count, ok := number(p.word(i)); ok && p.word(i) != "a" && p.word(i) != "an" {
+		n, interval = 1, count
+	} else if p.word(i) == "other" {
+		n, interval = 1, 2
+	}
+
+	switch unit := strings.TrimSuffix(p.word(i+n), "s"); unit {
+	case "day":
+		p.frequency, p.interval = "DAILY", interval
+		return n + 1
+	case "week":
+		p.frequency, p.interval = "WEEKLY", interval
+		return n + 1
+	case "month":
+		p.frequency, p.interval = "MONTHLY", interval
+		return n + 1
+	case "year":
+		p.frequency, p.interval = "YEARLY", interval
+		return n + 1
+	case "weekday":
+		if n > 0 {
+			return 0
+		}
+		p.frequency, p.interval = "WEEKLY", 1
+		p.days = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+		return 1
+	}
+
+	// "every monday", "every other fri", "every mon, wed and fri"
+	var days []time.Weekday
+	j := i + n
+	for {
+		day, ok := weekdayNamed(p.word(j), true)
+		if !ok {
+			break
+		}
+		days = append(days, day)
+		j++
+		if p.word(j) == "and" {
+			if _, ok := weekdayNamed(p.word(j+1), true); ok {
+				j++
+			}
+		}
+	}
+	if len(days) == 0 {
+		return 0
+	}
+	p.frequency, p.interval, p.days = "WEEKLY", interval, days
+	return j - i
+}
+
+// rrule renders the recurrence as an iCalendar RRULE.
+func (p *parser) rrule() string {
+	rule := "FREQ=" + p.frequency
+	if p.interval > 1 {
+		rule += ";INTERVAL=" + strconv.Itoa(p.interval)
+	}
+	if len(p.days) > 0 {
+		names := make([]string, len(p.days))
+		for i, day := range p.days {
+			names[i] = byDay[day]
+		}
+		rule += ";BYDAY=" + strings.Join(names, ",")
+	}
+	return rule
+}
+
+// resolveDue combines date, time and recurrence into the due time. A time
+// without a date is its next occurrence; a recurrence without a date starts
+// at its first occurrence.
+func (p *parser) resolveDue() {
+	if p.frequency != "" {
+		p.result.Todo.Recurrence = p.rrule()
+	}
+	if p.exact != nil {
+		p.result.Todo.DueAt = p.exact
+		return
+	}
+
+	if p.clock == nil && p.tonight {
+		p.clock = &[2]int{tonight, 0}
+	}
+
+	loc := p.now.Location()
+	day, explicit := dayOf(p.now), p.date != nil
+	if explicit {
+		day = *p.date
+	} else if p.clock == nil && p.frequency == "" {
+		return
+	}
+	if !explicit && len(p.days) > 0 {
+		day = day.nextOf(p.days)
+	}
+
+	hour, minute := 0, 0
+	if p.clock != nil {
+		hour, minute = p.clock[0], p.clock[1]
+	}
+	due := time.Date(day.year, day.month, day.day, hour, minute, 0, 0, loc)
+	if !explicit && p.clock != nil && !due.After(p.now) {
+		// Already past today: the next day, or the next matching weekday
+		day = day.add(0, 0, 1)
+		if len(p.days) > 0 {
+			day = day.nextOf(p.days)
+		}
+		due = time.Date(day.year, day.month, day.day, hour, minute, 0, 0, loc)
+	}
+	p.result.Todo.DueAt = &due
+	p.result.Todo.AllDay = p.clock == nil
+}
+
+func (p *parser) setDate(d date, n int) (int, string) {
+	p.date = &d
+	return n, fmt.Sprintf("%04d-%02d-%02d", d.year, d.month, d.day)
+}
+
+func (p *parser) setClock(hour, minute, n int) (int, string) {
+	p.clock = &[2]int{hour, minute}
+	return n, fmt.Sprintf("%02d:%02d", hour, minute)
+}
+
+// use marks n tokens from i as recognized and records their span.
+func (p *parser) use(i, n int, kind, value string) int {
+	first, last := p.tokens[i], p.tokens[i+n-1]
+	var text []string
+	for j := i; j < i+n; j++ {
+		p.tokens[j].used = true
+		text = append(text, p.tokens[j].text)
+	}
+	p.result.Spans = append(p.result.Spans, dto.QuickAddSpan{
+		Kind:  kind,
+		Start: first.start,
+		End:   last.end,
+		Text:  strings.Join(text, " "),
+		Value: value,
+	})
+	return n
+}
+
+// word returns the matching form of token i, or "" past the end or for
+// tokens already recognized.
+func (p *parser) word(i int) string {
+	if i >= len(p.tokens) || p.tokens[i].used {
+		return ""
+	}
+	return p.tokens[i].word
+}
+
+func tokenize(text string) []token {
+	var tokens []token
+	start, offset := -1, 0
+	for i, r := range text {
+		if unicode.IsSpace(r) {
+			if start >= 0 {
+				tokens = append(tokens, newToken(text[start:i], offset-utf8.RuneCountInString(text[start:i]), offset))
+				start = -1
+			}
+		} else if start < 0 {
+			start = i
+		}
+		offset++
+	}
+	if start >= 0 {
+		tokens = append(tokens, newToken(text[start:], offset-utf8.RuneCountInString(text[start:]), offset))
+	}
+	return tokens
+}
+
+func newToken(text string, start, end int) token {
+	return token{
+		text:  text,
+		word:  strings.TrimRight(strings.ToLower(text), ",.;:!?"),
+		start: start,
+		end:   end,
+	}
+}
+
+// weekdayNamed reads a weekday name; abbreviations only count when
+// abbreviated is set.
+func weekdayNamed(word string, abbreviated bool) (time.Weekday, bool) {
+	if day, ok := weekdays[word]; ok {
+		return day, true
+	}
+	if abbreviated {
+		day, ok := weekdayAbbreviations[word]
+		return day, ok
+	}
+	return 0, false
+}
+
+func number(word string) (int, bool) {
+	if n, ok := numbers[word]; ok {
+		return n, true
+	}
+	n, err := strconv.Atoi(word)
+	return n, err == nil && n > 0 && n < 1000
+}
+
+func dayOf(t time.Time) date {
+	return date{t.Year(), t.Month(), t.Day()}
+}
+
+func validDate(year int, month time.Month, day int) (date, bool) {
+	d := date{year, month, day}
+	return d, d.normalize() == d
+}
+
+func (d date) normalize() date {
+	return dayOf(time.Date(d.year, d.month, d.day, 0, 0, 0, 0, time.UTC))
+}
+
+func (d date) add(years, months, days int) date {
+	return dayOf(time.Date(d.year, d.month, d.day, 0, 0, 0, 0, time.UTC).AddDate(years, months, days))
+}
+
+func (d date) weekday() time.Weekday {
+	return time.Date(d.year, d.month, d.day, 0, 0, 0, 0, time.UTC).Weekday()
+}
+
+func (d date) before(other date) bool {
+	return d.year < other.year ||
+		d.year == other.year && (d.month < other.month || d.month == other.month && d.day < other.day)
+}
+
+// next returns the next day falling on weekday, today included if
+// includeToday is set.
+func (d date) next(weekday time.Weekday, includeToday bool) date {
+	days := (int(weekday) - int(d.weekday()) + 7) % 7
+	if days == 0 && !includeToday {
+		days = 7
+	}
+	return d.add(0, 0, days)
+}
+
+// nextOf returns the first day from d on, d included, falling on one of
+// weekdays.
+func (d date) nextOf(weekdays []time.Weekday) date {
+	for i := 0; i < 7; i++ {
+		candidate := d.add(0, 0, i)
+		for _, day := range weekdays {
+			if candidate.weekday() == day {
+				return candidate
+			}
+		}
+	}
+	return d
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
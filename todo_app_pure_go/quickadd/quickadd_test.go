@@ -0,0 +1,92 @@
+package quickadd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"todo-app/dto"
+	"todo-app/models"
+)
+
+// now is Tuesday, 10:00 in the user's time zone.
+var now = time.Date(2024, 3, 5, 10, 0, 0, 0, time.FixedZone("+03", 3*60*60))
+
+func at(year int, month time.Month, day, hour, minute int) *time.Time {
+	t := time.Date(year, month, day, hour, minute, 0, 0, now.Location())
+	return &t
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text       string
+		title      string
+		priority   models.Priority
+		tags       []string
+		project    string
+		due        *time.Time
+		allDay     bool
+		recurrence string
+	}{
+		{"Buy milk tomorrow 5pm !high #home", "Buy milk", models.HIGH, []string{"home"}, "", at(2024, 3, 6, 17, 0), false, ""},
+		{"Call Bob on fri at 9:30am +Work", "Call Bob", "", nil, "work", at(2024, 3, 8, 9, 30), false, ""},
+		{"Report due by 2024-04-01 #Work #work", "Report", "", []string{"work"}, "", at(2024, 4, 1, 0, 0), true, ""},
+		{"Submit taxes 15th of april 2024 !!", "Submit taxes", models.MEDIUM, nil, "", at(2024, 4, 15, 0, 0), true, ""},
+		// Dates without a year are the next such date
+		{"Dentist march 1, 2pm", "Dentist", "", nil, "", at(2025, 3, 1, 14, 0), false, ""},
+		{"Plan trip next week", "Plan trip", "", nil, "", at(2024, 3, 11, 0, 0), true, ""},
+		{"Review in a week", "Review", "", nil, "", at(2024, 3, 12, 0, 0), true, ""},
+		{"Call back in 2 hours", "Call back", "", nil, "", at(2024, 3, 5, 12, 0), false, ""},
+		{"Movie tonight", "Movie", "", nil, "", at(2024, 3, 5, 20, 0), false, ""},
+		{"Lunch at noon", "Lunch", "", nil, "", at(2024, 3, 5, 12, 0), false, ""},
+		// A time that has passed today is tomorrow
+		{"Breakfast 8am", "Breakfast", "", nil, "", at(2024, 3, 6, 8, 0), false, ""},
+		{"Pay rent every month !1", "Pay rent", models.HIGH, nil, "", at(2024, 3, 5, 0, 0), true, "FREQ=MONTHLY"},
+		{"Water plants every other day", "Water plants", "", nil, "", at(2024, 3, 5, 0, 0), true, "FREQ=DAILY;INTERVAL=2"},
+		{"Standup every weekday at 9am", "Standup", "", nil, "", at(2024, 3, 6, 9, 0), false, "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR"},
+		{"Gym every mon, wed and fri 7pm", "Gym", "", nil, "", at(2024, 3, 6, 19, 0), false, "FREQ=WEEKLY;BYDAY=MO,WE,FR"},
+		{"Team sync every 2 weeks on thursday 11:00", "Team sync", "", nil, "", at(2024, 3, 7, 11, 0), false, "FREQ=WEEKLY;INTERVAL=2"},
+		// Words that only look like dates, times, tags or projects stay
+		{"Fix bug #123 and buy sun cream +1", "Fix bug #123 and buy sun cream +1", "", nil, "", nil, false, ""},
+		{"Meet may 40 people at the lake", "Meet may 40 people at the lake", "", nil, "", nil, false, ""},
+		{"Every so often clean the attic", "Every so often clean the attic", "", nil, "", nil, false, ""},
+		// The first priority counts; later ones stay in the title
+		{"Weekly report !low !high", "Weekly report !high", models.LOW, nil, "", nil, false, ""},
+		{"#home", "", "", []string{"home"}, "", nil, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got := Parse(tt.text, now)
+			if got.Todo.Title != tt.title || got.Todo.Priority != tt.priority {
+				t.Errorf("todo = %+v, want title %q and priority %q", got.Todo, tt.title, tt.priority)
+			}
+			if tt.tags == nil {
+				tt.tags = []string{}
+			}
+			if !reflect.DeepEqual(got.Todo.Tags, tt.tags) || got.Todo.Project != tt.project {
+				t.Errorf("tags = %v, project = %q; want %v, %q", got.Todo.Tags, got.Todo.Project, tt.tags, tt.project)
+			}
+			if (got.Todo.DueAt == nil) != (tt.due == nil) || got.Todo.DueAt != nil && !got.Todo.DueAt.Equal(*tt.due) || got.Todo.AllDay != tt.allDay {
+				t.Errorf("due = %v (all day %v), want %v (all day %v)", got.Todo.DueAt, got.Todo.AllDay, tt.due, tt.allDay)
+			}
+			if got.Todo.Recurrence != tt.recurrence {
+				t.Errorf("recurrence = %q, want %q", got.Todo.Recurrence, tt.recurrence)
+			}
+		})
+	}
+}
+
+func TestParseSpans(t *testing.T) {
+	got := Parse("Çiçekleri sula due tomorrow  at 5 pm #ev", now)
+	want := []dto.QuickAddSpan{
+		{Kind: KindDate, Start: 15, End: 27, Text: "due tomorrow", Value: "2024-03-06"},
+		{Kind: KindTime, Start: 29, End: 36, Text: "at 5 pm", Value: "17:00"},
+		{Kind: KindTag, Start: 37, End: 40, Text: "#ev", Value: "ev"},
+	}
+	if !reflect.DeepEqual(got.Spans, want) {
+		t.Errorf("spans = %+v\nwant %+v", got.Spans, want)
+	}
+	if got.Todo.Title != "Çiçekleri sula" {
+		t.Errorf("title = %q", got.Todo.Title)
+	}
+}
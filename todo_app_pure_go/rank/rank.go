@@ -0,0 +1,187 @@
+// Package rank generates lexicographic ranks for manually ordered lists.
+// A rank is a string of base-36 digits read as a fraction (0.xyz), so ranks
+// sort in the order of the items they rank and there is always another rank
+// between two different ones: moving an item only changes its own rank.
+//
+// Ranks only use 0-9 and a-z and never end in 0, so byte order, the order of
+// common database collations and numeric order all agree.
+package rank
+
+import (
+	"errors"
+	"strings"
+)
+
+const digits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+// base is len(digits)
+const base = 36
+
+// MaxLength is the length beyond which ranks should be spread out again
+// with Spread. Ranks grow by about one digit every few moves into the same
+// gap.
+const MaxLength = 16
+
+// ErrOrder is returned when the lower bound does not sort before the upper
+// bound, which happens when two items share a rank.
+var ErrOrder = errors.New("rank: lower bound does not sort before upper bound")
+
+// Valid reports whether r is a rank.
+func Valid(r string) bool {
+	if r == "" || r[len(r)-1] == '0' {
+		return false
+	}
+	for i := 0; i < len(r); i++ {
+		if strings.IndexByte(digits, r[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Between returns a rank that sorts after a and before b. Either bound may
+// be empty for the start or end of the list. Ranks at either end step away
+// from their neighbour rather than halving the gap, so adding items to the
+// start or end of a list keeps ranks short.
+func Between(a, b string) (string, error) {
+	if (a != "" && !Valid(a)) || (b != "" && !Valid(b)) {
+		return "", errors.New("rank: invalid bound")
+	}
+	switch {
+	case a == "" && b == "":
+		return midpoint("", ""), nil
+	case b == "":
+		return after(a), nil
+	case a == "":
+		return before(b), nil
+	case a >= b:
+		return "", ErrOrder
+	}
+	return midpoint(a, b), nil
+}
+
+// BetweenN returns n ascending ranks between a and b, as Between does for
+// one. Bisecting the gap keeps them about log36(n) digits longer than the
+// bounds.
+func BetweenN(a, b string, n int) ([]string, error) {
+	if n <= 0 {
+		return []string{}, nil
+	}
+	mid, err := Between(a, b)
+	if err != nil {
+		return nil, err
+	}
+	lower, err := BetweenN(a, mid, (n-1)/2)
+	if err != nil {
+		return nil, err
+	}
+	upper, err := BetweenN(mid, b, n-1-(n-1)/2)
+	if err != nil {
+		return nil, err
+	}
+	ranks := append(lower, mid)
+	return append(ranks, upper...), nil
+}
+
+// Spread returns n ascending short ranks, evenly spaced so that many items
+// fit between any two of them.
+func Spread(n int) []string {
+	ranks := make([]string, n)
+	if n == 0 {
+		return ranks
+	}
+	// Leave room for about base items in every gap; 36^12 still fits in
+	// an int64
+	length, space := 1, int64(base)
+	for length < 12 && space < int64(n+1)*base {
+		length++
+		space *= base
+	}
+	step := space / int64(n+1)
+	for i := range ranks {
+		ranks[i] = encode(int64(i+1)*step, length)
+	}
+	return ranks
+}
+
+// encode writes v as a fraction of length digits, without trailing zeros.
+func encode(v int64, length int) string {
+	b := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		b[i] = digits[v%int64(base)]
+		v /= int64(base)
+	}
+	return strings.TrimRight(string(b), "0")
+}
+
+// digitAt returns the value of the digit of r at i; r continues with zeros.
+func digitAt(r string, i int) int {
+	if i >= len(r) {
+		return 0
+	}
+	return strings.IndexByte(digits, r[i])
+}
+
+func suffix(r string, i int) string {
+	if i >= len(r) {
+		return ""
+	}
+	return r[i:]
+}
+
+// midpoint returns a rank between a and b, where b is empty for 1.0.
+func midpoint(a, b string) string {
+	// Keep the common prefix, reading a past its end as zeros
+	n := 0
+	for n < len(b) && digitAt(a, n) == digitAt(b, n) {
+		n++
+	}
+	if n > 0 {
+		return b[:n] + midpoint(suffix(a, n), b[n:])
+	}
+
+	da, db := digitAt(a, 0), base
+	if b != "" {
+		db = digitAt(b, 0)
+	}
+	if db-da > 1 {
+		return string(digits[(da+db)/2])
+	}
+	// The first digits are adjacent: b truncated to its first digit still
+	// sorts after a if b has more digits, otherwise continue after a
+	if len(b) > 1 {
+		return b[:1]
+	}
+	return string(digits[da]) + midpoint(suffix(a, 1), "")
+}
+
+// after returns a short rank after a: a's first digit that is not the
+// largest, incremented. Past all largest digits it starts from the
+// smallest, leaving the most steps before the rank grows again.
+func after(a string) string {
+	for i := 0; i < len(a); i++ {
+		if d := digitAt(a, i); d < base-1 {
+			return a[:i] + string(digits[d+1])
+		}
+	}
+	return a + string(digits[1])
+}
+
+// before returns a short rank before b: b's first digit that can be
+// decremented without producing a rank that ends in 0, decremented.
+func before(b string) string {
+	for i := 0; i < len(b); i++ {
+		d := digitAt(b, i)
+		switch {
+		case d > 1:
+			return b[:i] + string(digits[d-1])
+		case d == 1 && i < len(b)-1:
+			// b[:i+1] is a prefix of b and so sorts before it
+			return b[:i+1]
+		case d == 1:
+			return b[:i] + "0" + string(digits[base-1])
+		}
+	}
+	// Unreachable for valid ranks, which do not end in 0
+	return midpoint("", b)
+}
@@ -0,0 +1,120 @@
+package rank
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestBetween(t *testing.T) {
+	for _, tt := range []struct{ a, b, want string }{
+		{"", "", "i"},
+		{"i", "", "j"},
+		{"z", "", "z1"},
+		{"", "i", "h"},
+		{"", "1", "0z"},
+		{"", "11", "1"},
+		{"i", "k", "j"},
+		{"i", "j", "ii"},
+		{"i", "j5", "j"},
+		{"i5", "i6", "i5i"},
+		{"i", "i5", "i2"},
+	} {
+		got, err := Between(tt.a, tt.b)
+		if err != nil || got != tt.want {
+			t.Errorf("Between(%q, %q) = %q, %v; want %q", tt.a, tt.b, got, err, tt.want)
+		}
+	}
+
+	for _, bounds := range [][2]string{{"j", "i"}, {"i", "i"}} {
+		if _, err := Between(bounds[0], bounds[1]); !errors.Is(err, ErrOrder) {
+			t.Errorf("Between(%q, %q) error = %v", bounds[0], bounds[1], err)
+		}
+	}
+	for _, bounds := range [][2]string{{"i0", ""}, {"", "I"}, {"-", "i"}} {
+		if _, err := Between(bounds[0], bounds[1]); err == nil || errors.Is(err, ErrOrder) {
+			t.Errorf("Between(%q, %q) accepted an invalid rank", bounds[0], bounds[1])
+		}
+	}
+}
+
+// TestRandomMoves inserts at random places and checks that ranks stay
+// valid, ordered and reasonably short.
+func TestRandomMoves(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	ranks := []string{}
+	for i := 0; i < 2000; i++ {
+		at := random.Intn(len(ranks) + 1)
+		var a, b string
+		if at > 0 {
+			a = ranks[at-1]
+		}
+		if at < len(ranks) {
+			b = ranks[at]
+		}
+		r, err := Between(a, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !Valid(r) || (a != "" && r <= a) || (b != "" && r >= b) {
+			t.Fatalf("Between(%q, %q) = %q", a, b, r)
+		}
+		ranks = append(ranks[:at], append([]string{r}, ranks[at:]...)...)
+	}
+	for _, r := range ranks {
+		if len(r) > MaxLength {
+			t.Errorf("rank %q is longer than %d", r, MaxLength)
+		}
+	}
+}
+
+func TestEnds(t *testing.T) {
+	// Adding to the start or end grows ranks slowly
+	first, last := "i", "i"
+	for i := 0; i < 400; i++ {
+		first, _ = Between("", first)
+		last, _ = Between(last, "")
+	}
+	if len(first) > 16 || len(last) > 16 {
+		t.Errorf("after 400 items the ends are %q and %q", first, last)
+	}
+}
+
+func TestBetweenN(t *testing.T) {
+	ranks, err := BetweenN("", "1", 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranks) != 1000 || !sort.StringsAreSorted(ranks) || ranks[len(ranks)-1] >= "1" {
+		t.Fatalf("BetweenN returned %d ranks, sorted %v", len(ranks), sort.StringsAreSorted(ranks))
+	}
+	for i, r := range ranks {
+		if !Valid(r) || len(r) > 6 || (i > 0 && r == ranks[i-1]) {
+			t.Fatalf("rank %d is %q", i, r)
+		}
+	}
+}
+
+func TestSpread(t *testing.T) {
+	for _, n := range []int{0, 1, 3, 35, 36, 5000} {
+		ranks := Spread(n)
+		if len(ranks) != n || !sort.StringsAreSorted(ranks) {
+			t.Fatalf("Spread(%d) = %v", n, ranks)
+		}
+		for i, r := range ranks {
+			if !Valid(r) || (i > 0 && r == ranks[i-1]) {
+				t.Fatalf("Spread(%d)[%d] = %q", n, i, r)
+			}
+			// There is room around every rank
+			if i > 0 {
+				if _, err := Between(ranks[i-1], r); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+	if got := Spread(3); got[0] != "9" || got[1] != "i" || got[2] != "r" {
+		t.Errorf("Spread(3) = %v", got)
+	}
+}
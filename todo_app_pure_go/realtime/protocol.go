@@ -0,0 +1,92 @@
+// Package realtime implements the bidirectional WebSocket channel of the
+// todo API. Clients subscribe to filtered views of the todo list, receive
+// the matching change events and send mutations that go through the same
+// TodoService as the REST endpoints.
+//
+// Every frame is a JSON object with a "type". Client messages:
+//
+//	{"type":"subscribe","subscription":"open","filter":{"completed":false}}
+//	{"type":"unsubscribe","subscription":"open"}
+//	{"type":"create","ref":"1","data":{"title":"Buy milk"}}
+//	{"type":"update","ref":"2","todo_id":7,"data":{"priority":"HIGH"}}
+//	{"type":"toggle","ref":"3","todo_id":7}
+//
+// The server answers every client message with an "ack" or an "error"
+// carrying the same ref, and pushes "event" messages listing the
+// subscriptions the event matched. Mutations spend the same rate limits as
+// the REST endpoints; one over the limit is answered with a "rate_limited"
+// error whose retry_after says how many seconds to wait.
+package realtime
+
+import (
+	"encoding/json"
+
+	"todo-app/dto"
+	"todo-app/events"
+	"todo-app/models"
+)
+
+const (
+	TypeSubscribe   = "subscribe"
+	TypeUnsubscribe = "unsubscribe"
+	TypeCreate      = "create"
+	TypeUpdate      = "update"
+	TypeToggle      = "toggle"
+
+	TypeAck   = "ack"
+	TypeError = "error"
+	TypeEvent = "event"
+)
+
+// Error codes sent in error messages.
+const (
+	CodeBadRequest  = "bad_request"
+	CodeValidation  = "validation_failed"
+	CodeNotFound    = "not_found"
+	CodeForbidden   = "forbidden"
+	CodeConflict    = "conflict"
+	CodeRateLimited = "rate_limited"
+	CodeInternal    = "internal_error"
+)
+
+// Filter selects todos the same way the list endpoint's query parameters do.
+// The zero value matches every todo.
+type Filter struct {
+	Completed *bool            `json:"completed,omitempty"`
+	Priority  *models.Priority `json:"priority,omitempty"`
+}
+
+func (f Filter) Matches(todo *dto.TodoResponse) bool {
+	if todo == nil {
+		return true
+	}
+	if f.Completed != nil && todo.Completed != *f.Completed {
+		return false
+	}
+	if f.Priority != nil && todo.Priority != *f.Priority {
+		return false
+	}
+	return true
+}
+
+type ClientMessage struct {
+	Type         string          `json:"type"`
+	Ref          string          `json:"ref,omitempty"`
+	Subscription string          `json:"subscription,omitempty"`
+	Filter       Filter          `json:"filter"`
+	TodoID       uint            `json:"todo_id,omitempty"`
+	Data         json.RawMessage `json:"data,omitempty"`
+}
+
+type ServerMessage struct {
+	Type          string        `json:"type"`
+	Ref           string        `json:"ref,omitempty"`
+	Subscriptions []string      `json:"subscriptions,omitempty"`
+	Seq           uint64        `json:"seq,omitempty"`
+	Event         *events.Event `json:"event,omitempty"`
+	Data          interface{}   `json:"data,omitempty"`
+	Code          string        `json:"code,omitempty"`
+	Error         string        `json:"error,omitempty"`
+	// RetryAfter is the number of seconds to wait after a rate_limited error
+	RetryAfter int `json:"retry_after,omitempty"`
+}
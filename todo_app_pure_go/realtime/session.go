@@ -0,0 +1,327 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"todo-app/auth"
+	"todo-app/dto"
+	"todo-app/models"
+	"todo-app/service"
+	"todo-app/stream"
+	"todo-app/tenant"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// writeWait bounds a single write; a client that cannot take a frame in
+	// this time is disconnected.
+	writeWait = 10 * time.Second
+	// pongWait is how long the connection may stay silent, including the
+	// answer to our pings, before it is considered dead.
+	pongWait   = 60 * time.Second
+	pingPeriod = pongWait * 9 / 10
+
+	maxMessageSize   = 64 << 10
+	maxSubscriptions = 32
+	// replyBuffer is how many replies may queue up before the session stops
+	// reading further client messages.
+	replyBuffer = 32
+)
+
+// Limiter decides whether a mutation of the given message type (create,
+// update or toggle) may be made now, and otherwise how long the client has
+// to wait.
+type Limiter func(msgType string) (allowed bool, retryAfter time.Duration)
+
+// closeSlowConsumer is the close reason sent along with 1013 (try again
+// later) to clients that fell too far behind the event stream.
+const closeSlowConsumer = "slow consumer"
+
+type session struct {
+	conn *websocket.Conn
+	// ctx carries the workspace the connection acts in
+	ctx       context.Context
+	workspace uint
+	// identity is the principal the connection acts for
+	identity    string
+	todoService service.TodoService
+	sub         *stream.Subscription
+	limit       Limiter
+
+	replies chan ServerMessage
+	done    chan struct{}
+	once    sync.Once
+
+	mu            sync.Mutex
+	subscriptions map[string]Filter
+}
+
+// Serve runs the protocol on an upgraded connection until either side
+// closes it or the hub shuts down, and closes conn before returning.
+// Mutations are made as identity in the workspace of ctx, and identity only
+// receives events about todos it can see there. Each mutation is first
+// passed to limit, unless it is nil.
+func Serve(ctx context.Context, conn *websocket.Conn, identity string, todoService service.TodoService, hub *stream.Hub, limit Limiter) {
+	sub, _, _, err := hub.Subscribe(0, false)
+	if err != nil {
+		closeConn(conn, websocket.CloseGoingAway, "server is shutting down")
+		return
+	}
+	defer sub.Close()
+
+	workspace, _ := tenant.FromContext(ctx)
+	s := &session{
+		conn:          conn,
+		ctx:           ctx,
+		workspace:     workspace,
+		identity:      identity,
+		todoService:   todoService,
+		sub:           sub,
+		limit:         limit,
+		replies:       make(chan ServerMessage, replyBuffer),
+		done:          make(chan struct{}),
+		subscriptions: make(map[string]Filter),
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.writeLoop()
+	}()
+	s.readLoop()
+	wg.Wait()
+}
+
+func (s *session) stop() {
+	s.once.Do(func() { close(s.done) })
+}
+
+func (s *session) readLoop() {
+	defer s.stop()
+
+	s.conn.SetReadLimit(maxMessageSize)
+	s.conn.SetReadDeadline(time.Now().Add(pongWait))
+	s.conn.SetPongHandler(func(string) error {
+		return s.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := s.conn.ReadMessage()
+		if err != nil {
+			// Closed by the client, timed out or closed by the writer
+			return
+		}
+		s.conn.SetReadDeadline(time.Now().Add(pongWait))
+
+		var reply ServerMessage
+		var msg ClientMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			reply = errorMessage("", CodeBadRequest, "invalid message: "+err.Error())
+		} else {
+			reply = s.handle(&msg)
+		}
+		if !s.reply(reply) {
+			return
+		}
+	}
+}
+
+// reply queues a message for the writer. It blocks while the queue is full,
+// which stops reading from a client that does not read its replies.
+func (s *session) reply(msg ServerMessage) bool {
+	select {
+	case s.replies <- msg:
+		return true
+	case <-s.done:
+		return false
+	}
+}
+
+func (s *session) writeLoop() {
+	defer s.conn.Close()
+
+	ping := time.NewTicker(pingPeriod)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			closeConn(s.conn, websocket.CloseNormalClosure, "")
+			return
+		case msg := <-s.replies:
+			if err := s.write(msg); err != nil {
+				s.stop()
+				return
+			}
+		case msg, ok := <-s.sub.C:
+			if !ok {
+				s.stop()
+				if s.sub.Dropped() {
+					closeConn(s.conn, websocket.CloseTryAgainLater, closeSlowConsumer)
+				} else {
+					closeConn(s.conn, websocket.CloseGoingAway, "server is shutting down")
+				}
+				return
+			}
+			names := s.matching(msg)
+			if len(names) == 0 {
+				continue
+			}
+			event := msg.Event
+			if err := s.write(ServerMessage{Type: TypeEvent, Subscriptions: names, Seq: msg.ID, Event: &event}); err != nil {
+				s.stop()
+				return
+			}
+		case <-ping.C:
+			if err := s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				s.stop()
+				return
+			}
+		}
+	}
+}
+
+func (s *session) write(msg ServerMessage) error {
+	s.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return s.conn.WriteJSON(msg)
+}
+
+// matching returns the sorted names of the subscriptions msg matches.
+func (s *session) matching(msg stream.Message) []string {
+	if !msg.Event.VisibleTo(s.workspace, s.identity) {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var names []string
+	for name, filter := range s.subscriptions {
+		if filter.Matches(msg.Event.Todo) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *session) handle(msg *ClientMessage) ServerMessage {
+	switch msg.Type {
+	case TypeCreate, TypeUpdate, TypeToggle:
+		if err := auth.Require(s.ctx, auth.ScopeTodosWrite); err != nil {
+			return result(msg.Ref, nil, err)
+		}
+		if s.limit != nil {
+			if allowed, retryAfter := s.limit(msg.Type); !allowed {
+				reply := errorMessage(msg.Ref, CodeRateLimited, "rate limit exceeded, retry later")
+				reply.RetryAfter = int(math.Ceil(retryAfter.Seconds()))
+				return reply
+			}
+		}
+	}
+
+	switch msg.Type {
+	case TypeSubscribe:
+		return s.subscribe(msg)
+	case TypeUnsubscribe:
+		s.mu.Lock()
+		_, ok := s.subscriptions[msg.Subscription]
+		delete(s.subscriptions, msg.Subscription)
+		s.mu.Unlock()
+		if !ok {
+			return errorMessage(msg.Ref, CodeNotFound, "subscription not found")
+		}
+		return ServerMessage{Type: TypeAck, Ref: msg.Ref}
+	case TypeCreate:
+		var req dto.CreateTodoRequest
+		if err := decodeData(msg.Data, &req); err != nil {
+			return errorMessage(msg.Ref, CodeBadRequest, err.Error())
+		}
+		todo, err := s.todoService.CreateTodo(s.ctx, s.identity, &req)
+		return result(msg.Ref, todo, err)
+	case TypeUpdate:
+		if msg.TodoID == 0 {
+			return errorMessage(msg.Ref, CodeBadRequest, "todo_id is required")
+		}
+		var req dto.UpdateTodoRequest
+		if err := decodeData(msg.Data, &req); err != nil {
+			return errorMessage(msg.Ref, CodeBadRequest, err.Error())
+		}
+		todo, err := s.todoService.UpdateTodo(s.ctx, s.identity, msg.TodoID, &req)
+		return result(msg.Ref, todo, err)
+	case TypeToggle:
+		if msg.TodoID == 0 {
+			return errorMessage(msg.Ref, CodeBadRequest, "todo_id is required")
+		}
+		todo, err := s.todoService.ToggleTodoComplete(s.ctx, s.identity, msg.TodoID)
+		return result(msg.Ref, todo, err)
+	default:
+		return errorMessage(msg.Ref, CodeBadRequest, fmt.Sprintf("unknown message type %q", msg.Type))
+	}
+}
+
+func (s *session) subscribe(msg *ClientMessage) ServerMessage {
+	if msg.Subscription == "" {
+		return errorMessage(msg.Ref, CodeBadRequest, "subscription is required")
+	}
+	if p := msg.Filter.Priority; p != nil && *p != models.LOW && *p != models.MEDIUM && *p != models.HIGH {
+		return errorMessage(msg.Ref, CodeBadRequest, "priority must be one of: LOW MEDIUM HIGH")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.subscriptions[msg.Subscription]; !exists && len(s.subscriptions) >= maxSubscriptions {
+		return errorMessage(msg.Ref, CodeBadRequest, fmt.Sprintf("at most %d subscriptions per connection", maxSubscriptions))
+	}
+	// Subscribing again under the same name replaces the filter
+	s.subscriptions[msg.Subscription] = msg.Filter
+	return ServerMessage{Type: TypeAck, Ref: msg.Ref, Subscriptions: []string{msg.Subscription}}
+}
+
+func decodeData(data json.RawMessage, v interface{}) error {
+	if len(data) == 0 {
+		return fmt.Errorf("data is required")
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid data: %v", err)
+	}
+	return nil
+}
+
+// result turns a service call into an ack or an error message, mapping the
+// service errors the same way the REST controller does.
+func result(ref string, todo *dto.TodoResponse, err error) ServerMessage {
+	if err != nil {
+		switch {
+		case err.Error() == "todo not found":
+			return errorMessage(ref, CodeNotFound, "Todo not found")
+		case strings.HasPrefix(err.Error(), "forbidden"):
+			return errorMessage(ref, CodeForbidden, err.Error())
+		case strings.HasPrefix(err.Error(), "validation failed"):
+			return errorMessage(ref, CodeValidation, err.Error())
+		case strings.HasPrefix(err.Error(), "blocked"):
+			return errorMessage(ref, CodeConflict, err.Error())
+		default:
+			return errorMessage(ref, CodeInternal, err.Error())
+		}
+	}
+	return ServerMessage{Type: TypeAck, Ref: ref, Data: todo}
+}
+
+func errorMessage(ref, code, message string) ServerMessage {
+	return ServerMessage{Type: TypeError, Ref: ref, Code: code, Error: message}
+}
+
+func closeConn(conn *websocket.Conn, code int, reason string) {
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(writeWait))
+	conn.Close()
+}
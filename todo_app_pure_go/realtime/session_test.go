@@ -0,0 +1,218 @@
+package realtime
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"todo-app/dto"
+	"todo-app/events"
+	"todo-app/models"
+	"todo-app/repository"
+	"todo-app/service"
+	"todo-app/stream"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gorilla/websocket"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestServer(t *testing.T, limit Limiter) (*httptest.Server, *stream.Hub) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.Todo{}, &models.TodoChange{}, &models.TodoStatusChange{}, &models.TodoDependency{}, &models.Attachment{}, &models.Comment{}, &models.CommentRevision{}, &models.TodoGrant{}, &models.ListGrant{}); err != nil {
+		t.Fatal(err)
+	}
+
+	bus := events.NewBus()
+	hub := stream.NewHub(10)
+	bus.Subscribe(hub.Publish)
+	todoService := service.NewTodoService(repository.NewTodoRepository(db), repository.NewAccessRepository(db), nil, bus, nil)
+
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		Serve(r.Context(), conn, "", todoService, hub, limit)
+	}))
+	t.Cleanup(server.Close)
+	return server, hub
+}
+
+func dial(t *testing.T, server *httptest.Server) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+func send(t *testing.T, conn *websocket.Conn, msg string) {
+	t.Helper()
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func receive(t *testing.T, conn *websocket.Conn) ServerMessage {
+	t.Helper()
+	var msg ServerMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func TestMutationsAreBroadcastToMatchingSubscriptions(t *testing.T) {
+	server, _ := newTestServer(t, nil)
+	alice := dial(t, server)
+	bob := dial(t, server)
+
+	send(t, bob, `{"type":"subscribe","ref":"s1","subscription":"high","filter":{"priority":"HIGH"}}`)
+	send(t, bob, `{"type":"subscribe","ref":"s2","subscription":"all"}`)
+	for _, ref := range []string{"s1", "s2"} {
+		if ack := receive(t, bob); ack.Type != TypeAck || ack.Ref != ref {
+			t.Fatalf("expected ack for %s, got %+v", ref, ack)
+		}
+	}
+
+	send(t, alice, `{"type":"create","ref":"c1","data":{"title":"Ship it","priority":"HIGH"}}`)
+	ack := receive(t, alice)
+	if ack.Type != TypeAck || ack.Ref != "c1" {
+		t.Fatalf("expected ack, got %+v", ack)
+	}
+
+	event := receive(t, bob)
+	if event.Type != TypeEvent || event.Event.Type != events.TodoCreated || event.Event.Todo.Title != "Ship it" {
+		t.Fatalf("unexpected event: %+v", event)
+	}
+	if strings.Join(event.Subscriptions, ",") != "all,high" {
+		t.Errorf("expected both subscriptions to match, got %v", event.Subscriptions)
+	}
+
+	// A LOW todo only matches "all"
+	send(t, alice, `{"type":"create","ref":"c2","data":{"title":"Later","priority":"LOW"}}`)
+	receive(t, alice)
+	if event := receive(t, bob); strings.Join(event.Subscriptions, ",") != "all" {
+		t.Errorf("expected only all to match, got %v", event.Subscriptions)
+	}
+
+	// Alice never subscribed, so her next message is the toggle's ack
+	var created dto.TodoResponse
+	decode(t, ack.Data, &created)
+	send(t, alice, fmt.Sprintf(`{"type":"toggle","ref":"t1","todo_id":%d}`, created.ID))
+	if toggled := receive(t, alice); toggled.Type != TypeAck || toggled.Ref != "t1" {
+		t.Errorf("expected toggle ack, got %+v", toggled)
+	}
+	if event := receive(t, bob); event.Event.Type != events.TodoCompleted {
+		t.Errorf("expected todo.completed, got %s", event.Event.Type)
+	}
+}
+
+func TestMutationErrors(t *testing.T) {
+	server, _ := newTestServer(t, nil)
+	conn := dial(t, server)
+
+	cases := []struct {
+		msg  string
+		code string
+	}{
+		{`{"type":"create","ref":"1","data":{"title":""}}`, CodeValidation},
+		{`{"type":"update","ref":"2","todo_id":99,"data":{"title":"x"}}`, CodeNotFound},
+		{`{"type":"toggle","ref":"3"}`, CodeBadRequest},
+		{`{"type":"subscribe","ref":"4","subscription":"x","filter":{"priority":"URGENT"}}`, CodeBadRequest},
+		{`{"type":"explode","ref":"5"}`, CodeBadRequest},
+		{`not json`, CodeBadRequest},
+	}
+	for _, tc := range cases {
+		send(t, conn, tc.msg)
+		if reply := receive(t, conn); reply.Type != TypeError || reply.Code != tc.code {
+			t.Errorf("%s: expected %s error, got %+v", tc.msg, tc.code, reply)
+		}
+	}
+}
+
+func TestMutationsAreRateLimited(t *testing.T) {
+	var limited []string
+	server, _ := newTestServer(t, func(msgType string) (bool, time.Duration) {
+		limited = append(limited, msgType)
+		return len(limited) < 2, 1500 * time.Millisecond
+	})
+	conn := dial(t, server)
+
+	send(t, conn, `{"type":"create","ref":"1","data":{"title":"Buy milk"}}`)
+	if reply := receive(t, conn); reply.Type != TypeAck {
+		t.Fatalf("first create: %+v", reply)
+	}
+	send(t, conn, `{"type":"create","ref":"2","data":{"title":"Buy bread"}}`)
+	if reply := receive(t, conn); reply.Code != CodeRateLimited || reply.RetryAfter != 2 {
+		t.Errorf("expected rate_limited with retry_after 2, got %+v", reply)
+	}
+	// Subscriptions are not mutations
+	send(t, conn, `{"type":"subscribe","ref":"3","subscription":"all"}`)
+	if reply := receive(t, conn); reply.Type != TypeAck {
+		t.Errorf("subscribe: %+v", reply)
+	}
+	if strings.Join(limited, ",") != "create,create" {
+		t.Errorf("limited %v", limited)
+	}
+}
+
+func TestResultMapsServiceErrors(t *testing.T) {
+	cases := []struct {
+		err  error
+		code string
+	}{
+		{errors.New("todo not found"), CodeNotFound},
+		{errors.New("forbidden: editor role required"), CodeForbidden},
+		{errors.New("validation failed: title is required"), CodeValidation},
+		{errors.New("blocked: todo is blocked by 1 open todo(s)"), CodeConflict},
+		{errors.New("database is locked"), CodeInternal},
+	}
+	for _, tc := range cases {
+		if reply := result("1", nil, tc.err); reply.Type != TypeError || reply.Code != tc.code {
+			t.Errorf("%v: expected %s error, got %+v", tc.err, tc.code, reply)
+		}
+	}
+}
+
+func TestHubCloseDisconnectsClients(t *testing.T) {
+	server, hub := newTestServer(t, nil)
+	conn := dial(t, server)
+
+	// Wait until the session is registered with the hub
+	send(t, conn, `{"type":"subscribe","ref":"1","subscription":"all"}`)
+	receive(t, conn)
+
+	hub.Close()
+	_, _, err := conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("expected going away close, got %v", err)
+	}
+}
+
+// decode converts a generically decoded payload into v.
+func decode(t *testing.T, data interface{}, v interface{}) {
+	t.Helper()
+	raw, err := json.Marshal(data)
+	if err == nil {
+		err = json.Unmarshal(raw, v)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
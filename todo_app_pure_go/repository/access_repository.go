@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+	"todo-app/models"
+)
+
+type AccessRepository interface {
+	// RoleOf returns the most privileged role principal was granted on the
+	// todo, directly or through a share of its owner's list; "" if none.
+	RoleOf(ctx context.Context, principal string, todo *models.Todo) (models.Role, error)
+	// Audience returns every principal with a grant on the todo.
+	Audience(ctx context.Context, todo *models.Todo) ([]string, error)
+	GetTodoGrants(ctx context.Context, todoID uint) ([]*models.TodoGrant, error)
+	// GrantTodo creates the grant or changes the role of an existing one.
+	GrantTodo(ctx context.Context, todoID uint, principal string, role models.Role) (*models.TodoGrant, error)
+	RevokeTodo(ctx context.Context, todoID uint, principal string) error
+	GetListGrants(ctx context.Context, owner string) ([]*models.ListGrant, error)
+	// GrantList creates the grant or changes the role of an existing one.
+	GrantList(ctx context.Context, owner, principal string, role models.Role) (*models.ListGrant, error)
+	RevokeList(ctx context.Context, owner, principal string) error
+}
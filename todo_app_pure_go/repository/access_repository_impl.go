@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"todo-app/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AccessRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewAccessRepository(db *gorm.DB) AccessRepository {
+	return &AccessRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *AccessRepositoryImpl) RoleOf(ctx context.Context, principal string, todo *models.Todo) (models.Role, error) {
+	var roles []models.Role
+	err := r.db.WithContext(ctx).Model(&models.TodoGrant{}).
+		Where("todo_id = ? AND principal = ?", todo.ID, principal).
+		Pluck("role", &roles).Error
+	if err != nil {
+		return "", err
+	}
+	var listRoles []models.Role
+	err = r.db.WithContext(ctx).Model(&models.ListGrant{}).
+		Where("owner = ? AND principal = ?", todo.Owner, principal).
+		Pluck("role", &listRoles).Error
+	if err != nil {
+		return "", err
+	}
+
+	var role models.Role
+	for _, granted := range append(roles, listRoles...) {
+		role = role.Max(granted)
+	}
+	return role, nil
+}
+
+func (r *AccessRepositoryImpl) Audience(ctx context.Context, todo *models.Todo) ([]string, error) {
+	var principals []string
+	err := r.db.WithContext(ctx).Model(&models.TodoGrant{}).
+		Where("todo_id = ?", todo.ID).
+		Pluck("principal", &principals).Error
+	if err != nil {
+		return nil, err
+	}
+	var listPrincipals []string
+	err = r.db.WithContext(ctx).Model(&models.ListGrant{}).
+		Where("owner = ?", todo.Owner).
+		Pluck("principal", &listPrincipals).Error
+	if err != nil {
+		return nil, err
+	}
+	return append(principals, listPrincipals...), nil
+}
+
+func (r *AccessRepositoryImpl) GetTodoGrants(ctx context.Context, todoID uint) ([]*models.TodoGrant, error) {
+	var grants []*models.TodoGrant
+	if err := r.db.WithContext(ctx).Where("todo_id = ?", todoID).Order("principal").Find(&grants).Error; err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+func (r *AccessRepositoryImpl) GrantTodo(ctx context.Context, todoID uint, principal string, role models.Role) (*models.TodoGrant, error) {
+	grant := &models.TodoGrant{TodoID: todoID, Principal: principal, Role: role}
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "todo_id"}, {Name: "principal"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role", "updated_at"}),
+	}).Create(grant).Error
+	if err != nil {
+		return nil, err
+	}
+	// The upsert does not report the existing row's ID and creation time
+	if err := r.db.WithContext(ctx).Where("todo_id = ? AND principal = ?", todoID, principal).First(grant).Error; err != nil {
+		return nil, err
+	}
+	return grant, nil
+}
+
+func (r *AccessRepositoryImpl) RevokeTodo(ctx context.Context, todoID uint, principal string) error {
+	result := r.db.WithContext(ctx).Where("todo_id = ? AND principal = ?", todoID, principal).Delete(&models.TodoGrant{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("grant not found")
+	}
+	return nil
+}
+
+func (r *AccessRepositoryImpl) GetListGrants(ctx context.Context, owner string) ([]*models.ListGrant, error) {
+	var grants []*models.ListGrant
+	if err := r.db.WithContext(ctx).Where("owner = ?", owner).Order("principal").Find(&grants).Error; err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+func (r *AccessRepositoryImpl) GrantList(ctx context.Context, owner, principal string, role models.Role) (*models.ListGrant, error) {
+	grant := &models.ListGrant{Owner: owner, Principal: principal, Role: role}
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "workspace_id"}, {Name: "owner"}, {Name: "principal"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role", "updated_at"}),
+	}).Create(grant).Error
+	if err != nil {
+		return nil, err
+	}
+	// The upsert does not report the existing row's ID and creation time
+	if err := r.db.WithContext(ctx).Where("owner = ? AND principal = ?", owner, principal).First(grant).Error; err != nil {
+		return nil, err
+	}
+	return grant, nil
+}
+
+func (r *AccessRepositoryImpl) RevokeList(ctx context.Context, owner, principal string) error {
+	result := r.db.WithContext(ctx).Where("owner = ? AND principal = ?", owner, principal).Delete(&models.ListGrant{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("grant not found")
+	}
+	return nil
+}
+
+// visibleTo limits a todo query to the todos principal may see: those
+// without an owner, its own, and those shared with it directly or through
+// their owner's list. List grants are matched within the todo's workspace,
+// where owner names are unique.
+func visibleTo(principal string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(`(todos.owner = '' OR todos.owner = ? OR
+			EXISTS (SELECT 1 FROM todo_grants WHERE todo_grants.todo_id = todos.id AND todo_grants.principal = ?) OR
+			EXISTS (SELECT 1 FROM list_grants WHERE list_grants.workspace_id = todos.workspace_id AND list_grants.owner = todos.owner AND list_grants.principal = ?))`,
+			principal, principal, principal)
+	}
+}
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"todo-app/models"
+)
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *models.APIKey) (*models.APIKey, error)
+	GetByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+	GetAllByOwner(ctx context.Context, owner string) ([]*models.APIKey, error)
+	// Revoke marks the key revoked; revoking it again changes nothing.
+	Revoke(ctx context.Context, id uint, owner string, at time.Time) (*models.APIKey, error)
+	// Touch records that the key was used at the given time.
+	Touch(ctx context.Context, id uint, at time.Time) error
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"todo-app/models"
+
+	"gorm.io/gorm"
+)
+
+type APIKeyRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &APIKeyRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *APIKeyRepositoryImpl) Create(ctx context.Context, key *models.APIKey) (*models.APIKey, error) {
+	if err := r.db.WithContext(ctx).Create(key).Error; err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (r *APIKeyRepositoryImpl) GetByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := r.db.WithContext(ctx).Where("key_hash = ?", keyHash).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("api key not found")
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *APIKeyRepositoryImpl) GetAllByOwner(ctx context.Context, owner string) ([]*models.APIKey, error) {
+	var keys []*models.APIKey
+	if err := r.db.WithContext(ctx).Where("owner = ?", owner).Order("created_at DESC").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *APIKeyRepositoryImpl) Revoke(ctx context.Context, id uint, owner string, at time.Time) (*models.APIKey, error) {
+	db := r.db.WithContext(ctx)
+	var key models.APIKey
+	if err := db.Where("id = ? AND owner = ?", id, owner).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("api key not found")
+		}
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return &key, nil
+	}
+	if err := db.Model(&key).Update("revoked_at", at).Error; err != nil {
+		return nil, err
+	}
+	key.RevokedAt = &at
+	return &key, nil
+}
+
+func (r *APIKeyRepositoryImpl) Touch(ctx context.Context, id uint, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.APIKey{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+	"todo-app/models"
+)
+
+type AttachmentRepository interface {
+	Create(ctx context.Context, attachment *models.Attachment) (*models.Attachment, error)
+	// GetByID returns the attachment only if it belongs to the todo.
+	GetByID(ctx context.Context, todoID, id uint) (*models.Attachment, error)
+	GetAllByTodo(ctx context.Context, todoID uint) ([]*models.Attachment, error)
+	Delete(ctx context.Context, id uint) error
+	// IsReferenced reports whether any attachment uses the blob with digest.
+	IsReferenced(ctx context.Context, digest string) (bool, error)
+}
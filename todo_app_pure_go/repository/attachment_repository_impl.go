@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"todo-app/models"
+
+	"gorm.io/gorm"
+)
+
+type AttachmentRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewAttachmentRepository(db *gorm.DB) AttachmentRepository {
+	return &AttachmentRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *AttachmentRepositoryImpl) Create(ctx context.Context, attachment *models.Attachment) (*models.Attachment, error) {
+	if err := r.db.WithContext(ctx).Create(attachment).Error; err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+func (r *AttachmentRepositoryImpl) GetByID(ctx context.Context, todoID, id uint) (*models.Attachment, error) {
+	var attachment models.Attachment
+	if err := r.db.WithContext(ctx).Where("todo_id = ?", todoID).First(&attachment, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("attachment not found")
+		}
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+func (r *AttachmentRepositoryImpl) GetAllByTodo(ctx context.Context, todoID uint) ([]*models.Attachment, error) {
+	var attachments []*models.Attachment
+	if err := r.db.WithContext(ctx).Where("todo_id = ?", todoID).Order("created_at, id").Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+func (r *AttachmentRepositoryImpl) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.Attachment{}, id).Error
+}
+
+func (r *AttachmentRepositoryImpl) IsReferenced(ctx context.Context, digest string) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&models.Attachment{}).Where("sha256 = ?", digest).Limit(1).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
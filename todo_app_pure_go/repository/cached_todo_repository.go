@@ -0,0 +1,555 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"todo-app/models"
+	"todo-app/tenant"
+	"todo-app/workflow"
+)
+
+type CacheOptions struct {
+	// Size is the maximum number of cached lookups, lists and counts together.
+	Size int
+	// TTL bounds how long an entry is served, which also limits staleness
+	// after writes that bypass the decorator.
+	TTL time.Duration
+}
+
+// CacheStats are counters since the cache was created.
+type CacheStats struct {
+	Hits          uint64  `json:"hits"`
+	Misses        uint64  `json:"misses"`
+	HitRatio      float64 `json:"hit_ratio"`
+	Evictions     uint64  `json:"evictions"`
+	Invalidations uint64  `json:"invalidations"`
+	Entries       int     `json:"entries"`
+}
+
+type cacheKind uint8
+
+const (
+	cacheByID cacheKind = iota
+	cacheList
+	cacheCount
+)
+
+// todoFilter is the comparable form of the completed/blocked/priority
+// filters.
+type todoFilter struct {
+	hasCompleted bool
+	completed    bool
+	hasBlocked   bool
+	blocked      bool
+	hasPriority  bool
+	priority     models.Priority
+}
+
+func newTodoFilter(completed, blocked *bool, priority *models.Priority) todoFilter {
+	var f todoFilter
+	if completed != nil {
+		f.hasCompleted, f.completed = true, *completed
+	}
+	if blocked != nil {
+		f.hasBlocked, f.blocked = true, *blocked
+	}
+	if priority != nil {
+		f.hasPriority, f.priority = true, *priority
+	}
+	return f
+}
+
+// matches reports whether todo may be in lists with the filter. Whether a
+// todo is blocked depends on the todos blocking it, so a write to any todo
+// may change a list filtered by it.
+func (f todoFilter) matches(todo *models.Todo) bool {
+	if f.hasBlocked {
+		return true
+	}
+	return (!f.hasCompleted || f.completed == todo.Completed) &&
+		(!f.hasPriority || f.priority == todo.Priority)
+}
+
+type cacheKey struct {
+	kind      cacheKind
+	workspace uint
+	id        uint
+	// principal is the caller lists and counts were filtered for
+	principal     string
+	filter        todoFilter
+	sort          models.TodoSort
+	limit, offset int
+}
+
+type cacheValue struct {
+	todo  *models.Todo
+	todos []*models.Todo
+	count int64
+}
+
+// CachedTodoRepository is a read-through cache in front of another
+// TodoRepository. By-ID lookups and filtered lists and counts are cached;
+// a write drops the todo's own entry and every list and count whose filter
+// matched the todo before or after the write, leaving the rest untouched.
+// Entries are cached per workspace, and lists and counts per principal, as
+// each sees different todos. Lookups outside a single workspace, such as
+// background jobs, bypass the cache.
+// Errors, including "todo not found", are never cached.
+type CachedTodoRepository struct {
+	inner TodoRepository
+
+	mu      sync.Mutex
+	entries *lru[cacheKey, cacheValue]
+	// generation is bumped by every write; a lookup only stores its result
+	// if no write happened while it queried the inner repository.
+	generation uint64
+	stats      CacheStats
+}
+
+func NewCachedTodoRepository(inner TodoRepository, opts CacheOptions) *CachedTodoRepository {
+	return &CachedTodoRepository{
+		inner:   inner,
+		entries: newLRU[cacheKey, cacheValue](opts.Size, opts.TTL),
+	}
+}
+
+func (r *CachedTodoRepository) Stats() CacheStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := r.stats
+	stats.Entries = r.entries.len()
+	if lookups := stats.Hits + stats.Misses; lookups > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(lookups)
+	}
+	return stats
+}
+
+func (r *CachedTodoRepository) Create(ctx context.Context, todo *models.Todo) (*models.Todo, error) {
+	created, err := r.inner.Create(ctx, todo)
+	if err != nil {
+		return nil, err
+	}
+	r.invalidate(nil, created)
+	return created, nil
+}
+
+func (r *CachedTodoRepository) GetByID(ctx context.Context, id uint) (*models.Todo, error) {
+	workspace, ok := tenant.FromContext(ctx)
+	if !ok {
+		return r.inner.GetByID(ctx, id)
+	}
+	key := cacheKey{kind: cacheByID, workspace: workspace, id: id}
+	value, generation, ok := r.lookup(key)
+	if ok {
+		return cloneTodo(value.todo), nil
+	}
+
+	todo, err := r.inner.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	r.store(key, cacheValue{todo: cloneTodo(todo)}, generation)
+	return todo, nil
+}
+
+func (r *CachedTodoRepository) GetAll(ctx context.Context, principal string, completed, blocked *bool, priority *models.Priority, sort models.TodoSort, limit, offset int) ([]*models.Todo, error) {
+	workspace, ok := tenant.FromContext(ctx)
+	if !ok {
+		return r.inner.GetAll(ctx, principal, completed, blocked, priority, sort, limit, offset)
+	}
+	key := cacheKey{kind: cacheList, workspace: workspace, principal: principal, filter: newTodoFilter(completed, blocked, priority), sort: sort, limit: limit, offset: offset}
+	value, generation, ok := r.lookup(key)
+	if ok {
+		return cloneTodos(value.todos), nil
+	}
+
+	todos, err := r.inner.GetAll(ctx, principal, completed, blocked, priority, sort, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	r.store(key, cacheValue{todos: cloneTodos(todos)}, generation)
+	return todos, nil
+}
+
+func (r *CachedTodoRepository) GetTotalCount(ctx context.Context, principal string, completed, blocked *bool, priority *models.Priority) (int64, error) {
+	workspace, ok := tenant.FromContext(ctx)
+	if !ok {
+		return r.inner.GetTotalCount(ctx, principal, completed, blocked, priority)
+	}
+	key := cacheKey{kind: cacheCount, workspace: workspace, principal: principal, filter: newTodoFilter(completed, blocked, priority)}
+	value, generation, ok := r.lookup(key)
+	if ok {
+		return value.count, nil
+	}
+
+	count, err := r.inner.GetTotalCount(ctx, principal, completed, blocked, priority)
+	if err != nil {
+		return 0, err
+	}
+	r.store(key, cacheValue{count: count}, generation)
+	return count, nil
+}
+
+func (r *CachedTodoRepository) Update(ctx context.Context, id uint, todo *models.Todo) (*models.Todo, error) {
+	before, err := r.previous(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := r.inner.Update(ctx, id, todo)
+	if err != nil {
+		return nil, err
+	}
+	r.invalidate(before, updated)
+	return updated, nil
+}
+
+func (r *CachedTodoRepository) Delete(ctx context.Context, id uint) ([]string, error) {
+	before, err := r.previous(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	attachments, err := r.inner.Delete(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	r.invalidate(before, nil)
+	return attachments, nil
+}
+
+func (r *CachedTodoRepository) ToggleComplete(ctx context.Context, id uint, wf *workflow.Workflow) (*models.Todo, error) {
+	toggled, err := r.inner.ToggleComplete(ctx, id, wf)
+	if err != nil {
+		return nil, err
+	}
+	before := cloneTodo(toggled)
+	before.Completed = !toggled.Completed
+	r.invalidate(before, toggled)
+	return toggled, nil
+}
+
+// ForEach streams exports straight from the inner repository; they are rare
+// and unbounded, so caching them would only evict useful entries.
+func (r *CachedTodoRepository) ForEach(ctx context.Context, principal string, completed *bool, priority *models.Priority, fn func(todo *models.Todo) error) error {
+	return r.inner.ForEach(ctx, principal, completed, priority, fn)
+}
+
+func (r *CachedTodoRepository) CreateAll(ctx context.Context, todos []*models.Todo) error {
+	if err := r.inner.CreateAll(ctx, todos); err != nil {
+		return err
+	}
+	r.invalidate(nil, todos...)
+	return nil
+}
+
+func (r *CachedTodoRepository) Move(ctx context.Context, id uint, after, before *uint) (*models.Todo, error) {
+	moved, err := r.inner.Move(ctx, id, after, before)
+	if err != nil {
+		return nil, err
+	}
+	r.invalidate(nil, moved)
+	return moved, nil
+}
+
+// Rebalance may change the position of every todo in the workspace.
+func (r *CachedTodoRepository) Rebalance(ctx context.Context) error {
+	if err := r.inner.Rebalance(ctx); err != nil {
+		return err
+	}
+	r.forgetWorkspace(ctx)
+	return nil
+}
+
+// BackfillPositions runs at startup, before anything is cached.
+func (r *CachedTodoRepository) BackfillPositions(ctx context.Context) (int64, error) {
+	return r.inner.BackfillPositions(ctx)
+}
+
+// OpenBlockers is checked before completing a todo and must see every
+// write; it is not cached.
+func (r *CachedTodoRepository) OpenBlockers(ctx context.Context, id uint) ([]*models.Todo, error) {
+	return r.inner.OpenBlockers(ctx, id)
+}
+
+// StatusHistory is only read for a single todo on request; it is not cached.
+func (r *CachedTodoRepository) StatusHistory(ctx context.Context, id uint) ([]*models.TodoStatusChange, error) {
+	return r.inner.StatusHistory(ctx, id)
+}
+
+// BackfillStatus runs at startup, before anything is cached.
+func (r *CachedTodoRepository) BackfillStatus(ctx context.Context, open, done string) (int64, error) {
+	return r.inner.BackfillStatus(ctx, open, done)
+}
+
+// Count is only used to enforce quotas, which must see every write.
+func (r *CachedTodoRepository) Count(ctx context.Context) (int64, error) {
+	return r.inner.Count(ctx)
+}
+
+// WrapSync returns a SyncRepository that invalidates this cache for the
+// todos its Save writes, so sync updates are not hidden behind stale entries.
+func (r *CachedTodoRepository) WrapSync(syncRepo SyncRepository) SyncRepository {
+	return &cachedSyncRepository{SyncRepository: syncRepo, cache: r}
+}
+
+type cachedSyncRepository struct {
+	SyncRepository
+	cache *CachedTodoRepository
+}
+
+func (s *cachedSyncRepository) Save(ctx context.Context, todo *models.Todo) error {
+	before, err := s.cache.previous(ctx, todo.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.SyncRepository.Save(ctx, todo); err != nil {
+		return err
+	}
+	s.cache.invalidate(before, todo)
+	return nil
+}
+
+// WrapComments returns a CommentRepository that invalidates this cache for
+// the todo whose comment count a comment write changes.
+func (r *CachedTodoRepository) WrapComments(commentRepo CommentRepository) CommentRepository {
+	return &cachedCommentRepository{CommentRepository: commentRepo, cache: r}
+}
+
+type cachedCommentRepository struct {
+	CommentRepository
+	cache *CachedTodoRepository
+}
+
+func (c *cachedCommentRepository) Create(ctx context.Context, comment *models.Comment) (*models.Comment, error) {
+	created, err := c.CommentRepository.Create(ctx, comment)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.touch(ctx, comment.TodoID)
+	return created, nil
+}
+
+func (c *cachedCommentRepository) Delete(ctx context.Context, comment *models.Comment) error {
+	if err := c.CommentRepository.Delete(ctx, comment); err != nil {
+		return err
+	}
+	c.cache.touch(ctx, comment.TodoID)
+	return nil
+}
+
+// WrapDependencies returns a DependencyRepository that invalidates the
+// lists and counts filtered by whether todos are blocked when it adds or
+// removes a dependency.
+func (r *CachedTodoRepository) WrapDependencies(dependencyRepo DependencyRepository) DependencyRepository {
+	return &cachedDependencyRepository{DependencyRepository: dependencyRepo, cache: r}
+}
+
+type cachedDependencyRepository struct {
+	DependencyRepository
+	cache *CachedTodoRepository
+}
+
+func (d *cachedDependencyRepository) Add(ctx context.Context, todoID, blockerID uint) (*models.TodoDependency, error) {
+	dependency, err := d.DependencyRepository.Add(ctx, todoID, blockerID)
+	if err != nil {
+		return nil, err
+	}
+	d.cache.forgetBlocked(ctx)
+	return dependency, nil
+}
+
+func (d *cachedDependencyRepository) Remove(ctx context.Context, todoID, blockerID uint) error {
+	if err := d.DependencyRepository.Remove(ctx, todoID, blockerID); err != nil {
+		return err
+	}
+	d.cache.forgetBlocked(ctx)
+	return nil
+}
+
+// WrapAccess returns an AccessRepository that invalidates the lists and
+// counts of a principal whose grants it changes.
+func (r *CachedTodoRepository) WrapAccess(accessRepo AccessRepository) AccessRepository {
+	return &cachedAccessRepository{AccessRepository: accessRepo, cache: r}
+}
+
+type cachedAccessRepository struct {
+	AccessRepository
+	cache *CachedTodoRepository
+}
+
+func (a *cachedAccessRepository) GrantTodo(ctx context.Context, todoID uint, principal string, role models.Role) (*models.TodoGrant, error) {
+	grant, err := a.AccessRepository.GrantTodo(ctx, todoID, principal, role)
+	if err != nil {
+		return nil, err
+	}
+	a.cache.forgetPrincipal(ctx, principal)
+	return grant, nil
+}
+
+func (a *cachedAccessRepository) RevokeTodo(ctx context.Context, todoID uint, principal string) error {
+	if err := a.AccessRepository.RevokeTodo(ctx, todoID, principal); err != nil {
+		return err
+	}
+	a.cache.forgetPrincipal(ctx, principal)
+	return nil
+}
+
+func (a *cachedAccessRepository) GrantList(ctx context.Context, owner, principal string, role models.Role) (*models.ListGrant, error) {
+	grant, err := a.AccessRepository.GrantList(ctx, owner, principal, role)
+	if err != nil {
+		return nil, err
+	}
+	a.cache.forgetPrincipal(ctx, principal)
+	return grant, nil
+}
+
+func (a *cachedAccessRepository) RevokeList(ctx context.Context, owner, principal string) error {
+	if err := a.AccessRepository.RevokeList(ctx, owner, principal); err != nil {
+		return err
+	}
+	a.cache.forgetPrincipal(ctx, principal)
+	return nil
+}
+
+// forgetPrincipal invalidates the lists and counts cached for a principal
+// after the todos it can see changed. Principals are named per workspace.
+func (r *CachedTodoRepository) forgetPrincipal(ctx context.Context, principal string) {
+	workspace, _ := tenant.FromContext(ctx)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.generation++
+	r.stats.Invalidations += uint64(r.entries.removeIf(func(key cacheKey) bool {
+		return key.kind != cacheByID && key.workspace == workspace && key.principal == principal
+	}))
+}
+
+// forgetBlocked invalidates the lists and counts of the workspace in ctx
+// that are filtered by whether todos are blocked.
+func (r *CachedTodoRepository) forgetBlocked(ctx context.Context) {
+	workspace, _ := tenant.FromContext(ctx)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.generation++
+	r.stats.Invalidations += uint64(r.entries.removeIf(func(key cacheKey) bool {
+		return key.kind != cacheByID && key.workspace == workspace && key.filter.hasBlocked
+	}))
+}
+
+// forgetWorkspace invalidates every entry of the workspace in ctx.
+func (r *CachedTodoRepository) forgetWorkspace(ctx context.Context) {
+	workspace, _ := tenant.FromContext(ctx)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.generation++
+	r.stats.Invalidations += uint64(r.entries.removeIf(func(key cacheKey) bool {
+		// By-ID entries are keyed by workspace too
+		return key.workspace == workspace
+	}))
+}
+
+// touch invalidates the entries containing a todo after a change that does
+// not affect which filters it matches.
+func (r *CachedTodoRepository) touch(ctx context.Context, id uint) {
+	workspace, _ := tenant.FromContext(ctx)
+	// Without the todo's state every list might contain it
+	todo, _ := r.previous(ctx, id)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.generation++
+	r.stats.Invalidations += uint64(r.entries.removeIf(func(key cacheKey) bool {
+		if key.kind == cacheByID {
+			return key.id == id
+		}
+		return key.kind == cacheList && key.workspace == workspace && (todo == nil || key.filter.matches(todo))
+	}))
+}
+
+// previous returns the state of a todo before a write, preferring the
+// cached copy to avoid a query.
+func (r *CachedTodoRepository) previous(ctx context.Context, id uint) (*models.Todo, error) {
+	if workspace, ok := tenant.FromContext(ctx); ok {
+		r.mu.Lock()
+		value, ok := r.entries.get(cacheKey{kind: cacheByID, workspace: workspace, id: id})
+		r.mu.Unlock()
+		if ok {
+			return value.todo, nil
+		}
+	}
+	return r.inner.GetByID(ctx, id)
+}
+
+func (r *CachedTodoRepository) lookup(key cacheKey) (cacheValue, uint64, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	value, ok := r.entries.get(key)
+	if ok {
+		r.stats.Hits++
+	} else {
+		r.stats.Misses++
+	}
+	return value, r.generation, ok
+}
+
+func (r *CachedTodoRepository) store(key cacheKey, value cacheValue, generation uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if generation != r.generation {
+		return
+	}
+	if r.entries.put(key, value) {
+		r.stats.Evictions++
+	}
+}
+
+// invalidate drops the entries a write from before to after may have made
+// stale. Either side may be nil for creates and deletes.
+func (r *CachedTodoRepository) invalidate(before *models.Todo, after ...*models.Todo) {
+	states := append([]*models.Todo{before}, after...)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.generation++
+	removed := r.entries.removeIf(func(key cacheKey) bool {
+		for _, todo := range states {
+			if todo == nil {
+				continue
+			}
+			if key.kind == cacheByID && key.id == todo.ID {
+				return true
+			}
+			if key.kind != cacheByID && key.workspace == todo.WorkspaceID && key.filter.matches(todo) {
+				return true
+			}
+		}
+		return false
+	})
+	r.stats.Invalidations += uint64(removed)
+}
+
+// cloneTodo copies todo so callers cannot modify cached values. Pointer
+// fields are shared; they are replaced rather than written through.
+func cloneTodo(todo *models.Todo) *models.Todo {
+	clone := *todo
+	return &clone
+}
+
+func cloneTodos(todos []*models.Todo) []*models.Todo {
+	clones := make([]*models.Todo, len(todos))
+	for i, todo := range todos {
+		clones[i] = cloneTodo(todo)
+	}
+	return clones
+}
@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"todo-app/models"
+	"todo-app/tenant"
+	"todo-app/workflow"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// ctx is the workspace of the tests that only use one.
+var ctx = tenant.WithWorkspace(context.Background(), 1)
+
+// countingRepository counts the reads that reach the database.
+type countingRepository struct {
+	TodoRepository
+	reads int
+}
+
+func (r *countingRepository) GetByID(ctx context.Context, id uint) (*models.Todo, error) {
+	r.reads++
+	return r.TodoRepository.GetByID(ctx, id)
+}
+
+func (r *countingRepository) GetAll(ctx context.Context, principal string, completed, blocked *bool, priority *models.Priority, sort models.TodoSort, limit, offset int) ([]*models.Todo, error) {
+	r.reads++
+	return r.TodoRepository.GetAll(ctx, principal, completed, blocked, priority, sort, limit, offset)
+}
+
+func (r *countingRepository) GetTotalCount(ctx context.Context, principal string, completed, blocked *bool, priority *models.Priority) (int64, error) {
+	r.reads++
+	return r.TodoRepository.GetTotalCount(ctx, principal, completed, blocked, priority)
+}
+
+func newCachedTestRepository(t *testing.T, opts CacheOptions) (*CachedTodoRepository, *countingRepository, *gorm.DB) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(tenant.Plugin{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.Todo{}, &models.TodoChange{}, &models.TodoStatusChange{}, &models.TodoDependency{}, &models.Attachment{}, &models.Comment{}, &models.CommentRevision{}, &models.TodoGrant{}, &models.ListGrant{}); err != nil {
+		t.Fatal(err)
+	}
+	inner := &countingRepository{TodoRepository: NewTodoRepository(db)}
+	return NewCachedTodoRepository(inner, opts), inner, db
+}
+
+func TestCachedTodoRepositoryInvalidatesPrecisely(t *testing.T) {
+	cache, inner, _ := newCachedTestRepository(t, CacheOptions{Size: 100, TTL: time.Minute})
+
+	high, low := models.HIGH, models.LOW
+	done := true
+	a, _ := cache.Create(ctx, &models.Todo{Title: "a", Priority: models.HIGH})
+	cache.Create(ctx, &models.Todo{Title: "b", Priority: models.LOW})
+
+	// Warm the cache, then read everything again from memory
+	read := func() {
+		cache.GetByID(ctx, a.ID)
+		cache.GetAll(ctx, "", nil, nil, &high, "", 10, 0)
+		cache.GetAll(ctx, "", nil, nil, &low, "", 10, 0)
+		cache.GetTotalCount(ctx, "", &done, nil, nil)
+	}
+	read()
+	read()
+	if inner.reads != 4 {
+		t.Fatalf("reads = %d, want 4", inner.reads)
+	}
+
+	// Mutating a returned todo must not change the cached copy
+	got, _ := cache.GetByID(ctx, a.ID)
+	got.Title = "changed"
+	if again, _ := cache.GetByID(ctx, a.ID); again.Title != "a" {
+		t.Fatalf("cached todo was modified through a returned copy: %q", again.Title)
+	}
+
+	// Completing a HIGH todo touches its own entry, the HIGH list and the
+	// completed count; the LOW list stays cached
+	inner.reads = 0
+	if _, err := cache.ToggleComplete(ctx, a.ID, workflow.Default()); err != nil {
+		t.Fatal(err)
+	}
+	read()
+	if inner.reads != 3 {
+		t.Errorf("reads after toggle = %d, want 3", inner.reads)
+	}
+	if count, _ := cache.GetTotalCount(ctx, "", &done, nil, nil); count != 1 {
+		t.Errorf("completed count = %d, want 1", count)
+	}
+
+	// Moving a todo from HIGH to LOW invalidates both lists
+	inner.reads = 0
+	todo, _ := cache.GetByID(ctx, a.ID)
+	todo.Priority = models.LOW
+	if _, err := cache.Update(ctx, a.ID, todo); err != nil {
+		t.Fatal(err)
+	}
+	if todos, _ := cache.GetAll(ctx, "", nil, nil, &low, "", 10, 0); len(todos) != 2 {
+		t.Errorf("LOW list has %d todos, want 2", len(todos))
+	}
+	if todos, _ := cache.GetAll(ctx, "", nil, nil, &high, "", 10, 0); len(todos) != 0 {
+		t.Errorf("HIGH list has %d todos, want 0", len(todos))
+	}
+
+	if _, err := cache.Delete(ctx, a.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.GetByID(ctx, a.ID); err == nil || err.Error() != "todo not found" {
+		t.Errorf("GetByID after delete: %v", err)
+	}
+
+	stats := cache.Stats()
+	if stats.Hits == 0 || stats.Misses == 0 || stats.Invalidations == 0 || stats.HitRatio <= 0 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestCachedTodoRepositoryInvalidatesSyncSaves(t *testing.T) {
+	cache, _, db := newCachedTestRepository(t, CacheOptions{Size: 100, TTL: time.Minute})
+	syncRepo := cache.WrapSync(NewSyncRepository(db))
+
+	todo, _ := cache.Create(ctx, &models.Todo{Title: "before", Priority: models.MEDIUM})
+	cache.GetByID(ctx, todo.ID)
+
+	todo.Title = "after"
+	if err := syncRepo.Save(ctx, todo); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := cache.GetByID(ctx, todo.ID); got.Title != "after" {
+		t.Errorf("title = %q, want the synced title", got.Title)
+	}
+}
+
+func TestCachedTodoRepositoryKeepsWorkspacesApart(t *testing.T) {
+	cache, _, _ := newCachedTestRepository(t, CacheOptions{Size: 100, TTL: time.Minute})
+	other := tenant.WithWorkspace(context.Background(), 2)
+
+	todo, _ := cache.Create(ctx, &models.Todo{Title: "a", Priority: models.HIGH})
+	cache.GetByID(ctx, todo.ID)
+	cache.GetAll(ctx, "", nil, nil, nil, "", 10, 0)
+	cache.GetTotalCount(ctx, "", nil, nil, nil)
+
+	// The same lookups from another workspace must not be served the entries
+	if _, err := cache.GetByID(other, todo.ID); err == nil || err.Error() != "todo not found" {
+		t.Errorf("GetByID from another workspace: %v", err)
+	}
+	if todos, _ := cache.GetAll(other, "", nil, nil, nil, "", 10, 0); len(todos) != 0 {
+		t.Errorf("GetAll from another workspace = %d todos", len(todos))
+	}
+	if count, _ := cache.GetTotalCount(other, "", nil, nil, nil); count != 0 {
+		t.Errorf("GetTotalCount from another workspace = %d", count)
+	}
+
+	// Writes in another workspace leave the entries alone and the todo too
+	cache.Create(other, &models.Todo{Title: "b", Priority: models.LOW})
+	if _, err := cache.Delete(other, todo.ID); err == nil {
+		t.Error("deleted a todo of another workspace")
+	}
+	if count, _ := cache.GetTotalCount(ctx, "", nil, nil, nil); count != 1 {
+		t.Errorf("GetTotalCount = %d, want 1", count)
+	}
+}
+
+func TestLRUEvictsAndExpires(t *testing.T) {
+	now := time.Now()
+	c := newLRU[int, string](2, time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.put(1, "one")
+	c.put(2, "two")
+	c.get(1)
+	if evicted := c.put(3, "three"); !evicted {
+		t.Fatal("expected an eviction")
+	}
+	if _, ok := c.get(2); ok {
+		t.Error("least recently used entry was not evicted")
+	}
+	if _, ok := c.get(1); !ok {
+		t.Error("recently used entry was evicted")
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := c.get(1); ok {
+		t.Error("expired entry was served")
+	}
+	if c.len() != 1 {
+		t.Errorf("len = %d, want 1", c.len())
+	}
+}
@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"context"
+	"todo-app/models"
+)
+
+type CalendarFeedRepository interface {
+	Create(ctx context.Context, feed *models.CalendarFeed) (*models.CalendarFeed, error)
+	GetByTokenHash(ctx context.Context, tokenHash string) (*models.CalendarFeed, error)
+	GetAllByOwner(ctx context.Context, owner string) ([]*models.CalendarFeed, error)
+	Delete(ctx context.Context, id uint, owner string) error
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"todo-app/models"
+
+	"gorm.io/gorm"
+)
+
+type CalendarFeedRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewCalendarFeedRepository(db *gorm.DB) CalendarFeedRepository {
+	return &CalendarFeedRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *CalendarFeedRepositoryImpl) Create(ctx context.Context, feed *models.CalendarFeed) (*models.CalendarFeed, error) {
+	if err := r.db.WithContext(ctx).Create(feed).Error; err != nil {
+		return nil, err
+	}
+	return feed, nil
+}
+
+func (r *CalendarFeedRepositoryImpl) GetByTokenHash(ctx context.Context, tokenHash string) (*models.CalendarFeed, error) {
+	var feed models.CalendarFeed
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&feed).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("calendar feed not found")
+		}
+		return nil, err
+	}
+	return &feed, nil
+}
+
+func (r *CalendarFeedRepositoryImpl) GetAllByOwner(ctx context.Context, owner string) ([]*models.CalendarFeed, error) {
+	var feeds []*models.CalendarFeed
+	if err := r.db.WithContext(ctx).Where("owner = ?", owner).Order("created_at DESC").Find(&feeds).Error; err != nil {
+		return nil, err
+	}
+	return feeds, nil
+}
+
+func (r *CalendarFeedRepositoryImpl) Delete(ctx context.Context, id uint, owner string) error {
+	result := r.db.WithContext(ctx).Where("id = ? AND owner = ?", id, owner).Delete(&models.CalendarFeed{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("calendar feed not found")
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+	"todo-app/models"
+)
+
+type CommentRepository interface {
+	// Create inserts the comment and counts it on its todo.
+	Create(ctx context.Context, comment *models.Comment) (*models.Comment, error)
+	// GetByID returns the comment only if it belongs to the todo.
+	GetByID(ctx context.Context, todoID, id uint) (*models.Comment, error)
+	// GetAllByTodo returns every comment of the todo, oldest first.
+	GetAllByTodo(ctx context.Context, todoID uint) ([]*models.Comment, error)
+	// Update saves a new body and keeps previousBody as a revision.
+	Update(ctx context.Context, comment *models.Comment, previousBody string) (*models.Comment, error)
+	// Delete removes the comment, or only clears it while it has replies,
+	// and no longer counts it on its todo. Cleared ancestors left without
+	// replies are removed too.
+	Delete(ctx context.Context, comment *models.Comment) error
+	// GetRevisions returns the earlier bodies of a comment, oldest first.
+	GetRevisions(ctx context.Context, commentID uint) ([]*models.CommentRevision, error)
+}
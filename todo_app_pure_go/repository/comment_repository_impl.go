@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"todo-app/models"
+
+	"gorm.io/gorm"
+)
+
+type CommentRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewCommentRepository(db *gorm.DB) CommentRepository {
+	return &CommentRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *CommentRepositoryImpl) Create(ctx context.Context, comment *models.Comment) (*models.Comment, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(comment).Error; err != nil {
+			return err
+		}
+		return adjustCommentCount(tx, comment.TodoID, 1)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+func (r *CommentRepositoryImpl) GetByID(ctx context.Context, todoID, id uint) (*models.Comment, error) {
+	var comment models.Comment
+	if err := r.db.WithContext(ctx).Where("todo_id = ?", todoID).First(&comment, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("comment not found")
+		}
+		return nil, err
+	}
+	return &comment, nil
+}
+
+func (r *CommentRepositoryImpl) GetAllByTodo(ctx context.Context, todoID uint) ([]*models.Comment, error) {
+	var comments []*models.Comment
+	if err := r.db.WithContext(ctx).Where("todo_id = ?", todoID).Order("created_at, id").Find(&comments).Error; err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+func (r *CommentRepositoryImpl) Update(ctx context.Context, comment *models.Comment, previousBody string) (*models.Comment, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&models.CommentRevision{CommentID: comment.ID, Body: previousBody}).Error; err != nil {
+			return err
+		}
+		return tx.Model(comment).Select("body", "edited_at").Updates(comment).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+func (r *CommentRepositoryImpl) Delete(ctx context.Context, comment *models.Comment) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := adjustCommentCount(tx, comment.TodoID, -1); err != nil {
+			return err
+		}
+
+		hasReplies, err := commentHasReplies(tx, comment.ID)
+		if err != nil {
+			return err
+		}
+		if hasReplies {
+			// The edit history would still reveal the removed body
+			if err := tx.Where("comment_id = ?", comment.ID).Delete(&models.CommentRevision{}).Error; err != nil {
+				return err
+			}
+			return tx.Model(comment).Select("deleted", "body").
+				Updates(&models.Comment{Deleted: true, Body: ""}).Error
+		}
+		if err := deleteComment(tx, comment.ID); err != nil {
+			return err
+		}
+
+		// Walk up through cleared ancestors that only existed for this reply
+		for parentID := comment.ParentID; parentID != nil; {
+			var parent models.Comment
+			if err := tx.First(&parent, *parentID).Error; err != nil {
+				return err
+			}
+			if !parent.Deleted {
+				return nil
+			}
+			if hasReplies, err := commentHasReplies(tx, parent.ID); err != nil || hasReplies {
+				return err
+			}
+			if err := deleteComment(tx, parent.ID); err != nil {
+				return err
+			}
+			parentID = parent.ParentID
+		}
+		return nil
+	})
+}
+
+func (r *CommentRepositoryImpl) GetRevisions(ctx context.Context, commentID uint) ([]*models.CommentRevision, error) {
+	var revisions []*models.CommentRevision
+	if err := r.db.WithContext(ctx).Where("comment_id = ?", commentID).Order("created_at, id").Find(&revisions).Error; err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+func commentHasReplies(tx *gorm.DB, id uint) (bool, error) {
+	var count int64
+	err := tx.Model(&models.Comment{}).Where("parent_id = ?", id).Limit(1).Count(&count).Error
+	return count > 0, err
+}
+
+func deleteComment(tx *gorm.DB, id uint) error {
+	if err := tx.Where("comment_id = ?", id).Delete(&models.CommentRevision{}).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&models.Comment{}, id).Error
+}
+
+// adjustCommentCount changes the comment count of a todo without touching
+// its other columns or updated_at.
+func adjustCommentCount(tx *gorm.DB, todoID uint, delta int) error {
+	return tx.Exec("UPDATE todos SET comment_count = comment_count + ? WHERE id = ?", delta, todoID).Error
+}
+
+// deleteTodoComments removes every comment of a todo with its revisions. It
+// must run in the transaction that deletes the todo.
+func deleteTodoComments(tx *gorm.DB, todoID uint) error {
+	comments := tx.Model(&models.Comment{}).Select("id").Where("todo_id = ?", todoID)
+	if err := tx.Where("comment_id IN (?)", comments).Delete(&models.CommentRevision{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("todo_id = ?", todoID).Delete(&models.Comment{}).Error
+}
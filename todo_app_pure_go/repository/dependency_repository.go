@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"todo-app/models"
+)
+
+// DependencyRepository stores which todos block which. Lookups only return
+// the todos visible to principal.
+type DependencyRepository interface {
+	// Add records that todoID is blocked by blockerID. Adding a dependency
+	// that exists changes nothing; one that would close a cycle fails with
+	// an error starting with "dependency cycle".
+	Add(ctx context.Context, todoID, blockerID uint) (*models.TodoDependency, error)
+	Remove(ctx context.Context, todoID, blockerID uint) error
+	// ForTodo returns the todos blocking the todo and the todos it blocks.
+	ForTodo(ctx context.Context, principal string, id uint) (blockers, blocked []*models.Todo, err error)
+	// Graph returns every dependency between todos visible to principal,
+	// and those todos.
+	Graph(ctx context.Context, principal string) ([]*models.Todo, []*models.TodoDependency, error)
+}
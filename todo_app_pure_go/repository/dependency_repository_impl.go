@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"todo-app/models"
+
+	"gorm.io/gorm"
+)
+
+// dependencyLock is the Postgres advisory lock key that serialises
+// dependency inserts, so two concurrent inserts cannot each miss the other
+// and close a cycle together.
+const dependencyLock = 0x64657073
+
+type DependencyRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewDependencyRepository(db *gorm.DB) DependencyRepository {
+	return &DependencyRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *DependencyRepositoryImpl) Add(ctx context.Context, todoID, blockerID uint) (*models.TodoDependency, error) {
+	dependency := &models.TodoDependency{TodoID: todoID, BlockerID: blockerID}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if tx.Dialector.Name() == "postgres" {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", dependencyLock).Error; err != nil {
+				return err
+			}
+		}
+		err := tx.Where("todo_id = ? AND blocker_id = ?", todoID, blockerID).First(dependency).Error
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		// The new dependency closes a cycle if the blocker already waits,
+		// directly or not, for the todo. The todos in between may be
+		// invisible to the caller, so the error does not name them.
+		cycle, err := waitsFor(tx, blockerID, todoID)
+		if err != nil {
+			return err
+		}
+		if cycle {
+			return fmt.Errorf("dependency cycle: todo %d already blocks todo %d, directly or through other todos", todoID, blockerID)
+		}
+		return tx.Create(dependency).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dependency, nil
+}
+
+func (r *DependencyRepositoryImpl) Remove(ctx context.Context, todoID, blockerID uint) error {
+	result := r.db.WithContext(ctx).Where("todo_id = ? AND blocker_id = ?", todoID, blockerID).Delete(&models.TodoDependency{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("dependency not found")
+	}
+	return nil
+}
+
+func (r *DependencyRepositoryImpl) ForTodo(ctx context.Context, principal string, id uint) ([]*models.Todo, []*models.Todo, error) {
+	var blockers, blocked []*models.Todo
+	db := r.db.WithContext(ctx)
+	if err := db.Scopes(visibleTo(principal)).
+		Where("id IN (?)", db.Model(&models.TodoDependency{}).Select("blocker_id").Where("todo_id = ?", id)).
+		Order("id").Find(&blockers).Error; err != nil {
+		return nil, nil, err
+	}
+	if err := db.Scopes(visibleTo(principal)).
+		Where("id IN (?)", db.Model(&models.TodoDependency{}).Select("todo_id").Where("blocker_id = ?", id)).
+		Order("id").Find(&blocked).Error; err != nil {
+		return nil, nil, err
+	}
+	return blockers, blocked, nil
+}
+
+func (r *DependencyRepositoryImpl) Graph(ctx context.Context, principal string) ([]*models.Todo, []*models.TodoDependency, error) {
+	var dependencies []*models.TodoDependency
+	if err := r.db.WithContext(ctx).Order("todo_id, blocker_id").Find(&dependencies).Error; err != nil {
+		return nil, nil, err
+	}
+	if len(dependencies) == 0 {
+		return []*models.Todo{}, dependencies, nil
+	}
+
+	linked := make([]uint, 0, 2*len(dependencies))
+	for _, d := range dependencies {
+		linked = append(linked, d.TodoID, d.BlockerID)
+	}
+	var todos []*models.Todo
+	if err := r.db.WithContext(ctx).Scopes(visibleTo(principal)).Where("id IN ?", linked).Order("id").Find(&todos).Error; err != nil {
+		return nil, nil, err
+	}
+
+	// Leave out dependencies on todos the principal cannot see, and the
+	// todos left without any
+	visible := make(map[uint]bool, len(todos))
+	for _, todo := range todos {
+		visible[todo.ID] = true
+	}
+	shown := dependencies[:0]
+	connected := make(map[uint]bool, len(todos))
+	for _, d := range dependencies {
+		if visible[d.TodoID] && visible[d.BlockerID] {
+			shown = append(shown, d)
+			connected[d.TodoID], connected[d.BlockerID] = true, true
+		}
+	}
+	nodes := todos[:0]
+	for _, todo := range todos {
+		if connected[todo.ID] {
+			nodes = append(nodes, todo)
+		}
+	}
+	return nodes, shown, nil
+}
+
+// waitsFor reports whether "from" is blocked by "to", directly or through
+// other todos.
+func waitsFor(tx *gorm.DB, from, to uint) (bool, error) {
+	if from == to {
+		return true, nil
+	}
+	// Breadth-first over the blockers
+	seen := map[uint]bool{from: true}
+	frontier := []uint{from}
+	for len(frontier) > 0 {
+		var blockers []uint
+		if err := tx.Model(&models.TodoDependency{}).Where("todo_id IN ?", frontier).Pluck("blocker_id", &blockers).Error; err != nil {
+			return false, err
+		}
+		frontier = frontier[:0]
+		for _, blocker := range blockers {
+			if blocker == to {
+				return true, nil
+			}
+			if !seen[blocker] {
+				seen[blocker] = true
+				frontier = append(frontier, blocker)
+			}
+		}
+	}
+	return false, nil
+}
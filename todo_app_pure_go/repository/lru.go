@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"container/list"
+	"time"
+)
+
+// lru is a size-bounded least-recently-used map whose entries expire after a
+// fixed TTL. It is not safe for concurrent use.
+type lru[K comparable, V any] struct {
+	size    int
+	ttl     time.Duration
+	order   *list.List // front is most recently used
+	entries map[K]*list.Element
+	now     func() time.Time
+}
+
+type lruEntry[K comparable, V any] struct {
+	key       K
+	value     V
+	expiresAt time.Time
+}
+
+func newLRU[K comparable, V any](size int, ttl time.Duration) *lru[K, V] {
+	return &lru[K, V]{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[K]*list.Element, size),
+		now:     time.Now,
+	}
+}
+
+// get returns the value for key unless it is missing or expired.
+func (c *lru[K, V]) get(key K) (V, bool) {
+	elem, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	entry := elem.Value.(*lruEntry[K, V])
+	if !c.now().Before(entry.expiresAt) {
+		c.removeElement(elem)
+		var zero V
+		return zero, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.value, true
+}
+
+// put stores value under key and reports whether another entry was evicted
+// to make room.
+func (c *lru[K, V]) put(key K, value V) bool {
+	expiresAt := c.now().Add(c.ttl)
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*lruEntry[K, V])
+		entry.value, entry.expiresAt = value, expiresAt
+		c.order.MoveToFront(elem)
+		return false
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry[K, V]{key: key, value: value, expiresAt: expiresAt})
+	if c.order.Len() <= c.size {
+		return false
+	}
+	c.removeElement(c.order.Back())
+	return true
+}
+
+func (c *lru[K, V]) remove(key K) bool {
+	elem, ok := c.entries[key]
+	if ok {
+		c.removeElement(elem)
+	}
+	return ok
+}
+
+// removeIf deletes every entry whose key matches and returns how many were
+// deleted.
+func (c *lru[K, V]) removeIf(match func(key K) bool) int {
+	removed := 0
+	for key, elem := range c.entries {
+		if match(key) {
+			c.removeElement(elem)
+			removed++
+		}
+	}
+	return removed
+}
+
+func (c *lru[K, V]) len() int {
+	return c.order.Len()
+}
+
+func (c *lru[K, V]) removeElement(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*lruEntry[K, V]).key)
+}
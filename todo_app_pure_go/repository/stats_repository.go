@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"todo-app/models"
+)
+
+type PriorityCount struct {
+	Priority  models.Priority
+	Total     int64
+	Completed int64
+}
+
+// BucketCount is the number of todos in one day or week, identified by the
+// date (YYYY-MM-DD, UTC) it starts on.
+type BucketCount struct {
+	Bucket string
+	Count  int64
+}
+
+// StatsRepository computes todo statistics with aggregate queries over the
+// todos visible to principal.
+type StatsRepository interface {
+	CountByPriority(ctx context.Context, principal string) ([]PriorityCount, error)
+	// AverageCompletionSeconds is the mean time from creation to completion
+	// of todos completed in [from, to); nil when there are none.
+	AverageCompletionSeconds(ctx context.Context, principal string, from, to time.Time) (*float64, error)
+	// CreatedPerBucket counts todos created in [from, to) per day, or per
+	// ISO week starting on Monday when week is set.
+	CreatedPerBucket(ctx context.Context, principal string, from, to time.Time, week bool) ([]BucketCount, error)
+	// CompletedPerBucket is CreatedPerBucket for completion times.
+	CompletedPerBucket(ctx context.Context, principal string, from, to time.Time, week bool) ([]BucketCount, error)
+	OldestOpen(ctx context.Context, principal string, limit int) ([]*models.Todo, error)
+	// BackfillCompletedAt sets completed_at to the last update for todos
+	// completed before completed_at was recorded.
+	BackfillCompletedAt(ctx context.Context) (int64, error)
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"todo-app/models"
+
+	"gorm.io/gorm"
+)
+
+type StatsRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewStatsRepository(db *gorm.DB) StatsRepository {
+	return &StatsRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *StatsRepositoryImpl) CountByPriority(ctx context.Context, principal string) ([]PriorityCount, error) {
+	var counts []PriorityCount
+	err := r.db.WithContext(ctx).Model(&models.Todo{}).Scopes(visibleTo(principal)).
+		Select("priority, COUNT(*) AS total, SUM(CASE WHEN completed THEN 1 ELSE 0 END) AS completed").
+		Group("priority").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+func (r *StatsRepositoryImpl) AverageCompletionSeconds(ctx context.Context, principal string, from, to time.Time) (*float64, error) {
+	duration := "(julianday(completed_at) - julianday(created_at)) * 86400"
+	if r.isPostgres() {
+		duration = "EXTRACT(EPOCH FROM (completed_at - created_at))"
+	}
+
+	var avg *float64
+	err := r.db.WithContext(ctx).Model(&models.Todo{}).Scopes(visibleTo(principal)).
+		Select("AVG("+duration+")").
+		Where("completed_at >= ? AND completed_at < ?", from, to).
+		Scan(&avg).Error
+	if err != nil {
+		return nil, err
+	}
+	return avg, nil
+}
+
+func (r *StatsRepositoryImpl) CreatedPerBucket(ctx context.Context, principal string, from, to time.Time, week bool) ([]BucketCount, error) {
+	return r.countPerBucket(ctx, principal, "created_at", from, to, week)
+}
+
+func (r *StatsRepositoryImpl) CompletedPerBucket(ctx context.Context, principal string, from, to time.Time, week bool) ([]BucketCount, error) {
+	return r.countPerBucket(ctx, principal, "completed_at", from, to, week)
+}
+
+func (r *StatsRepositoryImpl) countPerBucket(ctx context.Context, principal, column string, from, to time.Time, week bool) ([]BucketCount, error) {
+	bucket := r.bucketExpr(column, week)
+
+	var counts []BucketCount
+	err := r.db.WithContext(ctx).Model(&models.Todo{}).Scopes(visibleTo(principal)).
+		Select(bucket+" AS bucket, COUNT(*) AS count").
+		Where(column+" >= ? AND "+column+" < ?", from, to).
+		Group(bucket).
+		Order("bucket").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// bucketExpr formats the start of the day or week a timestamp falls in as
+// YYYY-MM-DD. Postgres sessions run in UTC (see ConnectDatabase) and SQLite
+// date functions convert to UTC.
+func (r *StatsRepositoryImpl) bucketExpr(column string, week bool) string {
+	if r.isPostgres() {
+		unit := "day"
+		if week {
+			unit = "week"
+		}
+		return fmt.Sprintf("to_char(date_trunc('%s', %s), 'YYYY-MM-DD')", unit, column)
+	}
+	if week {
+		// Forward to Sunday (or stay on it), then back to that week's Monday
+		return fmt.Sprintf("strftime('%%Y-%%m-%%d', %s, 'weekday 0', '-6 days')", column)
+	}
+	return fmt.Sprintf("strftime('%%Y-%%m-%%d', %s)", column)
+}
+
+func (r *StatsRepositoryImpl) OldestOpen(ctx context.Context, principal string, limit int) ([]*models.Todo, error) {
+	var todos []*models.Todo
+	if err := r.db.WithContext(ctx).Model(&models.Todo{}).Scopes(visibleTo(principal)).Where("completed = ?", false).Order("created_at, id").Limit(limit).Find(&todos).Error; err != nil {
+		return nil, err
+	}
+	return todos, nil
+}
+
+func (r *StatsRepositoryImpl) BackfillCompletedAt(ctx context.Context) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&models.Todo{}).
+		Where("completed = ? AND completed_at IS NULL", true).
+		UpdateColumn("completed_at", gorm.Expr("updated_at"))
+	return result.RowsAffected, result.Error
+}
+
+func (r *StatsRepositoryImpl) isPostgres() bool {
+	return r.db.Dialector.Name() == "postgres"
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"todo-app/models"
+)
+
+// SyncRepository reads the todo change log for delta sync. Entries are
+// written by TodoRepository in the same transaction as the change itself.
+type SyncRepository interface {
+	// ChangesSince returns up to limit change log entries with a sequence
+	// number greater than seq, oldest first.
+	ChangesSince(ctx context.Context, seq uint64, limit int) ([]*models.TodoChange, error)
+	// GetByIDs returns the todos with the given IDs that principal can see;
+	// other IDs are skipped.
+	GetByIDs(ctx context.Context, principal string, ids []uint) ([]*models.Todo, error)
+	// VisibleTombstones returns the tombstones among changes of todos
+	// principal could see when they were deleted.
+	VisibleTombstones(ctx context.Context, principal string, changes []*models.TodoChange) ([]*models.TodoChange, error)
+	GetByClientID(ctx context.Context, clientID string) (*models.Todo, error)
+	// HasTombstone reports whether the todo with id was deleted.
+	HasTombstone(ctx context.Context, id uint) (bool, error)
+	// Save writes every field of todo, including zero values, and records
+	// the change.
+	Save(ctx context.Context, todo *models.Todo) error
+	// Backfill records a change for every todo that has none, so todos
+	// created before the change log existed are part of a full sync. It
+	// covers every workspace.
+	Backfill(ctx context.Context) error
+	// PruneTombstones deletes tombstones older than before.
+	PruneTombstones(ctx context.Context, before time.Time) (int64, error)
+}
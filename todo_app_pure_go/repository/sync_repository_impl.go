@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+	"todo-app/models"
+	"todo-app/rank"
+
+	"gorm.io/gorm"
+)
+
+// changeLogLock is the Postgres advisory lock key that serialises change log
+// writes, so sequence numbers become visible in commit order and a sync
+// token never skips a change that committed late.
+const changeLogLock = 0x746f646f
+
+type SyncRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewSyncRepository(db *gorm.DB) SyncRepository {
+	return &SyncRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *SyncRepositoryImpl) ChangesSince(ctx context.Context, seq uint64, limit int) ([]*models.TodoChange, error) {
+	var changes []*models.TodoChange
+	if err := r.db.WithContext(ctx).Where("seq > ?", seq).Order("seq").Limit(limit).Find(&changes).Error; err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+func (r *SyncRepositoryImpl) GetByIDs(ctx context.Context, principal string, ids []uint) ([]*models.Todo, error) {
+	var todos []*models.Todo
+	if len(ids) == 0 {
+		return todos, nil
+	}
+	if err := r.db.WithContext(ctx).Model(&models.Todo{}).Scopes(visibleTo(principal)).Where("id IN ?", ids).Find(&todos).Error; err != nil {
+		return nil, err
+	}
+	return todos, nil
+}
+
+func (r *SyncRepositoryImpl) VisibleTombstones(ctx context.Context, principal string, changes []*models.TodoChange) ([]*models.TodoChange, error) {
+	// The same rules as visibleTo, with the grants recorded on the tombstone
+	var owners []string
+	if err := r.db.WithContext(ctx).Model(&models.ListGrant{}).Where("principal = ?", principal).Pluck("owner", &owners).Error; err != nil {
+		return nil, err
+	}
+	listOwners := make(map[string]bool, len(owners))
+	for _, owner := range owners {
+		listOwners[owner] = true
+	}
+
+	var visible []*models.TodoChange
+	for _, change := range changes {
+		if !change.Deleted {
+			continue
+		}
+		if change.Owner == "" || change.Owner == principal || listOwners[change.Owner] || slices.Contains(change.Viewers, principal) {
+			visible = append(visible, change)
+		}
+	}
+	return visible, nil
+}
+
+func (r *SyncRepositoryImpl) GetByClientID(ctx context.Context, clientID string) (*models.Todo, error) {
+	var todo models.Todo
+	if err := r.db.WithContext(ctx).Where("client_id = ?", clientID).First(&todo).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("todo not found")
+		}
+		return nil, err
+	}
+	return &todo, nil
+}
+
+func (r *SyncRepositoryImpl) HasTombstone(ctx context.Context, id uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.TodoChange{}).Where("todo_id = ? AND deleted = ?", id, true).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *SyncRepositoryImpl) Save(ctx context.Context, todo *models.Todo) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		before, err := savedStatus(tx, todo)
+		if err != nil {
+			return err
+		}
+		// New todos go first in the manual order; sync never moves todos
+		query := tx.Omit("position")
+		if todo.ID == 0 {
+			first, err := firstPosition(tx)
+			if err != nil {
+				return err
+			}
+			if todo.Position, err = rank.Between("", first); err != nil {
+				return err
+			}
+			query = tx
+		}
+		if err := query.Save(todo).Error; err != nil {
+			return err
+		}
+		if err := recordStatusChange(tx, todo, before); err != nil {
+			return err
+		}
+		return recordChange(tx, todo.ID)
+	})
+}
+
+func (r *SyncRepositoryImpl) Backfill(ctx context.Context) error {
+	return r.db.WithContext(ctx).Exec(`INSERT INTO todo_changes (todo_id, workspace_id, deleted, changed_at)
+		SELECT id, workspace_id, ?, updated_at FROM todos
+		WHERE NOT EXISTS (SELECT 1 FROM todo_changes WHERE todo_changes.todo_id = todos.id)
+		ORDER BY id`, false).Error
+}
+
+func (r *SyncRepositoryImpl) PruneTombstones(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("deleted = ? AND changed_at < ?", true, before).Delete(&models.TodoChange{})
+	return result.RowsAffected, result.Error
+}
+
+// recordChange replaces the change log entry of a todo with a new one at the
+// end of the log. It must run in the transaction that changed the todo.
+func recordChange(tx *gorm.DB, todoID uint) error {
+	return appendChange(tx, &models.TodoChange{TodoID: todoID})
+}
+
+// recordTombstone is recordChange for a deleted todo that viewers, besides
+// its owner, were granted access to.
+func recordTombstone(tx *gorm.DB, todo *models.Todo, viewers []string) error {
+	return appendChange(tx, &models.TodoChange{TodoID: todo.ID, Deleted: true, Owner: todo.Owner, Viewers: viewers})
+}
+
+func appendChange(tx *gorm.DB, change *models.TodoChange) error {
+	if tx.Dialector.Name() == "postgres" {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", changeLogLock).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Where("todo_id = ?", change.TodoID).Delete(&models.TodoChange{}).Error; err != nil {
+		return err
+	}
+	change.ChangedAt = time.Now()
+	return tx.Create(change).Error
+}
@@ -1,15 +1,52 @@
 package repository
 
 import (
+	"context"
 	"todo-app/models"
+	"todo-app/workflow"
 )
 
+// TodoRepository stores todos. Like every repository it works within the
+// workspace of ctx; see package tenant.
 type TodoRepository interface {
-	Create(todo *models.Todo) (*models.Todo, error)
-	GetByID(id uint) (*models.Todo, error)
-	GetAll(completed *bool, priority *models.Priority, limit, offset int) ([]*models.Todo, error)
-	Update(id uint, todo *models.Todo) (*models.Todo, error)
-	Delete(id uint) error
-	ToggleComplete(id uint) (*models.Todo, error)
-	GetTotalCount(completed *bool, priority *models.Priority) (int64, error)
+	Create(ctx context.Context, todo *models.Todo) (*models.Todo, error)
+	GetByID(ctx context.Context, id uint) (*models.Todo, error)
+	// GetAll, GetTotalCount and ForEach only see the todos visible to
+	// principal. A todo is blocked while any todo blocking it is open.
+	GetAll(ctx context.Context, principal string, completed, blocked *bool, priority *models.Priority, sort models.TodoSort, limit, offset int) ([]*models.Todo, error)
+	Update(ctx context.Context, id uint, todo *models.Todo) (*models.Todo, error)
+	// Delete deletes the todo with its attachments, comments, grants and
+	// dependencies, and returns the content digests of the attachments.
+	Delete(ctx context.Context, id uint) (attachments []string, err error)
+	// ToggleComplete completes or reopens the todo, moving it through wf
+	// the way workflow.SetCompleted does.
+	ToggleComplete(ctx context.Context, id uint, wf *workflow.Workflow) (*models.Todo, error)
+	GetTotalCount(ctx context.Context, principal string, completed, blocked *bool, priority *models.Priority) (int64, error)
+	// ForEach streams every todo matching the filters, in batches, without a limit.
+	ForEach(ctx context.Context, principal string, completed *bool, priority *models.Priority, fn func(todo *models.Todo) error) error
+	// CreateAll inserts all todos in a single transaction.
+	CreateAll(ctx context.Context, todos []*models.Todo) error
+	// Move gives a todo a position between the todos after and before;
+	// with one of them nil it goes right next to the other. Only the moved
+	// todo's row changes.
+	// It fails with rank.ErrOrder when the neighbours share a position,
+	// which Rebalance fixes.
+	Move(ctx context.Context, id uint, after, before *uint) (*models.Todo, error)
+	// Rebalance spreads the positions of every todo in the workspace
+	// evenly, keeping their order, when they have grown too long.
+	Rebalance(ctx context.Context) error
+	// BackfillPositions orders todos from before positions existed newest
+	// first, after those that have one.
+	BackfillPositions(ctx context.Context) (int64, error)
+	// OpenBlockers returns the todos blocking the todo that are not
+	// completed, whether or not the caller may see them.
+	OpenBlockers(ctx context.Context, id uint) ([]*models.Todo, error)
+	// StatusHistory returns the status changes of a todo, oldest first.
+	// Every write above records them when a todo's status changes.
+	StatusHistory(ctx context.Context, id uint) ([]*models.TodoStatusChange, error)
+	// BackfillStatus gives todos from before workflows existed the status
+	// open or done, matching their completed flag.
+	BackfillStatus(ctx context.Context, open, done string) (int64, error)
+	// Count returns how many todos the workspace holds, whoever can see them.
+	Count(ctx context.Context) (int64, error)
 }
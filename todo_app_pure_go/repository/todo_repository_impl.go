@@ -1,8 +1,12 @@
 package repository
 
 import (
+	"context"
 	"errors"
+	"time"
 	"todo-app/models"
+	"todo-app/rank"
+	"todo-app/workflow"
 
 	"gorm.io/gorm"
 )
@@ -17,16 +21,38 @@ func NewTodoRepository(db *gorm.DB) TodoRepository {
 	}
 }
 
-func (r *TodoRepositoryImpl) Create(todo *models.Todo) (*models.Todo, error) {
-	if err := r.db.Create(todo).Error; err != nil {
+// positionOrder is the manual order of todos, ties broken newest first.
+const positionOrder = "position, id DESC"
+
+// Create puts the todo first in the manual order, as it is first when todos
+// are listed newest first.
+func (r *TodoRepositoryImpl) Create(ctx context.Context, todo *models.Todo) (*models.Todo, error) {
+	todo.Clock.Fill(time.Now())
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		first, err := firstPosition(tx)
+		if err != nil {
+			return err
+		}
+		if todo.Position, err = rank.Between("", first); err != nil {
+			return err
+		}
+		if err := tx.Create(todo).Error; err != nil {
+			return err
+		}
+		if err := recordStatusChange(tx, todo, ""); err != nil {
+			return err
+		}
+		return recordChange(tx, todo.ID)
+	})
+	if err != nil {
 		return nil, err
 	}
 	return todo, nil
 }
 
-func (r *TodoRepositoryImpl) GetByID(id uint) (*models.Todo, error) {
+func (r *TodoRepositoryImpl) GetByID(ctx context.Context, id uint) (*models.Todo, error) {
 	var todo models.Todo
-	if err := r.db.First(&todo, id).Error; err != nil {
+	if err := r.db.WithContext(ctx).First(&todo, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("todo not found")
 		}
@@ -35,9 +61,9 @@ func (r *TodoRepositoryImpl) GetByID(id uint) (*models.Todo, error) {
 	return &todo, nil
 }
 
-func (r *TodoRepositoryImpl) GetAll(completed *bool, priority *models.Priority, limit, offset int) ([]*models.Todo, error) {
+func (r *TodoRepositoryImpl) GetAll(ctx context.Context, principal string, completed, blocked *bool, priority *models.Priority, sort models.TodoSort, limit, offset int) ([]*models.Todo, error) {
 	var todos []*models.Todo
-	query := r.db.Model(&models.Todo{})
+	query := r.db.WithContext(ctx).Model(&models.Todo{}).Scopes(visibleTo(principal), blockedIs(blocked))
 
 	if completed != nil {
 		query = query.Where("completed = ?", *completed)
@@ -47,61 +73,124 @@ func (r *TodoRepositoryImpl) GetAll(completed *bool, priority *models.Priority,
 		query = query.Where("priority = ?", *priority)
 	}
 
-	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&todos).Error; err != nil {
+	order := "created_at DESC"
+	if sort == models.SortPosition {
+		order = positionOrder
+	}
+
+	if err := query.Order(order).Limit(limit).Offset(offset).Find(&todos).Error; err != nil {
 		return nil, err
 	}
 
 	return todos, nil
 }
 
-func (r *TodoRepositoryImpl) Update(id uint, todo *models.Todo) (*models.Todo, error) {
+func (r *TodoRepositoryImpl) Update(ctx context.Context, id uint, todo *models.Todo) (*models.Todo, error) {
 	var existingTodo models.Todo
-	if err := r.db.First(&existingTodo, id).Error; err != nil {
+	if err := r.db.WithContext(ctx).First(&existingTodo, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("todo not found")
 		}
 		return nil, err
 	}
 
-	if err := r.db.Model(&existingTodo).Updates(todo).Error; err != nil {
+	before := existingTodo.Status
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Select all columns so zero values such as completed=false are
+		// written; positions only change through Move and Rebalance
+		if err := tx.Model(&existingTodo).Select("*").Omit("id", "created_at", "position").Updates(todo).Error; err != nil {
+			return err
+		}
+		if err := recordStatusChange(tx, &existingTodo, before); err != nil {
+			return err
+		}
+		return recordChange(tx, id)
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return &existingTodo, nil
 }
 
-func (r *TodoRepositoryImpl) Delete(id uint) error {
+func (r *TodoRepositoryImpl) Delete(ctx context.Context, id uint) ([]string, error) {
 	var todo models.Todo
-	if err := r.db.First(&todo, id).Error; err != nil {
+	if err := r.db.WithContext(ctx).First(&todo, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("todo not found")
+			return nil, errors.New("todo not found")
 		}
-		return err
+		return nil, err
 	}
 
-	return r.db.Delete(&todo).Error
+	var attachments, viewers []string
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&todo).Error; err != nil {
+			return err
+		}
+		// Blobs no longer referenced are removed by the attachment service
+		if err := tx.Model(&models.Attachment{}).Where("todo_id = ?", id).Distinct().Pluck("sha256", &attachments).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("todo_id = ?", id).Delete(&models.Attachment{}).Error; err != nil {
+			return err
+		}
+		if err := deleteTodoComments(tx, id); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.TodoGrant{}).Where("todo_id = ?", id).Pluck("principal", &viewers).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("todo_id = ?", id).Delete(&models.TodoGrant{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("todo_id = ?", id).Delete(&models.TodoStatusChange{}).Error; err != nil {
+			return err
+		}
+		// Todos it blocked are no longer waiting for it
+		if err := tx.Where("todo_id = ? OR blocker_id = ?", id, id).Delete(&models.TodoDependency{}).Error; err != nil {
+			return err
+		}
+		return recordTombstone(tx, &todo, viewers)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return attachments, nil
 }
 
-func (r *TodoRepositoryImpl) ToggleComplete(id uint) (*models.Todo, error) {
+func (r *TodoRepositoryImpl) ToggleComplete(ctx context.Context, id uint, wf *workflow.Workflow) (*models.Todo, error) {
 	var todo models.Todo
-	if err := r.db.First(&todo, id).Error; err != nil {
+	if err := r.db.WithContext(ctx).First(&todo, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("todo not found")
 		}
 		return nil, err
 	}
 
-	todo.Completed = !todo.Completed
-	if err := r.db.Save(&todo).Error; err != nil {
+	now := time.Now()
+	before := todo.Status
+	wf.SetCompleted(&todo, !todo.Completed, now)
+	todo.Clock.Completed = &now
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Positions only change through Move and Rebalance
+		if err := tx.Omit("position").Save(&todo).Error; err != nil {
+			return err
+		}
+		if err := recordStatusChange(tx, &todo, before); err != nil {
+			return err
+		}
+		return recordChange(tx, id)
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return &todo, nil
 }
 
-func (r *TodoRepositoryImpl) GetTotalCount(completed *bool, priority *models.Priority) (int64, error) {
+func (r *TodoRepositoryImpl) GetTotalCount(ctx context.Context, principal string, completed, blocked *bool, priority *models.Priority) (int64, error) {
 	var count int64
-	query := r.db.Model(&models.Todo{})
+	query := r.db.WithContext(ctx).Model(&models.Todo{}).Scopes(visibleTo(principal), blockedIs(blocked))
 
 	if completed != nil {
 		query = query.Where("completed = ?", *completed)
@@ -117,3 +206,290 @@ func (r *TodoRepositoryImpl) GetTotalCount(completed *bool, priority *models.Pri
 
 	return count, nil
 }
+
+func (r *TodoRepositoryImpl) ForEach(ctx context.Context, principal string, completed *bool, priority *models.Priority, fn func(todo *models.Todo) error) error {
+	query := r.db.WithContext(ctx).Model(&models.Todo{}).Scopes(visibleTo(principal))
+
+	if completed != nil {
+		query = query.Where("completed = ?", *completed)
+	}
+
+	if priority != nil {
+		query = query.Where("priority = ?", *priority)
+	}
+
+	var batch []*models.Todo
+	return query.Order("id").FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+		for _, todo := range batch {
+			if err := fn(todo); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
+
+func (r *TodoRepositoryImpl) CreateAll(ctx context.Context, todos []*models.Todo) error {
+	if len(todos) == 0 {
+		return nil
+	}
+	now := time.Now()
+	for _, todo := range todos {
+		todo.Clock.Fill(now)
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The todos go first in the manual order, in the order given
+		first, err := firstPosition(tx)
+		if err != nil {
+			return err
+		}
+		positions, err := rank.BetweenN("", first, len(todos))
+		if err != nil {
+			return err
+		}
+		for i, todo := range todos {
+			todo.Position = positions[i]
+		}
+		if err := tx.CreateInBatches(todos, 100).Error; err != nil {
+			return err
+		}
+		for _, todo := range todos {
+			if err := recordStatusChange(tx, todo, ""); err != nil {
+				return err
+			}
+			if err := recordChange(tx, todo.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *TodoRepositoryImpl) Count(ctx context.Context) (int64, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&models.Todo{}).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *TodoRepositoryImpl) Move(ctx context.Context, id uint, after, before *uint) (*models.Todo, error) {
+	var todo models.Todo
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&todo, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("todo not found")
+			}
+			return err
+		}
+
+		// Find the positions to go between; a single neighbour is paired
+		// with the todo next to it, not counting the one that moves
+		var lower, upper string
+		var err error
+		if after != nil {
+			if lower, err = positionOf(tx, *after); err != nil {
+				return err
+			}
+		}
+		if before != nil {
+			if upper, err = positionOf(tx, *before); err != nil {
+				return err
+			}
+		}
+		switch {
+		case after != nil && before != nil:
+			if lower > upper {
+				return errors.New("validation failed: the todo to move after comes after the todo to move before")
+			}
+		case after != nil:
+			upper, err = nextPosition(tx.Where("position > ?", lower).Order("position"), id)
+		case before != nil:
+			lower, err = nextPosition(tx.Where("position < ?", upper).Order("position DESC"), id)
+		}
+		if err != nil {
+			return err
+		}
+
+		position, err := rank.Between(lower, upper)
+		if err != nil {
+			return err
+		}
+		todo.Position = position
+		if err := tx.Model(&todo).Update("position", position).Error; err != nil {
+			return err
+		}
+		return recordChange(tx, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &todo, nil
+}
+
+// Rebalance rewrites every position, so the change log records every todo
+// for sync clients to pick the new positions up.
+func (r *TodoRepositoryImpl) Rebalance(ctx context.Context) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		changed, err := spreadPositions(tx.Order(positionOrder))
+		if err != nil {
+			return err
+		}
+		for _, id := range changed {
+			if err := recordChange(tx, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// BackfillPositions runs at startup across workspaces. Spreading positions
+// over all of them at once keeps each workspace's order. The change log is
+// left alone; clients see the positions on their next full sync or change
+// to the todo.
+func (r *TodoRepositoryImpl) BackfillPositions(ctx context.Context) (int64, error) {
+	var missing int64
+	if err := r.db.WithContext(ctx).Model(&models.Todo{}).Where("position = ?", "").Count(&missing).Error; err != nil || missing == 0 {
+		return 0, err
+	}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		_, err := spreadPositions(tx.Order("CASE WHEN position = '' THEN 1 ELSE 0 END, " + positionOrder))
+		return err
+	})
+	return missing, err
+}
+
+func (r *TodoRepositoryImpl) OpenBlockers(ctx context.Context, id uint) ([]*models.Todo, error) {
+	var blockers []*models.Todo
+	db := r.db.WithContext(ctx)
+	if err := db.Where("completed = ? AND id IN (?)", false, db.Model(&models.TodoDependency{}).Select("blocker_id").Where("todo_id = ?", id)).
+		Order("id").Find(&blockers).Error; err != nil {
+		return nil, err
+	}
+	return blockers, nil
+}
+
+func (r *TodoRepositoryImpl) StatusHistory(ctx context.Context, id uint) ([]*models.TodoStatusChange, error) {
+	var changes []*models.TodoStatusChange
+	if err := r.db.WithContext(ctx).Where("todo_id = ?", id).Order("changed_at, id").Find(&changes).Error; err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// BackfillStatus takes completed todos to have entered done when they were
+// completed and open ones to have been open since they were created.
+func (r *TodoRepositoryImpl) BackfillStatus(ctx context.Context, open, done string) (int64, error) {
+	var backfilled int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Todo{}).Where("status = ? AND completed = ?", "", true).
+			UpdateColumns(map[string]interface{}{"status": done, "status_changed_at": gorm.Expr("COALESCE(completed_at, updated_at)")})
+		if result.Error != nil {
+			return result.Error
+		}
+		backfilled += result.RowsAffected
+		result = tx.Model(&models.Todo{}).Where("status = ? AND completed = ?", "", false).
+			UpdateColumns(map[string]interface{}{"status": open, "status_changed_at": gorm.Expr("created_at")})
+		backfilled += result.RowsAffected
+		return result.Error
+	})
+	return backfilled, err
+}
+
+// recordStatusChange adds the status of todo to its status history when it
+// differs from before, the status the todo was saved with until now or ""
+// for a new todo. It must run in the transaction that changed the todo.
+func recordStatusChange(tx *gorm.DB, todo *models.Todo, before string) error {
+	if todo.Status == before || todo.Status == "" {
+		return nil
+	}
+	changedAt := time.Now()
+	if todo.StatusChangedAt != nil {
+		changedAt = *todo.StatusChangedAt
+	}
+	return tx.Create(&models.TodoStatusChange{TodoID: todo.ID, From: before, To: todo.Status, ChangedAt: changedAt}).Error
+}
+
+// savedStatus returns the status todo was last saved with, "" if it has
+// not been saved yet.
+func savedStatus(tx *gorm.DB, todo *models.Todo) (string, error) {
+	if todo.ID == 0 {
+		return "", nil
+	}
+	var statuses []string
+	if err := tx.Model(&models.Todo{}).Where("id = ?", todo.ID).Pluck("status", &statuses).Error; err != nil {
+		return "", err
+	}
+	if len(statuses) == 0 {
+		return "", nil
+	}
+	return statuses[0], nil
+}
+
+// firstPosition returns the lowest position in the workspace, "" if no todo
+// has one.
+func firstPosition(tx *gorm.DB) (string, error) {
+	return nextPosition(tx.Where("position <> ?", "").Order("position"), 0)
+}
+
+// nextPosition returns the position of the first todo in query other than
+// the one with id skip, "" if there is none.
+func nextPosition(query *gorm.DB, skip uint) (string, error) {
+	var positions []string
+	if err := query.Model(&models.Todo{}).Where("id <> ?", skip).Limit(1).Pluck("position", &positions).Error; err != nil {
+		return "", err
+	}
+	if len(positions) == 0 {
+		return "", nil
+	}
+	return positions[0], nil
+}
+
+func positionOf(tx *gorm.DB, id uint) (string, error) {
+	var todo models.Todo
+	if err := tx.Select("id", "position").First(&todo, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", errors.New("todo not found")
+		}
+		return "", err
+	}
+	return todo.Position, nil
+}
+
+// spreadPositions gives the todos in the order of query evenly spread
+// positions and returns the IDs of those whose position changed.
+func spreadPositions(query *gorm.DB) ([]uint, error) {
+	var todos []*models.Todo
+	if err := query.Model(&models.Todo{}).Select("id", "position").Find(&todos).Error; err != nil {
+		return nil, err
+	}
+	var changed []uint
+	for i, position := range rank.Spread(len(todos)) {
+		if todos[i].Position == position {
+			continue
+		}
+		if err := query.Session(&gorm.Session{NewDB: true}).Model(&models.Todo{}).Where("id = ?", todos[i].ID).UpdateColumn("position", position).Error; err != nil {
+			return nil, err
+		}
+		changed = append(changed, todos[i].ID)
+	}
+	return changed, nil
+}
+
+// blockedIs keeps the todos that are, or are not, blocked by an open todo;
+// nil keeps all.
+func blockedIs(blocked *bool) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if blocked == nil {
+			return db
+		}
+		condition := `EXISTS (SELECT 1 FROM todo_dependencies
+			JOIN todos blockers ON blockers.id = todo_dependencies.blocker_id
+			WHERE todo_dependencies.todo_id = todos.id AND blockers.completed = ?)`
+		if !*blocked {
+			condition = "NOT " + condition
+		}
+		return db.Where(condition, false)
+	}
+}
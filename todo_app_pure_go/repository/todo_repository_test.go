@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"testing"
+
+	"todo-app/models"
+	"todo-app/tenant"
+	"todo-app/workflow"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestToggleCompleteKeepsConcurrentMoves(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(tenant.Plugin{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.Todo{}, &models.TodoChange{}, &models.TodoStatusChange{}, &models.TodoDependency{}); err != nil {
+		t.Fatal(err)
+	}
+	repo := NewTodoRepository(db)
+	todo, err := repo.Create(ctx, &models.Todo{Title: "Write report", Priority: models.MEDIUM})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Move the todo right after the toggle has read it
+	moved := false
+	err = db.Callback().Query().After("gorm:query").Register("test:concurrent_move", func(tx *gorm.DB) {
+		if moved {
+			return
+		}
+		if _, ok := tx.Statement.Dest.(*models.Todo); !ok {
+			return
+		}
+		moved = true
+		if err := db.Exec("UPDATE todos SET position = ? WHERE id = ?", "zz", todo.ID).Error; err != nil {
+			t.Error(err)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.ToggleComplete(ctx, todo.ID, workflow.Default()); err != nil {
+		t.Fatal(err)
+	}
+	if !moved {
+		t.Fatal("the move did not run")
+	}
+	stored, err := repo.GetByID(ctx, todo.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored.Completed || stored.Position != "zz" {
+		t.Errorf("completed = %v, position = %q; want true, zz", stored.Completed, stored.Position)
+	}
+}
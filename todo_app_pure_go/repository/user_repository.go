@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"todo-app/models"
+)
+
+type UserRepository interface {
+	Create(ctx context.Context, user *models.User) (*models.User, error)
+	GetByID(ctx context.Context, id uint) (*models.User, error)
+	// GetByIdentity returns the user the provider knows as subject.
+	GetByIdentity(ctx context.Context, issuer, subject string) (*models.User, error)
+	GetByPrincipal(ctx context.Context, principal string) (*models.User, error)
+	// RecordLogin stores the profile the provider sent with a login.
+	RecordLogin(ctx context.Context, id uint, email, name string, at time.Time) error
+}
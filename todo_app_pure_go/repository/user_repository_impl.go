@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"todo-app/models"
+
+	"gorm.io/gorm"
+)
+
+type UserRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewUserRepository(db *gorm.DB) UserRepository {
+	return &UserRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *UserRepositoryImpl) Create(ctx context.Context, user *models.User) (*models.User, error) {
+	if err := r.db.WithContext(ctx).Create(user).Error; err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *UserRepositoryImpl) GetByID(ctx context.Context, id uint) (*models.User, error) {
+	return r.first(r.db.WithContext(ctx).Where("id = ?", id))
+}
+
+func (r *UserRepositoryImpl) GetByIdentity(ctx context.Context, issuer, subject string) (*models.User, error) {
+	return r.first(r.db.WithContext(ctx).Where("issuer = ? AND subject = ?", issuer, subject))
+}
+
+func (r *UserRepositoryImpl) GetByPrincipal(ctx context.Context, principal string) (*models.User, error) {
+	return r.first(r.db.WithContext(ctx).Where("principal = ?", principal))
+}
+
+func (r *UserRepositoryImpl) RecordLogin(ctx context.Context, id uint, email, name string, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"email": email, "name": name, "last_login_at": at}).Error
+}
+
+func (r *UserRepositoryImpl) first(query *gorm.DB) (*models.User, error) {
+	var user models.User
+	if err := query.First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+	return &user, nil
+}
@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"todo-app/models"
+)
+
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *models.Webhook) (*models.Webhook, error)
+	GetByID(ctx context.Context, id uint) (*models.Webhook, error)
+	GetAllByOwner(ctx context.Context, owner string) ([]*models.Webhook, error)
+	// GetActive returns every active webhook of the workspace regardless of
+	// owner.
+	GetActive(ctx context.Context) ([]*models.Webhook, error)
+	Update(ctx context.Context, webhook *models.Webhook) (*models.Webhook, error)
+	// Delete removes the webhook together with its delivery log.
+	Delete(ctx context.Context, id uint) error
+
+	CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) (*models.WebhookDelivery, error)
+	GetDelivery(ctx context.Context, id uint) (*models.WebhookDelivery, error)
+	GetDeliveries(ctx context.Context, webhookID uint, limit, offset int) ([]*models.WebhookDelivery, int64, error)
+	// GetDueDeliveries returns pending deliveries whose next attempt is due.
+	GetDueDeliveries(ctx context.Context, now time.Time, limit int) ([]*models.WebhookDelivery, error)
+	UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+}
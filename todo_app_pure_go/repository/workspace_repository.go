@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+	"todo-app/models"
+)
+
+// WorkspaceRepository stores the workspaces themselves, which are not
+// workspace-owned and so are not limited by the workspace of ctx.
+type WorkspaceRepository interface {
+	// Ensure returns the workspace with slug, creating it if it is missing.
+	Ensure(ctx context.Context, slug string) (*models.Workspace, error)
+	GetByID(ctx context.Context, id uint) (*models.Workspace, error)
+	GetBySlug(ctx context.Context, slug string) (*models.Workspace, error)
+	UpdateSettings(ctx context.Context, id uint, settings models.WorkspaceSettings) (*models.Workspace, error)
+}
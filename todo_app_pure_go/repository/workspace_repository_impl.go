@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"todo-app/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WorkspaceRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewWorkspaceRepository(db *gorm.DB) WorkspaceRepository {
+	return &WorkspaceRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *WorkspaceRepositoryImpl) Ensure(ctx context.Context, slug string) (*models.Workspace, error) {
+	workspace := &models.Workspace{Slug: slug, Name: slug}
+	// Instances starting together may race to create the same workspace
+	if err := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(workspace).Error; err != nil {
+		return nil, err
+	}
+	return r.GetBySlug(ctx, slug)
+}
+
+func (r *WorkspaceRepositoryImpl) GetByID(ctx context.Context, id uint) (*models.Workspace, error) {
+	var workspace models.Workspace
+	if err := r.db.WithContext(ctx).First(&workspace, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("workspace not found")
+		}
+		return nil, err
+	}
+	return &workspace, nil
+}
+
+func (r *WorkspaceRepositoryImpl) GetBySlug(ctx context.Context, slug string) (*models.Workspace, error) {
+	var workspace models.Workspace
+	if err := r.db.WithContext(ctx).Where("slug = ?", slug).First(&workspace).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("workspace not found")
+		}
+		return nil, err
+	}
+	return &workspace, nil
+}
+
+func (r *WorkspaceRepositoryImpl) UpdateSettings(ctx context.Context, id uint, settings models.WorkspaceSettings) (*models.Workspace, error) {
+	result := r.db.WithContext(ctx).Model(&models.Workspace{ID: id}).Select("settings", "updated_at").
+		Updates(&models.Workspace{Settings: settings})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("workspace not found")
+	}
+	return r.GetByID(ctx, id)
+}
@@ -1,51 +1,251 @@
 package routes
 
 import (
+	"context"
+	"errors"
+	"strings"
+
+	"todo-app/auth"
+	"todo-app/config"
 	"todo-app/controller"
+	"todo-app/dto"
+	"todo-app/gql"
+	"todo-app/health"
 	"todo-app/middleware"
 	"todo-app/service"
+	"todo-app/stream"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
-func SetupRoutes(todoService service.TodoService) *gin.Engine {
+// Dependencies are the services the HTTP handlers are built from.
+type Dependencies struct {
+	TodoService       service.TodoService
+	CalendarService   service.CalendarService
+	WebhookService    service.WebhookService
+	SyncService       service.SyncService
+	StatsService      service.StatsService
+	AttachmentService service.AttachmentService
+	CommentService    service.CommentService
+	DependencyService service.DependencyService
+	AccessService     service.AccessService
+	WorkspaceService  service.WorkspaceService
+	APIKeyService     service.APIKeyService
+	// AuthService is set when single sign-on is configured
+	AuthService    service.AuthService
+	HealthRegistry *health.Registry
+	EventHub       *stream.Hub
+	GraphQL        *gql.Server
+	// TodoCache is set when the todo repository is cached
+	TodoCache controller.CacheStatsSource
+}
+
+func SetupRoutes(cfg *config.Config, deps Dependencies) *gin.Engine {
 	router := gin.New()
+	// Without trusted proxies c.ClientIP() is the connection's address, so
+	// clients cannot pick their own rate limit key with X-Forwarded-For.
+	// config.Validate has checked the entries.
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		panic(err)
+	}
 
 	// Middleware
 	router.Use(middleware.RecoveryMiddleware())
-	router.Use(middleware.LoggerMiddleware())
-	router.Use(middleware.CORSMiddleware())
+	if cfg.Log.AccessLog {
+		router.Use(middleware.LoggerMiddleware())
+	}
+	router.Use(middleware.CORSMiddleware(cfg.CORS))
 
 	// Swagger documentation
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	// Controllers
-	todoController := controller.NewTodoController(todoService)
+	todoController := controller.NewTodoController(deps.TodoService)
+	calendarController := controller.NewCalendarController(deps.TodoService, deps.CalendarService, cfg.Server.PublicURL)
+	webhookController := controller.NewWebhookController(deps.WebhookService)
+	syncController := controller.NewSyncController(deps.SyncService)
+	statsController := controller.NewStatsController(deps.StatsService)
+	attachmentController := controller.NewAttachmentController(deps.AttachmentService, int64(cfg.Attachments.MaxSize))
+	commentController := controller.NewCommentController(deps.CommentService)
+	dependencyController := controller.NewDependencyController(deps.DependencyService)
+	accessController := controller.NewAccessController(deps.AccessService)
+	workspaceController := controller.NewWorkspaceController(deps.WorkspaceService)
+	apiKeyController := controller.NewAPIKeyController(deps.APIKeyService)
+	healthController := controller.NewHealthController(deps.HealthRegistry)
+	eventStreamController := controller.NewEventStreamController(deps.EventHub, cfg.Stream.HeartbeatInterval)
+
+	// Rate limiting: a general budget for the whole API plus tighter
+	// per-route limits on writes
+	limiter := middleware.NewRateLimiter(middleware.NewMemoryRateLimitStore(), middleware.ClientKey)
+	// WebSocket and GraphQL mutations share the buckets of the matching
+	// routes below, by mutation kind
+	mutationLimits := map[string]middleware.RouteLimit{
+		"create": {Name: "todos:create", Rate: middleware.PerMinute(30)},
+		"update": {Name: "todos:update", Rate: middleware.PerMinute(60)},
+		"toggle": {Name: "todos:update", Rate: middleware.PerMinute(60)},
+		"delete": {Name: "todos:delete", Rate: middleware.PerMinute(30)},
+	}
+	realtimeController := controller.NewRealtimeController(deps.TodoService, deps.EventHub, cfg.CORS, limiter, mutationLimits)
+	// GraphiQL is only offered when enabled, for development
+	graphQLController := controller.NewGraphQLController(deps.GraphQL, cfg.GraphQL.MaxBatch, cfg.GraphQL.GraphiQL, limiter, mutationLimits)
+
+	// API keys authenticate before rate limiting so limits follow the
+	// caller, and name the workspace before it is resolved
+	apiKeys := middleware.APIKeyMiddleware(deps.APIKeyService.Authenticate)
+	// Other bearer tokens are session tokens from single sign-on, and are
+	// all refused while it is off
+	authenticateSession := func(context.Context, string) (*dto.UserResponse, error) {
+		return nil, errors.New("invalid session")
+	}
+	if deps.AuthService != nil {
+		authenticateSession = deps.AuthService.Authenticate
+	}
+	sessions := middleware.SessionMiddleware(authenticateSession)
+
+	// Everything under /api and /graphql acts in one workspace
+	tenancy := middleware.TenantMiddleware(cfg.Tenancy, deps.WorkspaceService.Resolve)
+
+	// Requests made with an API key may only use the routes its scopes allow
+	todosRead := middleware.RequireScope(auth.ScopeTodosRead)
+	todosWrite := middleware.RequireScope(auth.ScopeTodosWrite)
+	webhooksRead := middleware.RequireScope(auth.ScopeWebhooksRead)
+	webhooksWrite := middleware.RequireScope(auth.ScopeWebhooksWrite)
+	keysRead := middleware.RequireScope(auth.ScopeKeysRead)
+	keysWrite := middleware.RequireScope(auth.ScopeKeysWrite)
+	workspaceRead := middleware.RequireScope(auth.ScopeWorkspaceRead)
+	workspaceWrite := middleware.RequireScope(auth.ScopeWorkspaceWrite)
 
 	// API routes
 	api := router.Group("/api")
+	api.Use(apiKeys, sessions, limiter.Limit("api", middleware.PerSecond(20)), tenancy)
 	{
+		// The current workspace and its settings, which anonymous callers
+		// cannot change
+		api.GET("/workspace", workspaceRead, workspaceController.GetWorkspace)
+		api.PUT("/workspace/settings", middleware.RequireIdentity(), workspaceWrite, limiter.Limit("workspace:write", middleware.PerMinute(10)), workspaceController.UpdateSettings)
+
 		// Todo routes
 		todos := api.Group("/todos")
 		{
-			todos.GET("", todoController.GetAllTodos)
-			todos.POST("", todoController.CreateTodo)
-			todos.GET("/:id", todoController.GetTodoByID)
-			todos.PUT("/:id", todoController.UpdateTodo)
-			todos.DELETE("/:id", todoController.DeleteTodo)
-			todos.PATCH("/:id/toggle", todoController.ToggleTodoComplete)
-		}
-	}
-
-	// Health check endpoint
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status":  "ok",
-			"message": "Todo API is running",
-		})
-	})
+			todos.GET("", todosRead, todoController.GetAllTodos)
+			todos.POST("", todosWrite, limiter.Limit("todos:create", middleware.PerMinute(30)), todoController.CreateTodo)
+			todos.POST("/quick", todosWrite, limiter.Limit("todos:create", middleware.PerMinute(30)), todoController.QuickAdd)
+			todos.GET("/export.csv", todosRead, limiter.Limit("todos:export", middleware.PerMinute(10)), todoController.ExportTodosCSV)
+			todos.POST("/import", todosWrite, limiter.Limit("todos:import", middleware.PerMinute(5)), todoController.ImportTodosCSV)
+			todos.GET("/export.ics", todosRead, limiter.Limit("todos:export", middleware.PerMinute(10)), calendarController.ExportTodosICS)
+			todos.POST("/import.ics", todosWrite, limiter.Limit("todos:import", middleware.PerMinute(5)), calendarController.ImportTodosICS)
+			todos.GET("/events", todosRead, limiter.Limit("todos:events", middleware.PerMinute(30)), eventStreamController.StreamTodoEvents)
+			todos.GET("/ws", todosRead, limiter.Limit("todos:events", middleware.PerMinute(30)), realtimeController.Connect)
+			todos.GET("/dependencies/graph", todosRead, limiter.Limit("todos:export", middleware.PerMinute(10)), dependencyController.GetGraph)
+			todos.GET("/:id", todosRead, todoController.GetTodoByID)
+			todos.PUT("/:id", todosWrite, limiter.Limit("todos:update", middleware.PerMinute(60)), todoController.UpdateTodo)
+			todos.DELETE("/:id", todosWrite, limiter.Limit("todos:delete", middleware.PerMinute(30)), todoController.DeleteTodo)
+			todos.PATCH("/:id/toggle", todosWrite, limiter.Limit("todos:update", middleware.PerMinute(60)), todoController.ToggleTodoComplete)
+			todos.POST("/:id/transition", todosWrite, limiter.Limit("todos:update", middleware.PerMinute(60)), todoController.TransitionTodo)
+			todos.GET("/:id/status-history", todosRead, todoController.GetStatusHistory)
+			todos.POST("/:id/move", todosWrite, limiter.Limit("todos:update", middleware.PerMinute(60)), todoController.MoveTodo)
+			todos.GET("/:id/attachments", todosRead, attachmentController.GetAttachments)
+			todos.POST("/:id/attachments", todosWrite, limiter.Limit("attachments:upload", middleware.PerMinute(20)), attachmentController.UploadAttachment)
+			todos.GET("/:id/attachments/:attachmentId", todosRead, attachmentController.DownloadAttachment)
+			todos.DELETE("/:id/attachments/:attachmentId", todosWrite, limiter.Limit("todos:delete", middleware.PerMinute(30)), attachmentController.DeleteAttachment)
+			todos.GET("/:id/comments", todosRead, commentController.GetComments)
+			todos.POST("/:id/comments", todosWrite, limiter.Limit("comments:write", middleware.PerMinute(30)), commentController.CreateComment)
+			todos.GET("/:id/comments/:commentId", todosRead, commentController.GetComment)
+			todos.PUT("/:id/comments/:commentId", todosWrite, limiter.Limit("comments:write", middleware.PerMinute(30)), commentController.UpdateComment)
+			todos.DELETE("/:id/comments/:commentId", todosWrite, limiter.Limit("comments:write", middleware.PerMinute(30)), commentController.DeleteComment)
+			todos.GET("/:id/comments/:commentId/revisions", todosRead, commentController.GetRevisions)
+			todos.GET("/:id/dependencies", todosRead, dependencyController.GetDependencies)
+			todos.POST("/:id/dependencies", todosWrite, limiter.Limit("todos:update", middleware.PerMinute(60)), dependencyController.AddDependency)
+			todos.DELETE("/:id/dependencies/:blockerId", todosWrite, limiter.Limit("todos:update", middleware.PerMinute(60)), dependencyController.RemoveDependency)
+			todos.GET("/:id/access", todosRead, accessController.GetTodoAccess)
+			todos.POST("/:id/access", todosWrite, limiter.Limit("access:write", middleware.PerMinute(30)), accessController.GrantTodoAccess)
+			todos.DELETE("/:id/access", todosWrite, limiter.Limit("access:write", middleware.PerMinute(30)), accessController.RevokeTodoAccess)
+		}
+
+		// Sharing all of the caller's todos at once
+		shares := api.Group("/shares")
+		{
+			shares.GET("", todosRead, accessController.GetShares)
+			shares.POST("", todosWrite, limiter.Limit("access:write", middleware.PerMinute(30)), accessController.ShareList)
+			shares.DELETE("", todosWrite, limiter.Limit("access:write", middleware.PerMinute(30)), accessController.UnshareList)
+		}
+
+		api.GET("/workflow", todosRead, todoController.GetWorkflow)
+
+		// Statistics run several aggregate queries per request
+		api.GET("/stats", todosRead, limiter.Limit("stats", middleware.PerMinute(30)), statsController.GetStats)
+
+		if deps.TodoCache != nil {
+			api.GET("/cache/stats", workspaceRead, controller.NewCacheController(deps.TodoCache).GetStats)
+		}
+
+		// API keys for automation; a key can only create keys with scopes
+		// of its own, and anonymous callers own no keys
+		keys := api.Group("/keys", middleware.RequireIdentity())
+		{
+			keys.GET("", keysRead, apiKeyController.GetKeys)
+			keys.POST("", keysWrite, limiter.Limit("keys:write", middleware.PerMinute(10)), apiKeyController.CreateKey)
+			keys.DELETE("/:id", keysWrite, apiKeyController.RevokeKey)
+		}
+
+		// Delta sync for offline-first clients
+		sync := api.Group("/sync", limiter.Limit("sync", middleware.PerMinute(60)))
+		{
+			sync.GET("", todosRead, syncController.Pull)
+			sync.POST("", todosWrite, syncController.Sync)
+		}
+
+		// Calendar subscription routes
+		feeds := api.Group("/calendar/feeds")
+		{
+			feeds.GET("", todosRead, calendarController.GetFeeds)
+			feeds.POST("", todosWrite, limiter.Limit("feeds:create", middleware.PerMinute(10)), calendarController.CreateFeed)
+			feeds.DELETE("/:id", todosWrite, calendarController.DeleteFeed)
+		}
+
+		// Webhook routes
+		webhooks := api.Group("/webhooks")
+		{
+			webhooks.GET("", webhooksRead, webhookController.GetWebhooks)
+			webhooks.POST("", webhooksWrite, limiter.Limit("webhooks:write", middleware.PerMinute(20)), webhookController.CreateWebhook)
+			webhooks.GET("/:id", webhooksRead, webhookController.GetWebhookByID)
+			webhooks.PUT("/:id", webhooksWrite, limiter.Limit("webhooks:write", middleware.PerMinute(20)), webhookController.UpdateWebhook)
+			webhooks.DELETE("/:id", webhooksWrite, limiter.Limit("webhooks:write", middleware.PerMinute(20)), webhookController.DeleteWebhook)
+			webhooks.GET("/:id/deliveries", webhooksRead, webhookController.GetDeliveries)
+			webhooks.POST("/:id/deliveries/:deliveryId/redeliver", webhooksWrite, limiter.Limit("webhooks:redeliver", middleware.PerMinute(20)), webhookController.Redeliver)
+		}
+	}
+
+	// GraphQL shares the general API budget; mutations also need the
+	// todos:write scope and spend the write limits
+	graphQL := router.Group("/graphql", apiKeys, sessions, limiter.Limit("api", middleware.PerSecond(20)), tenancy)
+	{
+		graphQL.GET("", todosRead, graphQLController.QueryGET)
+		graphQL.POST("", todosRead, graphQLController.Query)
+	}
+
+	// Single sign-on: login names the workspace like the API does, the
+	// callback finds it in the login state
+	if deps.AuthService != nil {
+		authController := controller.NewAuthController(deps.AuthService, strings.HasPrefix(cfg.OIDCRedirectURL(), "https://"))
+		login := router.Group("/auth", limiter.Limit("auth", middleware.PerMinute(20)))
+		{
+			login.GET("/login", tenancy, authController.Login)
+			login.GET("/callback", authController.Callback)
+		}
+	}
+
+	// Calendar apps poll subscription URLs without credentials; the secret
+	// token in the path authorizes the request and names its workspace
+	router.GET("/calendar/:token", limiter.Limit("calendar:feed", middleware.PerMinute(30)), calendarController.Feed)
+
+	// Health check endpoints
+	router.GET("/healthz/live", healthController.Live)
+	router.GET("/healthz/ready", healthController.Ready)
+	// Kept for existing monitors; reports readiness
+	router.GET("/health", healthController.Ready)
 
 	return router
 }
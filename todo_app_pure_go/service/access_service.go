@@ -0,0 +1,24 @@
+package service
+
+import (
+	"context"
+	"todo-app/dto"
+)
+
+// AccessService shares todos with other principals, one todo at a time or
+// all todos of an owner at once. principal is the authenticated identity;
+// todos created without one are open to everyone and cannot be shared.
+type AccessService interface {
+	// GetTodoAccess lists the grants on a todo the caller can see.
+	GetTodoAccess(ctx context.Context, principal string, todoID uint) (*dto.TodoAccessResponse, error)
+	// GrantTodoAccess gives a principal a role on the todo, or changes the
+	// role it has. Only owners may share a todo.
+	GrantTodoAccess(ctx context.Context, principal string, todoID uint, req *dto.GrantAccessRequest) (*dto.GrantResponse, error)
+	// RevokeTodoAccess removes grantee's grant on the todo. Owners may
+	// revoke any grant; everyone may give up their own.
+	RevokeTodoAccess(ctx context.Context, principal string, todoID uint, grantee string) error
+	// GetShares lists who the caller shared all of its todos with.
+	GetShares(ctx context.Context, principal string) ([]*dto.GrantResponse, error)
+	ShareList(ctx context.Context, principal string, req *dto.GrantAccessRequest) (*dto.GrantResponse, error)
+	UnshareList(ctx context.Context, principal, grantee string) error
+}
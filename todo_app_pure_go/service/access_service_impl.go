@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"todo-app/dto"
+	"todo-app/models"
+	"todo-app/repository"
+	"todo-app/utils"
+)
+
+type AccessServiceImpl struct {
+	accessRepo repository.AccessRepository
+	access     authorizer
+}
+
+func NewAccessService(accessRepo repository.AccessRepository, todoRepo repository.TodoRepository) AccessService {
+	return &AccessServiceImpl{
+		accessRepo: accessRepo,
+		access:     authorizer{todoRepo: todoRepo, accessRepo: accessRepo},
+	}
+}
+
+func (s *AccessServiceImpl) GetTodoAccess(ctx context.Context, principal string, todoID uint) (*dto.TodoAccessResponse, error) {
+	todo, role, err := s.access.authorize(ctx, principal, todoID, models.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	grants, err := s.accessRepo.GetTodoGrants(ctx, todoID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &dto.TodoAccessResponse{
+		TodoID: todo.ID,
+		Owner:  todo.Owner,
+		Role:   role,
+		Grants: make([]*dto.GrantResponse, len(grants)),
+	}
+	for i, grant := range grants {
+		response.Grants[i] = todoGrantToResponse(grant)
+	}
+	return response, nil
+}
+
+func (s *AccessServiceImpl) GrantTodoAccess(ctx context.Context, principal string, todoID uint, req *dto.GrantAccessRequest) (*dto.GrantResponse, error) {
+	if validationErrors := utils.ValidateStruct(req); len(validationErrors) > 0 {
+		return nil, errors.New("validation failed: " + validationErrors[0])
+	}
+	todo, _, err := s.access.authorize(ctx, principal, todoID, models.RoleOwner)
+	if err != nil {
+		return nil, err
+	}
+	if todo.Owner == "" {
+		return nil, errors.New("validation failed: todos without an owner are open to everyone")
+	}
+	if req.Principal == todo.Owner {
+		return nil, errors.New("validation failed: the owner already has full access")
+	}
+
+	grant, err := s.accessRepo.GrantTodo(ctx, todoID, req.Principal, req.Role)
+	if err != nil {
+		return nil, err
+	}
+	return todoGrantToResponse(grant), nil
+}
+
+func (s *AccessServiceImpl) RevokeTodoAccess(ctx context.Context, principal string, todoID uint, grantee string) error {
+	if grantee == "" {
+		return errors.New("validation failed: principal is required")
+	}
+	required := models.RoleOwner
+	if grantee == principal {
+		required = models.RoleViewer
+	}
+	if _, _, err := s.access.authorize(ctx, principal, todoID, required); err != nil {
+		return err
+	}
+	return s.accessRepo.RevokeTodo(ctx, todoID, grantee)
+}
+
+func (s *AccessServiceImpl) GetShares(ctx context.Context, principal string) ([]*dto.GrantResponse, error) {
+	grants, err := s.accessRepo.GetListGrants(ctx, principal)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*dto.GrantResponse, len(grants))
+	for i, grant := range grants {
+		responses[i] = listGrantToResponse(grant)
+	}
+	return responses, nil
+}
+
+func (s *AccessServiceImpl) ShareList(ctx context.Context, principal string, req *dto.GrantAccessRequest) (*dto.GrantResponse, error) {
+	if validationErrors := utils.ValidateStruct(req); len(validationErrors) > 0 {
+		return nil, errors.New("validation failed: " + validationErrors[0])
+	}
+	if principal == "" {
+		return nil, errors.New("forbidden: sharing requires an authenticated identity")
+	}
+	if req.Principal == principal {
+		return nil, errors.New("validation failed: cannot share todos with yourself")
+	}
+
+	grant, err := s.accessRepo.GrantList(ctx, principal, req.Principal, req.Role)
+	if err != nil {
+		return nil, err
+	}
+	return listGrantToResponse(grant), nil
+}
+
+func (s *AccessServiceImpl) UnshareList(ctx context.Context, principal, grantee string) error {
+	if grantee == "" {
+		return errors.New("validation failed: principal is required")
+	}
+	return s.accessRepo.RevokeList(ctx, principal, grantee)
+}
+
+func todoGrantToResponse(grant *models.TodoGrant) *dto.GrantResponse {
+	return &dto.GrantResponse{
+		Principal: grant.Principal,
+		Role:      grant.Role,
+		CreatedAt: grant.CreatedAt,
+		UpdatedAt: grant.UpdatedAt,
+	}
+}
+
+func listGrantToResponse(grant *models.ListGrant) *dto.GrantResponse {
+	return &dto.GrantResponse{
+		Principal: grant.Principal,
+		Role:      grant.Role,
+		CreatedAt: grant.CreatedAt,
+		UpdatedAt: grant.UpdatedAt,
+	}
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"todo-app/dto"
+	"todo-app/events"
+	"todo-app/models"
+	"todo-app/repository"
+)
+
+type recordingPublisher struct {
+	events []events.Event
+}
+
+func (p *recordingPublisher) Publish(event events.Event) {
+	p.events = append(p.events, event)
+}
+
+type accessTestEnv struct {
+	access   AccessService
+	todos    TodoService
+	comments CommentService
+	sync     SyncService
+	events   *recordingPublisher
+}
+
+func newAccessTestEnv(t *testing.T) *accessTestEnv {
+	t.Helper()
+	db := newTestDB(t)
+	// Go through the cache so lists cached before a grant changed would show up
+	cache := repository.NewCachedTodoRepository(repository.NewTodoRepository(db), repository.CacheOptions{Size: 100, TTL: time.Minute})
+	accessRepo := cache.WrapAccess(repository.NewAccessRepository(db))
+	publisher := &recordingPublisher{}
+	return &accessTestEnv{
+		access:   NewAccessService(accessRepo, cache),
+		todos:    NewTodoService(cache, accessRepo, nil, publisher, nil),
+		comments: NewCommentService(cache.WrapComments(repository.NewCommentRepository(db)), cache, accessRepo),
+		sync:     NewSyncService(cache, cache.WrapSync(repository.NewSyncRepository(db)), accessRepo, nil, publisher, nil, 24*time.Hour, 10),
+		events:   publisher,
+	}
+}
+
+func (e *accessTestEnv) visible(t *testing.T, principal string) []string {
+	t.Helper()
+	todos, total, err := e.todos.GetAllTodos(ctx, principal, nil, nil, nil, "", 100, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int(total) != len(todos) {
+		t.Errorf("total = %d for %d todos", total, len(todos))
+	}
+	titles := make([]string, len(todos))
+	for i, todo := range todos {
+		titles[i] = todo.Title
+	}
+	return titles
+}
+
+func wantError(t *testing.T, err error, prefix string) {
+	t.Helper()
+	if err == nil || !strings.HasPrefix(err.Error(), prefix) {
+		t.Errorf("error = %v, want %q", err, prefix)
+	}
+}
+
+func TestTodoVisibilityAndRoles(t *testing.T) {
+	env := newAccessTestEnv(t)
+	private, _ := env.todos.CreateTodo(ctx, "alice", &dto.CreateTodoRequest{Title: "Private"})
+	env.todos.CreateTodo(ctx, "", &dto.CreateTodoRequest{Title: "Public"})
+
+	if got := env.visible(t, "bob"); len(got) != 1 || got[0] != "Public" {
+		t.Fatalf("bob sees %v before sharing", got)
+	}
+	if got := env.visible(t, "alice"); len(got) != 2 {
+		t.Fatalf("alice sees %v", got)
+	}
+	// Someone without access cannot tell the todo exists
+	_, err := env.todos.GetTodoByID(ctx, "bob", private.ID)
+	wantError(t, err, "todo not found")
+	wantError(t, env.todos.DeleteTodo(ctx, "bob", private.ID), "todo not found")
+
+	if _, err := env.access.GrantTodoAccess(ctx, "alice", private.ID, &dto.GrantAccessRequest{Principal: "bob", Role: models.RoleViewer}); err != nil {
+		t.Fatal(err)
+	}
+	if got := env.visible(t, "bob"); len(got) != 2 {
+		t.Fatalf("bob sees %v after sharing", got)
+	}
+	// Someone who can see the todo learns they lack the role
+	_, err = env.todos.UpdateTodo(ctx, "bob", private.ID, &dto.UpdateTodoRequest{Title: strPtr("Mine")})
+	wantError(t, err, "forbidden")
+	_, err = env.comments.CreateComment(ctx, "bob", private.ID, &dto.CreateCommentRequest{Body: "Hi"})
+	wantError(t, err, "forbidden")
+
+	// Granting again changes the role
+	if _, err := env.access.GrantTodoAccess(ctx, "alice", private.ID, &dto.GrantAccessRequest{Principal: "bob", Role: models.RoleEditor}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.todos.ToggleTodoComplete(ctx, "bob", private.ID); err != nil {
+		t.Errorf("editor toggle: %v", err)
+	}
+	if _, err := env.comments.CreateComment(ctx, "bob", private.ID, &dto.CreateCommentRequest{Body: "Done"}); err != nil {
+		t.Errorf("editor comment: %v", err)
+	}
+	wantError(t, env.todos.DeleteTodo(ctx, "bob", private.ID), "forbidden")
+	_, err = env.access.GrantTodoAccess(ctx, "bob", private.ID, &dto.GrantAccessRequest{Principal: "carol", Role: models.RoleViewer})
+	wantError(t, err, "forbidden")
+
+	access, err := env.access.GetTodoAccess(ctx, "bob", private.ID)
+	if err != nil || access.Owner != "alice" || access.Role != models.RoleEditor || len(access.Grants) != 1 {
+		t.Errorf("GetTodoAccess = %+v, %v", access, err)
+	}
+
+	// The last event went to alice and bob only
+	last := env.events.events[len(env.events.events)-1]
+	if !last.VisibleTo(1, "alice") || !last.VisibleTo(1, "bob") || last.VisibleTo(1, "carol") || last.VisibleTo(1, "") {
+		t.Errorf("audience = %v", last.Audience)
+	}
+
+	// Only the owner may revoke others; anyone may leave
+	wantError(t, env.access.RevokeTodoAccess(ctx, "carol", private.ID, "bob"), "todo not found")
+	if err := env.access.RevokeTodoAccess(ctx, "bob", private.ID, "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if got := env.visible(t, "bob"); len(got) != 1 {
+		t.Errorf("bob sees %v after leaving", got)
+	}
+	wantError(t, env.access.RevokeTodoAccess(ctx, "alice", private.ID, "bob"), "grant not found")
+	if err := env.todos.DeleteTodo(ctx, "alice", private.ID); err != nil {
+		t.Errorf("owner delete: %v", err)
+	}
+}
+
+func TestGrantValidation(t *testing.T) {
+	env := newAccessTestEnv(t)
+	private, _ := env.todos.CreateTodo(ctx, "alice", &dto.CreateTodoRequest{Title: "Private"})
+	public, _ := env.todos.CreateTodo(ctx, "", &dto.CreateTodoRequest{Title: "Public"})
+
+	tests := []struct {
+		name      string
+		principal string
+		todoID    uint
+		req       dto.GrantAccessRequest
+		want      string
+	}{
+		{"unknown role", "alice", private.ID, dto.GrantAccessRequest{Principal: "bob", Role: "admin"}, "validation failed"},
+		{"missing principal", "alice", private.ID, dto.GrantAccessRequest{Role: models.RoleViewer}, "validation failed"},
+		{"to the owner", "alice", private.ID, dto.GrantAccessRequest{Principal: "alice", Role: models.RoleViewer}, "validation failed"},
+		{"todo without owner", "alice", public.ID, dto.GrantAccessRequest{Principal: "bob", Role: models.RoleViewer}, "validation failed"},
+		{"missing todo", "alice", 99, dto.GrantAccessRequest{Principal: "bob", Role: models.RoleViewer}, "todo not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := env.access.GrantTodoAccess(ctx, tt.principal, tt.todoID, &tt.req)
+			wantError(t, err, tt.want)
+		})
+	}
+
+	_, err := env.access.ShareList(ctx, "", &dto.GrantAccessRequest{Principal: "bob", Role: models.RoleViewer})
+	wantError(t, err, "forbidden")
+	_, err = env.access.ShareList(ctx, "alice", &dto.GrantAccessRequest{Principal: "alice", Role: models.RoleViewer})
+	wantError(t, err, "validation failed")
+}
+
+func TestListShares(t *testing.T) {
+	env := newAccessTestEnv(t)
+	env.todos.CreateTodo(ctx, "alice", &dto.CreateTodoRequest{Title: "Before"})
+	env.todos.CreateTodo(ctx, "dave", &dto.CreateTodoRequest{Title: "Unrelated"})
+	if got := env.visible(t, "carol"); len(got) != 0 {
+		t.Fatalf("carol sees %v before sharing", got)
+	}
+
+	if _, err := env.access.ShareList(ctx, "alice", &dto.GrantAccessRequest{Principal: "carol", Role: models.RoleCommenter}); err != nil {
+		t.Fatal(err)
+	}
+	later, _ := env.todos.CreateTodo(ctx, "alice", &dto.CreateTodoRequest{Title: "After"})
+	if got := env.visible(t, "carol"); len(got) != 2 {
+		t.Fatalf("carol sees %v after sharing", got)
+	}
+	if !env.events.events[len(env.events.events)-1].VisibleTo(1, "carol") {
+		t.Error("creation event of a shared list not sent to carol")
+	}
+	if _, err := env.comments.CreateComment(ctx, "carol", later.ID, &dto.CreateCommentRequest{Body: "Noted"}); err != nil {
+		t.Errorf("commenter comment: %v", err)
+	}
+	_, err := env.todos.UpdateTodo(ctx, "carol", later.ID, &dto.UpdateTodoRequest{Title: strPtr("Changed")})
+	wantError(t, err, "forbidden")
+
+	shares, err := env.access.GetShares(ctx, "alice")
+	if err != nil || len(shares) != 1 || shares[0].Principal != "carol" {
+		t.Errorf("GetShares = %+v, %v", shares, err)
+	}
+	if err := env.access.UnshareList(ctx, "alice", "carol"); err != nil {
+		t.Fatal(err)
+	}
+	if got := env.visible(t, "carol"); len(got) != 0 {
+		t.Errorf("carol sees %v after unsharing", got)
+	}
+}
+
+func TestSyncOnlySeesVisibleTodos(t *testing.T) {
+	env := newAccessTestEnv(t)
+	private, _ := env.todos.CreateTodo(ctx, "alice", &dto.CreateTodoRequest{Title: "Private"})
+	env.todos.CreateTodo(ctx, "bob", &dto.CreateTodoRequest{Title: "Own"})
+
+	resp, err := env.sync.Sync(ctx, "bob", &dto.SyncRequest{Mutations: []dto.SyncMutation{
+		{Op: dto.SyncUpdate, ID: private.ID, Fields: dto.UpdateTodoRequest{Title: strPtr("Mine")}, UpdatedAt: time.Now()},
+		{Op: dto.SyncCreate, ClientID: "c1", Fields: dto.UpdateTodoRequest{Title: strPtr("Offline")}, UpdatedAt: time.Now()},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Rejected) != 1 || resp.Rejected[0].Reason != dto.SyncRejectNotFound {
+		t.Errorf("rejected = %+v", resp.Rejected)
+	}
+	if len(resp.Changes) != 2 {
+		t.Fatalf("bob pulled %+v", resp.Changes)
+	}
+	for _, change := range resp.Changes {
+		if change.Todo.Owner != "bob" {
+			t.Errorf("bob pulled %+v", change.Todo)
+		}
+	}
+
+	env.access.GrantTodoAccess(ctx, "alice", private.ID, &dto.GrantAccessRequest{Principal: "bob", Role: models.RoleViewer})
+	resp, err = env.sync.Sync(ctx, "bob", &dto.SyncRequest{Mutations: []dto.SyncMutation{
+		{Op: dto.SyncDelete, ID: private.ID, UpdatedAt: time.Now()},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Rejected) != 1 || resp.Rejected[0].Reason != dto.SyncRejectForbidden || len(resp.Changes) != 3 {
+		t.Errorf("viewer delete: rejected %+v, %d changes", resp.Rejected, len(resp.Changes))
+	}
+}
@@ -0,0 +1,17 @@
+package service
+
+import (
+	"context"
+	"todo-app/dto"
+)
+
+type APIKeyService interface {
+	// CreateKey creates a key acting for owner. A request made with an API
+	// key can only create keys with scopes of its own.
+	CreateKey(ctx context.Context, owner string, req *dto.CreateAPIKeyRequest) (*dto.APIKeyResponse, error)
+	GetKeys(ctx context.Context, owner string) ([]*dto.APIKeyResponse, error)
+	RevokeKey(ctx context.Context, owner string, id uint) (*dto.APIKeyResponse, error)
+	// Authenticate returns the key a secret belongs to, in whichever
+	// workspace it is, and records that it was used.
+	Authenticate(ctx context.Context, key string) (*dto.APIKeyResponse, error)
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"todo-app/auth"
+	"todo-app/dto"
+	"todo-app/models"
+	"todo-app/repository"
+	"todo-app/tenant"
+	"todo-app/utils"
+)
+
+// apiKeyPrefixLength is how much of a key is stored in the clear to
+// identify it.
+const apiKeyPrefixLength = len(auth.APIKeyPrefix) + 8
+
+// lastUsedInterval limits how often using a key writes its last-used time.
+const lastUsedInterval = time.Minute
+
+type APIKeyServiceImpl struct {
+	keyRepo       repository.APIKeyRepository
+	workspaceRepo repository.WorkspaceRepository
+	now           func() time.Time
+}
+
+func NewAPIKeyService(keyRepo repository.APIKeyRepository, workspaceRepo repository.WorkspaceRepository) APIKeyService {
+	return &APIKeyServiceImpl{
+		keyRepo:       keyRepo,
+		workspaceRepo: workspaceRepo,
+		now:           time.Now,
+	}
+}
+
+func (s *APIKeyServiceImpl) CreateKey(ctx context.Context, owner string, req *dto.CreateAPIKeyRequest) (*dto.APIKeyResponse, error) {
+	// Validate request
+	if validationErrors := utils.ValidateStruct(req); len(validationErrors) > 0 {
+		return nil, errors.New("validation failed: " + validationErrors[0])
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(s.now()) {
+		return nil, errors.New("validation failed: expires_at must be in the future")
+	}
+	scopes := uniqueStrings(req.Scopes)
+	for _, scope := range scopes {
+		if !auth.Allows(ctx, scope) {
+			return nil, fmt.Errorf("forbidden: cannot grant the %s scope the API key in use lacks", scope)
+		}
+	}
+
+	secret, err := newAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := s.keyRepo.Create(ctx, &models.APIKey{
+		Owner:     owner,
+		Name:      req.Name,
+		Prefix:    secret[:apiKeyPrefixLength],
+		KeyHash:   hashAPIKey(secret),
+		Scopes:    strings.Join(scopes, ","),
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := s.keyToResponse(key)
+	response.Key = secret
+	return response, nil
+}
+
+func (s *APIKeyServiceImpl) GetKeys(ctx context.Context, owner string) ([]*dto.APIKeyResponse, error) {
+	keys, err := s.keyRepo.GetAllByOwner(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*dto.APIKeyResponse, len(keys))
+	for i, key := range keys {
+		responses[i] = s.keyToResponse(key)
+	}
+	return responses, nil
+}
+
+func (s *APIKeyServiceImpl) RevokeKey(ctx context.Context, owner string, id uint) (*dto.APIKeyResponse, error) {
+	key, err := s.keyRepo.Revoke(ctx, id, owner, s.now())
+	if err != nil {
+		return nil, err
+	}
+	return s.keyToResponse(key), nil
+}
+
+func (s *APIKeyServiceImpl) Authenticate(ctx context.Context, secret string) (*dto.APIKeyResponse, error) {
+	if !strings.HasPrefix(secret, auth.APIKeyPrefix) {
+		return nil, errors.New("api key not found")
+	}
+
+	// Keys are unique everywhere; the key tells which workspace it acts in
+	key, err := s.keyRepo.GetByHash(tenant.AllWorkspaces(ctx), hashAPIKey(secret))
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	if key.RevokedAt != nil {
+		return nil, errors.New("api key revoked")
+	}
+	if key.ExpiresAt != nil && !key.ExpiresAt.After(now) {
+		return nil, errors.New("api key expired")
+	}
+
+	ctx = tenant.WithWorkspace(ctx, key.WorkspaceID)
+	workspace, err := s.workspaceRepo.GetByID(ctx, key.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedInterval {
+		if err := s.keyRepo.Touch(ctx, key.ID, now); err != nil {
+			return nil, err
+		}
+		key.LastUsedAt = &now
+	}
+
+	response := s.keyToResponse(key)
+	response.Workspace = workspace.Slug
+	return response, nil
+}
+
+// Helper method to convert APIKey model to APIKeyResponse DTO
+func (s *APIKeyServiceImpl) keyToResponse(key *models.APIKey) *dto.APIKeyResponse {
+	return &dto.APIKeyResponse{
+		ID:         key.ID,
+		Owner:      key.Owner,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.ScopeList(),
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		RevokedAt:  key.RevokedAt,
+		CreatedAt:  key.CreatedAt,
+	}
+}
+
+// newAPIKey returns a key of 256 random bits, URL-safe encoded.
+func newAPIKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return auth.APIKeyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"todo-app/auth"
+	"todo-app/dto"
+	"todo-app/repository"
+	"todo-app/tenant"
+)
+
+func newAPIKeyTestService(t *testing.T) (*APIKeyServiceImpl, *time.Time) {
+	t.Helper()
+	db := newTestDB(t)
+	workspaceRepo := repository.NewWorkspaceRepository(db)
+	for _, slug := range []string{"acme", "globex"} {
+		if _, err := workspaceRepo.Ensure(tenant.AllWorkspaces(context.Background()), slug); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+	s := NewAPIKeyService(repository.NewAPIKeyRepository(db), workspaceRepo).(*APIKeyServiceImpl)
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	s, now := newAPIKeyTestService(t)
+	expires := now.Add(24 * time.Hour)
+
+	created, err := s.CreateKey(ctx, "ci", &dto.CreateAPIKeyRequest{
+		Name:      "Deploy bot",
+		Scopes:    []string{auth.ScopeTodosRead, auth.ScopeTodosWrite, auth.ScopeTodosRead},
+		ExpiresAt: &expires,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(created.Key, created.Prefix) || !strings.HasPrefix(created.Prefix, auth.APIKeyPrefix) || len(created.Scopes) != 2 {
+		t.Fatalf("unexpected key: %+v", created)
+	}
+
+	keys, _ := s.GetKeys(ctx, "ci")
+	if len(keys) != 1 || keys[0].Key != "" || keys[0].Prefix != created.Prefix {
+		t.Errorf("GetKeys = %+v", keys)
+	}
+	if keys, _ := s.GetKeys(ctx, "someone-else"); len(keys) != 0 {
+		t.Errorf("another owner sees %+v", keys)
+	}
+
+	// Authenticating records the use, at most once a minute
+	key, err := s.Authenticate(context.Background(), created.Key)
+	if err != nil || key.Owner != "ci" || key.Workspace != "acme" || key.LastUsedAt == nil {
+		t.Fatalf("Authenticate = %+v, %v", key, err)
+	}
+	used := *now
+	*now = now.Add(30 * time.Second)
+	s.Authenticate(context.Background(), created.Key)
+	if keys, _ := s.GetKeys(ctx, "ci"); !keys[0].LastUsedAt.Equal(used) {
+		t.Errorf("last_used_at = %v, want %v", keys[0].LastUsedAt, used)
+	}
+
+	for _, secret := range []string{"", "tk_wrong", created.Prefix, "Bearer " + created.Key} {
+		if _, err := s.Authenticate(context.Background(), secret); err == nil || err.Error() != "api key not found" {
+			t.Errorf("Authenticate(%q): %v", secret, err)
+		}
+	}
+
+	*now = expires
+	_, err = s.Authenticate(context.Background(), created.Key)
+	wantError(t, err, "api key expired")
+
+	_, err = s.RevokeKey(ctx, "someone-else", created.ID)
+	wantError(t, err, "api key not found")
+	revoked, err := s.RevokeKey(ctx, "ci", created.ID)
+	if err != nil || revoked.RevokedAt == nil {
+		t.Fatalf("RevokeKey = %+v, %v", revoked, err)
+	}
+	_, err = s.Authenticate(context.Background(), created.Key)
+	wantError(t, err, "api key revoked")
+}
+
+func TestAPIKeyValidation(t *testing.T) {
+	s, now := newAPIKeyTestService(t)
+	past := now.Add(-time.Minute)
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		req  dto.CreateAPIKeyRequest
+		want string
+	}{
+		{"missing name", ctx, dto.CreateAPIKeyRequest{Scopes: []string{auth.ScopeTodosRead}}, "validation failed"},
+		{"no scopes", ctx, dto.CreateAPIKeyRequest{Name: "bot"}, "validation failed"},
+		{"unknown scope", ctx, dto.CreateAPIKeyRequest{Name: "bot", Scopes: []string{"todos:admin"}}, "validation failed"},
+		{"expired", ctx, dto.CreateAPIKeyRequest{Name: "bot", Scopes: []string{auth.ScopeTodosRead}, ExpiresAt: &past}, "validation failed"},
+		// A key cannot mint a key with more access than it has
+		{"escalation", auth.WithScopes(ctx, []string{auth.ScopeKeysWrite, auth.ScopeTodosRead}),
+			dto.CreateAPIKeyRequest{Name: "bot", Scopes: []string{auth.ScopeTodosRead, auth.ScopeTodosWrite}}, "forbidden"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.CreateKey(tt.ctx, "ci", &tt.req)
+			wantError(t, err, tt.want)
+		})
+	}
+
+	if _, err := s.CreateKey(auth.WithScopes(ctx, []string{auth.ScopeKeysWrite, auth.ScopeTodosRead}), "ci",
+		&dto.CreateAPIKeyRequest{Name: "reader", Scopes: []string{auth.ScopeTodosRead}}); err != nil {
+		t.Errorf("key with a subset of the scopes: %v", err)
+	}
+}
+
+func TestAPIKeysStayInTheirWorkspace(t *testing.T) {
+	s, _ := newAPIKeyTestService(t)
+	globex := tenant.WithWorkspace(context.Background(), 2)
+
+	created, err := s.CreateKey(globex, "ci", &dto.CreateAPIKeyRequest{Name: "bot", Scopes: []string{auth.ScopeTodosRead}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys, _ := s.GetKeys(ctx, "ci"); len(keys) != 0 {
+		t.Errorf("acme lists %+v", keys)
+	}
+	_, err = s.RevokeKey(ctx, "ci", created.ID)
+	wantError(t, err, "api key not found")
+	if key, err := s.Authenticate(context.Background(), created.Key); err != nil || key.Workspace != "globex" {
+		t.Errorf("Authenticate = %+v, %v", key, err)
+	}
+}
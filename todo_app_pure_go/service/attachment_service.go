@@ -0,0 +1,30 @@
+package service
+
+import (
+	"context"
+	"io"
+	"todo-app/dto"
+	"todo-app/events"
+)
+
+// AttachmentService manages the files attached to todos on behalf of
+// principal. Listing and downloading need the viewer role on the todo,
+// uploading and deleting the editor role.
+type AttachmentService interface {
+	// Upload stores content as a new attachment of the todo. The content
+	// type is sniffed from the content, not taken from the client. A
+	// non-empty checksum is the hex SHA-256 the content must have.
+	Upload(ctx context.Context, principal string, todoID uint, filename string, content io.Reader, checksum string) (*dto.AttachmentResponse, error)
+	GetAttachments(ctx context.Context, principal string, todoID uint) ([]*dto.AttachmentResponse, error)
+	// Open returns an attachment with its content; the caller must close it.
+	Open(ctx context.Context, principal string, todoID, id uint) (*dto.AttachmentResponse, io.ReadSeekCloser, error)
+	DeleteAttachment(ctx context.Context, principal string, todoID, id uint) error
+	// CollectGarbage deletes blobs no attachment in any workspace refers to
+	// any more, such as those of deleted todos, and returns how many were
+	// deleted.
+	CollectGarbage(ctx context.Context) (int, error)
+	// HandleEvent deletes the blobs of a deleted todo's attachments in the
+	// background, unless other attachments still refer to them. It is meant
+	// to be subscribed to the event bus.
+	HandleEvent(event events.Event)
+}
@@ -0,0 +1,262 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"todo-app/dto"
+	"todo-app/events"
+	"todo-app/models"
+	"todo-app/repository"
+	"todo-app/storage"
+	"todo-app/tenant"
+	"unicode"
+	"unicode/utf8"
+)
+
+// sniffLen is how much content http.DetectContentType looks at.
+const sniffLen = 512
+
+type AttachmentOptions struct {
+	// MaxSize is the largest accepted attachment in bytes.
+	MaxSize int64
+	// AllowedTypes lists the accepted media types, such as image/png.
+	AllowedTypes []string
+}
+
+type AttachmentServiceImpl struct {
+	attachmentRepo repository.AttachmentRepository
+	access         authorizer
+	store          storage.BlobStore
+	opts           AttachmentOptions
+
+	// blobs lets uploads run concurrently but keeps them apart from blob
+	// deletion: an upload of content that is about to lose its last
+	// reference must not have its blob deleted before its row exists.
+	blobs sync.RWMutex
+}
+
+func NewAttachmentService(attachmentRepo repository.AttachmentRepository, todoRepo repository.TodoRepository, accessRepo repository.AccessRepository, store storage.BlobStore, opts AttachmentOptions) AttachmentService {
+	return &AttachmentServiceImpl{
+		attachmentRepo: attachmentRepo,
+		access:         authorizer{todoRepo: todoRepo, accessRepo: accessRepo},
+		store:          store,
+		opts:           opts,
+	}
+}
+
+func (s *AttachmentServiceImpl) Upload(ctx context.Context, principal string, todoID uint, filename string, content io.Reader, checksum string) (*dto.AttachmentResponse, error) {
+	if checksum != "" && !storage.ValidDigest(strings.ToLower(checksum)) {
+		return nil, errors.New("validation failed: checksum must be a hex SHA-256 digest")
+	}
+	if _, _, err := s.access.authorize(ctx, principal, todoID, models.RoleEditor); err != nil {
+		return nil, err
+	}
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(content, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, errors.New("validation failed: file is empty")
+	}
+	head = head[:n]
+
+	contentType := http.DetectContentType(head)
+	if !s.allowed(contentType) {
+		return nil, fmt.Errorf("unsupported attachment type: %s", contentType)
+	}
+
+	s.blobs.RLock()
+	defer s.blobs.RUnlock()
+
+	blob, err := s.store.Put(io.MultiReader(bytes.NewReader(head), content), storage.PutOptions{
+		MaxSize: s.opts.MaxSize,
+		SHA256:  checksum,
+	})
+	switch {
+	case errors.Is(err, storage.ErrTooLarge):
+		return nil, fmt.Errorf("attachment too large: the maximum size is %d bytes", s.opts.MaxSize)
+	case errors.Is(err, storage.ErrChecksumMismatch):
+		return nil, errors.New("validation failed: checksum does not match the uploaded content")
+	case err != nil:
+		return nil, err
+	}
+
+	attachment, err := s.attachmentRepo.Create(ctx, &models.Attachment{
+		TodoID:      todoID,
+		Filename:    cleanFilename(filename),
+		ContentType: contentType,
+		Size:        blob.Size,
+		SHA256:      blob.SHA256,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return attachmentToResponse(attachment), nil
+}
+
+func (s *AttachmentServiceImpl) GetAttachments(ctx context.Context, principal string, todoID uint) ([]*dto.AttachmentResponse, error) {
+	if _, _, err := s.access.authorize(ctx, principal, todoID, models.RoleViewer); err != nil {
+		return nil, err
+	}
+	attachments, err := s.attachmentRepo.GetAllByTodo(ctx, todoID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*dto.AttachmentResponse, len(attachments))
+	for i, attachment := range attachments {
+		responses[i] = attachmentToResponse(attachment)
+	}
+	return responses, nil
+}
+
+func (s *AttachmentServiceImpl) Open(ctx context.Context, principal string, todoID, id uint) (*dto.AttachmentResponse, io.ReadSeekCloser, error) {
+	if _, _, err := s.access.authorize(ctx, principal, todoID, models.RoleViewer); err != nil {
+		return nil, nil, err
+	}
+	attachment, err := s.attachmentRepo.GetByID(ctx, todoID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	content, err := s.store.Open(attachment.SHA256)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil, fmt.Errorf("content of attachment %d is missing", id)
+		}
+		return nil, nil, err
+	}
+	return attachmentToResponse(attachment), content, nil
+}
+
+func (s *AttachmentServiceImpl) DeleteAttachment(ctx context.Context, principal string, todoID, id uint) error {
+	if _, _, err := s.access.authorize(ctx, principal, todoID, models.RoleEditor); err != nil {
+		return err
+	}
+
+	s.blobs.Lock()
+	defer s.blobs.Unlock()
+
+	attachment, err := s.attachmentRepo.GetByID(ctx, todoID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.attachmentRepo.Delete(ctx, attachment.ID); err != nil {
+		return err
+	}
+	return s.deleteIfUnreferenced(ctx, attachment.SHA256)
+}
+
+func (s *AttachmentServiceImpl) CollectGarbage(ctx context.Context) (int, error) {
+	ctx = tenant.AllWorkspaces(ctx)
+
+	s.blobs.Lock()
+	defer s.blobs.Unlock()
+
+	var digests []string
+	if err := s.store.Walk(func(digest string) error {
+		digests = append(digests, digest)
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, digest := range digests {
+		referenced, err := s.attachmentRepo.IsReferenced(ctx, digest)
+		if err != nil {
+			return deleted, err
+		}
+		if referenced {
+			continue
+		}
+		if err := s.store.Delete(digest); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+func (s *AttachmentServiceImpl) HandleEvent(event events.Event) {
+	if event.Type != events.TodoDeleted || len(event.Attachments) == 0 {
+		return
+	}
+	go func() {
+		s.blobs.Lock()
+		defer s.blobs.Unlock()
+
+		for _, digest := range event.Attachments {
+			if err := s.deleteIfUnreferenced(context.Background(), digest); err != nil {
+				log.Printf("Failed to delete attachments of todo %d: %v", event.Todo.ID, err)
+				return
+			}
+		}
+	}()
+}
+
+func (s *AttachmentServiceImpl) deleteIfUnreferenced(ctx context.Context, digest string) error {
+	// Identical uploads share a blob, whichever workspace they are in
+	referenced, err := s.attachmentRepo.IsReferenced(tenant.AllWorkspaces(ctx), digest)
+	if err != nil || referenced {
+		return err
+	}
+	return s.store.Delete(digest)
+}
+
+func (s *AttachmentServiceImpl) allowed(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range s.opts.AllowedTypes {
+		if strings.EqualFold(mediaType, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanFilename keeps the base name of a client-supplied file name without
+// control characters, so it is safe to echo in Content-Disposition.
+func cleanFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	// Drop leading runes rather than the extension
+	for len(name) > 255 {
+		_, size := utf8.DecodeRuneInString(name)
+		name = name[size:]
+	}
+	return name
+}
+
+func attachmentToResponse(attachment *models.Attachment) *dto.AttachmentResponse {
+	return &dto.AttachmentResponse{
+		ID:          attachment.ID,
+		TodoID:      attachment.TodoID,
+		Filename:    attachment.Filename,
+		ContentType: attachment.ContentType,
+		Size:        attachment.Size,
+		SHA256:      attachment.SHA256,
+		CreatedAt:   attachment.CreatedAt,
+	}
+}
@@ -0,0 +1,19 @@
+package service
+
+import (
+	"context"
+	"todo-app/dto"
+)
+
+// AuthService signs users in through an OpenID Connect provider and issues
+// the session tokens they then authenticate with.
+type AuthService interface {
+	// StartLogin begins a login to the workspace of ctx.
+	StartLogin(ctx context.Context) (*dto.LoginRedirect, error)
+	// FinishLogin completes the login StartLogin returned state for. The
+	// first login to a workspace creates the user there.
+	FinishLogin(ctx context.Context, state string, callback *dto.LoginCallback) (*dto.SessionResponse, error)
+	// Authenticate returns the user a session token belongs to, in
+	// whichever workspace it is.
+	Authenticate(ctx context.Context, token string) (*dto.UserResponse, error)
+}
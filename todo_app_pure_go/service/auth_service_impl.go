@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"todo-app/dto"
+	"todo-app/jwt"
+	"todo-app/models"
+	"todo-app/oidc"
+	"todo-app/repository"
+	"todo-app/tenant"
+)
+
+// loginTTL is how long a user has to complete a login at the provider.
+const loginTTL = 10 * time.Minute
+
+// sessionIssuer names the application in the session tokens it issues.
+const sessionIssuer = "todo-app"
+
+// Login state and session tokens are signed with the same secret; their
+// use claim keeps one from being passed off as the other.
+const (
+	useLogin   = "login"
+	useSession = "session"
+)
+
+// AuthOptions configure the session tokens AuthService issues.
+type AuthOptions struct {
+	// SessionSecret signs login state and session tokens
+	SessionSecret []byte
+	SessionTTL    time.Duration
+}
+
+type AuthServiceImpl struct {
+	provider      *oidc.Provider
+	userRepo      repository.UserRepository
+	workspaceRepo repository.WorkspaceRepository
+	opts          AuthOptions
+	now           func() time.Time
+}
+
+func NewAuthService(provider *oidc.Provider, userRepo repository.UserRepository, workspaceRepo repository.WorkspaceRepository, opts AuthOptions) AuthService {
+	return &AuthServiceImpl{
+		provider:      provider,
+		userRepo:      userRepo,
+		workspaceRepo: workspaceRepo,
+		opts:          opts,
+		now:           time.Now,
+	}
+}
+
+// loginState is what the application remembers about a login in progress:
+// the state and nonce it sent and the PKCE verifier to redeem the code with.
+type loginState struct {
+	Use       string `json:"use"`
+	State     string `json:"state"`
+	Nonce     string `json:"nonce"`
+	Verifier  string `json:"verifier"`
+	Workspace uint   `json:"wid"`
+	Expiry    int64  `json:"exp"`
+}
+
+// sessionClaims are the claims of a session token.
+type sessionClaims struct {
+	Use         string `json:"use"`
+	Issuer      string `json:"iss"`
+	Subject     string `json:"sub"`
+	Workspace   string `json:"ws"`
+	WorkspaceID uint   `json:"wid"`
+	IssuedAt    int64  `json:"iat"`
+	Expiry      int64  `json:"exp"`
+}
+
+func (s *AuthServiceImpl) StartLogin(ctx context.Context) (*dto.LoginRedirect, error) {
+	workspaceID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, errors.New("workspace not found")
+	}
+
+	login := loginState{Use: useLogin, Workspace: workspaceID, Expiry: s.now().Add(loginTTL).Unix()}
+	for _, value := range []*string{&login.State, &login.Nonce, &login.Verifier} {
+		random, err := oidc.RandomString()
+		if err != nil {
+			return nil, err
+		}
+		*value = random
+	}
+	state, err := jwt.SignHS256(login, s.opts.SessionSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.LoginRedirect{
+		URL:   s.provider.AuthCodeURL(login.State, login.Nonce, login.Verifier),
+		State: state,
+	}, nil
+}
+
+func (s *AuthServiceImpl) FinishLogin(ctx context.Context, state string, callback *dto.LoginCallback) (*dto.SessionResponse, error) {
+	var login loginState
+	if err := s.verify(state, &login); err != nil || login.Use != useLogin || !s.now().Before(time.Unix(login.Expiry, 0)) ||
+		callback.State == "" || callback.State != login.State {
+		return nil, errors.New("invalid login state")
+	}
+	if callback.Error != "" {
+		return nil, fmt.Errorf("login failed: %s", strings.TrimSpace(callback.Error+" "+callback.ErrorDescription))
+	}
+	if callback.Code == "" {
+		return nil, errors.New("validation failed: code is required")
+	}
+
+	rawIDToken, err := s.provider.Exchange(ctx, callback.Code, login.Verifier)
+	if err != nil {
+		return nil, fmt.Errorf("provider error: %w", err)
+	}
+	idToken, err := s.provider.Verify(ctx, rawIDToken, login.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("login failed: %w", err)
+	}
+	// Users act under their email address, so the provider must vouch for it
+	if idToken.Email == "" || !idToken.EmailVerified {
+		return nil, errors.New("login failed: the provider did not share a verified email address")
+	}
+
+	ctx = tenant.WithWorkspace(ctx, login.Workspace)
+	workspace, err := s.workspaceRepo.GetByID(ctx, login.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.provision(ctx, idToken)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	expires := now.Add(s.opts.SessionTTL)
+	token, err := jwt.SignHS256(sessionClaims{
+		Use:         useSession,
+		Issuer:      sessionIssuer,
+		Subject:     strconv.FormatUint(uint64(user.ID), 10),
+		Workspace:   workspace.Slug,
+		WorkspaceID: workspace.ID,
+		IssuedAt:    now.Unix(),
+		Expiry:      expires.Unix(),
+	}, s.opts.SessionSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.SessionResponse{
+		Token:     token,
+		ExpiresAt: time.Unix(expires.Unix(), 0).UTC(),
+		User:      userToResponse(user, workspace.Slug),
+	}, nil
+}
+
+// provision returns the user of idToken in the workspace of ctx, creating
+// it on the first login and updating its profile on later ones.
+func (s *AuthServiceImpl) provision(ctx context.Context, idToken *oidc.IDToken) (*models.User, error) {
+	now := s.now()
+	user, err := s.userRepo.GetByIdentity(ctx, idToken.Issuer, idToken.Subject)
+	if err == nil {
+		// The principal stays what it was, even if the email changed
+		if err := s.userRepo.RecordLogin(ctx, user.ID, idToken.Email, idToken.Name, now); err != nil {
+			return nil, err
+		}
+		user.Email, user.Name, user.LastLoginAt = idToken.Email, idToken.Name, now
+		return user, nil
+	}
+	if err.Error() != "user not found" {
+		return nil, err
+	}
+
+	principal := strings.ToLower(idToken.Email)
+	if _, err := s.userRepo.GetByPrincipal(ctx, principal); err == nil {
+		return nil, fmt.Errorf("forbidden: %s belongs to another account", principal)
+	} else if err.Error() != "user not found" {
+		return nil, err
+	}
+	return s.userRepo.Create(ctx, &models.User{
+		Issuer:      idToken.Issuer,
+		Subject:     idToken.Subject,
+		Principal:   principal,
+		Email:       idToken.Email,
+		Name:        idToken.Name,
+		LastLoginAt: now,
+	})
+}
+
+func (s *AuthServiceImpl) Authenticate(ctx context.Context, token string) (*dto.UserResponse, error) {
+	var claims sessionClaims
+	if err := s.verify(token, &claims); err != nil || claims.Use != useSession || claims.Issuer != sessionIssuer {
+		return nil, errors.New("invalid session")
+	}
+	if !s.now().Before(time.Unix(claims.Expiry, 0)) {
+		return nil, errors.New("session expired")
+	}
+	id, err := strconv.ParseUint(claims.Subject, 10, 0)
+	if err != nil {
+		return nil, errors.New("invalid session")
+	}
+
+	// Users removed since the session was issued are signed out
+	user, err := s.userRepo.GetByID(tenant.WithWorkspace(ctx, claims.WorkspaceID), uint(id))
+	if err != nil {
+		if err.Error() == "user not found" {
+			return nil, errors.New("invalid session")
+		}
+		return nil, err
+	}
+	return userToResponse(user, claims.Workspace), nil
+}
+
+// verify checks that token was signed by this application and decodes its
+// claims into v.
+func (s *AuthServiceImpl) verify(token string, v interface{}) error {
+	if len(s.opts.SessionSecret) == 0 {
+		return errors.New("no session secret configured")
+	}
+	parsed, err := jwt.Parse(token)
+	if err != nil {
+		return err
+	}
+	if err := parsed.VerifyHS256(s.opts.SessionSecret); err != nil {
+		return err
+	}
+	return parsed.Claims(v)
+}
+
+func userToResponse(user *models.User, workspace string) *dto.UserResponse {
+	return &dto.UserResponse{
+		ID:          user.ID,
+		Principal:   user.Principal,
+		Email:       user.Email,
+		Name:        user.Name,
+		Workspace:   workspace,
+		CreatedAt:   user.CreatedAt,
+		LastLoginAt: user.LastLoginAt,
+	}
+}
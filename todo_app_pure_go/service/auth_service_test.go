@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"todo-app/dto"
+	"todo-app/oidc"
+	"todo-app/oidc/oidctest"
+	"todo-app/repository"
+	"todo-app/tenant"
+)
+
+var alice = &oidctest.User{Subject: "u-1", Email: "Alice@example.com", EmailVerified: true, Name: "Alice"}
+
+func newAuthTestService(t *testing.T) (*AuthServiceImpl, *oidctest.Provider) {
+	t.Helper()
+	db := newTestDB(t)
+	workspaceRepo := repository.NewWorkspaceRepository(db)
+	for _, slug := range []string{"acme", "globex"} {
+		if _, err := workspaceRepo.Ensure(tenant.AllWorkspaces(context.Background()), slug); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fake := oidctest.NewProvider("todo-app", "s3cret")
+	t.Cleanup(fake.Close)
+	provider, err := oidc.Discover(context.Background(), fake.Issuer(), oidc.Config{
+		ClientID:     fake.ClientID,
+		ClientSecret: fake.ClientSecret,
+		RedirectURL:  "https://todo.example.com/auth/callback",
+		Scopes:       []string{"email", "profile"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewAuthService(provider, repository.NewUserRepository(db), workspaceRepo, AuthOptions{
+		SessionSecret: []byte("0123456789abcdef0123456789abcdef"),
+		SessionTTL:    time.Hour,
+	}).(*AuthServiceImpl)
+	return s, fake
+}
+
+// login signs in to the workspace of ctx the way a browser would: it
+// follows the redirect to the provider and hands the callback back.
+func login(t *testing.T, s *AuthServiceImpl, ctx context.Context) (*dto.SessionResponse, error) {
+	t.Helper()
+	redirect, err := s.StartLogin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.FinishLogin(context.Background(), redirect.State, authorize(t, redirect.URL))
+}
+
+func authorize(t *testing.T, authURL string) *dto.LoginCallback {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := location.Query()
+	return &dto.LoginCallback{Code: q.Get("code"), State: q.Get("state"), Error: q.Get("error")}
+}
+
+func TestLoginProvisionsUserAndIssuesSession(t *testing.T) {
+	s, fake := newAuthTestService(t)
+	fake.SignIn(alice)
+
+	session, err := login(t, s, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := session.User
+	if user.Principal != "alice@example.com" || user.Name != "Alice" || user.Workspace != "acme" || session.Token == "" {
+		t.Fatalf("unexpected session %+v, user %+v", session, user)
+	}
+
+	authenticated, err := s.Authenticate(context.Background(), session.Token)
+	if err != nil || authenticated.ID != user.ID || authenticated.Workspace != "acme" {
+		t.Fatalf("Authenticate = %+v, %v", authenticated, err)
+	}
+
+	// Later logins find the same user and refresh the profile, but the
+	// principal stays
+	fake.SignIn(&oidctest.User{Subject: "u-1", Email: "alice@example.org", EmailVerified: true, Name: "Alice A."})
+	again, err := login(t, s, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.User.ID != user.ID || again.User.Principal != "alice@example.com" || again.User.Name != "Alice A." {
+		t.Errorf("second login = %+v", again.User)
+	}
+
+	// Another account at the provider cannot take the principal over
+	fake.SignIn(&oidctest.User{Subject: "u-2", Email: "alice@example.com", EmailVerified: true})
+	_, err = login(t, s, ctx)
+	wantError(t, err, "forbidden")
+}
+
+func TestSessionsBelongToOneWorkspace(t *testing.T) {
+	s, fake := newAuthTestService(t)
+	fake.SignIn(alice)
+
+	acme, err := login(t, s, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	globex, err := login(t, s, tenant.WithWorkspace(context.Background(), 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The same person is a separate user in each workspace
+	if globex.User.Workspace != "globex" || globex.User.ID == acme.User.ID {
+		t.Errorf("globex user = %+v, acme user = %+v", globex.User, acme.User)
+	}
+	if user, err := s.Authenticate(context.Background(), globex.Token); err != nil || user.Workspace != "globex" {
+		t.Errorf("Authenticate = %+v, %v", user, err)
+	}
+}
+
+func TestLoginFailures(t *testing.T) {
+	s, fake := newAuthTestService(t)
+
+	// Nobody is signed in at the provider
+	_, err := login(t, s, ctx)
+	wantError(t, err, "login failed: access_denied")
+
+	fake.SignIn(alice)
+	redirect, _ := s.StartLogin(ctx)
+	callback := authorize(t, redirect.URL)
+	other, _ := s.StartLogin(ctx)
+
+	// The callback must come with the state of the login it belongs to
+	for name, state := range map[string]string{"missing": "", "other login": other.State, "garbage": "x.y.z"} {
+		_, err = s.FinishLogin(context.Background(), state, callback)
+		if err == nil || err.Error() != "invalid login state" {
+			t.Errorf("%s state: %v", name, err)
+		}
+	}
+	// A session token is not login state
+	session, err := s.FinishLogin(context.Background(), redirect.State, callback)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.FinishLogin(context.Background(), session.Token, callback)
+	wantError(t, err, "invalid login state")
+
+	// Codes work once
+	_, err = s.FinishLogin(context.Background(), redirect.State, callback)
+	wantError(t, err, "provider error")
+
+	// Login state expires
+	redirect, _ = s.StartLogin(ctx)
+	callback = authorize(t, redirect.URL)
+	s.now = func() time.Time { return time.Now().Add(loginTTL) }
+	_, err = s.FinishLogin(context.Background(), redirect.State, callback)
+	wantError(t, err, "invalid login state")
+	s.now = time.Now
+
+	// The email address must be verified
+	fake.SignIn(&oidctest.User{Subject: "u-3", Email: "mallory@example.com"})
+	_, err = login(t, s, ctx)
+	wantError(t, err, "login failed")
+
+	// ID tokens for another client are refused
+	fake.SignIn(alice)
+	fake.Tamper = func(claims map[string]interface{}) { claims["aud"] = "another-app" }
+	_, err = login(t, s, ctx)
+	wantError(t, err, "login failed")
+}
+
+func TestAuthenticateSession(t *testing.T) {
+	s, fake := newAuthTestService(t)
+	fake.SignIn(alice)
+	session, err := login(t, s, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	redirect, _ := s.StartLogin(ctx)
+	parts := strings.Split(session.Token, ".")
+	for name, token := range map[string]string{
+		"garbage":           "not-a-token",
+		"login state":       redirect.State,
+		"tampered":          parts[0] + "." + strings.Split(redirect.State, ".")[1] + "." + parts[2],
+		"provider ID token": fake.Sign(fake.IDTokenClaims(*alice, "")),
+	} {
+		if _, err := s.Authenticate(context.Background(), token); err == nil || err.Error() != "invalid session" {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	s.now = func() time.Time { return session.ExpiresAt }
+	_, err = s.Authenticate(context.Background(), session.Token)
+	wantError(t, err, "session expired")
+}
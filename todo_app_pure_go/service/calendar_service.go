@@ -0,0 +1,15 @@
+package service
+
+import (
+	"context"
+	"todo-app/dto"
+)
+
+type CalendarService interface {
+	CreateFeed(ctx context.Context, owner string, req *dto.CreateCalendarFeedRequest) (*dto.CalendarFeedResponse, error)
+	GetFeeds(ctx context.Context, owner string) ([]*dto.CalendarFeedResponse, error)
+	DeleteFeed(ctx context.Context, owner string, id uint) error
+	// ResolveFeed returns the feed a secret subscription token belongs to,
+	// in whichever workspace it is.
+	ResolveFeed(ctx context.Context, token string) (*dto.CalendarFeedResponse, error)
+}
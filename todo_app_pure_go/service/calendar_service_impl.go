@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"todo-app/dto"
+	"todo-app/models"
+	"todo-app/repository"
+	"todo-app/tenant"
+	"todo-app/utils"
+)
+
+type CalendarServiceImpl struct {
+	feedRepo repository.CalendarFeedRepository
+}
+
+func NewCalendarService(feedRepo repository.CalendarFeedRepository) CalendarService {
+	return &CalendarServiceImpl{
+		feedRepo: feedRepo,
+	}
+}
+
+func (s *CalendarServiceImpl) CreateFeed(ctx context.Context, owner string, req *dto.CreateCalendarFeedRequest) (*dto.CalendarFeedResponse, error) {
+	// Validate request
+	if validationErrors := utils.ValidateStruct(req); len(validationErrors) > 0 {
+		return nil, errors.New("validation failed: " + validationErrors[0])
+	}
+
+	token, err := newFeedToken()
+	if err != nil {
+		return nil, err
+	}
+
+	feed, err := s.feedRepo.Create(ctx, &models.CalendarFeed{
+		Owner:     owner,
+		Name:      req.Name,
+		TokenHash: hashFeedToken(token),
+		Completed: req.Completed,
+		Priority:  req.Priority,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := s.feedToResponse(feed)
+	response.Token = token
+	return response, nil
+}
+
+func (s *CalendarServiceImpl) GetFeeds(ctx context.Context, owner string) ([]*dto.CalendarFeedResponse, error) {
+	feeds, err := s.feedRepo.GetAllByOwner(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*dto.CalendarFeedResponse, len(feeds))
+	for i, feed := range feeds {
+		responses[i] = s.feedToResponse(feed)
+	}
+	return responses, nil
+}
+
+func (s *CalendarServiceImpl) DeleteFeed(ctx context.Context, owner string, id uint) error {
+	return s.feedRepo.Delete(ctx, id, owner)
+}
+
+func (s *CalendarServiceImpl) ResolveFeed(ctx context.Context, token string) (*dto.CalendarFeedResponse, error) {
+	if token == "" {
+		return nil, errors.New("calendar feed not found")
+	}
+
+	// Tokens are unique everywhere; the feed tells which workspace to serve
+	feed, err := s.feedRepo.GetByTokenHash(tenant.AllWorkspaces(ctx), hashFeedToken(token))
+	if err != nil {
+		return nil, err
+	}
+	return s.feedToResponse(feed), nil
+}
+
+// Helper method to convert CalendarFeed model to CalendarFeedResponse DTO
+func (s *CalendarServiceImpl) feedToResponse(feed *models.CalendarFeed) *dto.CalendarFeedResponse {
+	return &dto.CalendarFeedResponse{
+		ID:        feed.ID,
+		Owner:     feed.Owner,
+		Workspace: feed.WorkspaceID,
+		Name:      feed.Name,
+		Completed: feed.Completed,
+		Priority:  feed.Priority,
+		CreatedAt: feed.CreatedAt,
+	}
+}
+
+// newFeedToken returns 256 random bits, URL-safe encoded.
+func newFeedToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashFeedToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
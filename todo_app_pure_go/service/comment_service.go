@@ -0,0 +1,22 @@
+package service
+
+import (
+	"context"
+	"todo-app/dto"
+)
+
+// CommentService manages discussion threads on todos. author and principal
+// are the authenticated identity, empty when authentication is disabled.
+// Reading comments needs the viewer role on the todo and writing them the
+// commenter role; only a comment's author may edit or delete it.
+type CommentService interface {
+	CreateComment(ctx context.Context, author string, todoID uint, req *dto.CreateCommentRequest) (*dto.CommentResponse, error)
+	// GetComments returns the todo's top-level comments with their replies
+	// nested below them, oldest first at every level.
+	GetComments(ctx context.Context, principal string, todoID uint) ([]*dto.CommentResponse, error)
+	GetComment(ctx context.Context, principal string, todoID, id uint) (*dto.CommentResponse, error)
+	UpdateComment(ctx context.Context, author string, todoID, id uint, req *dto.UpdateCommentRequest) (*dto.CommentResponse, error)
+	DeleteComment(ctx context.Context, author string, todoID, id uint) error
+	// GetRevisions returns the earlier bodies of a comment, oldest first.
+	GetRevisions(ctx context.Context, principal string, todoID, id uint) ([]*dto.CommentRevisionResponse, error)
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"todo-app/dto"
+	"todo-app/models"
+	"todo-app/repository"
+	"todo-app/utils"
+)
+
+type CommentServiceImpl struct {
+	commentRepo repository.CommentRepository
+	access      authorizer
+}
+
+func NewCommentService(commentRepo repository.CommentRepository, todoRepo repository.TodoRepository, accessRepo repository.AccessRepository) CommentService {
+	return &CommentServiceImpl{
+		commentRepo: commentRepo,
+		access:      authorizer{todoRepo: todoRepo, accessRepo: accessRepo},
+	}
+}
+
+func (s *CommentServiceImpl) CreateComment(ctx context.Context, author string, todoID uint, req *dto.CreateCommentRequest) (*dto.CommentResponse, error) {
+	if err := validateCommentBody(req, req.Body); err != nil {
+		return nil, err
+	}
+	if _, _, err := s.access.authorize(ctx, author, todoID, models.RoleCommenter); err != nil {
+		return nil, err
+	}
+
+	if req.ParentID != nil {
+		parent, err := s.commentRepo.GetByID(ctx, todoID, *req.ParentID)
+		if err != nil {
+			if err.Error() == "comment not found" {
+				return nil, errors.New("validation failed: parent_id must be a comment on the same todo")
+			}
+			return nil, err
+		}
+		if parent.Deleted {
+			return nil, errors.New("validation failed: cannot reply to a deleted comment")
+		}
+	}
+
+	comment, err := s.commentRepo.Create(ctx, &models.Comment{
+		TodoID:   todoID,
+		ParentID: req.ParentID,
+		Author:   author,
+		Body:     req.Body,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return commentToResponse(comment), nil
+}
+
+func (s *CommentServiceImpl) GetComments(ctx context.Context, principal string, todoID uint) ([]*dto.CommentResponse, error) {
+	if _, _, err := s.access.authorize(ctx, principal, todoID, models.RoleViewer); err != nil {
+		return nil, err
+	}
+	comments, err := s.commentRepo.GetAllByTodo(ctx, todoID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Comments come oldest first, so parents precede their replies
+	byID := make(map[uint]*dto.CommentResponse, len(comments))
+	threads := []*dto.CommentResponse{}
+	for _, comment := range comments {
+		response := commentToResponse(comment)
+		byID[comment.ID] = response
+		if comment.ParentID == nil {
+			threads = append(threads, response)
+			continue
+		}
+		if parent, ok := byID[*comment.ParentID]; ok {
+			parent.Replies = append(parent.Replies, response)
+		}
+	}
+	return threads, nil
+}
+
+func (s *CommentServiceImpl) GetComment(ctx context.Context, principal string, todoID, id uint) (*dto.CommentResponse, error) {
+	if _, _, err := s.access.authorize(ctx, principal, todoID, models.RoleViewer); err != nil {
+		return nil, err
+	}
+	comment, err := s.commentRepo.GetByID(ctx, todoID, id)
+	if err != nil {
+		return nil, err
+	}
+	return commentToResponse(comment), nil
+}
+
+func (s *CommentServiceImpl) UpdateComment(ctx context.Context, author string, todoID, id uint, req *dto.UpdateCommentRequest) (*dto.CommentResponse, error) {
+	if err := validateCommentBody(req, req.Body); err != nil {
+		return nil, err
+	}
+	comment, err := s.editable(ctx, author, todoID, id)
+	if err != nil {
+		return nil, err
+	}
+	if comment.Body == req.Body {
+		return commentToResponse(comment), nil
+	}
+
+	previousBody := comment.Body
+	now := time.Now()
+	comment.Body = req.Body
+	comment.EditedAt = &now
+	updated, err := s.commentRepo.Update(ctx, comment, previousBody)
+	if err != nil {
+		return nil, err
+	}
+	return commentToResponse(updated), nil
+}
+
+func (s *CommentServiceImpl) DeleteComment(ctx context.Context, author string, todoID, id uint) error {
+	comment, err := s.editable(ctx, author, todoID, id)
+	if err != nil {
+		return err
+	}
+	return s.commentRepo.Delete(ctx, comment)
+}
+
+func (s *CommentServiceImpl) GetRevisions(ctx context.Context, principal string, todoID, id uint) ([]*dto.CommentRevisionResponse, error) {
+	if _, _, err := s.access.authorize(ctx, principal, todoID, models.RoleViewer); err != nil {
+		return nil, err
+	}
+	comment, err := s.commentRepo.GetByID(ctx, todoID, id)
+	if err != nil {
+		return nil, err
+	}
+	revisions, err := s.commentRepo.GetRevisions(ctx, comment.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*dto.CommentRevisionResponse, len(revisions))
+	for i, revision := range revisions {
+		responses[i] = &dto.CommentRevisionResponse{Body: revision.Body, ReplacedAt: revision.CreatedAt}
+	}
+	return responses, nil
+}
+
+// editable returns a comment the author may change, as long as the author
+// may still comment on the todo. Deleted comments are gone as far as
+// callers are concerned.
+func (s *CommentServiceImpl) editable(ctx context.Context, author string, todoID, id uint) (*models.Comment, error) {
+	if _, _, err := s.access.authorize(ctx, author, todoID, models.RoleCommenter); err != nil {
+		return nil, err
+	}
+	comment, err := s.commentRepo.GetByID(ctx, todoID, id)
+	if err != nil {
+		return nil, err
+	}
+	if comment.Deleted {
+		return nil, errors.New("comment not found")
+	}
+	if comment.Author != author {
+		return nil, errors.New("forbidden: only the author can change a comment")
+	}
+	return comment, nil
+}
+
+func validateCommentBody(req interface{}, body string) error {
+	if validationErrors := utils.ValidateStruct(req); len(validationErrors) > 0 {
+		return errors.New("validation failed: " + validationErrors[0])
+	}
+	if strings.TrimSpace(body) == "" {
+		return errors.New("validation failed: body must not be blank")
+	}
+	return nil
+}
+
+func commentToResponse(comment *models.Comment) *dto.CommentResponse {
+	return &dto.CommentResponse{
+		ID:        comment.ID,
+		TodoID:    comment.TodoID,
+		ParentID:  comment.ParentID,
+		Author:    comment.Author,
+		Body:      comment.Body,
+		Deleted:   comment.Deleted,
+		CreatedAt: comment.CreatedAt,
+		UpdatedAt: comment.UpdatedAt,
+		EditedAt:  comment.EditedAt,
+	}
+}
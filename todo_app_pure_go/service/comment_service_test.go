@@ -0,0 +1,143 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"todo-app/dto"
+	"todo-app/models"
+	"todo-app/repository"
+
+	"gorm.io/gorm"
+)
+
+func newCommentTestServices(t *testing.T) (CommentService, TodoService, *gorm.DB) {
+	t.Helper()
+	db := newTestDB(t)
+	// Go through the cache so stale comment counts would show up
+	cache := repository.NewCachedTodoRepository(repository.NewTodoRepository(db), repository.CacheOptions{Size: 100, TTL: time.Minute})
+	access := cache.WrapAccess(repository.NewAccessRepository(db))
+	comments := NewCommentService(cache.WrapComments(repository.NewCommentRepository(db)), cache, access)
+	return comments, NewTodoService(cache, access, nil, nil, nil), db
+}
+
+func TestCommentThreads(t *testing.T) {
+	comments, todos, db := newCommentTestServices(t)
+	todo, _ := todos.CreateTodo(ctx, "", &dto.CreateTodoRequest{Title: "Plan release"})
+
+	create := func(author string, parentID *uint, body string) *dto.CommentResponse {
+		t.Helper()
+		comment, err := comments.CreateComment(ctx, author, todo.ID, &dto.CreateCommentRequest{Body: body, ParentID: parentID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return comment
+	}
+	commentCount := func() int64 {
+		t.Helper()
+		got, _ := todos.GetTodoByID(ctx, "", todo.ID)
+		return got.CommentCount
+	}
+
+	root := create("ada", nil, "Which **date**?")
+	reply := create("bob", &root.ID, "Friday")
+	create("ada", &reply.ID, "Works for me")
+	create("bob", nil, "Second thread")
+	if n := commentCount(); n != 4 {
+		t.Errorf("comment_count = %d, want 4", n)
+	}
+
+	threads, _ := comments.GetComments(ctx, "", todo.ID)
+	if len(threads) != 2 || len(threads[0].Replies) != 1 || len(threads[0].Replies[0].Replies) != 1 {
+		t.Fatalf("unexpected threads: %+v", threads)
+	}
+
+	// Updating the todo must not overwrite the comment count
+	title := "Plan the release"
+	if updated, _ := todos.UpdateTodo(ctx, "", todo.ID, &dto.UpdateTodoRequest{Title: &title}); updated.CommentCount != 4 {
+		t.Errorf("comment_count after todo update = %d, want 4", updated.CommentCount)
+	}
+
+	if _, err := comments.UpdateComment(ctx, "bob", todo.ID, root.ID, &dto.UpdateCommentRequest{Body: "x"}); err == nil || err.Error() != "forbidden: only the author can change a comment" {
+		t.Errorf("edit by another user: %v", err)
+	}
+	edited, err := comments.UpdateComment(ctx, "ada", todo.ID, root.ID, &dto.UpdateCommentRequest{Body: "Which *day*?"})
+	if err != nil || edited.EditedAt == nil {
+		t.Fatalf("edit: %+v, %v", edited, err)
+	}
+	if revisions, _ := comments.GetRevisions(ctx, "", todo.ID, root.ID); len(revisions) != 1 || revisions[0].Body != "Which **date**?" {
+		t.Errorf("unexpected revisions: %+v", revisions)
+	}
+
+	// A comment with replies stays as a placeholder without its history
+	if err := comments.DeleteComment(ctx, "ada", todo.ID, root.ID); err != nil {
+		t.Fatal(err)
+	}
+	deleted, _ := comments.GetComment(ctx, "", todo.ID, root.ID)
+	if !deleted.Deleted || deleted.Body != "" {
+		t.Errorf("deleted comment: %+v", deleted)
+	}
+	if revisions, _ := comments.GetRevisions(ctx, "", todo.ID, root.ID); len(revisions) != 0 {
+		t.Errorf("deleted comment kept %d revisions", len(revisions))
+	}
+	if _, err := comments.CreateComment(ctx, "bob", todo.ID, &dto.CreateCommentRequest{Body: "late", ParentID: &root.ID}); err == nil {
+		t.Error("replied to a deleted comment")
+	}
+	if n := commentCount(); n != 3 {
+		t.Errorf("comment_count after delete = %d, want 3", n)
+	}
+
+	// Removing the rest of the thread also removes the placeholder
+	threads, _ = comments.GetComments(ctx, "", todo.ID)
+	leaf := threads[0].Replies[0].Replies[0]
+	if err := comments.DeleteComment(ctx, "ada", todo.ID, leaf.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := comments.DeleteComment(ctx, "bob", todo.ID, reply.ID); err != nil {
+		t.Fatal(err)
+	}
+	if threads, _ = comments.GetComments(ctx, "", todo.ID); len(threads) != 1 || threads[0].Body != "Second thread" {
+		t.Errorf("unexpected threads after deletes: %+v", threads)
+	}
+	if n := commentCount(); n != 1 {
+		t.Errorf("comment_count = %d, want 1", n)
+	}
+
+	// Deleting the todo deletes its comments and their history
+	comments.UpdateComment(ctx, "bob", todo.ID, threads[0].ID, &dto.UpdateCommentRequest{Body: "edited"})
+	if err := todos.DeleteTodo(ctx, "", todo.ID); err != nil {
+		t.Fatal(err)
+	}
+	var left int64
+	db.WithContext(ctx).Model(&models.Comment{}).Count(&left)
+	var revisions int64
+	db.WithContext(ctx).Model(&models.CommentRevision{}).Count(&revisions)
+	if left != 0 || revisions != 0 {
+		t.Errorf("todo delete left %d comments and %d revisions", left, revisions)
+	}
+}
+
+func TestCommentValidation(t *testing.T) {
+	comments, todos, _ := newCommentTestServices(t)
+	todo, _ := todos.CreateTodo(ctx, "", &dto.CreateTodoRequest{Title: "a"})
+	other, _ := todos.CreateTodo(ctx, "", &dto.CreateTodoRequest{Title: "b"})
+	foreign, _ := comments.CreateComment(ctx, "", other.ID, &dto.CreateCommentRequest{Body: "elsewhere"})
+
+	tests := []struct {
+		name string
+		req  dto.CreateCommentRequest
+		id   uint
+		want string
+	}{
+		{"blank body", dto.CreateCommentRequest{Body: "  \n"}, todo.ID, "validation failed: body must not be blank"},
+		{"parent on another todo", dto.CreateCommentRequest{Body: "hi", ParentID: &foreign.ID}, todo.ID, "validation failed: parent_id must be a comment on the same todo"},
+		{"unknown todo", dto.CreateCommentRequest{Body: "hi"}, 99, "todo not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := comments.CreateComment(ctx, "", tt.id, &tt.req); err == nil || err.Error() != tt.want {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
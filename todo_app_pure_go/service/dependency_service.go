@@ -0,0 +1,19 @@
+package service
+
+import (
+	"context"
+	"todo-app/dto"
+)
+
+// DependencyService manages which todos block which. A todo cannot be
+// completed while a todo blocking it is open. Changing a todo's
+// dependencies needs the editor role on it and the viewer role on the
+// blocker; reading them needs the viewer role.
+type DependencyService interface {
+	AddDependency(ctx context.Context, principal string, todoID uint, req *dto.AddDependencyRequest) (*dto.DependenciesResponse, error)
+	RemoveDependency(ctx context.Context, principal string, todoID, blockerID uint) (*dto.DependenciesResponse, error)
+	GetDependencies(ctx context.Context, principal string, todoID uint) (*dto.DependenciesResponse, error)
+	// GetGraph returns the dependencies between the todos principal can
+	// see.
+	GetGraph(ctx context.Context, principal string) (*dto.DependencyGraph, error)
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"todo-app/dto"
+	"todo-app/models"
+	"todo-app/repository"
+	"todo-app/utils"
+)
+
+type DependencyServiceImpl struct {
+	dependencyRepo repository.DependencyRepository
+	access         authorizer
+}
+
+func NewDependencyService(dependencyRepo repository.DependencyRepository, todoRepo repository.TodoRepository, accessRepo repository.AccessRepository) DependencyService {
+	return &DependencyServiceImpl{
+		dependencyRepo: dependencyRepo,
+		access:         authorizer{todoRepo: todoRepo, accessRepo: accessRepo},
+	}
+}
+
+func (s *DependencyServiceImpl) AddDependency(ctx context.Context, principal string, todoID uint, req *dto.AddDependencyRequest) (*dto.DependenciesResponse, error) {
+	if validationErrors := utils.ValidateStruct(req); len(validationErrors) > 0 {
+		return nil, errors.New("validation failed: " + validationErrors[0])
+	}
+	if req.BlockedBy == todoID {
+		return nil, errors.New("validation failed: a todo cannot block itself")
+	}
+	if _, _, err := s.access.authorize(ctx, principal, todoID, models.RoleEditor); err != nil {
+		return nil, err
+	}
+	if _, _, err := s.access.authorize(ctx, principal, req.BlockedBy, models.RoleViewer); err != nil {
+		if err.Error() == "todo not found" {
+			return nil, fmt.Errorf("validation failed: todo %d not found", req.BlockedBy)
+		}
+		return nil, err
+	}
+
+	if _, err := s.dependencyRepo.Add(ctx, todoID, req.BlockedBy); err != nil {
+		return nil, err
+	}
+	return s.dependencies(ctx, principal, todoID)
+}
+
+func (s *DependencyServiceImpl) RemoveDependency(ctx context.Context, principal string, todoID, blockerID uint) (*dto.DependenciesResponse, error) {
+	if _, _, err := s.access.authorize(ctx, principal, todoID, models.RoleEditor); err != nil {
+		return nil, err
+	}
+	if err := s.dependencyRepo.Remove(ctx, todoID, blockerID); err != nil {
+		return nil, err
+	}
+	return s.dependencies(ctx, principal, todoID)
+}
+
+func (s *DependencyServiceImpl) GetDependencies(ctx context.Context, principal string, todoID uint) (*dto.DependenciesResponse, error) {
+	if _, _, err := s.access.authorize(ctx, principal, todoID, models.RoleViewer); err != nil {
+		return nil, err
+	}
+	return s.dependencies(ctx, principal, todoID)
+}
+
+// dependencies describes the dependencies of a todo the principal may see.
+// Whether it is blocked counts blockers the principal cannot see as well.
+func (s *DependencyServiceImpl) dependencies(ctx context.Context, principal string, todoID uint) (*dto.DependenciesResponse, error) {
+	blockers, blocked, err := s.dependencyRepo.ForTodo(ctx, principal, todoID)
+	if err != nil {
+		return nil, err
+	}
+	open, err := s.access.todoRepo.OpenBlockers(ctx, todoID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &dto.DependenciesResponse{
+		TodoID:    todoID,
+		Blocked:   len(open) > 0,
+		BlockedBy: make([]*dto.TodoResponse, len(blockers)),
+		Blocks:    make([]*dto.TodoResponse, len(blocked)),
+	}
+	for i, todo := range blockers {
+		response.BlockedBy[i] = todoToResponse(todo)
+	}
+	for i, todo := range blocked {
+		response.Blocks[i] = todoToResponse(todo)
+	}
+	return response, nil
+}
+
+func (s *DependencyServiceImpl) GetGraph(ctx context.Context, principal string) (*dto.DependencyGraph, error) {
+	todos, dependencies, err := s.dependencyRepo.Graph(ctx, principal)
+	if err != nil {
+		return nil, err
+	}
+
+	graph := &dto.DependencyGraph{
+		Nodes: make([]dto.DependencyNode, len(todos)),
+		Edges: make([]dto.DependencyEdge, len(dependencies)),
+	}
+	for i, todo := range todos {
+		graph.Nodes[i] = dto.DependencyNode{
+			ID:        todo.ID,
+			Title:     todo.Title,
+			Status:    todo.Status,
+			Completed: todo.Completed,
+		}
+	}
+	for i, d := range dependencies {
+		graph.Edges[i] = dto.DependencyEdge{From: d.BlockerID, To: d.TodoID}
+	}
+	return graph, nil
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"todo-app/dto"
+	"todo-app/models"
+	"todo-app/repository"
+)
+
+func newDependencyTestServices(t *testing.T) (DependencyService, TodoService) {
+	t.Helper()
+	db := newTestDB(t)
+	// Go through the cache so stale blocked lists would show up
+	cache := repository.NewCachedTodoRepository(repository.NewTodoRepository(db), repository.CacheOptions{Size: 100, TTL: time.Minute})
+	access := cache.WrapAccess(repository.NewAccessRepository(db))
+	dependencies := NewDependencyService(cache.WrapDependencies(repository.NewDependencyRepository(db)), cache, access)
+	return dependencies, NewTodoService(cache, access, nil, nil, nil)
+}
+
+func TestDependencies(t *testing.T) {
+	dependencies, todos := newDependencyTestServices(t)
+	ids := map[string]uint{}
+	for _, title := range []string{"design", "build", "ship"} {
+		todo, _ := todos.CreateTodo(ctx, "", &dto.CreateTodoRequest{Title: title})
+		ids[title] = todo.ID
+	}
+	block := func(todo, blocker string) error {
+		t.Helper()
+		_, err := dependencies.AddDependency(ctx, "", ids[todo], &dto.AddDependencyRequest{BlockedBy: ids[blocker]})
+		return err
+	}
+	blockedTitles := func(blocked bool) []string {
+		t.Helper()
+		list, _, err := todos.GetAllTodos(ctx, "", nil, &blocked, nil, models.SortPosition, 10, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		titles := []string{}
+		for _, todo := range list {
+			titles = append(titles, todo.Title)
+		}
+		return titles
+	}
+
+	if err := block("build", "design"); err != nil {
+		t.Fatal(err)
+	}
+	if err := block("ship", "build"); err != nil {
+		t.Fatal(err)
+	}
+	// Adding a dependency twice changes nothing
+	if err := block("ship", "build"); err != nil {
+		t.Fatal(err)
+	}
+	if got := blockedTitles(true); !reflect.DeepEqual(got, []string{"ship", "build"}) {
+		t.Errorf("blocked todos = %v", got)
+	}
+
+	wantError(t, block("design", "ship"), "dependency cycle: todo 1 already blocks todo 3")
+	wantError(t, block("design", "design"), "validation failed")
+	_, err := dependencies.AddDependency(ctx, "", ids["design"], &dto.AddDependencyRequest{BlockedBy: 99})
+	wantError(t, err, "validation failed")
+
+	// Blocked todos cannot be completed until their blockers are
+	_, err = todos.ToggleTodoComplete(ctx, "", ids["build"])
+	wantError(t, err, "blocked")
+	done := true
+	_, err = todos.UpdateTodo(ctx, "", ids["build"], &dto.UpdateTodoRequest{Completed: &done})
+	wantError(t, err, "blocked")
+	for _, status := range []string{"in_progress", "review"} {
+		if _, err := todos.TransitionTodo(ctx, "", ids["build"], &dto.TransitionTodoRequest{Status: status}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = todos.TransitionTodo(ctx, "", ids["build"], &dto.TransitionTodoRequest{Status: "done"})
+	wantError(t, err, "blocked")
+
+	if _, err := todos.ToggleTodoComplete(ctx, "", ids["design"]); err != nil {
+		t.Fatal(err)
+	}
+	if got := blockedTitles(true); !reflect.DeepEqual(got, []string{"ship"}) {
+		t.Errorf("blocked todos after completing design = %v", got)
+	}
+	if _, err := todos.ToggleTodoComplete(ctx, "", ids["build"]); err != nil {
+		t.Fatal(err)
+	}
+
+	build, err := dependencies.GetDependencies(ctx, "", ids["build"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if build.Blocked || len(build.BlockedBy) != 1 || build.BlockedBy[0].ID != ids["design"] || len(build.Blocks) != 1 || build.Blocks[0].ID != ids["ship"] {
+		t.Errorf("dependencies of build = %+v", build)
+	}
+
+	graph, err := dependencies.GetGraph(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantEdges := []dto.DependencyEdge{{From: ids["design"], To: ids["build"]}, {From: ids["build"], To: ids["ship"]}}
+	if len(graph.Nodes) != 3 || !reflect.DeepEqual(graph.Edges, wantEdges) {
+		t.Errorf("graph = %+v", graph)
+	}
+
+	// Removing or deleting a blocker unblocks the todo
+	if _, err := todos.ToggleTodoComplete(ctx, "", ids["design"]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dependencies.RemoveDependency(ctx, "", ids["build"], ids["design"]); err != nil {
+		t.Fatal(err)
+	}
+	_, err = dependencies.RemoveDependency(ctx, "", ids["build"], ids["design"])
+	wantError(t, err, "dependency not found")
+	if err := todos.DeleteTodo(ctx, "", ids["build"]); err != nil {
+		t.Fatal(err)
+	}
+	if got := blockedTitles(true); len(got) != 0 {
+		t.Errorf("blocked todos after deleting build = %v", got)
+	}
+	if got := blockedTitles(false); !reflect.DeepEqual(got, []string{"ship", "design"}) {
+		t.Errorf("unblocked todos = %v", got)
+	}
+}
+
+func TestDependencyVisibility(t *testing.T) {
+	dependencies, todos := newDependencyTestServices(t)
+	private, _ := todos.CreateTodo(ctx, "alice", &dto.CreateTodoRequest{Title: "Private"})
+	shared, _ := todos.CreateTodo(ctx, "", &dto.CreateTodoRequest{Title: "Shared"})
+
+	// Others cannot block their todos by todos they cannot see
+	_, err := dependencies.AddDependency(ctx, "bob", shared.ID, &dto.AddDependencyRequest{BlockedBy: private.ID})
+	wantError(t, err, "validation failed")
+
+	if _, err := dependencies.AddDependency(ctx, "alice", shared.ID, &dto.AddDependencyRequest{BlockedBy: private.ID}); err != nil {
+		t.Fatal(err)
+	}
+	// ...but still see that the todo is blocked, without the blocker
+	got, err := dependencies.GetDependencies(ctx, "bob", shared.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Blocked || len(got.BlockedBy) != 0 {
+		t.Errorf("dependencies seen by bob = %+v", got)
+	}
+	if graph, _ := dependencies.GetGraph(ctx, "bob"); len(graph.Nodes) != 0 || len(graph.Edges) != 0 {
+		t.Errorf("graph seen by bob = %+v", graph)
+	}
+
+	// A cycle through the private todo does not give it away
+	next, _ := todos.CreateTodo(ctx, "", &dto.CreateTodoRequest{Title: "Next"})
+	if _, err := dependencies.AddDependency(ctx, "alice", private.ID, &dto.AddDependencyRequest{BlockedBy: next.ID}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = dependencies.AddDependency(ctx, "bob", next.ID, &dto.AddDependencyRequest{BlockedBy: shared.ID})
+	want := fmt.Sprintf("dependency cycle: todo %d already blocks todo %d, directly or through other todos", next.ID, shared.ID)
+	if err == nil || err.Error() != want {
+		t.Errorf("cycle error = %v, want %q", err, want)
+	}
+}
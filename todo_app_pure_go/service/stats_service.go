@@ -0,0 +1,15 @@
+package service
+
+import (
+	"context"
+	"time"
+	"todo-app/dto"
+)
+
+type StatsService interface {
+	// GetStats returns statistics about the todos visible to principal.
+	// Counts describe all of them; the timeline and average time to
+	// complete cover the days from..to (inclusive, UTC) per "day" or
+	// "week". Zero dates default to the last 30 days.
+	GetStats(ctx context.Context, principal string, from, to time.Time, interval string, oldest int) (*dto.StatsResponse, error)
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+	"todo-app/dto"
+	"todo-app/models"
+	"todo-app/repository"
+)
+
+const (
+	dateLayout        = "2006-01-02"
+	defaultStatsDays  = 30
+	maxStatsDays      = 366
+	defaultOldestOpen = 5
+	maxOldestOpen     = 50
+	statsIntervalDay  = "day"
+	statsIntervalWeek = "week"
+)
+
+type StatsServiceImpl struct {
+	statsRepo repository.StatsRepository
+	now       func() time.Time
+}
+
+func NewStatsService(statsRepo repository.StatsRepository) StatsService {
+	return &StatsServiceImpl{
+		statsRepo: statsRepo,
+		now:       time.Now,
+	}
+}
+
+func (s *StatsServiceImpl) GetStats(ctx context.Context, principal string, from, to time.Time, interval string, oldest int) (*dto.StatsResponse, error) {
+	// Validate and default the query
+	if interval == "" {
+		interval = statsIntervalDay
+	}
+	if interval != statsIntervalDay && interval != statsIntervalWeek {
+		return nil, errors.New("validation failed: interval must be one of: day week")
+	}
+	if oldest <= 0 {
+		oldest = defaultOldestOpen
+	}
+	if oldest > maxOldestOpen {
+		oldest = maxOldestOpen
+	}
+	if to.IsZero() {
+		to = s.now()
+	}
+	to = truncateDay(to)
+	if from.IsZero() {
+		from = to.AddDate(0, 0, 1-defaultStatsDays)
+	}
+	from = truncateDay(from)
+	if from.After(to) {
+		return nil, errors.New("validation failed: from must not be after to")
+	}
+	if to.Sub(from) >= maxStatsDays*24*time.Hour {
+		return nil, errors.New("validation failed: the date range must not exceed 366 days")
+	}
+	end := to.AddDate(0, 0, 1)
+	week := interval == statsIntervalWeek
+
+	response := &dto.StatsResponse{
+		ByPriority: []dto.PriorityStats{},
+		OldestOpen: []*dto.TodoResponse{},
+	}
+
+	// Counts by priority and completion
+	counts, err := s.statsRepo.CountByPriority(ctx, principal)
+	if err != nil {
+		return nil, err
+	}
+	byPriority := make(map[models.Priority]repository.PriorityCount, len(counts))
+	for _, count := range counts {
+		byPriority[count.Priority] = count
+		response.Total += count.Total
+		response.Completed += count.Completed
+	}
+	response.Open = response.Total - response.Completed
+	response.CompletionRate = rate(response.Completed, response.Total)
+	for _, priority := range []models.Priority{models.HIGH, models.MEDIUM, models.LOW} {
+		count := byPriority[priority]
+		response.ByPriority = append(response.ByPriority, dto.PriorityStats{
+			Priority:       priority,
+			Total:          count.Total,
+			Completed:      count.Completed,
+			Open:           count.Total - count.Completed,
+			CompletionRate: rate(count.Completed, count.Total),
+		})
+	}
+
+	avg, err := s.statsRepo.AverageCompletionSeconds(ctx, principal, from, end)
+	if err != nil {
+		return nil, err
+	}
+	if avg != nil {
+		// sqlite derives durations from fractional Julian days
+		seconds := math.Round(*avg)
+		response.AverageTimeToCompleteSeconds = &seconds
+	}
+
+	// Created vs completed per bucket, including empty ones
+	created, err := s.statsRepo.CreatedPerBucket(ctx, principal, from, end, week)
+	if err != nil {
+		return nil, err
+	}
+	completed, err := s.statsRepo.CompletedPerBucket(ctx, principal, from, end, week)
+	if err != nil {
+		return nil, err
+	}
+	response.Timeline = dto.StatsTimeline{
+		Interval: interval,
+		From:     from.Format(dateLayout),
+		To:       to.Format(dateLayout),
+		Buckets:  buildBuckets(from, end, week, created, completed),
+	}
+
+	oldestOpen, err := s.statsRepo.OldestOpen(ctx, principal, oldest)
+	if err != nil {
+		return nil, err
+	}
+	for _, todo := range oldestOpen {
+		response.OldestOpen = append(response.OldestOpen, todoToResponse(todo))
+	}
+
+	return response, nil
+}
+
+// buildBuckets lists every day or week between from and end, so days
+// without activity appear with zero counts.
+func buildBuckets(from, end time.Time, week bool, created, completed []repository.BucketCount) []dto.StatsBucket {
+	start, step := from, 1
+	if week {
+		// Weeks start on Monday
+		start = from.AddDate(0, 0, -((int(from.Weekday()) + 6) % 7))
+		step = 7
+	}
+
+	var buckets []dto.StatsBucket
+	index := make(map[string]int)
+	for day := start; day.Before(end); day = day.AddDate(0, 0, step) {
+		key := day.Format(dateLayout)
+		index[key] = len(buckets)
+		buckets = append(buckets, dto.StatsBucket{Start: key})
+	}
+	for _, count := range created {
+		if i, ok := index[count.Bucket]; ok {
+			buckets[i].Created = count.Count
+		}
+	}
+	for _, count := range completed {
+		if i, ok := index[count.Bucket]; ok {
+			buckets[i].Completed = count.Count
+		}
+	}
+	return buckets
+}
+
+func truncateDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func rate(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total)
+}
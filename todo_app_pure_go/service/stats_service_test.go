@@ -0,0 +1,83 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"todo-app/models"
+	"todo-app/repository"
+)
+
+func TestGetStats(t *testing.T) {
+	db := newTestDB(t)
+
+	day := func(d, h int) time.Time { return time.Date(2024, 3, d, h, 0, 0, 0, time.UTC) }
+	at := func(t time.Time) *time.Time { return &t }
+	seed := []*models.Todo{
+		// Mon 4th: created, done two hours later
+		{Title: "a", Priority: models.HIGH, Completed: true, CreatedAt: day(4, 8), CompletedAt: at(day(4, 10))},
+		// Tue 5th: created, done on the 7th after 48h
+		{Title: "b", Priority: models.HIGH, Completed: true, CreatedAt: day(5, 9), CompletedAt: at(day(7, 9))},
+		{Title: "c", Priority: models.LOW, CreatedAt: day(5, 12)},
+		// Mon 11th, next week
+		{Title: "d", Priority: models.MEDIUM, CreatedAt: day(11, 7)},
+		// Before the range: still counted in totals and oldest open
+		{Title: "old", Priority: models.LOW, CreatedAt: day(1, 0)},
+	}
+	for _, todo := range seed {
+		if err := db.WithContext(ctx).Create(todo).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewStatsService(repository.NewStatsRepository(db)).(*StatsServiceImpl)
+	s.now = func() time.Time { return day(12, 15) }
+
+	stats, err := s.GetStats(ctx, "", day(4, 0), time.Time{}, "day", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Total != 5 || stats.Completed != 2 || stats.Open != 3 || stats.CompletionRate != 0.4 {
+		t.Errorf("unexpected totals: %+v", stats)
+	}
+	if high := stats.ByPriority[0]; high.Priority != models.HIGH || high.Total != 2 || high.CompletionRate != 1 {
+		t.Errorf("unexpected HIGH stats: %+v", high)
+	}
+	if medium := stats.ByPriority[1]; medium.Total != 1 || medium.Open != 1 {
+		t.Errorf("unexpected MEDIUM stats: %+v", medium)
+	}
+	if avg := stats.AverageTimeToCompleteSeconds; avg == nil || *avg != 25*3600 {
+		t.Errorf("average time to complete = %v, want 25h", *avg)
+	}
+
+	timeline := stats.Timeline
+	if timeline.From != "2024-03-04" || timeline.To != "2024-03-12" || len(timeline.Buckets) != 9 {
+		t.Fatalf("unexpected timeline: %+v", timeline)
+	}
+	if b := timeline.Buckets[1]; b.Start != "2024-03-05" || b.Created != 2 || b.Completed != 0 {
+		t.Errorf("unexpected bucket: %+v", b)
+	}
+	if b := timeline.Buckets[3]; b.Start != "2024-03-07" || b.Created != 0 || b.Completed != 1 {
+		t.Errorf("unexpected bucket: %+v", b)
+	}
+
+	if len(stats.OldestOpen) != 2 || stats.OldestOpen[0].Title != "old" || stats.OldestOpen[1].Title != "c" {
+		t.Errorf("unexpected oldest open: %+v", stats.OldestOpen)
+	}
+
+	weekly, err := s.GetStats(ctx, "", day(6, 0), day(12, 0), "week", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buckets := weekly.Timeline.Buckets
+	if len(buckets) != 2 || buckets[0].Start != "2024-03-04" || buckets[0].Completed != 1 || buckets[1].Created != 1 {
+		t.Errorf("unexpected weekly buckets: %+v", buckets)
+	}
+
+	if _, err := s.GetStats(ctx, "", day(12, 0), day(4, 0), "day", 0); err == nil {
+		t.Error("expected an error for from after to")
+	}
+	if _, err := s.GetStats(ctx, "", time.Time{}, time.Time{}, "month", 0); err == nil {
+		t.Error("expected an error for an unknown interval")
+	}
+}
@@ -0,0 +1,17 @@
+package service
+
+import (
+	"context"
+	"todo-app/dto"
+)
+
+// SyncService implements delta sync for offline-first clients.
+type SyncService interface {
+	// Sync applies the request's mutations in order, then returns every
+	// change since the request's sync token, including the ones just applied.
+	// Mutations need the same access as the equivalent TodoService calls,
+	// and only todos visible to principal are returned. Tombstones carry
+	// nothing but an ID and are returned to everyone; a client that lost
+	// access to a todo drops it on its next full sync.
+	Sync(ctx context.Context, principal string, req *dto.SyncRequest) (*dto.SyncResponse, error)
+}
@@ -0,0 +1,494 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"todo-app/dto"
+	"todo-app/events"
+	"todo-app/models"
+	"todo-app/repository"
+	"todo-app/tenant"
+	"todo-app/utils"
+	"todo-app/workflow"
+)
+
+const (
+	defaultSyncLimit = 500
+	maxSyncLimit     = 1000
+)
+
+// Conflict policy
+//
+// Every field of a todo (title, description, completed, priority) carries the
+// time it was last written: the server's clock for REST, GraphQL, gRPC and
+// WebSocket writes, the mutation's updated_at for sync writes. A synced
+// update is applied field by field, and a field is only overwritten when the
+// mutation's updated_at is strictly later than the field's time (last writer
+// wins, the server wins ties). Fields that lose are reported as a conflict
+// with the server's current todo; the other fields of the same mutation are
+// still applied. A delete wins only over a todo none of whose fields changed
+// after the delete's updated_at. Deletions are final: updates to a deleted
+// todo are rejected. Client times in the future are treated as now, so a fast
+// client clock cannot win every later conflict.
+
+type SyncServiceImpl struct {
+	todoRepo     repository.TodoRepository
+	syncRepo     repository.SyncRepository
+	access       authorizer
+	policy       workspacePolicy
+	publisher    events.Publisher
+	tombstoneTTL time.Duration
+	maxMutations int
+	workflow     *workflow.Workflow
+	now          func() time.Time
+}
+
+// NewSyncService creates the sync service. Tombstones are kept for
+// tombstoneTTL; tokens older than that are rejected because the deletions
+// they would need may be gone. Created todos follow the settings in
+// workspaceRepo, which may be nil. Applied changes are sent to publisher,
+// which may be nil. Completing and reopening todos moves them through wf,
+// or workflow.Default() if it is nil.
+func NewSyncService(todoRepo repository.TodoRepository, syncRepo repository.SyncRepository, accessRepo repository.AccessRepository, workspaceRepo repository.WorkspaceRepository, publisher events.Publisher, wf *workflow.Workflow, tombstoneTTL time.Duration, maxMutations int) SyncService {
+	if wf == nil {
+		wf = workflow.Default()
+	}
+	return &SyncServiceImpl{
+		todoRepo:     todoRepo,
+		syncRepo:     syncRepo,
+		access:       authorizer{todoRepo: todoRepo, accessRepo: accessRepo},
+		policy:       workspacePolicy{workspaceRepo: workspaceRepo, todoRepo: todoRepo},
+		publisher:    publisher,
+		tombstoneTTL: tombstoneTTL,
+		maxMutations: maxMutations,
+		workflow:     wf,
+		now:          time.Now,
+	}
+}
+
+func (s *SyncServiceImpl) Sync(ctx context.Context, principal string, req *dto.SyncRequest) (*dto.SyncResponse, error) {
+	if len(req.Mutations) > s.maxMutations {
+		return nil, fmt.Errorf("validation failed: at most %d mutations per sync", s.maxMutations)
+	}
+
+	now := s.now()
+	since, err := s.parseToken(req.SyncToken, now)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultSyncLimit
+	}
+	if limit > maxSyncLimit {
+		limit = maxSyncLimit
+	}
+
+	response := &dto.SyncResponse{
+		Changes:  []dto.SyncChange{},
+		Applied:  []dto.SyncApplied{},
+		Rejected: []dto.SyncRejection{},
+	}
+	for i := range req.Mutations {
+		if err := s.apply(ctx, principal, i, &req.Mutations[i], now, response); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := s.syncRepo.PruneTombstones(ctx, now.Add(-s.tombstoneTTL)); err != nil {
+		return nil, err
+	}
+	changes, err := s.syncRepo.ChangesSince(ctx, since, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) > limit {
+		changes = changes[:limit]
+		response.HasMore = true
+	}
+	if err := s.fillChanges(ctx, principal, response, changes); err != nil {
+		return nil, err
+	}
+
+	next := since
+	if len(changes) > 0 {
+		next = changes[len(changes)-1].Seq
+	}
+	response.SyncToken = encodeSyncToken(next, now)
+	return response, nil
+}
+
+// fillChanges adds the current state of each changed todo principal can
+// see, or a tombstone, to the response.
+func (s *SyncServiceImpl) fillChanges(ctx context.Context, principal string, response *dto.SyncResponse, changes []*models.TodoChange) error {
+	var ids []uint
+	for _, change := range changes {
+		if !change.Deleted {
+			ids = append(ids, change.TodoID)
+		}
+	}
+	todos, err := s.syncRepo.GetByIDs(ctx, principal, ids)
+	if err != nil {
+		return err
+	}
+	byID := make(map[uint]*models.Todo, len(todos))
+	for _, todo := range todos {
+		byID[todo.ID] = todo
+	}
+	tombstones, err := s.syncRepo.VisibleTombstones(ctx, principal, changes)
+	if err != nil {
+		return err
+	}
+	visibleTombstones := make(map[uint64]bool, len(tombstones))
+	for _, tombstone := range tombstones {
+		visibleTombstones[tombstone.Seq] = true
+	}
+
+	// Todos and tombstones the principal cannot see are left out
+	for _, change := range changes {
+		if change.Deleted {
+			if visibleTombstones[change.Seq] {
+				deletedAt := change.ChangedAt
+				response.Changes = append(response.Changes, dto.SyncChange{ID: change.TodoID, Deleted: true, DeletedAt: &deletedAt})
+			}
+			continue
+		}
+		// A todo deleted after the entries were read shows up as a
+		// tombstone in the next sync
+		if todo, ok := byID[change.TodoID]; ok {
+			response.Changes = append(response.Changes, dto.SyncChange{ID: todo.ID, Todo: todoToResponse(todo)})
+		}
+	}
+	return nil
+}
+
+// apply applies one mutation and records the outcome in response. Only
+// storage errors are returned; everything else is a rejection.
+func (s *SyncServiceImpl) apply(ctx context.Context, principal string, index int, m *dto.SyncMutation, now time.Time, response *dto.SyncResponse) error {
+	reject := func(reason, message string) {
+		response.Rejected = append(response.Rejected, dto.SyncRejection{
+			Index: index, Op: m.Op, ID: m.ID, ClientID: m.ClientID, Reason: reason, Message: message,
+		})
+	}
+
+	if validationErrors := utils.ValidateStruct(m); len(validationErrors) > 0 {
+		reject(dto.SyncRejectValidation, "validation failed: "+validationErrors[0])
+		return nil
+	}
+	if m.Op == dto.SyncCreate && m.ClientID == "" {
+		reject(dto.SyncRejectValidation, "validation failed: client_id is required")
+		return nil
+	}
+	if m.Op != dto.SyncCreate && m.ID == 0 {
+		reject(dto.SyncRejectValidation, "validation failed: id is required")
+		return nil
+	}
+	at := m.UpdatedAt
+	if at.After(now) {
+		at = now
+	}
+
+	switch m.Op {
+	case dto.SyncCreate:
+		return s.applyCreate(ctx, principal, index, m, at, response, reject)
+	case dto.SyncUpdate:
+		return s.applyUpdate(ctx, principal, index, m, at, response, reject)
+	default:
+		return s.applyDelete(ctx, principal, index, m, at, response, reject)
+	}
+}
+
+func (s *SyncServiceImpl) applyCreate(ctx context.Context, principal string, index int, m *dto.SyncMutation, at time.Time, response *dto.SyncResponse, reject func(reason, message string)) error {
+	applied := func(id uint) {
+		response.Applied = append(response.Applied, dto.SyncApplied{Index: index, Op: m.Op, ID: id, ClientID: m.ClientID})
+	}
+
+	// A replayed create, for example after a lost response, maps to the
+	// todo created the first time
+	existing, err := s.syncRepo.GetByClientID(ctx, m.ClientID)
+	if err == nil {
+		if existing.Owner != principal {
+			reject(dto.SyncRejectValidation, "validation failed: client_id is already in use")
+			return nil
+		}
+		applied(existing.ID)
+		return nil
+	}
+	if err.Error() != "todo not found" {
+		return err
+	}
+
+	if m.Fields.Title == nil {
+		reject(dto.SyncRejectValidation, "validation failed: title is required")
+		return nil
+	}
+	defaultPriority, err := s.policy.admit(ctx, 1)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "forbidden") {
+			reject(dto.SyncRejectForbidden, err.Error())
+			return nil
+		}
+		return err
+	}
+	todo := &models.Todo{
+		Title:       *m.Fields.Title,
+		Description: m.Fields.Description,
+		Priority:    defaultPriority,
+		ClientID:    &m.ClientID,
+		Owner:       principal,
+	}
+	s.workflow.SetCompleted(todo, m.Fields.Completed != nil && *m.Fields.Completed, at)
+	if m.Fields.Priority != nil {
+		todo.Priority = *m.Fields.Priority
+	}
+	todo.Clock.Fill(at)
+
+	created, err := s.todoRepo.Create(ctx, todo)
+	if err != nil {
+		return err
+	}
+	applied(created.ID)
+	s.publish(ctx, events.TodoCreated, todoToResponse(created), s.access.audience(ctx, created))
+	return nil
+}
+
+func (s *SyncServiceImpl) applyUpdate(ctx context.Context, principal string, index int, m *dto.SyncMutation, at time.Time, response *dto.SyncResponse, reject func(reason, message string)) error {
+	todo, ok, err := s.load(ctx, principal, m.ID, models.RoleEditor, reject)
+	if !ok {
+		return err
+	}
+	wasCompleted := todo.Completed
+
+	// Completing a todo waits for its blockers, as it does elsewhere
+	if f := m.Fields; f.Completed != nil && *f.Completed && !wasCompleted && at.After(fieldTime(todo.Clock.Completed, todo.UpdatedAt)) {
+		blockers, err := s.todoRepo.OpenBlockers(ctx, todo.ID)
+		if err != nil {
+			return err
+		}
+		if len(blockers) > 0 {
+			reject(dto.SyncRejectBlocked, fmt.Sprintf("todo is blocked by %d open todo(s)", len(blockers)))
+			return nil
+		}
+	}
+
+	var changed bool
+	var lost []string
+	resolve := func(name string, present bool, clock **time.Time, set func()) {
+		if !present {
+			return
+		}
+		if !at.After(fieldTime(*clock, todo.UpdatedAt)) {
+			lost = append(lost, name)
+			return
+		}
+		set()
+		t := at
+		*clock = &t
+		changed = true
+	}
+	f := m.Fields
+	resolve("title", f.Title != nil, &todo.Clock.Title, func() { todo.Title = *f.Title })
+	resolve("description", f.Description != nil, &todo.Clock.Description, func() { todo.Description = f.Description })
+	resolve("completed", f.Completed != nil, &todo.Clock.Completed, func() { s.workflow.SetCompleted(todo, *f.Completed, at) })
+	resolve("priority", f.Priority != nil, &todo.Clock.Priority, func() { todo.Priority = *f.Priority })
+
+	if changed {
+		if err := s.syncRepo.Save(ctx, todo); err != nil {
+			return err
+		}
+		response.Applied = append(response.Applied, dto.SyncApplied{Index: index, Op: m.Op, ID: todo.ID})
+		current := todoToResponse(todo)
+		audience := s.access.audience(ctx, todo)
+		if current.Completed && !wasCompleted {
+			s.publish(ctx, events.TodoCompleted, current, audience)
+		} else {
+			s.publish(ctx, events.TodoUpdated, current, audience)
+		}
+	}
+	if len(lost) > 0 {
+		response.Rejected = append(response.Rejected, dto.SyncRejection{
+			Index:   index,
+			Op:      m.Op,
+			ID:      todo.ID,
+			Reason:  dto.SyncRejectConflict,
+			Message: "fields were changed on the server after " + at.UTC().Format(time.RFC3339Nano),
+			Fields:  lost,
+			Current: todoToResponse(todo),
+		})
+	}
+	return nil
+}
+
+func (s *SyncServiceImpl) applyDelete(ctx context.Context, principal string, index int, m *dto.SyncMutation, at time.Time, response *dto.SyncResponse, reject func(reason, message string)) error {
+	todo, err := s.todoRepo.GetByID(ctx, m.ID)
+	if err != nil {
+		if err.Error() != "todo not found" {
+			return err
+		}
+		// Deleting twice is not an error
+		deleted, err := s.syncRepo.HasTombstone(ctx, m.ID)
+		if err != nil {
+			return err
+		}
+		if !deleted {
+			reject(dto.SyncRejectNotFound, "todo not found")
+			return nil
+		}
+		response.Applied = append(response.Applied, dto.SyncApplied{Index: index, Op: m.Op, ID: m.ID})
+		return nil
+	}
+	if ok, err := s.allowed(ctx, principal, todo, models.RoleOwner, reject); !ok {
+		return err
+	}
+
+	var newer []string
+	for _, field := range []struct {
+		name  string
+		clock *time.Time
+	}{
+		{"title", todo.Clock.Title},
+		{"description", todo.Clock.Description},
+		{"completed", todo.Clock.Completed},
+		{"priority", todo.Clock.Priority},
+	} {
+		if !at.After(fieldTime(field.clock, todo.UpdatedAt)) {
+			newer = append(newer, field.name)
+		}
+	}
+	if len(newer) > 0 {
+		response.Rejected = append(response.Rejected, dto.SyncRejection{
+			Index:   index,
+			Op:      m.Op,
+			ID:      todo.ID,
+			Reason:  dto.SyncRejectConflict,
+			Message: "todo was changed on the server after it was deleted on the client",
+			Fields:  newer,
+			Current: todoToResponse(todo),
+		})
+		return nil
+	}
+
+	// Grants are deleted with the todo
+	audience := s.access.audience(ctx, todo)
+	attachments, err := s.todoRepo.Delete(ctx, todo.ID)
+	if err != nil {
+		return err
+	}
+	response.Applied = append(response.Applied, dto.SyncApplied{Index: index, Op: m.Op, ID: todo.ID})
+	s.publishDeleted(ctx, todoToResponse(todo), audience, attachments)
+	return nil
+}
+
+// load fetches the todo a mutation refers to, rejecting the mutation when it
+// does not exist or principal lacks the required role. ok is false when the
+// mutation cannot proceed; err is only set for storage errors.
+func (s *SyncServiceImpl) load(ctx context.Context, principal string, id uint, required models.Role, reject func(reason, message string)) (todo *models.Todo, ok bool, err error) {
+	todo, err = s.todoRepo.GetByID(ctx, id)
+	if err == nil {
+		ok, err = s.allowed(ctx, principal, todo, required, reject)
+		return todo, ok, err
+	}
+	if err.Error() != "todo not found" {
+		return nil, false, err
+	}
+
+	deleted, err := s.syncRepo.HasTombstone(ctx, id)
+	if err != nil {
+		return nil, false, err
+	}
+	if deleted {
+		reject(dto.SyncRejectDeleted, "todo was deleted")
+	} else {
+		reject(dto.SyncRejectNotFound, "todo not found")
+	}
+	return nil, false, nil
+}
+
+// allowed checks principal's access to a todo, rejecting the mutation like
+// a missing todo when the principal cannot see it.
+func (s *SyncServiceImpl) allowed(ctx context.Context, principal string, todo *models.Todo, required models.Role, reject func(reason, message string)) (bool, error) {
+	_, err := s.access.check(ctx, principal, todo, required)
+	switch {
+	case err == nil:
+		return true, nil
+	case err.Error() == "todo not found":
+		reject(dto.SyncRejectNotFound, err.Error())
+	case strings.HasPrefix(err.Error(), "forbidden"):
+		reject(dto.SyncRejectForbidden, err.Error())
+	default:
+		return false, err
+	}
+	return false, nil
+}
+
+func (s *SyncServiceImpl) publish(ctx context.Context, eventType events.Type, todo *dto.TodoResponse, audience []string) {
+	if s.publisher != nil {
+		event := events.New(eventType, todo)
+		event.Workspace, _ = tenant.FromContext(ctx)
+		event.Audience = audience
+		s.publisher.Publish(event)
+	}
+}
+
+func (s *SyncServiceImpl) publishDeleted(ctx context.Context, todo *dto.TodoResponse, audience, attachments []string) {
+	if s.publisher != nil {
+		event := events.New(events.TodoDeleted, todo)
+		event.Workspace, _ = tenant.FromContext(ctx)
+		event.Audience = audience
+		event.Attachments = attachments
+		s.publisher.Publish(event)
+	}
+}
+
+// fieldTime is the time a field was last written. Todos written before field
+// clocks existed fall back to their last update.
+func fieldTime(clock *time.Time, updatedAt time.Time) time.Time {
+	if clock == nil {
+		return updatedAt
+	}
+	return *clock
+}
+
+// Sync tokens are opaque to clients: the change log sequence number and the
+// time the token was issued, which decides whether it outlived the
+// tombstones it depends on.
+
+func encodeSyncToken(seq uint64, issuedAt time.Time) string {
+	raw := strconv.FormatUint(seq, 10) + "." + strconv.FormatInt(issuedAt.Unix(), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func (s *SyncServiceImpl) parseToken(token string, now time.Time) (uint64, error) {
+	if token == "" {
+		return 0, nil
+	}
+
+	invalid := errors.New("validation failed: invalid sync token")
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, invalid
+	}
+	seqPart, issuedPart, found := strings.Cut(string(raw), ".")
+	if !found {
+		return 0, invalid
+	}
+	seq, err := strconv.ParseUint(seqPart, 10, 64)
+	if err != nil {
+		return 0, invalid
+	}
+	issued, err := strconv.ParseInt(issuedPart, 10, 64)
+	if err != nil {
+		return 0, invalid
+	}
+
+	if time.Unix(issued, 0).Before(now.Add(-s.tombstoneTTL)) {
+		return 0, errors.New("sync token expired")
+	}
+	return seq, nil
+}
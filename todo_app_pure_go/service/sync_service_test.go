@@ -0,0 +1,292 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"todo-app/dto"
+	"todo-app/models"
+	"todo-app/repository"
+)
+
+func newTestSyncService(t *testing.T) (*SyncServiceImpl, TodoService) {
+	t.Helper()
+	s, todos, _ := newTestSyncServices(t)
+	return s, todos
+}
+
+func newTestSyncServices(t *testing.T) (*SyncServiceImpl, TodoService, DependencyService) {
+	t.Helper()
+	db := newTestDB(t)
+
+	todoRepo := repository.NewTodoRepository(db)
+	accessRepo := repository.NewAccessRepository(db)
+	syncService := NewSyncService(todoRepo, repository.NewSyncRepository(db), accessRepo, nil, nil, nil, 24*time.Hour, 10).(*SyncServiceImpl)
+	return syncService, NewTodoService(todoRepo, accessRepo, nil, nil, nil), NewDependencyService(repository.NewDependencyRepository(db), todoRepo, accessRepo)
+}
+
+func mustSync(t *testing.T, s SyncService, req dto.SyncRequest) *dto.SyncResponse {
+	t.Helper()
+	resp, err := s.Sync(ctx, "", &req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func strPtr(s string) *string { return &s }
+
+func TestSyncPullsChangesAndTombstones(t *testing.T) {
+	s, todos := newTestSyncService(t)
+
+	first, _ := todos.CreateTodo(ctx, "", &dto.CreateTodoRequest{Title: "First"})
+	second, _ := todos.CreateTodo(ctx, "", &dto.CreateTodoRequest{Title: "Second"})
+
+	full := mustSync(t, s, dto.SyncRequest{})
+	if len(full.Changes) != 2 || full.Changes[0].Todo.Title != "First" || full.SyncToken == "" {
+		t.Fatalf("unexpected full sync: %+v", full)
+	}
+
+	// Nothing changed: same position, no changes
+	again := mustSync(t, s, dto.SyncRequest{SyncToken: full.SyncToken})
+	if len(again.Changes) != 0 {
+		t.Errorf("expected no changes, got %+v", again.Changes)
+	}
+
+	if _, err := todos.ToggleTodoComplete(ctx, "", first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := todos.DeleteTodo(ctx, "", second.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	delta := mustSync(t, s, dto.SyncRequest{SyncToken: again.SyncToken})
+	if len(delta.Changes) != 2 {
+		t.Fatalf("expected 2 changes, got %+v", delta.Changes)
+	}
+	if c := delta.Changes[0]; c.ID != first.ID || c.Deleted || !c.Todo.Completed {
+		t.Errorf("unexpected update change: %+v", c)
+	}
+	if c := delta.Changes[1]; c.ID != second.ID || !c.Deleted || c.DeletedAt == nil || c.Todo != nil {
+		t.Errorf("unexpected tombstone: %+v", c)
+	}
+}
+
+func TestSyncPagesWithLimit(t *testing.T) {
+	s, todos := newTestSyncService(t)
+	for _, title := range []string{"a", "b", "c"} {
+		todos.CreateTodo(ctx, "", &dto.CreateTodoRequest{Title: title})
+	}
+
+	page := mustSync(t, s, dto.SyncRequest{Limit: 2})
+	if len(page.Changes) != 2 || !page.HasMore {
+		t.Fatalf("unexpected first page: %+v", page)
+	}
+	page = mustSync(t, s, dto.SyncRequest{SyncToken: page.SyncToken, Limit: 2})
+	if len(page.Changes) != 1 || page.HasMore || page.Changes[0].Todo.Title != "c" {
+		t.Fatalf("unexpected second page: %+v", page)
+	}
+}
+
+func TestSyncCreateIsIdempotent(t *testing.T) {
+	s, _ := newTestSyncService(t)
+	req := dto.SyncRequest{Mutations: []dto.SyncMutation{{
+		Op:        dto.SyncCreate,
+		ClientID:  "local-1",
+		Fields:    dto.UpdateTodoRequest{Title: strPtr("Offline todo")},
+		UpdatedAt: time.Now().Add(-time.Minute),
+	}}}
+
+	first := mustSync(t, s, req)
+	replay := mustSync(t, s, req)
+	if len(first.Applied) != 1 || len(replay.Applied) != 1 || first.Applied[0].ID != replay.Applied[0].ID {
+		t.Fatalf("replayed create was not mapped to the same todo: %+v %+v", first.Applied, replay.Applied)
+	}
+	if len(replay.Changes) != 1 || replay.Changes[0].Todo.Priority != models.MEDIUM {
+		t.Errorf("expected exactly one todo, got %+v", replay.Changes)
+	}
+}
+
+func TestSyncLastWriterWinsPerField(t *testing.T) {
+	s, todos := newTestSyncService(t)
+	todo, _ := todos.CreateTodo(ctx, "", &dto.CreateTodoRequest{Title: "Original"})
+	start := time.Now()
+
+	// The server changes the title after the client's offline edit...
+	time.Sleep(time.Millisecond)
+	if _, err := todos.UpdateTodo(ctx, "", todo.ID, &dto.UpdateTodoRequest{Title: strPtr("Server title")}); err != nil {
+		t.Fatal(err)
+	}
+
+	// ...so the client's older title loses while its priority still applies
+	high := models.HIGH
+	resp := mustSync(t, s, dto.SyncRequest{Mutations: []dto.SyncMutation{{
+		Op:        dto.SyncUpdate,
+		ID:        todo.ID,
+		Fields:    dto.UpdateTodoRequest{Title: strPtr("Client title"), Priority: &high},
+		UpdatedAt: start,
+	}}})
+	if len(resp.Applied) != 1 || len(resp.Rejected) != 1 {
+		t.Fatalf("expected a partial apply, got %+v", resp)
+	}
+	rejection := resp.Rejected[0]
+	if rejection.Reason != dto.SyncRejectConflict || len(rejection.Fields) != 1 || rejection.Fields[0] != "title" {
+		t.Errorf("unexpected rejection: %+v", rejection)
+	}
+	current, _ := todos.GetTodoByID(ctx, "", todo.ID)
+	if current.Title != "Server title" || current.Priority != models.HIGH {
+		t.Errorf("unexpected merged todo: %+v", current)
+	}
+
+	// A newer client edit wins
+	resp = mustSync(t, s, dto.SyncRequest{Mutations: []dto.SyncMutation{{
+		Op:        dto.SyncUpdate,
+		ID:        todo.ID,
+		Fields:    dto.UpdateTodoRequest{Title: strPtr("Newest title")},
+		UpdatedAt: time.Now(),
+	}}})
+	if len(resp.Rejected) != 0 {
+		t.Errorf("unexpected rejection: %+v", resp.Rejected)
+	}
+	if current, _ := todos.GetTodoByID(ctx, "", todo.ID); current.Title != "Newest title" {
+		t.Errorf("newer edit lost: %+v", current)
+	}
+}
+
+func TestSyncCannotCompleteBlockedTodos(t *testing.T) {
+	s, todos, dependencies := newTestSyncServices(t)
+	blocker, _ := todos.CreateTodo(ctx, "", &dto.CreateTodoRequest{Title: "Design"})
+	todo, _ := todos.CreateTodo(ctx, "", &dto.CreateTodoRequest{Title: "Build"})
+	if _, err := dependencies.AddDependency(ctx, "", todo.ID, &dto.AddDependencyRequest{BlockedBy: blocker.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	done := true
+	complete := dto.SyncRequest{Mutations: []dto.SyncMutation{{
+		Op:        dto.SyncUpdate,
+		ID:        todo.ID,
+		Fields:    dto.UpdateTodoRequest{Title: strPtr("Build it"), Completed: &done},
+		UpdatedAt: time.Now(),
+	}}}
+	resp := mustSync(t, s, complete)
+	if len(resp.Applied) != 0 || len(resp.Rejected) != 1 || resp.Rejected[0].Reason != dto.SyncRejectBlocked {
+		t.Fatalf("expected a blocked rejection, got %+v", resp)
+	}
+	// The whole mutation is rejected
+	if current, _ := todos.GetTodoByID(ctx, "", todo.ID); current.Completed || current.Title != "Build" {
+		t.Errorf("blocked mutation applied: %+v", current)
+	}
+
+	// Once the blocker is done the todo can be completed
+	if _, err := todos.ToggleTodoComplete(ctx, "", blocker.ID); err != nil {
+		t.Fatal(err)
+	}
+	complete.Mutations[0].UpdatedAt = time.Now()
+	if resp := mustSync(t, s, complete); len(resp.Applied) != 1 || len(resp.Rejected) != 0 {
+		t.Fatalf("expected the mutation to apply, got %+v", resp)
+	}
+}
+
+func TestSyncDeleteConflictsAndTombstones(t *testing.T) {
+	s, todos := newTestSyncService(t)
+	todo, _ := todos.CreateTodo(ctx, "", &dto.CreateTodoRequest{Title: "Shared"})
+	deletedOffline := time.Now()
+	time.Sleep(time.Millisecond)
+	todos.ToggleTodoComplete(ctx, "", todo.ID)
+
+	resp := mustSync(t, s, dto.SyncRequest{Mutations: []dto.SyncMutation{
+		{Op: dto.SyncDelete, ID: todo.ID, UpdatedAt: deletedOffline},
+		{Op: dto.SyncDelete, ID: 999, UpdatedAt: time.Now()},
+		{Op: dto.SyncUpdate, UpdatedAt: time.Now()},
+	}})
+	if len(resp.Rejected) != 3 {
+		t.Fatalf("expected 3 rejections, got %+v", resp)
+	}
+	for i, reason := range []string{dto.SyncRejectConflict, dto.SyncRejectNotFound, dto.SyncRejectValidation} {
+		if resp.Rejected[i].Reason != reason || resp.Rejected[i].Index != i {
+			t.Errorf("rejection %d: got %+v, want %s", i, resp.Rejected[i], reason)
+		}
+	}
+	if fields := resp.Rejected[0].Fields; len(fields) != 1 || fields[0] != "completed" {
+		t.Errorf("unexpected conflicting fields %v", fields)
+	}
+
+	// A later delete wins, repeating it is harmless and updates are refused
+	resp = mustSync(t, s, dto.SyncRequest{Mutations: []dto.SyncMutation{
+		{Op: dto.SyncDelete, ID: todo.ID, UpdatedAt: time.Now()},
+		{Op: dto.SyncDelete, ID: todo.ID, UpdatedAt: time.Now()},
+		{Op: dto.SyncUpdate, ID: todo.ID, Fields: dto.UpdateTodoRequest{Title: strPtr("Too late")}, UpdatedAt: time.Now()},
+	}})
+	if len(resp.Applied) != 2 || len(resp.Rejected) != 1 || resp.Rejected[0].Reason != dto.SyncRejectDeleted {
+		t.Fatalf("unexpected result: %+v", resp)
+	}
+	if len(resp.Changes) != 1 || !resp.Changes[0].Deleted {
+		t.Errorf("expected a tombstone, got %+v", resp.Changes)
+	}
+}
+
+func TestSyncTombstonesFollowVisibility(t *testing.T) {
+	db := newTestDB(t)
+	todoRepo := repository.NewTodoRepository(db)
+	accessRepo := repository.NewAccessRepository(db)
+	s := NewSyncService(todoRepo, repository.NewSyncRepository(db), accessRepo, nil, nil, nil, 24*time.Hour, 10)
+	todos := NewTodoService(todoRepo, accessRepo, nil, nil, nil)
+	access := NewAccessService(accessRepo, todoRepo)
+
+	private, _ := todos.CreateTodo(ctx, "bob", &dto.CreateTodoRequest{Title: "Bob private"})
+	shared, _ := todos.CreateTodo(ctx, "bob", &dto.CreateTodoRequest{Title: "Bob shared"})
+	listed, _ := todos.CreateTodo(ctx, "carol", &dto.CreateTodoRequest{Title: "Carol listed"})
+	if _, err := access.GrantTodoAccess(ctx, "bob", shared.ID, &dto.GrantAccessRequest{Principal: "alice", Role: models.RoleViewer}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := access.ShareList(ctx, "carol", &dto.GrantAccessRequest{Principal: "alice", Role: models.RoleViewer}); err != nil {
+		t.Fatal(err)
+	}
+
+	sync := func(principal, token string) *dto.SyncResponse {
+		resp, err := s.Sync(ctx, principal, &dto.SyncRequest{SyncToken: token})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	before := map[string]string{"alice": sync("alice", "").SyncToken, "bob": sync("bob", "").SyncToken}
+	for _, todo := range []*dto.TodoResponse{private, shared, listed} {
+		if err := todos.DeleteTodo(ctx, todo.Owner, todo.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		principal string
+		want      []uint
+	}{
+		{"alice", []uint{shared.ID, listed.ID}},
+		{"bob", []uint{private.ID, shared.ID}},
+	}
+	for _, tt := range tests {
+		var got []uint
+		for _, change := range sync(tt.principal, before[tt.principal]).Changes {
+			if !change.Deleted {
+				t.Errorf("%s: unexpected change %+v", tt.principal, change)
+			}
+			got = append(got, change.ID)
+		}
+		if len(got) != len(tt.want) || got[0] != tt.want[0] || got[1] != tt.want[1] {
+			t.Errorf("%s got tombstones %v, want %v", tt.principal, got, tt.want)
+		}
+	}
+}
+
+func TestSyncTokenExpiry(t *testing.T) {
+	s, _ := newTestSyncService(t)
+	resp := mustSync(t, s, dto.SyncRequest{})
+
+	s.now = func() time.Time { return time.Now().Add(48 * time.Hour) }
+	if _, err := s.Sync(ctx, "", &dto.SyncRequest{SyncToken: resp.SyncToken}); err == nil || err.Error() != "sync token expired" {
+		t.Errorf("expected expired token, got %v", err)
+	}
+	if _, err := s.Sync(ctx, "", &dto.SyncRequest{SyncToken: "not-a-token"}); err == nil {
+		t.Error("expected invalid token error")
+	}
+}
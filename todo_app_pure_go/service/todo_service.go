@@ -1,15 +1,42 @@
 package service
 
 import (
+	"context"
 	"todo-app/dto"
 	"todo-app/models"
 )
 
+// TodoService manages todos on behalf of principal, the authenticated
+// identity or "" when authentication is disabled. Todos are owned by the
+// principal that creates them. Reading needs the viewer role, changing a
+// todo the editor role and deleting it the owner role; a todo the principal
+// cannot see is reported as not found.
 type TodoService interface {
-	CreateTodo(req *dto.CreateTodoRequest) (*dto.TodoResponse, error)
-	GetTodoByID(id uint) (*dto.TodoResponse, error)
-	GetAllTodos(completed *bool, priority *models.Priority, limit, offset int) ([]*dto.TodoResponse, int64, error)
-	UpdateTodo(id uint, req *dto.UpdateTodoRequest) (*dto.TodoResponse, error)
-	DeleteTodo(id uint) error
-	ToggleTodoComplete(id uint) (*dto.TodoResponse, error)
+	CreateTodo(ctx context.Context, principal string, req *dto.CreateTodoRequest) (*dto.TodoResponse, error)
+	GetTodoByID(ctx context.Context, principal string, id uint) (*dto.TodoResponse, error)
+	// GetAllTodos lists todos newest first, or in their manual order when
+	// sort is models.SortPosition. blocked keeps only the todos that are,
+	// or are not, waiting for an open todo.
+	GetAllTodos(ctx context.Context, principal string, completed, blocked *bool, priority *models.Priority, sort models.TodoSort, limit, offset int) ([]*dto.TodoResponse, int64, error)
+	UpdateTodo(ctx context.Context, principal string, id uint, req *dto.UpdateTodoRequest) (*dto.TodoResponse, error)
+	DeleteTodo(ctx context.Context, principal string, id uint) error
+	// ToggleTodoComplete and UpdateTodo's completed field complete a todo
+	// into the workflow's done status and reopen it into its initial
+	// status, whatever the transitions allow. Neither they nor
+	// TransitionTodo complete a todo blocked by open todos.
+	ToggleTodoComplete(ctx context.Context, principal string, id uint) (*dto.TodoResponse, error)
+	// TransitionTodo moves a todo to a status the workflow allows it to
+	// move to from its current one.
+	TransitionTodo(ctx context.Context, principal string, id uint, req *dto.TransitionTodoRequest) (*dto.TodoResponse, error)
+	// GetStatusHistory reports the status changes of a todo and the time
+	// it spent in each status.
+	GetStatusHistory(ctx context.Context, principal string, id uint) (*dto.StatusHistoryResponse, error)
+	GetWorkflow(ctx context.Context) *dto.WorkflowResponse
+	// MoveTodo places a todo between two neighbours in the manual order.
+	MoveTodo(ctx context.Context, principal string, id uint, req *dto.MoveTodoRequest) (*dto.TodoResponse, error)
+	ExportTodos(ctx context.Context, principal string, completed *bool, priority *models.Priority, fn func(todo *dto.TodoResponse) error) error
+	ImportTodos(ctx context.Context, principal string, rows []dto.ImportRow, opts dto.ImportOptions) (*dto.ImportResult, error)
+	// QuickAdd parses a todo typed as one line of text and, unless preview
+	// is set, creates it.
+	QuickAdd(ctx context.Context, principal string, req *dto.QuickAddRequest, preview bool) (*dto.QuickAddResponse, error)
 }
@@ -1,63 +1,92 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"sort"
 	"strconv"
+	"time"
 	"todo-app/dto"
+	"todo-app/events"
 	"todo-app/models"
+	"todo-app/quickadd"
+	"todo-app/rank"
 	"todo-app/repository"
+	"todo-app/tenant"
 	"todo-app/utils"
+	"todo-app/workflow"
 )
 
 type TodoServiceImpl struct {
-	todoRepo repository.TodoRepository
+	todoRepo  repository.TodoRepository
+	access    authorizer
+	policy    workspacePolicy
+	publisher events.Publisher
+	workflow  *workflow.Workflow
 }
 
-func NewTodoService(todoRepo repository.TodoRepository) TodoService {
+// NewTodoService creates the todo service. New todos follow the settings in
+// workspaceRepo, which may be nil to use the defaults. Lifecycle events are
+// sent to publisher after each successful change; it may be nil. Todos move
+// through wf, or workflow.Default() if it is nil.
+func NewTodoService(todoRepo repository.TodoRepository, accessRepo repository.AccessRepository, workspaceRepo repository.WorkspaceRepository, publisher events.Publisher, wf *workflow.Workflow) TodoService {
+	if wf == nil {
+		wf = workflow.Default()
+	}
 	return &TodoServiceImpl{
-		todoRepo: todoRepo,
+		todoRepo:  todoRepo,
+		access:    authorizer{todoRepo: todoRepo, accessRepo: accessRepo},
+		policy:    workspacePolicy{workspaceRepo: workspaceRepo, todoRepo: todoRepo},
+		publisher: publisher,
+		workflow:  wf,
 	}
 }
 
-func (s *TodoServiceImpl) CreateTodo(req *dto.CreateTodoRequest) (*dto.TodoResponse, error) {
+func (s *TodoServiceImpl) CreateTodo(ctx context.Context, principal string, req *dto.CreateTodoRequest) (*dto.TodoResponse, error) {
 	// Validate request
 	if validationErrors := utils.ValidateStruct(req); len(validationErrors) > 0 {
 		return nil, errors.New("validation failed: " + validationErrors[0])
 	}
 
-	// Set default priority if not provided
+	// Check the workspace's quota and set its default priority if not provided
+	defaultPriority, err := s.policy.admit(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
 	if req.Priority == "" {
-		req.Priority = models.MEDIUM
+		req.Priority = defaultPriority
 	}
 
-	// Create todo model
-	todo := &models.Todo{
-		Title:       req.Title,
-		Description: req.Description,
-		Priority:    req.Priority,
-		Completed:   false,
-	}
+	// Create todo model, in the first status of the workflow
+	todo := newTodo(principal, req)
+	s.workflow.SetCompleted(todo, false, time.Now())
 
 	// Save to database
-	createdTodo, err := s.todoRepo.Create(todo)
+	createdTodo, err := s.todoRepo.Create(ctx, todo)
 	if err != nil {
 		return nil, err
 	}
+	if createdTodo, err = s.rebalanced(ctx, createdTodo); err != nil {
+		return nil, err
+	}
 
 	// Convert to response DTO
-	return s.todoToResponse(createdTodo), nil
+	response := todoToResponse(createdTodo)
+	s.publish(ctx, events.TodoCreated, response, s.access.audience(ctx, createdTodo))
+	return response, nil
 }
 
-func (s *TodoServiceImpl) GetTodoByID(id uint) (*dto.TodoResponse, error) {
-	todo, err := s.todoRepo.GetByID(id)
+func (s *TodoServiceImpl) GetTodoByID(ctx context.Context, principal string, id uint) (*dto.TodoResponse, error) {
+	todo, _, err := s.access.authorize(ctx, principal, id, models.RoleViewer)
 	if err != nil {
 		return nil, err
 	}
 
-	return s.todoToResponse(todo), nil
+	return todoToResponse(todo), nil
 }
 
-func (s *TodoServiceImpl) GetAllTodos(completed *bool, priority *models.Priority, limit, offset int) ([]*dto.TodoResponse, int64, error) {
+func (s *TodoServiceImpl) GetAllTodos(ctx context.Context, principal string, completed, blocked *bool, priority *models.Priority, sort models.TodoSort, limit, offset int) ([]*dto.TodoResponse, int64, error) {
 	// Validate pagination parameters
 	if limit <= 0 {
 		limit = 10
@@ -70,13 +99,13 @@ func (s *TodoServiceImpl) GetAllTodos(completed *bool, priority *models.Priority
 	}
 
 	// Get todos from repository
-	todos, err := s.todoRepo.GetAll(completed, priority, limit, offset)
+	todos, err := s.todoRepo.GetAll(ctx, principal, completed, blocked, priority, sort, limit, offset)
 	if err != nil {
 		return nil, 0, err
 	}
 
 	// Get total count
-	total, err := s.todoRepo.GetTotalCount(completed, priority)
+	total, err := s.todoRepo.GetTotalCount(ctx, principal, completed, blocked, priority)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -84,70 +113,498 @@ func (s *TodoServiceImpl) GetAllTodos(completed *bool, priority *models.Priority
 	// Convert to response DTOs
 	responses := make([]*dto.TodoResponse, len(todos))
 	for i, todo := range todos {
-		responses[i] = s.todoToResponse(todo)
+		responses[i] = todoToResponse(todo)
 	}
 
 	return responses, total, nil
 }
 
-func (s *TodoServiceImpl) UpdateTodo(id uint, req *dto.UpdateTodoRequest) (*dto.TodoResponse, error) {
+func (s *TodoServiceImpl) UpdateTodo(ctx context.Context, principal string, id uint, req *dto.UpdateTodoRequest) (*dto.TodoResponse, error) {
 	// Validate request
 	if validationErrors := utils.ValidateStruct(req); len(validationErrors) > 0 {
 		return nil, errors.New("validation failed: " + validationErrors[0])
 	}
 
-	// Check if todo exists
-	existingTodo, err := s.todoRepo.GetByID(id)
+	// Check if todo exists and may be changed
+	existingTodo, _, err := s.access.authorize(ctx, principal, id, models.RoleEditor)
 	if err != nil {
 		return nil, err
 	}
+	wasCompleted := existingTodo.Completed
 
-	// Update fields if provided
+	// Update fields if provided, recording when each was written for sync
+	now := time.Now()
 	if req.Title != nil {
 		existingTodo.Title = *req.Title
+		existingTodo.Clock.Title = &now
 	}
 	if req.Description != nil {
 		existingTodo.Description = req.Description
+		existingTodo.Clock.Description = &now
 	}
 	if req.Completed != nil {
-		existingTodo.Completed = *req.Completed
+		if *req.Completed && !wasCompleted {
+			if err := s.checkUnblocked(ctx, id); err != nil {
+				return nil, err
+			}
+		}
+		s.workflow.SetCompleted(existingTodo, *req.Completed, now)
+		existingTodo.Clock.Completed = &now
 	}
 	if req.Priority != nil {
 		existingTodo.Priority = *req.Priority
+		existingTodo.Clock.Priority = &now
 	}
 
 	// Save to database
-	updatedTodo, err := s.todoRepo.Update(id, existingTodo)
+	updatedTodo, err := s.todoRepo.Update(ctx, id, existingTodo)
 	if err != nil {
 		return nil, err
 	}
 
-	return s.todoToResponse(updatedTodo), nil
+	response := todoToResponse(updatedTodo)
+	s.publishChange(ctx, wasCompleted, response, s.access.audience(ctx, updatedTodo))
+	return response, nil
+}
+
+func (s *TodoServiceImpl) DeleteTodo(ctx context.Context, principal string, id uint) error {
+	// Load the todo first so the event can carry its last state
+	todo, _, err := s.access.authorize(ctx, principal, id, models.RoleOwner)
+	if err != nil {
+		return err
+	}
+	// Grants are deleted with the todo
+	audience := s.access.audience(ctx, todo)
+
+	attachments, err := s.todoRepo.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	s.publishDeleted(ctx, todoToResponse(todo), audience, attachments)
+	return nil
 }
 
-func (s *TodoServiceImpl) DeleteTodo(id uint) error {
-	return s.todoRepo.Delete(id)
+func (s *TodoServiceImpl) ToggleTodoComplete(ctx context.Context, principal string, id uint) (*dto.TodoResponse, error) {
+	existingTodo, _, err := s.access.authorize(ctx, principal, id, models.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	if !existingTodo.Completed {
+		if err := s.checkUnblocked(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+	todo, err := s.todoRepo.ToggleComplete(ctx, id, s.workflow)
+	if err != nil {
+		return nil, err
+	}
+
+	response := todoToResponse(todo)
+	s.publishChange(ctx, !todo.Completed, response, s.access.audience(ctx, todo))
+	return response, nil
+}
+
+// TransitionTodo moves a todo to another status of the workflow, if the
+// workflow allows the move from its current status. Moving a todo to the
+// status it is in changes nothing.
+func (s *TodoServiceImpl) TransitionTodo(ctx context.Context, principal string, id uint, req *dto.TransitionTodoRequest) (*dto.TodoResponse, error) {
+	if validationErrors := utils.ValidateStruct(req); len(validationErrors) > 0 {
+		return nil, errors.New("validation failed: " + validationErrors[0])
+	}
+	if !s.workflow.Has(req.Status) {
+		return nil, errors.New("validation failed: unknown status " + req.Status)
+	}
+
+	todo, _, err := s.access.authorize(ctx, principal, id, models.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	if todo.Status == req.Status {
+		return todoToResponse(todo), nil
+	}
+	if !s.workflow.Allows(todo.Status, req.Status) {
+		return nil, fmt.Errorf("invalid transition: cannot move from %s to %s", todo.Status, req.Status)
+	}
+
+	if !todo.Completed && s.workflow.IsDone(req.Status) {
+		if err := s.checkUnblocked(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	// The status shares the completed flag's clock, as it decides the flag
+	wasCompleted := todo.Completed
+	now := time.Now()
+	s.workflow.Move(todo, req.Status, now)
+	todo.Clock.Completed = &now
+
+	updatedTodo, err := s.todoRepo.Update(ctx, id, todo)
+	if err != nil {
+		return nil, err
+	}
+
+	response := todoToResponse(updatedTodo)
+	s.publishChange(ctx, wasCompleted, response, s.access.audience(ctx, updatedTodo))
+	return response, nil
 }
 
-func (s *TodoServiceImpl) ToggleTodoComplete(id uint) (*dto.TodoResponse, error) {
-	todo, err := s.todoRepo.ToggleComplete(id)
+// GetStatusHistory adds up the time between the todo's status changes.
+// Todos from before workflows existed have no history of the time before
+// their first change; it is counted from their creation.
+func (s *TodoServiceImpl) GetStatusHistory(ctx context.Context, principal string, id uint) (*dto.StatusHistoryResponse, error) {
+	todo, _, err := s.access.authorize(ctx, principal, id, models.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := s.todoRepo.StatusHistory(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	return s.todoToResponse(todo), nil
+	response := &dto.StatusHistoryResponse{
+		TodoID:          todo.ID,
+		Status:          todo.Status,
+		StatusChangedAt: todo.StatusChangedAt,
+		Changes:         make([]dto.StatusChangeResponse, 0, len(changes)),
+		TimeInStatus:    []dto.StatusTime{},
+	}
+	times := make(map[string]*dto.StatusTime)
+	spend := func(status string, from, to time.Time, entered bool) {
+		if status == "" {
+			return
+		}
+		t, ok := times[status]
+		if !ok {
+			t = &dto.StatusTime{Status: status}
+			times[status] = t
+		}
+		if to.After(from) {
+			t.Seconds += to.Sub(from).Seconds()
+		}
+		if entered {
+			t.Entries++
+		}
+	}
+
+	now := time.Now()
+	if len(changes) == 0 {
+		since := todo.CreatedAt
+		if todo.StatusChangedAt != nil {
+			since = *todo.StatusChangedAt
+		}
+		spend(todo.Status, since, now, true)
+	} else if first := changes[0]; first.From != "" {
+		spend(first.From, todo.CreatedAt, first.ChangedAt, true)
+	}
+	for i, change := range changes {
+		response.Changes = append(response.Changes, dto.StatusChangeResponse{
+			From:      change.From,
+			To:        change.To,
+			ChangedAt: change.ChangedAt,
+		})
+		until := now
+		if i+1 < len(changes) {
+			until = changes[i+1].ChangedAt
+		}
+		spend(change.To, change.ChangedAt, until, true)
+	}
+
+	for _, t := range times {
+		response.TimeInStatus = append(response.TimeInStatus, *t)
+	}
+	sort.Slice(response.TimeInStatus, func(i, j int) bool {
+		a, b := response.TimeInStatus[i].Status, response.TimeInStatus[j].Status
+		if oa, ob := s.workflow.Order(a), s.workflow.Order(b); oa != ob {
+			return oa < ob
+		}
+		return a < b
+	})
+	return response, nil
 }
 
-// Helper method to convert Todo model to TodoResponse DTO
-func (s *TodoServiceImpl) todoToResponse(todo *models.Todo) *dto.TodoResponse {
+// GetWorkflow describes the configured statuses and transitions.
+func (s *TodoServiceImpl) GetWorkflow(ctx context.Context) *dto.WorkflowResponse {
+	response := &dto.WorkflowResponse{Statuses: []dto.WorkflowStatus{}}
+	for _, status := range s.workflow.Statuses() {
+		response.Statuses = append(response.Statuses, dto.WorkflowStatus{
+			Name:    status.Name,
+			Initial: status.Name == s.workflow.Initial(),
+			Done:    status.Done,
+			Next:    status.Next,
+		})
+	}
+	return response
+}
+
+// checkUnblocked refuses to complete a todo while todos blocking it are
+// open. It only counts them, as the principal may not see them all.
+func (s *TodoServiceImpl) checkUnblocked(ctx context.Context, id uint) error {
+	blockers, err := s.todoRepo.OpenBlockers(ctx, id)
+	if err != nil {
+		return err
+	}
+	if len(blockers) > 0 {
+		return fmt.Errorf("blocked: todo is blocked by %d open todo(s)", len(blockers))
+	}
+	return nil
+}
+
+// MoveTodo needs the editor role on the moved todo and the viewer role on
+// its neighbours. Positions that grow too long or collide are spread out
+// again for the whole workspace.
+func (s *TodoServiceImpl) MoveTodo(ctx context.Context, principal string, id uint, req *dto.MoveTodoRequest) (*dto.TodoResponse, error) {
+	if req.After == nil && req.Before == nil {
+		return nil, errors.New("validation failed: after or before is required")
+	}
+	if req.After != nil && req.Before != nil && *req.After == *req.Before {
+		return nil, errors.New("validation failed: after and before must be different todos")
+	}
+	if _, _, err := s.access.authorize(ctx, principal, id, models.RoleEditor); err != nil {
+		return nil, err
+	}
+	for _, neighbour := range []*uint{req.After, req.Before} {
+		if neighbour == nil {
+			continue
+		}
+		if *neighbour == id {
+			return nil, errors.New("validation failed: a todo cannot be moved next to itself")
+		}
+		if _, _, err := s.access.authorize(ctx, principal, *neighbour, models.RoleViewer); err != nil {
+			if err.Error() == "todo not found" {
+				return nil, fmt.Errorf("validation failed: todo %d not found", *neighbour)
+			}
+			return nil, err
+		}
+	}
+
+	todo, err := s.todoRepo.Move(ctx, id, req.After, req.Before)
+	if errors.Is(err, rank.ErrOrder) {
+		// The neighbours share a position; spread them apart and retry
+		if err := s.todoRepo.Rebalance(ctx); err != nil {
+			return nil, err
+		}
+		todo, err = s.todoRepo.Move(ctx, id, req.After, req.Before)
+		if errors.Is(err, rank.ErrOrder) {
+			// Only a concurrent move puts them together again
+			return nil, errors.New("conflict: the neighbours were moved meanwhile, reload the list and try again")
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if todo, err = s.rebalanced(ctx, todo); err != nil {
+		return nil, err
+	}
+
+	response := todoToResponse(todo)
+	s.publish(ctx, events.TodoUpdated, response, s.access.audience(ctx, todo))
+	return response, nil
+}
+
+// rebalanced spreads the workspace's positions out again once todo's
+// position has grown past rank.MaxLength, and returns todo as it is after.
+func (s *TodoServiceImpl) rebalanced(ctx context.Context, todo *models.Todo) (*models.Todo, error) {
+	if len(todo.Position) <= rank.MaxLength {
+		return todo, nil
+	}
+	if err := s.todoRepo.Rebalance(ctx); err != nil {
+		return nil, err
+	}
+	return s.todoRepo.GetByID(ctx, todo.ID)
+}
+
+// ExportTodos streams every matching todo to fn. Unlike GetAllTodos it is
+// not paginated, so callers must not buffer the whole result.
+func (s *TodoServiceImpl) ExportTodos(ctx context.Context, principal string, completed *bool, priority *models.Priority, fn func(todo *dto.TodoResponse) error) error {
+	return s.todoRepo.ForEach(ctx, principal, completed, priority, func(todo *models.Todo) error {
+		return fn(todoToResponse(todo))
+	})
+}
+
+// ImportTodos validates every row and, unless this is a dry run, creates the
+// todos according to the import mode.
+func (s *TodoServiceImpl) ImportTodos(ctx context.Context, principal string, rows []dto.ImportRow, opts dto.ImportOptions) (*dto.ImportResult, error) {
+	if opts.Mode == "" {
+		opts.Mode = dto.ImportAllOrNothing
+	}
+	if opts.Mode != dto.ImportAllOrNothing && opts.Mode != dto.ImportBestEffort {
+		return nil, errors.New("validation failed: mode must be one of: all_or_nothing best_effort")
+	}
+
+	result := &dto.ImportResult{
+		Mode:   opts.Mode,
+		DryRun: opts.DryRun,
+		Total:  len(rows),
+		Failed: []dto.ImportRowError{},
+	}
+
+	// The quota is checked again once the valid rows are known
+	defaultPriority, err := s.policy.admit(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var valid []*models.Todo
+	for _, row := range rows {
+		req := row.Todo
+		rowErrors := append(utils.ValidateStruct(&req), row.Errors...)
+		if len(rowErrors) > 0 {
+			result.Failed = append(result.Failed, dto.ImportRowError{
+				Line:   row.Line,
+				Title:  req.Title,
+				Errors: rowErrors,
+			})
+			continue
+		}
+
+		if req.Priority == "" {
+			req.Priority = defaultPriority
+		}
+		todo := newTodo(principal, &req)
+		s.workflow.SetCompleted(todo, row.Completed, time.Now())
+		valid = append(valid, todo)
+	}
+	result.Valid = len(valid)
+
+	if opts.DryRun {
+		return result, nil
+	}
+	if opts.Mode == dto.ImportAllOrNothing && len(result.Failed) > 0 {
+		return result, nil
+	}
+	if _, err := s.policy.admit(ctx, len(valid)); err != nil {
+		return nil, err
+	}
+
+	if err := s.todoRepo.CreateAll(ctx, valid); err != nil {
+		return nil, err
+	}
+	result.Imported = len(valid)
+	tooLong := false
+	for _, todo := range valid {
+		tooLong = tooLong || len(todo.Position) > rank.MaxLength
+	}
+	if tooLong {
+		if err := s.todoRepo.Rebalance(ctx); err != nil {
+			return nil, err
+		}
+		for i, todo := range valid {
+			if valid[i], err = s.todoRepo.GetByID(ctx, todo.ID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// New todos share their owner's audience; none has grants of its own
+	if len(valid) > 0 {
+		audience := s.access.audience(ctx, valid[0])
+		for _, todo := range valid {
+			s.publish(ctx, events.TodoCreated, todoToResponse(todo), audience)
+		}
+	}
+
+	return result, nil
+}
+
+// publishChange emits todo.completed when a change completed the todo and
+// todo.updated otherwise.
+func (s *TodoServiceImpl) publishChange(ctx context.Context, wasCompleted bool, todo *dto.TodoResponse, audience []string) {
+	if todo.Completed && !wasCompleted {
+		s.publish(ctx, events.TodoCompleted, todo, audience)
+		return
+	}
+	s.publish(ctx, events.TodoUpdated, todo, audience)
+}
+
+// publish sends an event that only principals in audience receive; nil
+// means everyone in the workspace of ctx.
+func (s *TodoServiceImpl) publish(ctx context.Context, eventType events.Type, todo *dto.TodoResponse, audience []string) {
+	if s.publisher != nil {
+		event := events.New(eventType, todo)
+		event.Workspace, _ = tenant.FromContext(ctx)
+		event.Audience = audience
+		s.publisher.Publish(event)
+	}
+}
+
+// publishDeleted sends the todo.deleted event of a todo whose attachments
+// with the given digests were deleted with it.
+func (s *TodoServiceImpl) publishDeleted(ctx context.Context, todo *dto.TodoResponse, audience, attachments []string) {
+	if s.publisher != nil {
+		event := events.New(events.TodoDeleted, todo)
+		event.Workspace, _ = tenant.FromContext(ctx)
+		event.Audience = audience
+		event.Attachments = attachments
+		s.publisher.Publish(event)
+	}
+}
+
+// QuickAdd reads the text in the user's time zone and creates the todo with
+// everything it recognized.
+func (s *TodoServiceImpl) QuickAdd(ctx context.Context, principal string, req *dto.QuickAddRequest, preview bool) (*dto.QuickAddResponse, error) {
+	if validationErrors := utils.ValidateStruct(req); len(validationErrors) > 0 {
+		return nil, errors.New("validation failed: " + validationErrors[0])
+	}
+	loc := time.UTC
+	if req.TimeZone != "" {
+		var err error
+		if loc, err = time.LoadLocation(req.TimeZone); err != nil {
+			return nil, errors.New("validation failed: unknown time zone " + req.TimeZone)
+		}
+	}
+
+	parsed := quickadd.Parse(req.Text, time.Now().In(loc))
+	if preview {
+		return &dto.QuickAddResponse{Parsed: parsed}, nil
+	}
+	todo, err := s.CreateTodo(ctx, principal, &parsed.Todo)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.QuickAddResponse{Parsed: parsed, Todo: todo}, nil
+}
+
+// newTodo builds the todo principal creates with req.
+func newTodo(principal string, req *dto.CreateTodoRequest) *models.Todo {
+	return &models.Todo{
+		Title:       req.Title,
+		Description: req.Description,
+		Priority:    req.Priority,
+		DueAt:       req.DueAt,
+		AllDay:      req.AllDay,
+		Tags:        req.Tags,
+		Project:     req.Project,
+		Recurrence:  req.Recurrence,
+		Owner:       principal,
+	}
+}
+
+// Helper function to convert Todo model to TodoResponse DTO
+func todoToResponse(todo *models.Todo) *dto.TodoResponse {
+	tags := todo.Tags
+	if tags == nil {
+		tags = []string{}
+	}
 	return &dto.TodoResponse{
-		ID:          todo.ID,
-		Title:       todo.Title,
-		Description: todo.Description,
-		Completed:   todo.Completed,
-		Priority:    todo.Priority,
-		CreatedAt:   todo.CreatedAt,
-		UpdatedAt:   todo.UpdatedAt,
+		ID:              todo.ID,
+		Title:           todo.Title,
+		Description:     todo.Description,
+		Completed:       todo.Completed,
+		Priority:        todo.Priority,
+		CreatedAt:       todo.CreatedAt,
+		UpdatedAt:       todo.UpdatedAt,
+		CompletedAt:     todo.CompletedAt,
+		DueAt:           todo.DueAt,
+		AllDay:          todo.AllDay,
+		Tags:            tags,
+		Project:         todo.Project,
+		Recurrence:      todo.Recurrence,
+		Status:          todo.Status,
+		StatusChangedAt: todo.StatusChangedAt,
+		Position:        todo.Position,
+		Owner:           todo.Owner,
+		CommentCount:    todo.CommentCount,
 	}
 }
 
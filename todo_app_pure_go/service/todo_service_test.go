@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"todo-app/dto"
+	"todo-app/models"
+	"todo-app/rank"
+	"todo-app/repository"
+	"todo-app/workflow"
+)
+
+func TestCompletedAtFollowsCompletion(t *testing.T) {
+	db := newTestDB(t)
+	s := NewTodoService(repository.NewTodoRepository(db), repository.NewAccessRepository(db), nil, nil, nil)
+
+	todo, _ := s.CreateTodo(ctx, "", &dto.CreateTodoRequest{Title: "Write report"})
+	if todo.CompletedAt != nil {
+		t.Fatal("new todo has completed_at")
+	}
+
+	toggled, err := s.ToggleTodoComplete(ctx, "", todo.ID)
+	if err != nil || toggled.CompletedAt == nil {
+		t.Fatalf("toggle did not set completed_at: %+v, %v", toggled, err)
+	}
+
+	// Reopening through an update clears it, and must persist completed=false
+	open := false
+	if _, err := s.UpdateTodo(ctx, "", todo.ID, &dto.UpdateTodoRequest{Completed: &open}); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := s.GetTodoByID(ctx, "", todo.ID)
+	if stored.Completed || stored.CompletedAt != nil {
+		t.Errorf("reopened todo still completed: %+v", stored)
+	}
+
+	done := true
+	updated, _ := s.UpdateTodo(ctx, "", todo.ID, &dto.UpdateTodoRequest{Completed: &done})
+	if updated.CompletedAt == nil {
+		t.Error("update did not set completed_at")
+	}
+}
+
+func TestQuickAdd(t *testing.T) {
+	db := newTestDB(t)
+	s := NewTodoService(repository.NewTodoRepository(db), repository.NewAccessRepository(db), nil, nil, nil)
+
+	preview, err := s.QuickAdd(ctx, "alice", &dto.QuickAddRequest{Text: "Buy milk tomorrow 5pm !high #home", TimeZone: "Europe/Istanbul"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preview.Todo != nil || preview.Parsed.Todo.Title != "Buy milk" || preview.Parsed.Todo.DueAt == nil {
+		t.Fatalf("unexpected preview %+v", preview)
+	}
+	if _, offset := preview.Parsed.Todo.DueAt.Zone(); offset != 3*60*60 || preview.Parsed.Todo.DueAt.Hour() != 17 {
+		t.Errorf("due %v is not 17:00 in Istanbul", preview.Parsed.Todo.DueAt)
+	}
+	if _, total, _ := s.GetAllTodos(ctx, "alice", nil, nil, nil, "", 10, 0); total != 0 {
+		t.Error("preview created a todo")
+	}
+
+	created, err := s.QuickAdd(ctx, "alice", &dto.QuickAddRequest{Text: "Buy milk !high"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Todo == nil || created.Todo.Title != "Buy milk" || created.Todo.Priority != models.HIGH || created.Todo.Owner != "alice" {
+		t.Errorf("unexpected todo %+v", created.Todo)
+	}
+
+	// Everything that was read is stored with the todo
+	created, err = s.QuickAdd(ctx, "alice", &dto.QuickAddRequest{Text: "Water plants tomorrow #home #garden +chores every week"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := s.GetTodoByID(ctx, "alice", created.Todo.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Title != "Water plants" || stored.DueAt == nil || !stored.DueAt.Equal(*created.Parsed.Todo.DueAt) || !stored.AllDay ||
+		!reflect.DeepEqual(stored.Tags, []string{"home", "garden"}) || stored.Project != "chores" || stored.Recurrence != "FREQ=WEEKLY" {
+		t.Errorf("stored todo = %+v", stored)
+	}
+
+	for _, req := range []dto.QuickAddRequest{
+		{Text: ""},
+		{Text: "Buy milk", TimeZone: "Mars/Olympus_Mons"},
+		{Text: "#home !high"},
+	} {
+		_, err := s.QuickAdd(ctx, "alice", &req, false)
+		wantError(t, err, "validation failed")
+	}
+}
+
+func TestWorkflowTransitions(t *testing.T) {
+	db := newTestDB(t)
+	s := NewTodoService(repository.NewTodoRepository(db), repository.NewAccessRepository(db), nil, nil, nil)
+
+	todo, _ := s.CreateTodo(ctx, "alice", &dto.CreateTodoRequest{Title: "Ship release"})
+	if todo.Status != "backlog" || todo.Completed || todo.StatusChangedAt == nil {
+		t.Fatalf("new todo = %+v", todo)
+	}
+
+	// Only the workflow's moves are allowed
+	_, err := s.TransitionTodo(ctx, "alice", todo.ID, &dto.TransitionTodoRequest{Status: "review"})
+	wantError(t, err, "invalid transition")
+	_, err = s.TransitionTodo(ctx, "alice", todo.ID, &dto.TransitionTodoRequest{Status: "archived"})
+	wantError(t, err, "validation failed")
+	_, err = s.TransitionTodo(ctx, "bob", todo.ID, &dto.TransitionTodoRequest{Status: "in_progress"})
+	wantError(t, err, "todo not found")
+
+	for _, status := range []string{"in_progress", "review", "done"} {
+		if todo, err = s.TransitionTodo(ctx, "alice", todo.ID, &dto.TransitionTodoRequest{Status: status}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A done status completes the todo
+	if todo.Status != "done" || !todo.Completed || todo.CompletedAt == nil {
+		t.Fatalf("done todo = %+v", todo)
+	}
+	completed := true
+	if _, total, _ := s.GetAllTodos(ctx, "alice", &completed, nil, nil, "", 10, 0); total != 1 {
+		t.Errorf("completed filter found %d todos", total)
+	}
+
+	// The completed flag still works from any status, and reopens into the
+	// first status
+	toggled, _ := s.ToggleTodoComplete(ctx, "alice", todo.ID)
+	if toggled.Status != "backlog" || toggled.Completed {
+		t.Errorf("reopened todo = %+v", toggled)
+	}
+	toggled, _ = s.ToggleTodoComplete(ctx, "alice", todo.ID)
+	if toggled.Status != "done" || !toggled.Completed {
+		t.Errorf("completed todo = %+v", toggled)
+	}
+	open := false
+	updated, _ := s.UpdateTodo(ctx, "alice", todo.ID, &dto.UpdateTodoRequest{Completed: &open})
+	if updated.Status != "backlog" || updated.CompletedAt != nil {
+		t.Errorf("reopened todo = %+v", updated)
+	}
+	// Updates that leave the flag alone keep the status
+	if updated, err = s.TransitionTodo(ctx, "alice", todo.ID, &dto.TransitionTodoRequest{Status: "in_progress"}); err != nil {
+		t.Fatal(err)
+	}
+	title := "Ship release 2"
+	if updated, _ = s.UpdateTodo(ctx, "alice", todo.ID, &dto.UpdateTodoRequest{Title: &title, Completed: &open}); updated.Status != "in_progress" {
+		t.Errorf("update moved the todo to %s", updated.Status)
+	}
+
+	history, err := s.GetStatusHistory(ctx, "alice", todo.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var moves []string
+	for _, change := range history.Changes {
+		moves = append(moves, change.From+">"+change.To)
+	}
+	want := []string{">backlog", "backlog>in_progress", "in_progress>review", "review>done", "done>backlog", "backlog>done", "done>backlog", "backlog>in_progress"}
+	if !reflect.DeepEqual(moves, want) {
+		t.Errorf("changes = %v, want %v", moves, want)
+	}
+	var order []string
+	entries := map[string]int{}
+	for _, spent := range history.TimeInStatus {
+		order = append(order, spent.Status)
+		entries[spent.Status] = spent.Entries
+		if spent.Seconds < 0 {
+			t.Errorf("negative time in %s", spent.Status)
+		}
+	}
+	if !reflect.DeepEqual(order, []string{"backlog", "in_progress", "review", "done"}) || entries["backlog"] != 3 || entries["done"] != 2 {
+		t.Errorf("time in status = %+v", history.TimeInStatus)
+	}
+	if history.Status != "in_progress" {
+		t.Errorf("history status = %s", history.Status)
+	}
+}
+
+func TestCustomWorkflow(t *testing.T) {
+	db := newTestDB(t)
+	wf, err := workflow.New([]string{"todo", "doing", "shipped", "dropped"}, []string{"shipped", "dropped"}, []string{"todo->doing", "doing->shipped", "*->dropped"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewTodoService(repository.NewTodoRepository(db), repository.NewAccessRepository(db), nil, nil, wf)
+
+	described := s.GetWorkflow(ctx)
+	if len(described.Statuses) != 4 || !described.Statuses[0].Initial || !reflect.DeepEqual(described.Statuses[0].Next, []string{"doing", "dropped"}) {
+		t.Errorf("workflow = %+v", described)
+	}
+
+	todo, _ := s.CreateTodo(ctx, "", &dto.CreateTodoRequest{Title: "Prototype"})
+	// Any done status completes the todo; completing through the flag uses
+	// the first
+	dropped, err := s.TransitionTodo(ctx, "", todo.ID, &dto.TransitionTodoRequest{Status: "dropped"})
+	if err != nil || !dropped.Completed {
+		t.Fatalf("dropped todo = %+v, %v", dropped, err)
+	}
+	reopened, _ := s.ToggleTodoComplete(ctx, "", todo.ID)
+	completed, _ := s.ToggleTodoComplete(ctx, "", todo.ID)
+	if reopened.Status != "todo" || completed.Status != "shipped" {
+		t.Errorf("toggled to %s and %s", reopened.Status, completed.Status)
+	}
+}
+
+func TestMoveTodo(t *testing.T) {
+	db := newTestDB(t)
+	repo := repository.NewTodoRepository(db)
+	s := NewTodoService(repo, repository.NewAccessRepository(db), nil, nil, nil)
+
+	ids := map[string]uint{}
+	for _, title := range []string{"a", "b", "c"} {
+		todo, err := s.CreateTodo(ctx, "alice", &dto.CreateTodoRequest{Title: title})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[title] = todo.ID
+	}
+	order := func() string {
+		t.Helper()
+		todos, _, err := s.GetAllTodos(ctx, "alice", nil, nil, nil, models.SortPosition, 10, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var titles string
+		for _, todo := range todos {
+			titles += todo.Title
+		}
+		return titles
+	}
+	move := func(title string, after, before string) {
+		t.Helper()
+		req := &dto.MoveTodoRequest{}
+		if after != "" {
+			id := ids[after]
+			req.After = &id
+		}
+		if before != "" {
+			id := ids[before]
+			req.Before = &id
+		}
+		if _, err := s.MoveTodo(ctx, "alice", ids[title], req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// New todos go first
+	if got := order(); got != "cba" {
+		t.Fatalf("order = %s", got)
+	}
+	move("a", "", "c")
+	move("c", "b", "")
+	if got := order(); got != "abc" {
+		t.Errorf("order = %s, want abc", got)
+	}
+	move("b", "c", "")
+	move("b", "a", "c")
+	if got := order(); got != "abc" {
+		t.Errorf("order = %s, want abc", got)
+	}
+
+	a, c := ids["a"], ids["c"]
+	for _, req := range []dto.MoveTodoRequest{{}, {After: &a}, {After: &c, Before: &a}, {After: &c, Before: &c}} {
+		_, err := s.MoveTodo(ctx, "alice", a, &req)
+		wantError(t, err, "validation failed")
+	}
+	missing := uint(99)
+	_, err := s.MoveTodo(ctx, "alice", a, &dto.MoveTodoRequest{After: &missing})
+	wantError(t, err, "validation failed")
+	_, err = s.MoveTodo(ctx, "bob", a, &dto.MoveTodoRequest{After: &c})
+	wantError(t, err, "todo not found")
+
+	// Neighbours sharing a position, and positions grown too long, are
+	// spread out again
+	db.WithContext(ctx).Model(&models.Todo{}).Where("id IN ?", []uint{a, ids["b"]}).Update("position", "m")
+	if got := order(); got != "cba" {
+		t.Fatalf("order = %s, want ties newest first", got)
+	}
+	move("c", "b", "a")
+	if got := order(); got != "bca" {
+		t.Errorf("order = %s, want bca", got)
+	}
+	db.WithContext(ctx).Model(&models.Todo{}).Where("id = ?", c).Update("position", "zzzzzzzzzzzzzzzzz")
+	move("a", "c", "")
+	todos, _, _ := s.GetAllTodos(ctx, "alice", nil, nil, nil, models.SortPosition, 10, 0)
+	for _, todo := range todos {
+		if len(todo.Position) > 2 {
+			t.Errorf("position %q was not spread out", todo.Position)
+		}
+	}
+	if got := order(); got != "bca" {
+		t.Errorf("order = %s, want bca", got)
+	}
+
+	// Todos from before positions existed are ordered newest first
+	db.WithContext(ctx).Model(&models.Todo{}).Where("id <> ?", c).Update("position", "")
+	if n, err := repo.BackfillPositions(ctx); err != nil || n != 2 {
+		t.Fatalf("backfilled %d positions, %v", n, err)
+	}
+	if got := order(); got != "cba" {
+		t.Errorf("order = %s, want cba", got)
+	}
+
+	// Neighbours that still share a position after spreading out were
+	// moved concurrently
+	racing := NewTodoService(racingMoves{repo}, repository.NewAccessRepository(db), nil, nil, nil)
+	_, err = racing.MoveTodo(ctx, "alice", a, &dto.MoveTodoRequest{After: &c})
+	wantError(t, err, "conflict")
+}
+
+// racingMoves loses every move to a concurrent one.
+type racingMoves struct {
+	repository.TodoRepository
+}
+
+func (racingMoves) Move(context.Context, uint, *uint, *uint) (*models.Todo, error) {
+	return nil, rank.ErrOrder
+}
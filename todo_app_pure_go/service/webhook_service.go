@@ -0,0 +1,22 @@
+package service
+
+import (
+	"context"
+	"todo-app/dto"
+)
+
+type WebhookService interface {
+	CreateWebhook(ctx context.Context, owner string, req *dto.CreateWebhookRequest) (*dto.WebhookResponse, error)
+	GetWebhooks(ctx context.Context, owner string) ([]*dto.WebhookResponse, error)
+	GetWebhookByID(ctx context.Context, owner string, id uint) (*dto.WebhookResponse, error)
+	UpdateWebhook(ctx context.Context, owner string, id uint, req *dto.UpdateWebhookRequest) (*dto.WebhookResponse, error)
+	DeleteWebhook(ctx context.Context, owner string, id uint) error
+	GetDeliveries(ctx context.Context, owner string, webhookID uint, limit, offset int) ([]*dto.WebhookDeliveryResponse, int64, error)
+	// Redeliver sends the payload of an earlier delivery again as a new delivery.
+	Redeliver(ctx context.Context, owner string, webhookID, deliveryID uint) (*dto.WebhookDeliveryResponse, error)
+}
+
+// DeliveryQueue schedules a pending delivery for sending.
+type DeliveryQueue interface {
+	Enqueue(deliveryID uint)
+}
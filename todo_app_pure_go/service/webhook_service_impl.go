@@ -0,0 +1,287 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+	"todo-app/dto"
+	"todo-app/models"
+	"todo-app/netguard"
+	"todo-app/repository"
+	"todo-app/utils"
+)
+
+type WebhookOptions struct {
+	// AllowPrivateTargets lets webhooks target loopback, private and
+	// link-local addresses, which are refused by default so webhooks cannot
+	// reach into the server's own network.
+	AllowPrivateTargets bool
+}
+
+type WebhookServiceImpl struct {
+	webhookRepo repository.WebhookRepository
+	queue       DeliveryQueue
+	opts        WebhookOptions
+}
+
+func NewWebhookService(webhookRepo repository.WebhookRepository, queue DeliveryQueue, opts WebhookOptions) WebhookService {
+	return &WebhookServiceImpl{
+		webhookRepo: webhookRepo,
+		queue:       queue,
+		opts:        opts,
+	}
+}
+
+func (s *WebhookServiceImpl) CreateWebhook(ctx context.Context, owner string, req *dto.CreateWebhookRequest) (*dto.WebhookResponse, error) {
+	// Validate request
+	if validationErrors := utils.ValidateStruct(req); len(validationErrors) > 0 {
+		return nil, errors.New("validation failed: " + validationErrors[0])
+	}
+	if err := s.validateTargetURL(ctx, req.URL); err != nil {
+		return nil, err
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		var err error
+		if secret, err = newWebhookSecret(); err != nil {
+			return nil, err
+		}
+	}
+
+	active := true
+	if req.Active != nil {
+		active = *req.Active
+	}
+
+	webhook, err := s.webhookRepo.Create(ctx, &models.Webhook{
+		Owner:  owner,
+		URL:    req.URL,
+		Secret: secret,
+		Events: strings.Join(uniqueStrings(req.Events), ","),
+		Active: active,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := s.webhookToResponse(webhook)
+	response.Secret = secret
+	return response, nil
+}
+
+func (s *WebhookServiceImpl) GetWebhooks(ctx context.Context, owner string) ([]*dto.WebhookResponse, error) {
+	webhooks, err := s.webhookRepo.GetAllByOwner(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*dto.WebhookResponse, len(webhooks))
+	for i, webhook := range webhooks {
+		responses[i] = s.webhookToResponse(webhook)
+	}
+	return responses, nil
+}
+
+func (s *WebhookServiceImpl) GetWebhookByID(ctx context.Context, owner string, id uint) (*dto.WebhookResponse, error) {
+	webhook, err := s.getOwned(ctx, owner, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.webhookToResponse(webhook), nil
+}
+
+func (s *WebhookServiceImpl) UpdateWebhook(ctx context.Context, owner string, id uint, req *dto.UpdateWebhookRequest) (*dto.WebhookResponse, error) {
+	// Validate request
+	if validationErrors := utils.ValidateStruct(req); len(validationErrors) > 0 {
+		return nil, errors.New("validation failed: " + validationErrors[0])
+	}
+
+	webhook, err := s.getOwned(ctx, owner, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update fields if provided
+	if req.URL != nil {
+		if err := s.validateTargetURL(ctx, *req.URL); err != nil {
+			return nil, err
+		}
+		webhook.URL = *req.URL
+	}
+	if req.Secret != nil {
+		webhook.Secret = *req.Secret
+	}
+	if req.Events != nil {
+		webhook.Events = strings.Join(uniqueStrings(req.Events), ",")
+	}
+	if req.Active != nil {
+		webhook.Active = *req.Active
+	}
+
+	updated, err := s.webhookRepo.Update(ctx, webhook)
+	if err != nil {
+		return nil, err
+	}
+
+	response := s.webhookToResponse(updated)
+	if req.Secret != nil {
+		response.Secret = *req.Secret
+	}
+	return response, nil
+}
+
+func (s *WebhookServiceImpl) DeleteWebhook(ctx context.Context, owner string, id uint) error {
+	if _, err := s.getOwned(ctx, owner, id); err != nil {
+		return err
+	}
+	return s.webhookRepo.Delete(ctx, id)
+}
+
+func (s *WebhookServiceImpl) GetDeliveries(ctx context.Context, owner string, webhookID uint, limit, offset int) ([]*dto.WebhookDeliveryResponse, int64, error) {
+	// Validate pagination parameters
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	if _, err := s.getOwned(ctx, owner, webhookID); err != nil {
+		return nil, 0, err
+	}
+
+	deliveries, total, err := s.webhookRepo.GetDeliveries(ctx, webhookID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	responses := make([]*dto.WebhookDeliveryResponse, len(deliveries))
+	for i, delivery := range deliveries {
+		responses[i] = s.deliveryToResponse(delivery)
+	}
+	return responses, total, nil
+}
+
+func (s *WebhookServiceImpl) Redeliver(ctx context.Context, owner string, webhookID, deliveryID uint) (*dto.WebhookDeliveryResponse, error) {
+	webhook, err := s.getOwned(ctx, owner, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	original, err := s.webhookRepo.GetDelivery(ctx, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if original.WebhookID != webhook.ID {
+		return nil, errors.New("delivery not found")
+	}
+
+	now := time.Now()
+	delivery, err := s.webhookRepo.CreateDelivery(ctx, &models.WebhookDelivery{
+		WebhookID:     webhook.ID,
+		EventID:       original.EventID,
+		EventType:     original.EventType,
+		Payload:       original.Payload,
+		Status:        models.DeliveryPending,
+		RedeliveryOf:  &original.ID,
+		NextAttemptAt: &now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.queue.Enqueue(delivery.ID)
+	return s.deliveryToResponse(delivery), nil
+}
+
+// getOwned loads a webhook and hides webhooks of other owners as not found.
+func (s *WebhookServiceImpl) getOwned(ctx context.Context, owner string, id uint) (*models.Webhook, error) {
+	webhook, err := s.webhookRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if webhook.Owner != owner {
+		return nil, errors.New("webhook not found")
+	}
+	return webhook, nil
+}
+
+// Helper method to convert Webhook model to WebhookResponse DTO
+func (s *WebhookServiceImpl) webhookToResponse(webhook *models.Webhook) *dto.WebhookResponse {
+	return &dto.WebhookResponse{
+		ID:        webhook.ID,
+		URL:       webhook.URL,
+		Events:    webhook.EventList(),
+		Active:    webhook.Active,
+		CreatedAt: webhook.CreatedAt,
+		UpdatedAt: webhook.UpdatedAt,
+	}
+}
+
+// Helper method to convert WebhookDelivery model to WebhookDeliveryResponse DTO
+func (s *WebhookServiceImpl) deliveryToResponse(delivery *models.WebhookDelivery) *dto.WebhookDeliveryResponse {
+	return &dto.WebhookDeliveryResponse{
+		ID:            delivery.ID,
+		WebhookID:     delivery.WebhookID,
+		EventID:       delivery.EventID,
+		EventType:     delivery.EventType,
+		Status:        delivery.Status,
+		Attempts:      delivery.Attempts,
+		ResponseCode:  delivery.ResponseCode,
+		ResponseBody:  delivery.ResponseBody,
+		Error:         delivery.Error,
+		DurationMs:    delivery.DurationMs,
+		RedeliveryOf:  delivery.RedeliveryOf,
+		NextAttemptAt: delivery.NextAttemptAt,
+		DeliveredAt:   delivery.DeliveredAt,
+		CreatedAt:     delivery.CreatedAt,
+	}
+}
+
+// validateTargetURL checks that raw is a URL webhooks may be delivered to.
+// The dispatcher checks the address again when it connects, as the host
+// may resolve differently by then.
+func (s *WebhookServiceImpl) validateTargetURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("validation failed: url must be an absolute http or https URL")
+	}
+	if s.opts.AllowPrivateTargets {
+		return nil
+	}
+	if err := netguard.CheckHost(ctx, u.Hostname()); err != nil {
+		if errors.Is(err, netguard.ErrNotPublic) {
+			return errors.New("validation failed: url must not point to a loopback, private or link-local address")
+		}
+		return errors.New("validation failed: url host cannot be resolved")
+	}
+	return nil
+}
+
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var out []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"todo-app/models"
+	"todo-app/repository"
+	"todo-app/tenant"
+)
+
+// workspacePolicy applies the settings of the workspace of ctx to new todos.
+// Without a workspace repository, or outside a workspace, the application
+// defaults apply.
+type workspacePolicy struct {
+	workspaceRepo repository.WorkspaceRepository
+	todoRepo      repository.TodoRepository
+}
+
+func (p workspacePolicy) settings(ctx context.Context) (models.WorkspaceSettings, error) {
+	id, ok := tenant.FromContext(ctx)
+	if p.workspaceRepo == nil || !ok {
+		return models.WorkspaceSettings{}, nil
+	}
+	workspace, err := p.workspaceRepo.GetByID(ctx, id)
+	if err != nil {
+		return models.WorkspaceSettings{}, err
+	}
+	return workspace.Settings, nil
+}
+
+// admit checks that n more todos fit in the workspace and returns the
+// priority of those created without one.
+func (p workspacePolicy) admit(ctx context.Context, n int) (models.Priority, error) {
+	settings, err := p.settings(ctx)
+	if err != nil {
+		return "", err
+	}
+	if settings.MaxTodos > 0 {
+		count, err := p.todoRepo.Count(ctx)
+		if err != nil {
+			return "", err
+		}
+		if count+int64(n) > int64(settings.MaxTodos) {
+			return "", fmt.Errorf("forbidden: workspace limit of %d todos reached", settings.MaxTodos)
+		}
+	}
+	if settings.DefaultPriority != "" {
+		return settings.DefaultPriority, nil
+	}
+	return models.MEDIUM, nil
+}
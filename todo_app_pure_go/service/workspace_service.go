@@ -0,0 +1,15 @@
+package service
+
+import (
+	"context"
+	"todo-app/dto"
+)
+
+// WorkspaceService manages the workspace of ctx. Everyone who may act in a
+// workspace may read and change its settings.
+type WorkspaceService interface {
+	// Resolve returns the ID of the workspace with slug.
+	Resolve(ctx context.Context, slug string) (uint, error)
+	GetWorkspace(ctx context.Context) (*dto.WorkspaceResponse, error)
+	UpdateSettings(ctx context.Context, req *dto.UpdateWorkspaceSettingsRequest) (*dto.WorkspaceResponse, error)
+}
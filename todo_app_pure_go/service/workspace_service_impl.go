@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"todo-app/dto"
+	"todo-app/models"
+	"todo-app/repository"
+	"todo-app/tenant"
+	"todo-app/utils"
+)
+
+type WorkspaceServiceImpl struct {
+	workspaceRepo repository.WorkspaceRepository
+}
+
+func NewWorkspaceService(workspaceRepo repository.WorkspaceRepository) WorkspaceService {
+	return &WorkspaceServiceImpl{
+		workspaceRepo: workspaceRepo,
+	}
+}
+
+func (s *WorkspaceServiceImpl) Resolve(ctx context.Context, slug string) (uint, error) {
+	workspace, err := s.workspaceRepo.GetBySlug(ctx, slug)
+	if err != nil {
+		return 0, err
+	}
+	return workspace.ID, nil
+}
+
+func (s *WorkspaceServiceImpl) GetWorkspace(ctx context.Context) (*dto.WorkspaceResponse, error) {
+	id, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, errors.New("workspace not found")
+	}
+	workspace, err := s.workspaceRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return workspaceToResponse(workspace), nil
+}
+
+func (s *WorkspaceServiceImpl) UpdateSettings(ctx context.Context, req *dto.UpdateWorkspaceSettingsRequest) (*dto.WorkspaceResponse, error) {
+	if validationErrors := utils.ValidateStruct(req); len(validationErrors) > 0 {
+		return nil, errors.New("validation failed: " + validationErrors[0])
+	}
+	id, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, errors.New("workspace not found")
+	}
+
+	workspace, err := s.workspaceRepo.UpdateSettings(ctx, id, models.WorkspaceSettings{
+		DefaultPriority: req.DefaultPriority,
+		MaxTodos:        req.MaxTodos,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return workspaceToResponse(workspace), nil
+}
+
+func workspaceToResponse(workspace *models.Workspace) *dto.WorkspaceResponse {
+	return &dto.WorkspaceResponse{
+		ID:        workspace.ID,
+		Slug:      workspace.Slug,
+		Name:      workspace.Name,
+		Settings:  workspace.Settings,
+		CreatedAt: workspace.CreatedAt,
+		UpdatedAt: workspace.UpdatedAt,
+	}
+}
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"todo-app/dto"
+	"todo-app/models"
+	"todo-app/repository"
+	"todo-app/tenant"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// ctx is the workspace of the tests that only use one.
+var ctx = tenant.WithWorkspace(context.Background(), 1)
+
+// newTestDB opens an in-memory database with every model the services use,
+// that keeps workspaces apart like the application's.
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(tenant.Plugin{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.Workspace{}, &models.Todo{}, &models.TodoChange{}, &models.TodoStatusChange{}, &models.TodoDependency{}, &models.Attachment{}, &models.Comment{}, &models.CommentRevision{}, &models.TodoGrant{}, &models.ListGrant{}, &models.APIKey{}, &models.User{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+type workspaceTestEnv struct {
+	a, b       context.Context
+	todos      TodoService
+	comments   CommentService
+	access     AccessService
+	sync       SyncService
+	stats      StatsService
+	workspaces WorkspaceService
+	events     *recordingPublisher
+}
+
+func newWorkspaceTestEnv(t *testing.T) *workspaceTestEnv {
+	t.Helper()
+	db := newTestDB(t)
+	workspaceRepo := repository.NewWorkspaceRepository(db)
+	a, err := workspaceRepo.Ensure(tenant.AllWorkspaces(context.Background()), "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := workspaceRepo.Ensure(tenant.AllWorkspaces(context.Background()), "globex")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Go through the cache so entries shared between workspaces would show up
+	cache := repository.NewCachedTodoRepository(repository.NewTodoRepository(db), repository.CacheOptions{Size: 100, TTL: time.Minute})
+	accessRepo := cache.WrapAccess(repository.NewAccessRepository(db))
+	publisher := &recordingPublisher{}
+	return &workspaceTestEnv{
+		a:          tenant.WithWorkspace(context.Background(), a.ID),
+		b:          tenant.WithWorkspace(context.Background(), b.ID),
+		todos:      NewTodoService(cache, accessRepo, workspaceRepo, publisher, nil),
+		comments:   NewCommentService(cache.WrapComments(repository.NewCommentRepository(db)), cache, accessRepo),
+		access:     NewAccessService(accessRepo, cache),
+		sync:       NewSyncService(cache, cache.WrapSync(repository.NewSyncRepository(db)), accessRepo, workspaceRepo, publisher, nil, 24*time.Hour, 10),
+		stats:      NewStatsService(repository.NewStatsRepository(db)),
+		workspaces: NewWorkspaceService(workspaceRepo),
+		events:     publisher,
+	}
+}
+
+func TestWorkspacesCannotReadEachOther(t *testing.T) {
+	env := newWorkspaceTestEnv(t)
+	todo, err := env.todos.CreateTodo(env.a, "", &dto.CreateTodoRequest{Title: "Acme plans"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.comments.CreateComment(env.a, "ada", todo.ID, &dto.CreateCommentRequest{Body: "Secret"}); err != nil {
+		t.Fatal(err)
+	}
+	// Fill the cache from a's side first
+	if _, err := env.todos.GetTodoByID(env.a, "", todo.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := env.todos.GetAllTodos(env.a, "", nil, nil, nil, "", 10, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = env.todos.GetTodoByID(env.b, "", todo.ID)
+	wantError(t, err, "todo not found")
+	todos, total, err := env.todos.GetAllTodos(env.b, "", nil, nil, nil, "", 10, 0)
+	if err != nil || total != 0 || len(todos) != 0 {
+		t.Errorf("b lists %+v (total %d), %v", todos, total, err)
+	}
+	_, err = env.comments.GetComments(env.b, "", todo.ID)
+	wantError(t, err, "todo not found")
+	_, err = env.access.GetTodoAccess(env.b, "", todo.ID)
+	wantError(t, err, "todo not found")
+
+	resp, err := env.sync.Sync(env.b, "", &dto.SyncRequest{})
+	if err != nil || len(resp.Changes) != 0 {
+		t.Errorf("b pulled %+v, %v", resp, err)
+	}
+	stats, err := env.stats.GetStats(env.b, "", time.Time{}, time.Time{}, "day", 5)
+	if err != nil || stats.Total != 0 || len(stats.OldestOpen) != 0 {
+		t.Errorf("b stats = %+v, %v", stats, err)
+	}
+
+	// Events carry their workspace so streams and webhooks of b miss them
+	last := env.events.events[len(env.events.events)-1]
+	if !last.VisibleTo(1, "") || last.VisibleTo(2, "") {
+		t.Errorf("event of workspace %d reaches the wrong workspace", last.Workspace)
+	}
+}
+
+func TestWorkspacesCannotWriteEachOther(t *testing.T) {
+	env := newWorkspaceTestEnv(t)
+	todo, _ := env.todos.CreateTodo(env.a, "", &dto.CreateTodoRequest{Title: "Acme plans"})
+
+	_, err := env.todos.UpdateTodo(env.b, "", todo.ID, &dto.UpdateTodoRequest{Title: strPtr("Globex plans")})
+	wantError(t, err, "todo not found")
+	_, err = env.todos.ToggleTodoComplete(env.b, "", todo.ID)
+	wantError(t, err, "todo not found")
+	wantError(t, env.todos.DeleteTodo(env.b, "", todo.ID), "todo not found")
+	_, err = env.comments.CreateComment(env.b, "", todo.ID, &dto.CreateCommentRequest{Body: "Hi"})
+	wantError(t, err, "todo not found")
+
+	resp, err := env.sync.Sync(env.b, "", &dto.SyncRequest{Mutations: []dto.SyncMutation{
+		{Op: dto.SyncUpdate, ID: todo.ID, Fields: dto.UpdateTodoRequest{Title: strPtr("Globex plans")}, UpdatedAt: time.Now()},
+		{Op: dto.SyncDelete, ID: todo.ID, UpdatedAt: time.Now()},
+		// Client IDs only need to be unique within a workspace
+		{Op: dto.SyncCreate, ClientID: "c1", Fields: dto.UpdateTodoRequest{Title: strPtr("Offline")}, UpdatedAt: time.Now()},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Rejected) != 2 || resp.Rejected[0].Reason != dto.SyncRejectNotFound || resp.Rejected[1].Reason != dto.SyncRejectNotFound {
+		t.Errorf("rejected = %+v", resp.Rejected)
+	}
+	resp, err = env.sync.Sync(env.a, "", &dto.SyncRequest{Mutations: []dto.SyncMutation{
+		{Op: dto.SyncCreate, ClientID: "c1", Fields: dto.UpdateTodoRequest{Title: strPtr("Offline")}, UpdatedAt: time.Now()},
+	}})
+	if err != nil || len(resp.Rejected) != 0 || len(resp.Changes) != 2 {
+		t.Errorf("a sync = %+v, %v", resp, err)
+	}
+
+	got, err := env.todos.GetTodoByID(env.a, "", todo.ID)
+	if err != nil || got.Title != "Acme plans" || got.Completed || got.CommentCount != 0 {
+		t.Errorf("a's todo changed: %+v, %v", got, err)
+	}
+}
+
+func TestQueriesWithoutWorkspaceFail(t *testing.T) {
+	env := newWorkspaceTestEnv(t)
+	env.todos.CreateTodo(env.a, "", &dto.CreateTodoRequest{Title: "Acme plans"})
+
+	_, _, err := env.todos.GetAllTodos(context.Background(), "", nil, nil, nil, "", 10, 0)
+	if !errors.Is(err, tenant.ErrNoWorkspace) {
+		t.Errorf("GetAllTodos without workspace: %v", err)
+	}
+	_, err = env.todos.CreateTodo(context.Background(), "", &dto.CreateTodoRequest{Title: "Nowhere"})
+	if !errors.Is(err, tenant.ErrNoWorkspace) {
+		t.Errorf("CreateTodo without workspace: %v", err)
+	}
+}
+
+func TestWorkspaceSettings(t *testing.T) {
+	env := newWorkspaceTestEnv(t)
+
+	_, err := env.workspaces.UpdateSettings(env.a, &dto.UpdateWorkspaceSettingsRequest{DefaultPriority: "URGENT"})
+	wantError(t, err, "validation failed")
+	workspace, err := env.workspaces.UpdateSettings(env.a, &dto.UpdateWorkspaceSettingsRequest{DefaultPriority: models.HIGH, MaxTodos: 2})
+	if err != nil || workspace.Slug != "acme" || workspace.Settings.MaxTodos != 2 {
+		t.Fatalf("UpdateSettings = %+v, %v", workspace, err)
+	}
+
+	first, err := env.todos.CreateTodo(env.a, "", &dto.CreateTodoRequest{Title: "First"})
+	if err != nil || first.Priority != models.HIGH {
+		t.Fatalf("default priority: %+v, %v", first, err)
+	}
+	if _, err := env.todos.CreateTodo(env.a, "", &dto.CreateTodoRequest{Title: "Second", Priority: models.LOW}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = env.todos.CreateTodo(env.a, "", &dto.CreateTodoRequest{Title: "Third"})
+	wantError(t, err, "forbidden")
+	resp, err := env.sync.Sync(env.a, "", &dto.SyncRequest{Mutations: []dto.SyncMutation{
+		{Op: dto.SyncCreate, ClientID: "c1", Fields: dto.UpdateTodoRequest{Title: strPtr("Offline")}, UpdatedAt: time.Now()},
+	}})
+	if err != nil || len(resp.Rejected) != 1 || resp.Rejected[0].Reason != dto.SyncRejectForbidden {
+		t.Errorf("sync over the limit = %+v, %v", resp, err)
+	}
+	_, err = env.todos.ImportTodos(env.a, "", []dto.ImportRow{{Line: 2, Todo: dto.CreateTodoRequest{Title: "Imported"}}}, dto.ImportOptions{Mode: dto.ImportBestEffort})
+	wantError(t, err, "forbidden")
+
+	// The other workspace keeps the defaults
+	other, err := env.todos.CreateTodo(env.b, "", &dto.CreateTodoRequest{Title: "First"})
+	if err != nil || other.Priority != models.MEDIUM {
+		t.Errorf("b create = %+v, %v", other, err)
+	}
+	workspace, err = env.workspaces.GetWorkspace(env.b)
+	if err != nil || workspace.Slug != "globex" || workspace.Settings != (models.WorkspaceSettings{}) {
+		t.Errorf("GetWorkspace = %+v, %v", workspace, err)
+	}
+}
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStore keeps blobs as files below a root directory, fanned out by the
+// first two bytes of the digest: root/ab/cd/abcd....
+type LocalStore struct {
+	root string
+}
+
+// NewLocalStore creates the root directory if needed.
+func NewLocalStore(root string) (*LocalStore, error) {
+	if err := os.MkdirAll(filepath.Join(root, "tmp"), 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create blob directory: %w", err)
+	}
+	return &LocalStore{root: root}, nil
+}
+
+// Put writes the content to a temporary file while hashing it and renames
+// the file into place once the digest is known and verified.
+func (s *LocalStore) Put(r io.Reader, opts PutOptions) (Blob, error) {
+	tmp, err := os.CreateTemp(filepath.Join(s.root, "tmp"), "upload-*")
+	if err != nil {
+		return Blob{}, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if opts.MaxSize > 0 {
+		// Read one byte past the limit to tell "exactly at" from "over"
+		r = io.LimitReader(r, opts.MaxSize+1)
+	}
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), r)
+	if err != nil {
+		return Blob{}, err
+	}
+	if opts.MaxSize > 0 && size > opts.MaxSize {
+		return Blob{}, ErrTooLarge
+	}
+
+	blob := Blob{SHA256: hex.EncodeToString(hash.Sum(nil)), Size: size}
+	if opts.SHA256 != "" && !strings.EqualFold(opts.SHA256, blob.SHA256) {
+		return Blob{}, ErrChecksumMismatch
+	}
+
+	if err := tmp.Sync(); err != nil {
+		return Blob{}, err
+	}
+	if err := tmp.Close(); err != nil {
+		return Blob{}, err
+	}
+	path := s.path(blob.SHA256)
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return Blob{}, err
+	}
+	// Identical content has the same name, so replacing an existing blob
+	// is harmless
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return Blob{}, err
+	}
+	return blob, nil
+}
+
+func (s *LocalStore) Open(digest string) (io.ReadSeekCloser, error) {
+	if !ValidDigest(digest) {
+		return nil, ErrNotFound
+	}
+	f, err := os.Open(s.path(digest))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+func (s *LocalStore) Delete(digest string) error {
+	if !ValidDigest(digest) {
+		return nil
+	}
+	err := os.Remove(s.path(digest))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *LocalStore) Walk(fn func(digest string) error) error {
+	return filepath.WalkDir(s.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == "tmp" && filepath.Dir(path) == filepath.Clean(s.root) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !ValidDigest(d.Name()) {
+			return nil
+		}
+		return fn(d.Name())
+	})
+}
+
+func (s *LocalStore) path(digest string) string {
+	return filepath.Join(s.root, digest[:2], digest[2:4], digest)
+}
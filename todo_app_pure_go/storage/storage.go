@@ -0,0 +1,51 @@
+// Package storage keeps attachment content in content-addressed blob stores.
+// Blobs are named by the hex SHA-256 digest of their content.
+package storage
+
+import (
+	"errors"
+	"io"
+	"regexp"
+)
+
+var (
+	ErrNotFound         = errors.New("blob not found")
+	ErrTooLarge         = errors.New("blob exceeds the maximum size")
+	ErrChecksumMismatch = errors.New("blob checksum mismatch")
+)
+
+type PutOptions struct {
+	// MaxSize rejects content larger than this many bytes with ErrTooLarge.
+	// Zero means no limit.
+	MaxSize int64
+	// SHA256 is the digest the caller expects, if known. Content with a
+	// different digest is rejected with ErrChecksumMismatch.
+	SHA256 string
+}
+
+// Blob describes stored content.
+type Blob struct {
+	SHA256 string
+	Size   int64
+}
+
+// BlobStore stores immutable content by digest. Implementations must be safe
+// for concurrent use.
+type BlobStore interface {
+	// Put stores everything read from r. Nothing is stored if Put fails;
+	// storing content that already exists is not an error.
+	Put(r io.Reader, opts PutOptions) (Blob, error)
+	// Open returns the content of a blob for reading and seeking.
+	Open(digest string) (io.ReadSeekCloser, error)
+	// Delete removes a blob. Deleting a missing blob is not an error.
+	Delete(digest string) error
+	// Walk calls fn with the digest of every stored blob.
+	Walk(fn func(digest string) error) error
+}
+
+var digestPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// ValidDigest reports whether digest is a lowercase hex SHA-256 digest.
+func ValidDigest(digest string) bool {
+	return digestPattern.MatchString(digest)
+}
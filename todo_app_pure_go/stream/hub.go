@@ -0,0 +1,162 @@
+// Package stream fans todo events out to long-lived client connections such
+// as Server-Sent Events streams. Every event gets a sequence ID and the most
+// recent events are kept so reconnecting clients can resume where they left
+// off.
+package stream
+
+import (
+	"errors"
+	"sync"
+
+	"todo-app/events"
+)
+
+// clientBuffer is how many undelivered messages a client may lag behind
+// before it is disconnected as a slow consumer.
+const clientBuffer = 256
+
+// ErrClosed is returned by Subscribe once the hub has been closed.
+var ErrClosed = errors.New("stream hub is closed")
+
+type Message struct {
+	ID    uint64
+	Event events.Event
+}
+
+type Hub struct {
+	mu      sync.Mutex
+	nextID  uint64
+	replay  []Message // ring buffer of the latest messages
+	start   int       // index of the oldest message in replay
+	size    int
+	clients map[*Subscription]struct{}
+	closed  bool
+}
+
+// Subscription receives messages on C until it is closed, either by the
+// client, by the hub for falling too far behind, or by hub shutdown.
+type Subscription struct {
+	C <-chan Message
+
+	ch      chan Message
+	hub     *Hub
+	dropped bool
+}
+
+func NewHub(replaySize int) *Hub {
+	if replaySize <= 0 {
+		replaySize = 1
+	}
+	return &Hub{
+		nextID:  1,
+		replay:  make([]Message, replaySize),
+		clients: make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish assigns the event the next ID, stores it for replay and sends it to
+// every subscriber. It never blocks; subscribers that cannot keep up are
+// disconnected and expected to resume with their last ID.
+func (h *Hub) Publish(event events.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return
+	}
+
+	msg := Message{ID: h.nextID, Event: event}
+	h.nextID++
+
+	end := (h.start + h.size) % len(h.replay)
+	h.replay[end] = msg
+	if h.size < len(h.replay) {
+		h.size++
+	} else {
+		h.start = (h.start + 1) % len(h.replay)
+	}
+
+	for sub := range h.clients {
+		select {
+		case sub.ch <- msg:
+		default:
+			sub.dropped = true
+			h.remove(sub)
+		}
+	}
+}
+
+// Subscribe registers a new subscriber. When resume is true the messages
+// after lastID are returned for replay; complete is false if some of them
+// are no longer buffered and the client has to resynchronise.
+func (h *Hub) Subscribe(lastID uint64, resume bool) (sub *Subscription, replay []Message, complete bool, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, nil, false, ErrClosed
+	}
+
+	complete = true
+	if resume {
+		oldest := h.nextID - uint64(h.size)
+		if lastID+1 < oldest || lastID >= h.nextID {
+			// Either evicted already or from before a restart
+			complete = false
+		} else {
+			for i := 0; i < h.size; i++ {
+				msg := h.replay[(h.start+i)%len(h.replay)]
+				if msg.ID > lastID {
+					replay = append(replay, msg)
+				}
+			}
+		}
+	}
+
+	ch := make(chan Message, clientBuffer)
+	sub = &Subscription{C: ch, ch: ch, hub: h}
+	h.clients[sub] = struct{}{}
+	return sub, replay, complete, nil
+}
+
+// LastID returns the ID of the most recently published message.
+func (h *Hub) LastID() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.nextID - 1
+}
+
+// Close disconnects every subscriber and rejects new ones. It is safe to
+// call more than once.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for sub := range h.clients {
+		h.remove(sub)
+	}
+}
+
+// remove must be called with h.mu held.
+func (h *Hub) remove(sub *Subscription) {
+	if _, ok := h.clients[sub]; ok {
+		delete(h.clients, sub)
+		close(sub.ch)
+	}
+}
+
+// Close unsubscribes. It is safe to call after the hub closed the subscription.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
+
+// Dropped reports whether the hub closed the subscription because the
+// client fell too far behind.
+func (s *Subscription) Dropped() bool {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	return s.dropped
+}
@@ -0,0 +1,102 @@
+package stream
+
+import (
+	"testing"
+
+	"todo-app/events"
+)
+
+func publishN(h *Hub, n int) {
+	for i := 0; i < n; i++ {
+		h.Publish(events.New(events.TodoCreated, nil))
+	}
+}
+
+func TestSubscribeReplaysAfterLastID(t *testing.T) {
+	hub := NewHub(10)
+	publishN(hub, 5)
+
+	sub, replay, complete, err := hub.Subscribe(3, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	if !complete {
+		t.Fatal("expected complete replay")
+	}
+	if len(replay) != 2 || replay[0].ID != 4 || replay[1].ID != 5 {
+		t.Fatalf("unexpected replay: %+v", replay)
+	}
+
+	hub.Publish(events.New(events.TodoDeleted, nil))
+	if msg := <-sub.C; msg.ID != 6 || msg.Event.Type != events.TodoDeleted {
+		t.Errorf("unexpected live message: %+v", msg)
+	}
+}
+
+func TestSubscribeReportsEvictedEvents(t *testing.T) {
+	hub := NewHub(3)
+	publishN(hub, 10)
+
+	sub, replay, complete, err := hub.Subscribe(2, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	if complete || len(replay) != 0 {
+		t.Errorf("expected incomplete replay, got complete=%v replay=%d", complete, len(replay))
+	}
+
+	// The oldest buffered event is 8, so resuming after 7 still works
+	sub2, replay, complete, _ := hub.Subscribe(7, true)
+	defer sub2.Close()
+	if !complete || len(replay) != 3 {
+		t.Errorf("expected 3 replayed events, got complete=%v replay=%d", complete, len(replay))
+	}
+
+	// IDs from before a restart are ahead of the hub
+	sub3, _, complete, _ := hub.Subscribe(50, true)
+	defer sub3.Close()
+	if complete {
+		t.Error("expected an unknown future ID to be incomplete")
+	}
+}
+
+func TestSlowConsumerIsDropped(t *testing.T) {
+	hub := NewHub(10)
+	sub, _, _, _ := hub.Subscribe(0, false)
+
+	publishN(hub, clientBuffer+1)
+
+	received := 0
+	for range sub.C {
+		received++
+	}
+	if received != clientBuffer {
+		t.Errorf("expected %d buffered messages before the drop, got %d", clientBuffer, received)
+	}
+	if !sub.Dropped() {
+		t.Error("expected subscription to be marked dropped")
+	}
+	sub.Close()
+}
+
+func TestCloseEndsSubscriptions(t *testing.T) {
+	hub := NewHub(10)
+	sub, _, _, _ := hub.Subscribe(0, false)
+
+	hub.Close()
+	if _, ok := <-sub.C; ok {
+		t.Fatal("expected channel to be closed")
+	}
+	if sub.Dropped() {
+		t.Error("shutdown is not a drop")
+	}
+	sub.Close()
+	hub.Close()
+
+	if _, _, _, err := hub.Subscribe(0, false); err != ErrClosed {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+package tenant
+
+import (
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Field is the model field that holds the workspace of a row. Every model
+// with it is workspace-owned.
+const Field = "WorkspaceID"
+
+// Plugin scopes GORM statements on workspace-owned models to the workspace
+// of their context:
+//
+//   - queries, counts, updates and deletes get a workspace condition;
+//   - created rows get the workspace assigned, and upserts never take over
+//     a conflicting row of another workspace;
+//   - updates may not move a row to another workspace.
+//
+// A statement whose context has neither WithWorkspace nor AllWorkspaces
+// fails with ErrNoWorkspace, so a repository that forgets to pass its
+// context fails loudly instead of reading across workspaces. Raw SQL is
+// passed through unchanged and must only touch rows that were already
+// looked up through a scoped statement.
+type Plugin struct{}
+
+func (Plugin) Name() string {
+	return "tenant"
+}
+
+func (Plugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Create().Before("gorm:create").Register("tenant:create", assign); err != nil {
+		return err
+	}
+	if err := callbacks.Query().Before("gorm:query").Register("tenant:query", restrict); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("tenant:update", restrictUpdate); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().Before("gorm:delete").Register("tenant:delete", restrict); err != nil {
+		return err
+	}
+	return callbacks.Row().Before("gorm:row").Register("tenant:row", restrict)
+}
+
+// workspaceField returns the workspace field of the statement's model, or
+// nil when the statement does not need scoping.
+func workspaceField(db *gorm.DB) *schema.Field {
+	stmt := db.Statement
+	if db.Error != nil || stmt.Schema == nil || stmt.SQL.Len() > 0 {
+		return nil
+	}
+	return stmt.Schema.LookUpField(Field)
+}
+
+// current returns the scope of the statement, recording ErrNoWorkspace when
+// its context has none.
+func current(db *gorm.DB) (scope, bool) {
+	s, ok := scopeOf(db.Statement.Context)
+	if !ok || (!s.all && s.workspace == 0) {
+		db.AddError(ErrNoWorkspace)
+		return scope{}, false
+	}
+	return s, true
+}
+
+func condition(field *schema.Field, workspace uint) clause.Expression {
+	return clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: workspace}
+}
+
+func restrict(db *gorm.DB) {
+	field := workspaceField(db)
+	if field == nil {
+		return
+	}
+	s, ok := current(db)
+	if !ok || s.all {
+		return
+	}
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{condition(field, s.workspace)}})
+}
+
+func restrictUpdate(db *gorm.DB) {
+	field := workspaceField(db)
+	if field == nil {
+		return
+	}
+	s, ok := current(db)
+	if !ok || s.all {
+		return
+	}
+	// Updates that write every column, such as Save, keep the workspace
+	if dest := db.Statement.Dest; dest != nil && dest != db.Statement.Model {
+		if m, isMap := dest.(map[string]interface{}); isMap {
+			if _, changes := m[field.DBName]; changes {
+				db.AddError(ErrOtherWorkspace)
+				return
+			}
+		} else {
+			stamp(db, field, reflect.ValueOf(dest), s.workspace)
+		}
+	}
+	stamp(db, field, db.Statement.ReflectValue, s.workspace)
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{condition(field, s.workspace)}})
+}
+
+func assign(db *gorm.DB) {
+	field := workspaceField(db)
+	if field == nil {
+		return
+	}
+	s, ok := current(db)
+	if !ok {
+		return
+	}
+	if s.all {
+		// Rows created across workspaces must already name theirs
+		rows(db.Statement.ReflectValue, func(row reflect.Value) {
+			if _, zero := field.ValueOf(db.Statement.Context, row); zero {
+				db.AddError(ErrNoWorkspace)
+			}
+		})
+		return
+	}
+
+	if m, isMap := db.Statement.Dest.(map[string]interface{}); isMap {
+		m[field.DBName] = s.workspace
+	}
+	stamp(db, field, db.Statement.ReflectValue, s.workspace)
+
+	// An upsert updates the conflicting row only if it is in this workspace
+	if c, ok := db.Statement.Clauses["ON CONFLICT"]; ok {
+		if onConflict, ok := c.Expression.(clause.OnConflict); ok && !onConflict.DoNothing {
+			onConflict.Where.Exprs = append(onConflict.Where.Exprs, condition(field, s.workspace))
+			c.Expression = onConflict
+			db.Statement.Clauses["ON CONFLICT"] = c
+		}
+	}
+}
+
+// stamp sets the workspace of every row in value that has none and records
+// ErrOtherWorkspace for rows of another workspace.
+func stamp(db *gorm.DB, field *schema.Field, value reflect.Value, workspace uint) {
+	rows(value, func(row reflect.Value) {
+		current, zero := field.ValueOf(db.Statement.Context, row)
+		if zero {
+			if err := field.Set(db.Statement.Context, row, workspace); err != nil {
+				db.AddError(err)
+			}
+			return
+		}
+		if id, ok := current.(uint); !ok || id != workspace {
+			db.AddError(ErrOtherWorkspace)
+		}
+	})
+}
+
+// rows calls fn for each addressable struct in value, which may be a
+// struct, a pointer to one or a slice of either.
+func rows(value reflect.Value, fn func(row reflect.Value)) {
+	value = reflect.Indirect(value)
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			rows(value.Index(i), fn)
+		}
+	case reflect.Struct:
+		if value.CanAddr() {
+			fn(value)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+type note struct {
+	ID          uint   `gorm:"primaryKey"`
+	WorkspaceID uint   `gorm:"not null;default:0;uniqueIndex:idx_notes_workspace_key"`
+	Key         string `gorm:"uniqueIndex:idx_notes_workspace_key"`
+	Body        string
+}
+
+// shared is not workspace-owned and is left alone.
+type shared struct {
+	ID   uint `gorm:"primaryKey"`
+	Name string
+}
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(Plugin{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&note{}, &shared{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestStatementsStayInTheirWorkspace(t *testing.T) {
+	db := newTestDB(t)
+	a := db.WithContext(WithWorkspace(context.Background(), 1))
+	b := db.WithContext(WithWorkspace(context.Background(), 2))
+
+	mine := &note{Key: "k", Body: "a's"}
+	if err := a.Create(mine).Error; err != nil {
+		t.Fatal(err)
+	}
+	if mine.WorkspaceID != 1 {
+		t.Fatalf("created in workspace %d", mine.WorkspaceID)
+	}
+	// Unique keys only need to be unique within a workspace
+	if err := b.Create(&note{Key: "k", Body: "b's"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var found note
+	if err := b.First(&found, mine.ID).Error; !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("cross-workspace First: %v %+v", err, found)
+	}
+	var count int64
+	b.Model(&note{}).Where("body = ?", "a's").Count(&count)
+	if count != 0 {
+		t.Errorf("cross-workspace Count = %d", count)
+	}
+	var ids []uint
+	b.Model(&note{}).Where("id IN (?)", b.Model(&note{}).Select("id")).Pluck("id", &ids)
+	if len(ids) != 1 || ids[0] == mine.ID {
+		t.Errorf("subquery saw %v", ids)
+	}
+
+	if n := b.Model(&note{}).Where("id = ?", mine.ID).Update("body", "hijacked").RowsAffected; n != 0 {
+		t.Errorf("cross-workspace Update changed %d rows", n)
+	}
+	if n := b.Delete(&note{}, mine.ID).RowsAffected; n != 0 {
+		t.Errorf("cross-workspace Delete removed %d rows", n)
+	}
+	// Save falls back to an upsert when its update changes nothing
+	if err := b.Save(&note{ID: mine.ID, Key: "k", Body: "hijacked"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Clauses(clause.OnConflict{UpdateAll: true}).Create(&note{ID: mine.ID, Key: "x", Body: "hijacked"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.First(&found, mine.ID).Error; err != nil || found.Body != "a's" || found.WorkspaceID != 1 {
+		t.Errorf("after cross-workspace writes: %+v, %v", found, err)
+	}
+}
+
+func TestRowsCannotMoveBetweenWorkspaces(t *testing.T) {
+	db := newTestDB(t)
+	a := db.WithContext(WithWorkspace(context.Background(), 1))
+	b := db.WithContext(WithWorkspace(context.Background(), 2))
+
+	mine := &note{Key: "k"}
+	a.Create(mine)
+	if err := b.Create(&note{WorkspaceID: 1, Key: "other"}).Error; !errors.Is(err, ErrOtherWorkspace) {
+		t.Errorf("create into another workspace: %v", err)
+	}
+	mine.WorkspaceID = 2
+	if err := a.Save(mine).Error; !errors.Is(err, ErrOtherWorkspace) {
+		t.Errorf("save into another workspace: %v", err)
+	}
+	if err := a.Model(&note{}).Where("id = ?", mine.ID).Updates(map[string]interface{}{"workspace_id": 2}).Error; !errors.Is(err, ErrOtherWorkspace) {
+		t.Errorf("update of workspace_id: %v", err)
+	}
+}
+
+func TestStatementsNeedAWorkspace(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	var notes []note
+	if err := db.WithContext(ctx).Find(&notes).Error; !errors.Is(err, ErrNoWorkspace) {
+		t.Errorf("query without workspace: %v", err)
+	}
+	if err := db.WithContext(ctx).Create(&note{Key: "k"}).Error; !errors.Is(err, ErrNoWorkspace) {
+		t.Errorf("create without workspace: %v", err)
+	}
+	if err := db.WithContext(ctx).Create(&shared{Name: "x"}).Error; err != nil {
+		t.Errorf("tables without workspaces are not scoped: %v", err)
+	}
+
+	all := db.WithContext(AllWorkspaces(ctx))
+	if err := all.Create(&note{Key: "k"}).Error; !errors.Is(err, ErrNoWorkspace) {
+		t.Errorf("create across workspaces without naming one: %v", err)
+	}
+	all.Create(&note{WorkspaceID: 1, Key: "k"})
+	all.Create(&note{WorkspaceID: 2, Key: "k"})
+	if err := all.Find(&notes).Error; err != nil || len(notes) != 2 {
+		t.Errorf("across workspaces: %d notes, %v", len(notes), err)
+	}
+}
@@ -0,0 +1,53 @@
+// Package tenant keeps the data of workspaces that share one database apart.
+// The workspace a request acts in travels in its context, and the GORM
+// plugin limits every statement on a workspace-owned table to it.
+package tenant
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNoWorkspace is returned for statements on workspace-owned tables whose
+// context names no workspace.
+var ErrNoWorkspace = errors.New("tenant: no workspace in context")
+
+// ErrOtherWorkspace is returned when a statement would write a row of
+// another workspace.
+var ErrOtherWorkspace = errors.New("tenant: row belongs to another workspace")
+
+type contextKey struct{}
+
+type scope struct {
+	workspace uint
+	all       bool
+}
+
+// WithWorkspace returns a context whose statements only see and write the
+// rows of the workspace with the given ID.
+func WithWorkspace(ctx context.Context, id uint) context.Context {
+	return context.WithValue(ctx, contextKey{}, scope{workspace: id})
+}
+
+// AllWorkspaces returns a context whose statements are not limited to one
+// workspace. It is meant for startup tasks and background jobs that work
+// across workspaces, such as retrying webhook deliveries; rows they create
+// must name their workspace themselves.
+func AllWorkspaces(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextKey{}, scope{all: true})
+}
+
+// FromContext returns the workspace set by WithWorkspace. ok is false for
+// contexts without one, including those from AllWorkspaces.
+func FromContext(ctx context.Context) (id uint, ok bool) {
+	s, _ := ctx.Value(contextKey{}).(scope)
+	return s.workspace, !s.all && s.workspace != 0
+}
+
+func scopeOf(ctx context.Context) (scope, bool) {
+	if ctx == nil {
+		return scope{}, false
+	}
+	s, ok := ctx.Value(contextKey{}).(scope)
+	return s, ok
+}
@@ -0,0 +1,350 @@
+// Package tui is a full-screen terminal interface for the todo API built on
+// Bubble Tea. It talks to the server only through the client package.
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"todo-app/client"
+	"todo-app/dto"
+	"todo-app/models"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	requestTimeout = 10 * time.Second
+	// pageSize is the server's maximum limit; the TUI shows a single page.
+	pageSize = 100
+)
+
+// API is the subset of *client.Client the TUI uses.
+type API interface {
+	List(ctx context.Context, opts client.ListOptions) (*client.TodoList, error)
+	Create(ctx context.Context, req dto.CreateTodoRequest) (*dto.TodoResponse, error)
+	Update(ctx context.Context, id uint, req dto.UpdateTodoRequest) (*dto.TodoResponse, error)
+	Toggle(ctx context.Context, id uint) (*dto.TodoResponse, error)
+}
+
+type Options struct {
+	// RefreshInterval reloads the list periodically so changes made by
+	// others show up. Zero disables live refresh.
+	RefreshInterval time.Duration
+}
+
+// Run starts the TUI on the alternate screen and blocks until the user quits.
+func Run(api API, opts Options) error {
+	_, err := tea.NewProgram(New(api, opts), tea.WithAltScreen()).Run()
+	return err
+}
+
+type mode int
+
+const (
+	modeList mode = iota
+	modeCreate
+	modeEdit
+)
+
+type completionFilter int
+
+const (
+	showAll completionFilter = iota
+	showOpen
+	showDone
+)
+
+func (f completionFilter) String() string {
+	switch f {
+	case showOpen:
+		return "open"
+	case showDone:
+		return "done"
+	default:
+		return "all"
+	}
+}
+
+// priorityCycle is the order the priority filter steps through; the empty
+// priority means no filter.
+var priorityCycle = []models.Priority{"", models.HIGH, models.MEDIUM, models.LOW}
+
+// formPriorities is the order tab steps through in the create/edit form.
+var formPriorities = []models.Priority{models.LOW, models.MEDIUM, models.HIGH}
+
+type (
+	loadedMsg struct {
+		list *client.TodoList
+		err  error
+	}
+	savedMsg struct {
+		verb string
+		todo *dto.TodoResponse
+		err  error
+	}
+	tickMsg struct{}
+)
+
+type Model struct {
+	api     API
+	refresh time.Duration
+
+	todos  []*dto.TodoResponse
+	total  int64
+	cursor int
+
+	completed completionFilter
+	priority  models.Priority
+
+	mode         mode
+	input        textinput.Model
+	formPriority models.Priority
+	editID       uint
+
+	loading bool
+	status  string
+	err     error
+
+	width, height int
+}
+
+func New(api API, opts Options) Model {
+	input := textinput.New()
+	input.Placeholder = "title"
+	input.CharLimit = 100
+	input.Prompt = ""
+
+	return Model{
+		api:     api,
+		refresh: opts.RefreshInterval,
+		input:   input,
+		loading: true,
+	}
+}
+
+func (m Model) Init() tea.Cmd {
+	return tea.Batch(m.load(), m.tick())
+}
+
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.input.Width = max(msg.Width-30, 10)
+		return m, nil
+
+	case tickMsg:
+		if m.loading {
+			return m, m.tick()
+		}
+		m.loading = true
+		return m, tea.Batch(m.load(), m.tick())
+
+	case loadedMsg:
+		m.loading = false
+		if msg.err != nil {
+			m.err = msg.err
+			return m, nil
+		}
+		m.setTodos(msg.list)
+		return m, nil
+
+	case savedMsg:
+		if msg.err != nil {
+			m.err = msg.err
+			return m, nil
+		}
+		m.status = fmt.Sprintf("%s %q", msg.verb, msg.todo.Title)
+		m.loading = true
+		return m, m.load()
+
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+		if m.mode != modeList {
+			return m.updateForm(msg)
+		}
+		return m.updateList(msg)
+	}
+	return m, nil
+}
+
+func (m Model) updateList(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.err = nil
+	switch msg.String() {
+	case "q":
+		return m, tea.Quit
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < len(m.todos)-1 {
+			m.cursor++
+		}
+	case "home", "g":
+		m.cursor = 0
+	case "end", "G":
+		m.cursor = max(len(m.todos)-1, 0)
+	case " ", "x":
+		if todo := m.selected(); todo != nil {
+			return m, m.toggle(todo.ID)
+		}
+	case "n", "a":
+		m.mode = modeCreate
+		m.formPriority = models.MEDIUM
+		m.input.SetValue("")
+		return m, m.input.Focus()
+	case "e", "enter":
+		if todo := m.selected(); todo != nil {
+			m.mode = modeEdit
+			m.editID = todo.ID
+			m.formPriority = todo.Priority
+			m.input.SetValue(todo.Title)
+			m.input.CursorEnd()
+			return m, m.input.Focus()
+		}
+	case "f":
+		m.completed = (m.completed + 1) % 3
+		return m.reload()
+	case "p":
+		m.priority = nextPriority(priorityCycle, m.priority)
+		return m.reload()
+	case "r":
+		return m.reload()
+	}
+	return m, nil
+}
+
+func (m Model) updateForm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.mode = modeList
+		m.input.Blur()
+		return m, nil
+	case "tab":
+		m.formPriority = nextPriority(formPriorities, m.formPriority)
+		return m, nil
+	case "enter":
+		title := strings.TrimSpace(m.input.Value())
+		if title == "" {
+			m.err = errors.New("title is required")
+			return m, nil
+		}
+		m.err = nil
+		m.input.Blur()
+		cmd := m.save(m.mode, m.editID, title, m.formPriority)
+		m.mode = modeList
+		return m, cmd
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+func (m Model) reload() (tea.Model, tea.Cmd) {
+	m.loading = true
+	return m, m.load()
+}
+
+func (m Model) selected() *dto.TodoResponse {
+	if m.cursor < 0 || m.cursor >= len(m.todos) {
+		return nil
+	}
+	return m.todos[m.cursor]
+}
+
+// setTodos replaces the list and keeps the cursor on the same todo when it
+// is still visible.
+func (m *Model) setTodos(list *client.TodoList) {
+	var selectedID uint
+	if todo := m.selected(); todo != nil {
+		selectedID = todo.ID
+	}
+
+	m.todos = list.Todos
+	m.total = list.Meta.Total
+	for i, todo := range m.todos {
+		if todo.ID == selectedID {
+			m.cursor = i
+			return
+		}
+	}
+	m.cursor = min(m.cursor, max(len(m.todos)-1, 0))
+}
+
+func (m Model) listOptions() client.ListOptions {
+	opts := client.ListOptions{Priority: m.priority, Limit: pageSize}
+	switch m.completed {
+	case showOpen:
+		done := false
+		opts.Completed = &done
+	case showDone:
+		done := true
+		opts.Completed = &done
+	}
+	return opts
+}
+
+func (m Model) load() tea.Cmd {
+	opts := m.listOptions()
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+		list, err := m.api.List(ctx, opts)
+		return loadedMsg{list: list, err: err}
+	}
+}
+
+func (m Model) tick() tea.Cmd {
+	if m.refresh <= 0 {
+		return nil
+	}
+	return tea.Tick(m.refresh, func(time.Time) tea.Msg { return tickMsg{} })
+}
+
+func (m Model) toggle(id uint) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+		todo, err := m.api.Toggle(ctx, id)
+		if err != nil {
+			return savedMsg{err: err}
+		}
+		verb := "Reopened"
+		if todo.Completed {
+			verb = "Completed"
+		}
+		return savedMsg{verb: verb, todo: todo}
+	}
+}
+
+func (m Model) save(mode mode, id uint, title string, priority models.Priority) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+
+		if mode == modeCreate {
+			todo, err := m.api.Create(ctx, dto.CreateTodoRequest{Title: title, Priority: priority})
+			return savedMsg{verb: "Created", todo: todo, err: err}
+		}
+		todo, err := m.api.Update(ctx, id, dto.UpdateTodoRequest{Title: &title, Priority: &priority})
+		return savedMsg{verb: "Updated", todo: todo, err: err}
+	}
+}
+
+func nextPriority(cycle []models.Priority, current models.Priority) models.Priority {
+	for i, p := range cycle {
+		if p == current {
+			return cycle[(i+1)%len(cycle)]
+		}
+	}
+	return cycle[0]
+}
@@ -0,0 +1,191 @@
+package tui
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"todo-app/client"
+	"todo-app/dto"
+	"todo-app/models"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type fakeAPI struct {
+	todos  []*dto.TodoResponse
+	nextID uint
+}
+
+func (f *fakeAPI) List(_ context.Context, opts client.ListOptions) (*client.TodoList, error) {
+	list := &client.TodoList{}
+	for _, todo := range f.todos {
+		if opts.Completed != nil && todo.Completed != *opts.Completed {
+			continue
+		}
+		if opts.Priority != "" && todo.Priority != opts.Priority {
+			continue
+		}
+		copied := *todo
+		list.Todos = append(list.Todos, &copied)
+	}
+	list.Meta.Total = int64(len(list.Todos))
+	return list, nil
+}
+
+func (f *fakeAPI) Create(_ context.Context, req dto.CreateTodoRequest) (*dto.TodoResponse, error) {
+	f.nextID++
+	todo := &dto.TodoResponse{ID: f.nextID, Title: req.Title, Priority: req.Priority}
+	f.todos = append(f.todos, todo)
+	return todo, nil
+}
+
+func (f *fakeAPI) Update(_ context.Context, id uint, req dto.UpdateTodoRequest) (*dto.TodoResponse, error) {
+	todo := f.find(id)
+	if req.Title != nil {
+		todo.Title = *req.Title
+	}
+	if req.Priority != nil {
+		todo.Priority = *req.Priority
+	}
+	return todo, nil
+}
+
+func (f *fakeAPI) Toggle(_ context.Context, id uint) (*dto.TodoResponse, error) {
+	todo := f.find(id)
+	todo.Completed = !todo.Completed
+	return todo, nil
+}
+
+func (f *fakeAPI) find(id uint) *dto.TodoResponse {
+	for _, todo := range f.todos {
+		if todo.ID == id {
+			return todo
+		}
+	}
+	return nil
+}
+
+// send delivers msg and runs the resulting commands until the model is
+// idle. Commands that don't return promptly, like the text input's cursor
+// blink, are abandoned.
+func send(t *testing.T, m tea.Model, msg tea.Msg) tea.Model {
+	t.Helper()
+	m, cmd := m.Update(msg)
+	return drain(t, m, cmd)
+}
+
+func drain(t *testing.T, m tea.Model, cmd tea.Cmd) tea.Model {
+	t.Helper()
+	if cmd == nil {
+		return m
+	}
+	result := make(chan tea.Msg, 1)
+	go func() { result <- cmd() }()
+	var msg tea.Msg
+	select {
+	case msg = <-result:
+	case <-time.After(50 * time.Millisecond):
+		return m
+	}
+
+	switch msg := msg.(type) {
+	case tea.BatchMsg:
+		for _, c := range msg {
+			m = drain(t, m, c)
+		}
+		return m
+	case loadedMsg, savedMsg:
+		return send(t, m, msg)
+	default:
+		return m
+	}
+}
+
+func keys(t *testing.T, m tea.Model, ks ...string) tea.Model {
+	t.Helper()
+	for _, k := range ks {
+		var msg tea.KeyMsg
+		switch k {
+		case "enter":
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		case "tab":
+			msg = tea.KeyMsg{Type: tea.KeyTab}
+		case "esc":
+			msg = tea.KeyMsg{Type: tea.KeyEsc}
+		case "ctrl+u":
+			msg = tea.KeyMsg{Type: tea.KeyCtrlU}
+		case " ":
+			msg = tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}}
+		default:
+			msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+		}
+		m = send(t, m, msg)
+	}
+	return m
+}
+
+func TestCreateEditAndToggle(t *testing.T) {
+	api := &fakeAPI{}
+	var m tea.Model = New(api, Options{})
+	m = drain(t, m, m.Init())
+
+	// New todo: MEDIUM by default, tab moves to the next priority
+	m = keys(t, m, "n", "Buy milk", "tab", "enter")
+	if len(api.todos) != 1 || api.todos[0].Title != "Buy milk" || api.todos[0].Priority != models.HIGH {
+		t.Fatalf("unexpected todos after create: %+v", api.todos)
+	}
+	if view := m.View(); !strings.Contains(view, "Buy milk") || !strings.Contains(view, `Created "Buy milk"`) {
+		t.Errorf("view does not show the new todo:\n%s", view)
+	}
+
+	m = keys(t, m, "e", "ctrl+u", "Buy oat milk", "enter")
+	if api.todos[0].Title != "Buy oat milk" || api.todos[0].Priority != models.HIGH {
+		t.Errorf("unexpected todo after edit: %+v", api.todos[0])
+	}
+
+	m = keys(t, m, " ")
+	if !api.todos[0].Completed {
+		t.Error("space did not toggle the todo")
+	}
+
+	// An empty title is rejected without calling the API
+	m = keys(t, m, "n", "enter")
+	if len(api.todos) != 1 || !strings.Contains(m.View(), "title is required") {
+		t.Errorf("empty title was not rejected:\n%s", m.View())
+	}
+	keys(t, m, "esc")
+}
+
+func TestFiltersAndCursor(t *testing.T) {
+	api := &fakeAPI{todos: []*dto.TodoResponse{
+		{ID: 1, Title: "Open high", Priority: models.HIGH},
+		{ID: 2, Title: "Done low", Priority: models.LOW, Completed: true},
+		{ID: 3, Title: "Open low", Priority: models.LOW},
+	}}
+	var m tea.Model = New(api, Options{})
+	m = drain(t, m, m.Init())
+
+	m = keys(t, m, "j", "j", "j")
+	if got := m.(Model).selected().ID; got != 3 {
+		t.Errorf("cursor on %d, want 3", got)
+	}
+
+	// Filter to open todos: the cursor stays on todo 3
+	m = keys(t, m, "f")
+	model := m.(Model)
+	if len(model.todos) != 2 || model.selected().ID != 3 {
+		t.Errorf("unexpected open list %v, cursor %d", model.todos, model.cursor)
+	}
+
+	// Priority cycles all -> HIGH
+	m = keys(t, m, "p")
+	model = m.(Model)
+	if len(model.todos) != 1 || model.todos[0].ID != 1 || model.cursor != 0 {
+		t.Errorf("unexpected HIGH list %v, cursor %d", model.todos, model.cursor)
+	}
+	if view := m.View(); strings.Contains(view, "Open low") || !strings.Contains(view, "priority: HIGH") {
+		t.Errorf("view does not reflect filters:\n%s", view)
+	}
+}
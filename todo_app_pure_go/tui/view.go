@@ -0,0 +1,118 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"todo-app/dto"
+	"todo-app/models"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	titleStyle    = lipgloss.NewStyle().Bold(true)
+	dimStyle      = lipgloss.NewStyle().Faint(true)
+	doneStyle     = lipgloss.NewStyle().Faint(true).Strikethrough(true)
+	selectedStyle = lipgloss.NewStyle().Bold(true)
+	errorStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+
+	priorityStyles = map[models.Priority]lipgloss.Style{
+		models.HIGH:   lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Bold(true),
+		models.MEDIUM: lipgloss.NewStyle().Foreground(lipgloss.Color("11")),
+		models.LOW:    lipgloss.NewStyle().Foreground(lipgloss.Color("10")),
+	}
+)
+
+// chromeLines is the number of lines View uses around the list.
+const chromeLines = 5
+
+func (m Model) View() string {
+	var b strings.Builder
+
+	b.WriteString(titleStyle.Render("Todos"))
+	b.WriteString(dimStyle.Render(fmt.Sprintf("  showing: %s · priority: %s · %d of %d",
+		m.completed, priorityLabel(m.priority), len(m.todos), m.total)))
+	if m.loading {
+		b.WriteString(dimStyle.Render("  loading…"))
+	}
+	b.WriteString("\n\n")
+
+	if len(m.todos) == 0 && !m.loading {
+		b.WriteString(dimStyle.Render("  No todos. Press n to add one."))
+		b.WriteString("\n")
+	}
+	start, end := m.visibleRange()
+	for i := start; i < end; i++ {
+		b.WriteString(m.renderRow(m.todos[i], i == m.cursor))
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	switch m.mode {
+	case modeCreate, modeEdit:
+		label := "New todo: "
+		if m.mode == modeEdit {
+			label = "Edit todo: "
+		}
+		b.WriteString(label + m.input.View() + "  ")
+		b.WriteString(priorityStyles[m.formPriority].Render(string(m.formPriority)))
+		b.WriteString("\n")
+		b.WriteString(dimStyle.Render("enter save · tab priority · esc cancel"))
+	default:
+		switch {
+		case m.err != nil:
+			b.WriteString(errorStyle.Render("Error: " + m.err.Error()))
+		case m.status != "":
+			b.WriteString(m.status)
+		}
+		b.WriteString("\n")
+		b.WriteString(dimStyle.Render("↑/↓ move · space toggle · n new · e edit · f completed · p priority · r refresh · q quit"))
+	}
+	if m.mode != modeList && m.err != nil {
+		b.WriteString("\n" + errorStyle.Render("Error: "+m.err.Error()))
+	}
+	return b.String()
+}
+
+func (m Model) renderRow(todo *dto.TodoResponse, selected bool) string {
+	marker, check := "  ", "[ ]"
+	if selected {
+		marker = "› "
+	}
+	if todo.Completed {
+		check = "[x]"
+	}
+
+	priority := priorityStyles[todo.Priority].Render(fmt.Sprintf("%-6s", todo.Priority))
+	title := todo.Title
+	switch {
+	case todo.Completed:
+		title = doneStyle.Render(title)
+	case selected:
+		title = selectedStyle.Render(title)
+	}
+	return fmt.Sprintf("%s%s %s %s", marker, check, priority, title)
+}
+
+// visibleRange returns the slice of todos that fits the window with the
+// cursor in view. Before the first WindowSizeMsg everything is shown.
+func (m Model) visibleRange() (int, int) {
+	rows := m.height - chromeLines
+	if m.height == 0 || rows >= len(m.todos) {
+		return 0, len(m.todos)
+	}
+	rows = max(rows, 1)
+	start := 0
+	if m.cursor >= rows {
+		start = m.cursor - rows + 1
+	}
+	return start, start + rows
+}
+
+func priorityLabel(p models.Priority) string {
+	if p == "" {
+		return "all"
+	}
+	return string(p)
+}
@@ -23,6 +23,14 @@ func CreatedResponse(c *gin.Context, data interface{}, message string) {
 	})
 }
 
+func AcceptedResponse(c *gin.Context, data interface{}, message string) {
+	c.JSON(http.StatusAccepted, dto.APIResponse{
+		Success: true,
+		Message: message,
+		Data:    data,
+	})
+}
+
 func ErrorResponse(c *gin.Context, statusCode int, message string, err string) {
 	c.JSON(statusCode, dto.APIResponse{
 		Success: false,
@@ -52,6 +60,27 @@ func NotFoundResponse(c *gin.Context, message string) {
 	ErrorResponse(c, http.StatusNotFound, message, "Not Found")
 }
 
+func UnauthorizedResponse(c *gin.Context, message string) {
+	ErrorResponse(c, http.StatusUnauthorized, message, "Unauthorized")
+}
+
+func ForbiddenResponse(c *gin.Context, message string) {
+	ErrorResponse(c, http.StatusForbidden, message, "Forbidden")
+}
+
 func InternalServerErrorResponse(c *gin.Context, message string) {
 	ErrorResponse(c, http.StatusInternalServerError, message, "Internal Server Error")
 }
+
+func TooManyRequestsResponse(c *gin.Context, message string) {
+	ErrorResponse(c, http.StatusTooManyRequests, message, "Too Many Requests")
+}
+
+func UnprocessableEntityResponse(c *gin.Context, data interface{}, message string) {
+	c.JSON(http.StatusUnprocessableEntity, dto.APIResponse{
+		Success: false,
+		Message: message,
+		Data:    data,
+		Error:   "Unprocessable Entity",
+	})
+}
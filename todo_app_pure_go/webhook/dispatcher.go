@@ -0,0 +1,370 @@
+// Package webhook delivers todo lifecycle events to subscribed HTTP
+// endpoints, signing each request and retrying failures with exponential
+// backoff. Deliveries are persisted first, so pending retries survive a
+// restart.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"todo-app/events"
+	"todo-app/health"
+	"todo-app/models"
+	"todo-app/netguard"
+	"todo-app/repository"
+	"todo-app/tenant"
+)
+
+// Request headers sent with every delivery.
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderEventID   = "X-Webhook-Event-Id"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+const maxStoredBody = 1024
+
+type Options struct {
+	Workers             int
+	MaxAttempts         int
+	BaseDelay           time.Duration // delay before the first retry; doubled for each further one
+	MaxDelay            time.Duration
+	Timeout             time.Duration
+	PollInterval        time.Duration
+	AllowPrivateTargets bool // deliveries may connect to loopback, private and link-local addresses
+}
+
+type Dispatcher struct {
+	repo      repository.WebhookRepository
+	client    *http.Client
+	opts      Options
+	heartbeat *health.Heartbeat
+	now       func() time.Time
+
+	events chan events.Event
+	queue  chan uint
+
+	mu       sync.Mutex
+	inFlight map[uint]bool
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewDispatcher(repo repository.WebhookRepository, opts Options) *Dispatcher {
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	client := &http.Client{Timeout: opts.Timeout}
+	if !opts.AllowPrivateTargets {
+		// Check the address each delivery (and redirect) connects to, as a
+		// URL checked when its webhook was saved may resolve elsewhere now.
+		// A proxy would connect on the dialer's behalf, so none is used.
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = nil
+		transport.DialContext = (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control:   netguard.Control,
+		}).DialContext
+		client.Transport = transport
+	}
+	return &Dispatcher{
+		repo:      repo,
+		client:    client,
+		opts:      opts,
+		heartbeat: health.NewHeartbeat(3 * opts.PollInterval),
+		now:       time.Now,
+		events:    make(chan events.Event, 1024),
+		queue:     make(chan uint, 1024),
+		inFlight:  make(map[uint]bool),
+		stop:      make(chan struct{}),
+	}
+}
+
+// Heartbeat beats on every scheduler tick; register its check to detect a
+// stalled retry scheduler.
+func (d *Dispatcher) Heartbeat() *health.Heartbeat {
+	return d.heartbeat
+}
+
+// Handle accepts an event for delivery without blocking the caller. It is
+// meant to be subscribed to the event bus.
+func (d *Dispatcher) Handle(event events.Event) {
+	select {
+	case d.events <- event:
+	default:
+		log.Printf("webhook: event queue full, dropping %s %s", event.Type, event.ID)
+	}
+}
+
+// Enqueue schedules a pending delivery for an immediate attempt. If the
+// queue is full the scheduler picks the delivery up on a later tick.
+func (d *Dispatcher) Enqueue(deliveryID uint) {
+	d.mu.Lock()
+	if d.inFlight[deliveryID] {
+		d.mu.Unlock()
+		return
+	}
+	d.inFlight[deliveryID] = true
+	d.mu.Unlock()
+
+	select {
+	case d.queue <- deliveryID:
+	default:
+		d.done(deliveryID)
+	}
+}
+
+func (d *Dispatcher) done(deliveryID uint) {
+	d.mu.Lock()
+	delete(d.inFlight, deliveryID)
+	d.mu.Unlock()
+}
+
+// Start launches the recorder, the retry scheduler and the delivery workers.
+func (d *Dispatcher) Start() {
+	d.wg.Add(2 + d.opts.Workers)
+	go d.record()
+	go d.schedule()
+	for i := 0; i < d.opts.Workers; i++ {
+		go d.work()
+	}
+}
+
+// Stop waits for in-flight deliveries to finish or ctx to expire. Pending
+// deliveries stay in the database and are retried after the next start.
+func (d *Dispatcher) Stop(ctx context.Context) error {
+	close(d.stop)
+
+	finished := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// record turns events into pending deliveries for every matching webhook.
+func (d *Dispatcher) record() {
+	defer d.wg.Done()
+	for {
+		select {
+		case <-d.stop:
+			return
+		case event := <-d.events:
+			if err := d.recordEvent(event); err != nil {
+				log.Printf("webhook: failed to record %s %s: %v", event.Type, event.ID, err)
+			}
+		}
+	}
+}
+
+func (d *Dispatcher) recordEvent(event events.Event) error {
+	ctx := tenant.WithWorkspace(context.Background(), event.Workspace)
+	webhooks, err := d.repo.GetActive(ctx)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	now := d.now()
+	for _, webhook := range webhooks {
+		// Webhooks only hear about todos their owner can see
+		if !webhook.Subscribes(string(event.Type)) || !event.VisibleTo(webhook.WorkspaceID, webhook.Owner) {
+			continue
+		}
+		delivery, err := d.repo.CreateDelivery(ctx, &models.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventID:       event.ID,
+			EventType:     string(event.Type),
+			Payload:       string(payload),
+			Status:        models.DeliveryPending,
+			NextAttemptAt: &now,
+		})
+		if err != nil {
+			return err
+		}
+		d.Enqueue(delivery.ID)
+	}
+	return nil
+}
+
+// schedule periodically enqueues deliveries whose retry is due.
+func (d *Dispatcher) schedule() {
+	defer d.wg.Done()
+	ticker := time.NewTicker(d.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-ticker.C:
+			d.heartbeat.Beat()
+			// Retries are due in every workspace
+			due, err := d.repo.GetDueDeliveries(tenant.AllWorkspaces(context.Background()), d.now(), cap(d.queue))
+			if err != nil {
+				log.Printf("webhook: failed to load due deliveries: %v", err)
+				continue
+			}
+			for _, delivery := range due {
+				d.Enqueue(delivery.ID)
+			}
+		}
+	}
+}
+
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+	for {
+		select {
+		case <-d.stop:
+			return
+		case id := <-d.queue:
+			if err := d.deliver(id); err != nil {
+				log.Printf("webhook: delivery %d: %v", id, err)
+			}
+			d.done(id)
+		}
+	}
+}
+
+// deliver works across workspaces: a delivery ID was either just recorded
+// or found due by the scheduler, and its webhook is in its own workspace.
+func (d *Dispatcher) deliver(id uint) error {
+	ctx := tenant.AllWorkspaces(context.Background())
+	delivery, err := d.repo.GetDelivery(ctx, id)
+	if err != nil {
+		return err
+	}
+	if delivery.Status != models.DeliveryPending {
+		return nil
+	}
+
+	webhook, err := d.repo.GetByID(ctx, delivery.WebhookID)
+	if err != nil || !webhook.Active {
+		delivery.Status = models.DeliveryFailed
+		delivery.Error = "webhook was deleted or deactivated"
+		delivery.NextAttemptAt = nil
+		return d.repo.UpdateDelivery(ctx, delivery)
+	}
+
+	d.attempt(webhook, delivery)
+	return d.repo.UpdateDelivery(ctx, delivery)
+}
+
+// attempt sends the delivery once and records the outcome on it.
+func (d *Dispatcher) attempt(webhook *models.Webhook, delivery *models.WebhookDelivery) {
+	delivery.Attempts++
+	delivery.ResponseCode = 0
+	delivery.ResponseBody = ""
+	delivery.Error = ""
+
+	start := time.Now()
+	code, body, err := d.send(webhook, delivery)
+	delivery.DurationMs = time.Since(start).Milliseconds()
+	delivery.ResponseCode = code
+	delivery.ResponseBody = body
+
+	if err == nil && code >= 200 && code < 300 {
+		now := d.now()
+		delivery.Status = models.DeliverySucceeded
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+		return
+	}
+
+	if err != nil {
+		delivery.Error = err.Error()
+	} else {
+		delivery.Error = fmt.Sprintf("unexpected status %d", code)
+	}
+
+	if delivery.Attempts >= d.opts.MaxAttempts {
+		delivery.Status = models.DeliveryFailed
+		delivery.NextAttemptAt = nil
+		return
+	}
+	next := d.now().Add(d.backoff(delivery.Attempts))
+	delivery.NextAttemptAt = &next
+}
+
+func (d *Dispatcher) send(webhook *models.Webhook, delivery *models.WebhookDelivery) (int, string, error) {
+	payload := []byte(delivery.Payload)
+	timestamp := d.now().Unix()
+
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "todo-app-webhooks/1.0")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderEventID, delivery.EventID)
+	req.Header.Set(HeaderDelivery, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, timestamp, payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxStoredBody))
+	return resp.StatusCode, string(body), nil
+}
+
+// backoff returns the delay after the given number of failed attempts.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.opts.BaseDelay
+	for i := 1; i < attempts && delay < d.opts.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > d.opts.MaxDelay {
+		delay = d.opts.MaxDelay
+	}
+	return delay
+}
+
+// Sign returns the signature header value for a payload: the hex HMAC-SHA256
+// of "<timestamp>.<payload>" keyed with the webhook secret. Including the
+// timestamp lets receivers reject replayed requests.
+func Sign(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature produced by Sign in constant time.
+func Verify(secret string, timestamp int64, payload []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, payload)), []byte(signature))
+}
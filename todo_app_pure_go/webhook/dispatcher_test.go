@@ -0,0 +1,287 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"todo-app/dto"
+	"todo-app/events"
+	"todo-app/models"
+	"todo-app/repository"
+	"todo-app/service"
+	"todo-app/tenant"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// receiver is a local webhook endpoint that fails the first failures
+// requests and records everything it receives.
+type receiver struct {
+	mu       sync.Mutex
+	failures int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+
+	if rc.failures > 0 {
+		rc.failures--
+		http.Error(w, "try again", http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (rc *receiver) count() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return len(rc.requests)
+}
+
+// ctx is the workspace the webhooks and events of the tests belong to.
+var ctx = tenant.WithWorkspace(context.Background(), 1)
+
+// event returns an event of a todo in the workspace of ctx.
+func event(eventType events.Type, todo *dto.TodoResponse) events.Event {
+	e := events.New(eventType, todo)
+	e.Workspace = 1
+	return e
+}
+
+// setup starts a dispatcher. The receivers of the tests listen on loopback,
+// which only a dispatcher allowing private targets reaches.
+func setup(t *testing.T, maxAttempts int, allowPrivateTargets bool) (repository.WebhookRepository, *Dispatcher) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(tenant.Plugin{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.Webhook{}, &models.WebhookDelivery{}); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := repository.NewWebhookRepository(db)
+	d := NewDispatcher(repo, Options{
+		Workers:             2,
+		MaxAttempts:         maxAttempts,
+		BaseDelay:           10 * time.Millisecond,
+		MaxDelay:            40 * time.Millisecond,
+		Timeout:             time.Second,
+		PollInterval:        5 * time.Millisecond,
+		AllowPrivateTargets: allowPrivateTargets,
+	})
+	d.Start()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = d.Stop(ctx)
+	})
+	return repo, d
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func lastDelivery(t *testing.T, repo repository.WebhookRepository, webhookID uint) *models.WebhookDelivery {
+	t.Helper()
+	deliveries, _, err := repo.GetDeliveries(ctx, webhookID, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return deliveries[0]
+}
+
+func TestDeliverySignedAndFiltered(t *testing.T) {
+	repo, d := setup(t, 3, true)
+	rc := &receiver{}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	svc := service.NewWebhookService(repo, d, service.WebhookOptions{AllowPrivateTargets: true})
+	created, err := svc.CreateWebhook(ctx, "alice", &dto.CreateWebhookRequest{
+		URL:    server.URL,
+		Secret: "0123456789abcdef",
+		Events: []string{"todo.created", "todo.completed"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d.Handle(event(events.TodoDeleted, &dto.TodoResponse{ID: 1}))
+	// Events of another workspace never reach its webhooks
+	other := event(events.TodoCreated, &dto.TodoResponse{ID: 3})
+	other.Workspace = 2
+	d.Handle(other)
+	d.Handle(event(events.TodoCreated, &dto.TodoResponse{ID: 2, Title: "Ship it"}))
+
+	waitFor(t, "delivery", func() bool {
+		delivery := lastDelivery(t, repo, created.ID)
+		return delivery != nil && delivery.Status == models.DeliverySucceeded
+	})
+
+	if rc.count() != 1 {
+		t.Fatalf("expected only the subscribed event to be delivered, got %d requests", rc.count())
+	}
+
+	req, body := rc.requests[0], rc.bodies[0]
+	if req.Header.Get(HeaderEvent) != "todo.created" {
+		t.Errorf("unexpected event header %q", req.Header.Get(HeaderEvent))
+	}
+	timestamp, _ := strconv.ParseInt(req.Header.Get(HeaderTimestamp), 10, 64)
+	if !Verify("0123456789abcdef", timestamp, body, req.Header.Get(HeaderSignature)) {
+		t.Error("signature does not verify")
+	}
+	if Verify("wrong-secret-0000", timestamp, body, req.Header.Get(HeaderSignature)) {
+		t.Error("signature verifies with the wrong secret")
+	}
+
+	var event events.Event
+	if err := json.Unmarshal(body, &event); err != nil || event.Todo.Title != "Ship it" {
+		t.Errorf("unexpected payload %s: %v", body, err)
+	}
+
+	delivery := lastDelivery(t, repo, created.ID)
+	if delivery.ResponseCode != http.StatusNoContent || delivery.Attempts != 1 || delivery.DeliveredAt == nil {
+		t.Errorf("unexpected delivery log entry: %+v", delivery)
+	}
+}
+
+func TestRetriesWithBackoffUntilSuccess(t *testing.T) {
+	repo, d := setup(t, 5, true)
+	rc := &receiver{failures: 2}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	webhook, err := repo.Create(ctx, &models.Webhook{URL: server.URL, Secret: "s", Events: "todo.updated", Active: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d.Handle(event(events.TodoUpdated, &dto.TodoResponse{ID: 1}))
+
+	waitFor(t, "retried delivery", func() bool {
+		delivery := lastDelivery(t, repo, webhook.ID)
+		return delivery != nil && delivery.Status == models.DeliverySucceeded
+	})
+
+	delivery := lastDelivery(t, repo, webhook.ID)
+	if delivery.Attempts != 3 || rc.count() != 3 {
+		t.Errorf("expected 3 attempts, got %d (%d requests)", delivery.Attempts, rc.count())
+	}
+}
+
+func TestGivesUpAfterMaxAttemptsAndRedelivers(t *testing.T) {
+	repo, d := setup(t, 2, true)
+	rc := &receiver{failures: 2}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	svc := service.NewWebhookService(repo, d, service.WebhookOptions{AllowPrivateTargets: true})
+	created, err := svc.CreateWebhook(ctx, "alice", &dto.CreateWebhookRequest{URL: server.URL, Events: []string{"todo.deleted"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Secret == "" {
+		t.Error("expected a generated secret")
+	}
+
+	d.Handle(event(events.TodoDeleted, &dto.TodoResponse{ID: 1}))
+
+	waitFor(t, "failed delivery", func() bool {
+		delivery := lastDelivery(t, repo, created.ID)
+		return delivery != nil && delivery.Status == models.DeliveryFailed
+	})
+	failed := lastDelivery(t, repo, created.ID)
+	if failed.Attempts != 2 || failed.ResponseCode != http.StatusServiceUnavailable || failed.ResponseBody != "try again\n" {
+		t.Errorf("unexpected failed delivery: %+v", failed)
+	}
+
+	if _, err := svc.Redeliver(ctx, "mallory", created.ID, failed.ID); err == nil || err.Error() != "webhook not found" {
+		t.Errorf("other owners must not redeliver, got %v", err)
+	}
+
+	redelivery, err := svc.Redeliver(ctx, "alice", created.ID, failed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "redelivery", func() bool {
+		delivery, err := repo.GetDelivery(ctx, redelivery.ID)
+		return err == nil && delivery.Status == models.DeliverySucceeded
+	})
+
+	delivery, _ := repo.GetDelivery(ctx, redelivery.ID)
+	if delivery.RedeliveryOf == nil || *delivery.RedeliveryOf != failed.ID || delivery.Payload != failed.Payload {
+		t.Errorf("unexpected redelivery: %+v", delivery)
+	}
+}
+
+func TestRefusesPrivateTargets(t *testing.T) {
+	repo, d := setup(t, 1, false)
+	rc := &receiver{}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	svc := service.NewWebhookService(repo, d, service.WebhookOptions{})
+	for _, url := range []string{server.URL, "http://169.254.169.254/latest/meta-data", "http://[::1]:8080/hook"} {
+		if _, err := svc.CreateWebhook(ctx, "alice", &dto.CreateWebhookRequest{URL: url, Events: []string{"todo.deleted"}}); err == nil || !strings.HasPrefix(err.Error(), "validation failed") {
+			t.Errorf("webhook to %s: %v", url, err)
+		}
+	}
+
+	// A public name that resolves to loopback by the time of delivery is
+	// refused when connecting
+	webhook, err := repo.Create(ctx, &models.Webhook{Owner: "alice", URL: server.URL, Secret: "0123456789abcdef", Events: "todo.deleted", Active: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Handle(event(events.TodoDeleted, &dto.TodoResponse{ID: 1}))
+
+	waitFor(t, "failed delivery", func() bool {
+		delivery := lastDelivery(t, repo, webhook.ID)
+		return delivery != nil && delivery.Status == models.DeliveryFailed
+	})
+	if delivery := lastDelivery(t, repo, webhook.ID); !strings.Contains(delivery.Error, "not public") || rc.count() != 0 {
+		t.Errorf("delivery reached %d requests: %+v", rc.count(), delivery)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := &Dispatcher{opts: Options{BaseDelay: time.Second, MaxDelay: 5 * time.Second}}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, w := range want {
+		if got := d.backoff(i + 1); got != w {
+			t.Errorf("attempt %d: expected %s, got %s", i+1, w, got)
+		}
+	}
+}
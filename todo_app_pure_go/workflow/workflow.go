@@ -0,0 +1,208 @@
+// Package workflow defines the statuses a todo moves through, such as
+// backlog → in_progress → review → done, and which moves between them are
+// allowed. A todo is completed while its status is one of the done
+// statuses; the completed flag is derived from the status.
+package workflow
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"todo-app/models"
+)
+
+// Defaults are the statuses, done statuses and transitions used when none
+// are configured.
+var (
+	DefaultStatuses    = []string{"backlog", "in_progress", "review", "done"}
+	DefaultDone        = []string{"done"}
+	DefaultTransitions = []string{"backlog->in_progress", "in_progress->backlog", "in_progress->review", "review->in_progress", "review->done", "done->in_progress"}
+)
+
+// validName limits status names to what fits the status column.
+var validName = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+// Status is one step of a workflow.
+type Status struct {
+	Name string
+	// Done marks statuses in which a todo counts as completed
+	Done bool
+	// Next lists the statuses a todo may move to from this one
+	Next []string
+}
+
+// Workflow is an immutable set of statuses and the transitions between
+// them. The first status is the one new and reopened todos start in.
+type Workflow struct {
+	statuses []Status
+	index    map[string]int
+}
+
+// New builds a workflow from status names in order, the names of the done
+// statuses and transitions written "from->to". Either side of a transition
+// may be * for every status.
+func New(statuses, done, transitions []string) (*Workflow, error) {
+	w := &Workflow{index: make(map[string]int, len(statuses))}
+	for _, name := range statuses {
+		name = strings.TrimSpace(name)
+		if !validName.MatchString(name) {
+			return nil, fmt.Errorf("status %q must be lowercase letters, digits and underscores, at most 32 long", name)
+		}
+		if _, ok := w.index[name]; ok {
+			return nil, fmt.Errorf("status %q is listed twice", name)
+		}
+		w.index[name] = len(w.statuses)
+		w.statuses = append(w.statuses, Status{Name: name})
+	}
+	if len(w.statuses) < 2 {
+		return nil, fmt.Errorf("at least two statuses are required")
+	}
+
+	for _, name := range done {
+		i, ok := w.index[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("done status %q is not a status", name)
+		}
+		w.statuses[i].Done = true
+	}
+	if w.statuses[0].Done {
+		return nil, fmt.Errorf("the first status %q must not be a done status", w.statuses[0].Name)
+	}
+	if w.Done() == "" {
+		return nil, fmt.Errorf("at least one done status is required")
+	}
+
+	for _, transition := range transitions {
+		from, to, ok := strings.Cut(transition, "->")
+		if !ok {
+			return nil, fmt.Errorf("transition %q must be written from->to", transition)
+		}
+		froms, err := w.expand(strings.TrimSpace(from))
+		if err != nil {
+			return nil, fmt.Errorf("transition %q: %w", transition, err)
+		}
+		tos, err := w.expand(strings.TrimSpace(to))
+		if err != nil {
+			return nil, fmt.Errorf("transition %q: %w", transition, err)
+		}
+		for _, f := range froms {
+			for _, t := range tos {
+				if f != t && !w.Allows(f, t) {
+					w.statuses[w.index[f]].Next = append(w.statuses[w.index[f]].Next, t)
+				}
+			}
+		}
+	}
+	return w, nil
+}
+
+// Default returns the built-in workflow.
+func Default() *Workflow {
+	w, err := New(DefaultStatuses, DefaultDone, DefaultTransitions)
+	if err != nil {
+		panic(err)
+	}
+	return w
+}
+
+func (w *Workflow) expand(name string) ([]string, error) {
+	if name == "*" {
+		names := make([]string, len(w.statuses))
+		for i, s := range w.statuses {
+			names[i] = s.Name
+		}
+		return names, nil
+	}
+	if !w.Has(name) {
+		return nil, fmt.Errorf("unknown status %q", name)
+	}
+	return []string{name}, nil
+}
+
+// Statuses returns the statuses in order.
+func (w *Workflow) Statuses() []Status {
+	statuses := make([]Status, len(w.statuses))
+	for i, s := range w.statuses {
+		s.Next = append([]string{}, s.Next...)
+		statuses[i] = s
+	}
+	return statuses
+}
+
+// Initial returns the status new and reopened todos are in.
+func (w *Workflow) Initial() string {
+	return w.statuses[0].Name
+}
+
+// Done returns the status todos are completed into.
+func (w *Workflow) Done() string {
+	for _, s := range w.statuses {
+		if s.Done {
+			return s.Name
+		}
+	}
+	return ""
+}
+
+// Has reports whether the workflow has a status called name.
+func (w *Workflow) Has(name string) bool {
+	_, ok := w.index[name]
+	return ok
+}
+
+// IsDone reports whether todos in the status count as completed.
+func (w *Workflow) IsDone(name string) bool {
+	i, ok := w.index[name]
+	return ok && w.statuses[i].Done
+}
+
+// Allows reports whether a todo may move from one status to another.
+// Todos in a status the workflow no longer has may move to any status.
+func (w *Workflow) Allows(from, to string) bool {
+	if !w.Has(to) {
+		return false
+	}
+	i, ok := w.index[from]
+	if !ok {
+		return true
+	}
+	for _, next := range w.statuses[i].Next {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Order returns the position of the status in the workflow; statuses it
+// does not have come last.
+func (w *Workflow) Order(name string) int {
+	if i, ok := w.index[name]; ok {
+		return i
+	}
+	return len(w.statuses)
+}
+
+// Move puts the todo in status, completing or reopening it to match. It
+// does not check the transition; see Allows.
+func (w *Workflow) Move(todo *models.Todo, status string, at time.Time) {
+	todo.SetStatus(status, w.IsDone(status), at)
+}
+
+// SetCompleted completes or reopens the todo the way the completed flag
+// always has: a completed todo moves to the done status and a reopened one
+// back to the initial status, whatever the transitions allow. A todo whose
+// completion does not change keeps its status; one without a status gets
+// the status matching its completion.
+func (w *Workflow) SetCompleted(todo *models.Todo, completed bool, at time.Time) {
+	if todo.Status != "" && todo.Completed == completed {
+		return
+	}
+	if completed {
+		w.Move(todo, w.Done(), at)
+	} else {
+		w.Move(todo, w.Initial(), at)
+	}
+}
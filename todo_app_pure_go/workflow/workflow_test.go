@@ -0,0 +1,98 @@
+package workflow
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"todo-app/models"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name        string
+		statuses    []string
+		done        []string
+		transitions []string
+		err         string
+	}{
+		{"default", DefaultStatuses, DefaultDone, DefaultTransitions, ""},
+		{"wildcards", []string{"open", "closed"}, []string{"closed"}, []string{"*->*"}, ""},
+		{"one status", []string{"done"}, []string{"done"}, nil, "at least two statuses"},
+		{"bad name", []string{"To Do", "done"}, []string{"done"}, nil, `status "To Do"`},
+		{"duplicate", []string{"open", "done", "open"}, []string{"done"}, nil, "listed twice"},
+		{"unknown done", []string{"open", "done"}, []string{"closed"}, nil, `done status "closed"`},
+		{"no done", []string{"open", "done"}, nil, nil, "at least one done status"},
+		{"done first", []string{"done", "open"}, []string{"done"}, nil, "must not be a done status"},
+		{"bad transition", []string{"open", "done"}, []string{"done"}, []string{"open=>done"}, "must be written from->to"},
+		{"unknown transition", []string{"open", "done"}, []string{"done"}, []string{"open->closed"}, `unknown status "closed"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.statuses, tt.done, tt.transitions)
+			if tt.err == "" && err != nil {
+				t.Fatal(err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestAllows(t *testing.T) {
+	w, err := New([]string{"todo", "doing", "done", "dropped"}, []string{"done", "dropped"}, []string{"todo->doing", "doing -> done", "*->dropped", "dropped->todo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		from, to string
+		want     bool
+	}{
+		{"todo", "doing", true},
+		{"doing", "done", true},
+		{"todo", "done", false},
+		{"done", "doing", false},
+		{"done", "dropped", true},
+		{"dropped", "dropped", false},
+		{"dropped", "todo", true},
+		{"todo", "archived", false},
+		// Todos in statuses that were removed may go anywhere
+		{"review", "done", true},
+	} {
+		if got := w.Allows(tt.from, tt.to); got != tt.want {
+			t.Errorf("Allows(%s, %s) = %v", tt.from, tt.to, got)
+		}
+	}
+	if w.Initial() != "todo" || w.Done() != "done" || !w.IsDone("dropped") || w.IsDone("doing") {
+		t.Errorf("initial %s, done %s", w.Initial(), w.Done())
+	}
+}
+
+func TestSetCompleted(t *testing.T) {
+	w := Default()
+	start := time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)
+	later := start.Add(time.Hour)
+
+	todo := &models.Todo{}
+	w.SetCompleted(todo, false, start)
+	if todo.Status != "backlog" || todo.Completed || !todo.StatusChangedAt.Equal(start) {
+		t.Fatalf("new todo = %+v", todo)
+	}
+
+	// Completion that does not change keeps the status and its time
+	w.Move(todo, "review", start)
+	w.SetCompleted(todo, false, later)
+	if todo.Status != "review" || !todo.StatusChangedAt.Equal(start) {
+		t.Errorf("open todo moved to %s at %v", todo.Status, todo.StatusChangedAt)
+	}
+
+	w.SetCompleted(todo, true, later)
+	if todo.Status != "done" || !todo.Completed || !todo.CompletedAt.Equal(later) || !todo.StatusChangedAt.Equal(later) {
+		t.Errorf("completed todo = %+v", todo)
+	}
+	w.SetCompleted(todo, false, later)
+	if todo.Status != "backlog" || todo.Completed || todo.CompletedAt != nil {
+		t.Errorf("reopened todo = %+v", todo)
+	}
+}